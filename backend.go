@@ -1,4 +1,4 @@
-//go:build !linux && !darwin && !mock
+//go:build !linux && !darwin && !mock && !replay
 
 package main
 
@@ -1,4 +1,4 @@
-//go:build darwin && !mock
+//go:build darwin && !mock && !replay
 
 package main
 
@@ -1,4 +1,4 @@
-//go:build linux && !mock
+//go:build linux && !mock && !replay
 
 package main
 
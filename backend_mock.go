@@ -1,12 +1,24 @@
-//go:build mock
+//go:build mock && !replay
 
 package main
 
 import (
+	"os"
+
 	"github.com/shazow/wifitui/wifi"
 	mockBackend "github.com/shazow/wifitui/wifi/mock"
 )
 
+// GetBackend returns the mock backend, with the scenario in
+// WIFITUI_MOCK_SCENARIO when it is set.
 func GetBackend() (wifi.Backend, error) {
-	return mockBackend.New()
+	path := os.Getenv("WIFITUI_MOCK_SCENARIO")
+	if path == "" {
+		return mockBackend.New()
+	}
+	scenario, err := mockBackend.LoadScenario(path)
+	if err != nil {
+		return nil, err
+	}
+	return mockBackend.NewFromScenario(scenario)
 }
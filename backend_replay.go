@@ -0,0 +1,20 @@
+//go:build replay
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/trace"
+)
+
+// GetBackend replays the trace in WIFITUI_REPLAY, as recorded with --record.
+func GetBackend() (wifi.Backend, error) {
+	path := os.Getenv("WIFITUI_REPLAY")
+	if path == "" {
+		return nil, errors.New("WIFITUI_REPLAY must be set to a trace recorded with --record")
+	}
+	return trace.Load(path)
+}
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shazow/wifitui/internal/connectivity"
 	"github.com/shazow/wifitui/internal/helpers"
+	"github.com/shazow/wifitui/internal/profiles"
 	"github.com/shazow/wifitui/internal/tui"
+	"github.com/shazow/wifitui/qrwifi"
 	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/remote"
 )
 
 func runTUI(b wifi.Backend) error {
@@ -34,9 +45,18 @@ func formatNetwork(c wifi.Network) string {
 	if c.IsSecure {
 		parts = append(parts, "secure")
 	}
+	if c.Security != wifi.SecurityUnknown {
+		parts = append(parts, c.Security.String())
+	}
+	if bands := c.Bands(); len(bands) > 0 {
+		parts = append(parts, helpers.FormatBands(bands))
+	}
 	if c.IsActive {
 		parts = append(parts, "active")
 	}
+	if c.IsKnown && c.Priority != 0 {
+		parts = append(parts, fmt.Sprintf("priority %d", c.Priority))
+	}
 
 	return strings.Join(parts, ", ")
 }
@@ -48,6 +68,17 @@ func writeJSON(w io.Writer, v any) error {
 	return enc.Encode(v)
 }
 
+// filterNetworksByBand returns only the networks seen on any of the bands.
+func filterNetworksByBand(networks []wifi.Network, bands []wifi.Band) []wifi.Network {
+	var filtered []wifi.Network
+	for _, c := range networks {
+		if slices.ContainsFunc(bands, c.HasBand) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
 // filterVisibleNetworks returns only the networks that are currently visible.
 func filterVisibleNetworks(networks []wifi.Network) []wifi.Network {
 	var visible []wifi.Network
@@ -70,6 +101,56 @@ func findNetworkBySSID(networks []wifi.Network, ssid string) (wifi.Network, bool
 	return wifi.Network{}, false
 }
 
+// runQR writes a QR code to join the network ssid, as text for terminals or
+// as a PNG or SVG image. Text falls back to the WIFI: string when the code is
+// larger than width by height cells; zero sizes are not checked.
+func runQR(w io.Writer, ssid, format string, size, width, height int, b wifi.Backend) error {
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	c, found := findNetworkBySSID(result.Networks, ssid)
+	if !found {
+		return fmt.Errorf("network not found: %s: %w", ssid, wifi.ErrNotFound)
+	}
+	security := c.Security
+	if security.IsEnterprise() {
+		// Don't fetch an account password only to refuse to share it.
+		return fmt.Errorf("%s networks cannot be shared with a QR code: %w", security, wifi.ErrNotSupported)
+	}
+	if security == wifi.SecurityUnknown && c.IsSecure {
+		security = wifi.SecurityWPA
+	}
+	var secret string
+	if security.RequiresPassphrase() {
+		if secret, err = b.GetSecrets(ssid); err != nil {
+			return fmt.Errorf("failed to get network secret: %w", err)
+		}
+	}
+	content, err := qrwifi.WifiStringForSecurity(c.SSID, secret, security, c.IsHidden)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "png":
+		return qrwifi.WritePNG(w, content, size)
+	case "svg":
+		return qrwifi.WriteSVG(w, content)
+	}
+	modules, err := qrwifi.Modules(content)
+	if err != nil {
+		return err
+	}
+	qrWidth, qrHeight := qrwifi.HalfBlockSize(modules)
+	if (width > 0 && qrWidth > width) || (height > 0 && qrHeight > height) {
+		_, err = fmt.Fprintln(w, content)
+		return err
+	}
+	_, err = fmt.Fprintln(w, qrwifi.RenderHalfBlocks(modules))
+	return err
+}
+
 // writeNetworkDetails writes human-readable details for a network to w.
 func writeNetworkDetails(w io.Writer, c wifi.Network, secret string) error {
 	var writeErr error
@@ -84,44 +165,146 @@ func writeNetworkDetails(w io.Writer, c wifi.Network, secret string) error {
 	write("Active: %t\n", c.IsActive)
 	write("Known: %t\n", c.IsKnown)
 	write("Secure: %t\n", c.IsSecure)
+	write("Security: %s\n", c.Security)
 	write("Visible: %t\n", c.IsVisible)
 	write("Hidden: %t\n", c.IsHidden)
+	if c.IsKnown {
+		write("Priority: %d\n", c.Priority)
+	}
 	write("Strength: %d%%\n", c.Strength())
 	if c.LastConnected != nil {
 		write("Last Connected: %s\n", helpers.FormatDuration(*c.LastConnected))
 	}
+	if c.IPv4 != nil {
+		write("IPv4: %s\n", formatIPConfig(c.IPv4))
+	}
+	if c.IPv6 != nil {
+		write("IPv6: %s\n", formatIPConfig(c.IPv6))
+	}
+	if c.LockedBSSID != "" {
+		write("Locked BSSID: %s\n", c.LockedBSSID)
+	}
+	if bands := c.Bands(); len(bands) > 0 {
+		write("Bands: %s\n", helpers.FormatBands(bands))
+	}
+	if c.PreferredBand != wifi.BandUnknown {
+		write("Preferred Band: %s\n", helpers.FormatBandPreference(c.PreferredBand, c.PreferredChannel))
+	}
 	return writeErr
 }
 
-func runList(w io.Writer, errW io.Writer, jsonOut bool, all bool, scan bool, b wifi.Backend) error {
+// formatIPConfig summarizes an IP configuration on one line, such as
+// "manual 192.168.1.10/24 via 192.168.1.1, dns 1.1.1.1".
+func formatIPConfig(c *wifi.IPConfig) string {
+	parts := []string{string(c.Method)}
+	for _, prefix := range c.Addresses {
+		parts = append(parts, prefix.String())
+	}
+	if c.Gateway.IsValid() {
+		parts = append(parts, "via", c.Gateway.String())
+	}
+	s := strings.Join(parts, " ")
+	if len(c.DNS) > 0 {
+		var dns []string
+		for _, addr := range c.DNS {
+			dns = append(dns, addr.String())
+		}
+		s += ", dns " + strings.Join(dns, " ")
+	}
+	if len(c.DNSSearch) > 0 {
+		s += ", search " + strings.Join(c.DNSSearch, " ")
+	}
+	return s
+}
+
+func runList(w io.Writer, errW io.Writer, jsonOut bool, all bool, scan bool, bands []wifi.Band, b wifi.Backend) error {
+	networks, err := listNetworks(errW, all, scan, bands, b)
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		return writeJSON(w, networks)
+	}
+
+	for _, c := range networks {
+		fmt.Fprintf(w, "%s\t%s\n", c.SSID, formatNetwork(c))
+	}
+	return nil
+}
+
+// deviceNetworks are the networks seen by one wireless device.
+type deviceNetworks struct {
+	Interface       string         `json:"interface"`
+	HardwareAddress string         `json:"hardware_address,omitempty"`
+	Networks        []wifi.Network `json:"networks"`
+}
+
+// runListAllInterfaces lists the networks of every wireless device, grouped
+// by device. The selected device is restored afterwards.
+func runListAllInterfaces(w io.Writer, errW io.Writer, jsonOut bool, all bool, scan bool, bands []wifi.Band, b wifi.Backend) error {
+	devices, err := b.Devices()
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	if selected, ok := wifi.SelectedDevice(devices); ok {
+		defer b.SelectDevice(selected.Interface)
+	}
+
+	results := make([]deviceNetworks, 0, len(devices))
+	for _, device := range devices {
+		if err := b.SelectDevice(device.Interface); err != nil {
+			return fmt.Errorf("failed to select %s: %w", device.Interface, err)
+		}
+		networks, err := listNetworks(errW, all, scan, bands, b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", device.Interface, err)
+		}
+		results = append(results, deviceNetworks{Interface: device.Interface, HardwareAddress: device.HardwareAddress, Networks: networks})
+	}
+
+	if jsonOut {
+		return writeJSON(w, results)
+	}
+
+	for i, result := range results {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s:\n", result.Interface)
+		for _, c := range result.Networks {
+			fmt.Fprintf(w, "  %s\t%s\n", c.SSID, formatNetwork(c))
+		}
+	}
+	return nil
+}
+
+// listNetworks fetches and filters the networks for the list command,
+// reporting a failed scan on errW.
+func listNetworks(errW io.Writer, all bool, scan bool, bands []wifi.Band, b wifi.Backend) ([]wifi.Network, error) {
 	scanMode := wifi.ScanNever
 	if scan {
 		scanMode = wifi.ScanForce
 	}
 	result, err := b.ListNetworks(scanMode)
 	if err != nil {
-		return fmt.Errorf("failed to list networks: %w", err)
+		return nil, fmt.Errorf("failed to list networks: %w", err)
 	}
 	networks := result.Networks
 
 	if !all {
 		networks = filterVisibleNetworks(networks)
 	}
+	if len(bands) > 0 {
+		networks = filterNetworksByBand(networks, bands)
+	}
 
 	if result.ScanError != nil {
 		if _, err := fmt.Fprintf(errW, "Scan failed: %s\n", helpers.FormatScanFailure(result.ScanError)); err != nil {
-			return fmt.Errorf("failed to write scan diagnostic: %w", err)
+			return nil, fmt.Errorf("failed to write scan diagnostic: %w", err)
 		}
 	}
-
-	if jsonOut {
-		return writeJSON(w, networks)
-	}
-
-	for _, c := range networks {
-		fmt.Fprintf(w, "%s\t%s\n", c.SSID, formatNetwork(c))
-	}
-	return nil
+	return networks, nil
 }
 
 func runShow(w io.Writer, jsonOut bool, ssid string, b wifi.Backend) error {
@@ -146,19 +329,41 @@ func runShow(w io.Writer, jsonOut bool, ssid string, b wifi.Backend) error {
 		secret = "" // No secret available
 	}
 
+	var details *wifi.ConnectionDetails
+	if c.IsActive {
+		details, err = b.ActiveConnection()
+		if err != nil {
+			return fmt.Errorf("failed to get connection details: %w", err)
+		}
+		if details != nil && details.SSID != c.SSID {
+			details = nil
+		}
+	}
+
 	if jsonOut {
 		// We need a custom struct to include the passphrase
 		type networkWithSecret struct {
 			wifi.Network
-			Passphrase string `json:"passphrase,omitempty"`
+			Passphrase string                  `json:"passphrase,omitempty"`
+			Connection *wifi.ConnectionDetails `json:"connection,omitempty"`
 		}
-		return writeJSON(w, networkWithSecret{Network: c, Passphrase: secret})
+		return writeJSON(w, networkWithSecret{Network: c, Passphrase: secret, Connection: details})
 	}
 
-	return writeNetworkDetails(w, c, secret)
+	if err := writeNetworkDetails(w, c, secret); err != nil {
+		return err
+	}
+	if details != nil {
+		for _, field := range helpers.ConnectionDetailFields(*details) {
+			if _, err := fmt.Fprintf(w, "%s: %s\n", field.Label, field.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func attemptConnect(ssid string, passphrase string, security wifi.SecurityType, isHidden bool, shouldScan bool, b wifi.Backend) error {
+func attemptConnect(ssid string, opts wifi.JoinOptions, shouldScan bool, b wifi.Backend) error {
 	// Populate the backend's internal state (e.g. NetworkManager's saved profiles
 	// and access point caches).
 	// ActivateNetwork and JoinNetwork rely on this state being present.
@@ -172,10 +377,23 @@ func attemptConnect(ssid string, passphrase string, security wifi.SecurityType,
 	}
 
 	var connectErr error
-	if passphrase != "" || isHidden {
-		connectErr = b.JoinNetwork(ssid, passphrase, security, isHidden)
+	if opts.Password != "" || opts.IsHidden || opts.Enterprise != nil {
+		connectErr = b.JoinNetwork(ssid, opts)
 	} else {
-		connectErr = b.ActivateNetwork(ssid)
+		if opts.IPv4 != nil || opts.IPv6 != nil || opts.LockBSSID {
+			update := wifi.UpdateOptions{IPv4: opts.IPv4, IPv6: opts.IPv6}
+			if opts.LockBSSID {
+				update.BSSID = &opts.BSSID
+			}
+			if err := b.UpdateNetwork(ssid, update); err != nil {
+				return fmt.Errorf("failed to update network: %w", err)
+			}
+		}
+		if opts.BSSID != "" {
+			connectErr = b.ActivateAccessPoint(ssid, opts.BSSID)
+		} else {
+			connectErr = b.ActivateNetwork(ssid)
+		}
 	}
 	if connectErr != nil && result.ScanError != nil {
 		return fmt.Errorf("connection failed after scan failure: %w; scan failure: %w", connectErr, result.ScanError)
@@ -191,14 +409,14 @@ type RetryConfig struct {
 	Interval time.Duration
 }
 
-func runConnect(w io.Writer, ssid string, passphrase string, security wifi.SecurityType, isHidden bool, retry RetryConfig, b wifi.Backend) error {
+func runConnect(w io.Writer, ssid string, opts wifi.JoinOptions, retry RetryConfig, b wifi.Backend) error {
 	start := time.Now()
 	shouldScan := false
 
 	for {
 		fmt.Fprintf(w, "Connecting to network %q with scan=%v...\n", ssid, shouldScan)
 
-		err := attemptConnect(ssid, passphrase, security, isHidden, shouldScan, b)
+		err := attemptConnect(ssid, opts, shouldScan, b)
 		if err == nil {
 			return nil
 		}
@@ -220,6 +438,209 @@ func runConnect(w io.Writer, ssid string, passphrase string, security wifi.Secur
 	}
 }
 
+// errCaptivePortal and errLimitedConnectivity are returned when a network was
+// joined but does not reach the internet.
+var (
+	errCaptivePortal       = errors.New("captive portal requires login")
+	errLimitedConnectivity = errors.New("network does not reach the internet")
+)
+
+// errWaitOnlineTimeout is returned when the network did not get an address
+// in time.
+var errWaitOnlineTimeout = errors.New("timed out waiting for an IP address")
+
+// waitOnlineInterval is how often --wait-online checks the connection.
+var waitOnlineInterval = time.Second
+
+// ConnectCheck configures what connect confirms after the network is
+// activated.
+type ConnectCheck struct {
+	// Skip disables the connectivity check. With WaitOnline, only an
+	// address is waited for.
+	Skip bool
+	// URL is probed where the backend cannot check connectivity itself.
+	URL string
+	// OpenPortal opens the login page of a captive portal in a browser.
+	OpenPortal bool
+	// WaitOnline is how long to wait for an address and connectivity. Zero
+	// checks connectivity once without waiting.
+	WaitOnline time.Duration
+}
+
+// connectResult describes the outcome of connect for --json.
+type connectResult struct {
+	SSID          string            `json:"ssid"`
+	Connected     bool              `json:"connected"`
+	Connectivity  wifi.Connectivity `json:"connectivity,omitempty"`
+	PortalURL     string            `json:"portal_url,omitempty"`
+	Interface     string            `json:"interface,omitempty"`
+	IPv4Addresses []netip.Prefix    `json:"ipv4_addresses,omitempty"`
+	IPv6Addresses []netip.Prefix    `json:"ipv6_addresses,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	ExitCode      int               `json:"exit_code"`
+}
+
+// runConnectCommand connects to ssid and confirms the connection as check
+// asks. With jsonOut, progress is not reported and a connectResult is
+// written instead, whether connecting succeeded or not.
+func runConnectCommand(w io.Writer, jsonOut bool, ssid string, opts wifi.JoinOptions, retry RetryConfig, check ConnectCheck, b wifi.Backend) error {
+	progress := w
+	if jsonOut {
+		progress = io.Discard
+	}
+	result := connectResult{SSID: ssid}
+	err := runConnect(progress, ssid, opts, retry, b)
+	if err == nil {
+		result.Connected = true
+		if check.WaitOnline > 0 {
+			err = runWaitOnline(progress, ssid, check, b, &result)
+		} else if !check.Skip {
+			err = runConnectivityCheck(progress, check.URL, check.OpenPortal, b, &result)
+		}
+	}
+	if !jsonOut {
+		return err
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.ExitCode = exitCode(err)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(result); encodeErr != nil {
+		return encodeErr
+	}
+	return err
+}
+
+// readWifiURI parses the WIFI: URI given with --uri, or decodes it from the
+// QR code in the image at qrImage, where - reads the image from stdin.
+func readWifiURI(uri, qrImage string) (qrwifi.WifiConfig, error) {
+	if qrImage != "" {
+		var r io.Reader = os.Stdin
+		if qrImage != "-" {
+			f, err := os.Open(qrImage)
+			if err != nil {
+				return qrwifi.WifiConfig{}, err
+			}
+			defer f.Close()
+			r = f
+		}
+		var err error
+		if uri, err = qrwifi.DecodeQRCode(r); err != nil {
+			return qrwifi.WifiConfig{}, fmt.Errorf("%s: %w", qrImage, err)
+		}
+	}
+	return qrwifi.ParseWifiURI(uri)
+}
+
+// runConnectivityCheck reports whether the active connection reaches the
+// internet into result, and opens the login page of a captive portal if
+// openPortal is set. Nothing is reported when connectivity cannot be
+// checked.
+func runConnectivityCheck(w io.Writer, url string, openPortal bool, b wifi.Backend, result *connectResult) error {
+	checked, err := connectivity.Check(context.Background(), b, url)
+	if err != nil {
+		// The connection is up either way, and connectivity stays unknown.
+		return nil
+	}
+	result.Connectivity = checked.State
+	result.PortalURL = checked.PortalURL
+	switch checked.State {
+	case wifi.ConnectivityFull:
+		fmt.Fprintln(w, "Connected to the internet")
+	case wifi.ConnectivityPortal:
+		if checked.PortalURL == "" {
+			fmt.Fprintln(w, "A captive portal requires login before the internet is reachable")
+			return errCaptivePortal
+		}
+		fmt.Fprintf(w, "A captive portal requires login at %s\n", checked.PortalURL)
+		if openPortal {
+			if err := connectivity.OpenURL(checked.PortalURL); err != nil {
+				return err
+			}
+		}
+		return errCaptivePortal
+	case wifi.ConnectivityLimited, wifi.ConnectivityNone:
+		fmt.Fprintln(w, "Connected, but the network does not reach the internet")
+		return errLimitedConnectivity
+	}
+	return nil
+}
+
+// runWaitOnline blocks until the active connection to ssid has a routable
+// address and, unless check.Skip is set, reaches the internet. A captive
+// portal is opened once if check.OpenPortal is set, and waited on in case
+// the user logs in before check.WaitOnline runs out.
+func runWaitOnline(w io.Writer, ssid string, check ConnectCheck, b wifi.Backend, result *connectResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), check.WaitOnline)
+	defer cancel()
+
+	fmt.Fprintf(w, "Waiting up to %v for %q to come online...\n", check.WaitOnline, ssid)
+	opened := false
+	for {
+		details, err := b.ActiveConnection()
+		if err == nil && details != nil && details.SSID == ssid && hasRoutableAddress(details) {
+			result.Interface = details.Interface
+			result.IPv4Addresses = details.IPv4Addresses
+			result.IPv6Addresses = details.IPv6Addresses
+			if check.Skip {
+				fmt.Fprintln(w, "Got an IP address")
+				return nil
+			}
+			checked, err := connectivity.Check(ctx, b, check.URL)
+			if err == nil {
+				result.Connectivity = checked.State
+				result.PortalURL = checked.PortalURL
+				switch checked.State {
+				case wifi.ConnectivityFull:
+					fmt.Fprintln(w, "Connected to the internet")
+					return nil
+				case wifi.ConnectivityUnknown:
+					// Nothing more can be confirmed than the address.
+					fmt.Fprintln(w, "Got an IP address")
+					return nil
+				case wifi.ConnectivityPortal:
+					if check.OpenPortal && !opened && checked.PortalURL != "" {
+						fmt.Fprintf(w, "A captive portal requires login at %s\n", checked.PortalURL)
+						if err := connectivity.OpenURL(checked.PortalURL); err != nil {
+							return err
+						}
+						opened = true
+					}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			switch result.Connectivity {
+			case wifi.ConnectivityPortal:
+				if result.PortalURL != "" {
+					fmt.Fprintf(w, "A captive portal requires login at %s\n", result.PortalURL)
+				}
+				return errCaptivePortal
+			case wifi.ConnectivityLimited, wifi.ConnectivityNone:
+				return errLimitedConnectivity
+			}
+			return errWaitOnlineTimeout
+		case <-time.After(waitOnlineInterval):
+		}
+	}
+}
+
+// hasRoutableAddress reports whether the connection has an address other
+// than a link-local one, which is assigned before DHCP or router
+// advertisements complete.
+func hasRoutableAddress(details *wifi.ConnectionDetails) bool {
+	for _, p := range slices.Concat(details.IPv4Addresses, details.IPv6Addresses) {
+		if !p.Addr().IsLinkLocalUnicast() {
+			return true
+		}
+	}
+	return false
+}
+
 func runRadio(w io.Writer, action string, b wifi.Backend) error {
 	var enabled bool
 	switch action {
@@ -254,3 +675,599 @@ func runRadio(w io.Writer, action string, b wifi.Backend) error {
 	}
 	return nil
 }
+
+// networkPriority is the autoconnect priority of a known network.
+type networkPriority struct {
+	SSID        string `json:"ssid"`
+	Priority    int    `json:"priority"`
+	AutoConnect bool   `json:"autoconnect"`
+}
+
+// runPriority sets the autoconnect priority of a network if priority is
+// given. Otherwise it lists the known networks, or only the one named by
+// ssid, from the highest priority to the lowest.
+func runPriority(w io.Writer, jsonOut bool, ssid string, priority *int, b wifi.Backend) error {
+	if priority != nil {
+		if err := b.UpdateNetwork(ssid, wifi.UpdateOptions{Priority: priority}); err != nil {
+			return fmt.Errorf("failed to set priority: %w", err)
+		}
+		fmt.Fprintf(w, "Priority of %q is now %d\n", ssid, *priority)
+		return nil
+	}
+
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	priorities := []networkPriority{}
+	for _, c := range result.Networks {
+		if c.IsKnown && (ssid == "" || c.SSID == ssid) {
+			priorities = append(priorities, networkPriority{SSID: c.SSID, Priority: c.Priority, AutoConnect: c.AutoConnect})
+		}
+	}
+	if ssid != "" && len(priorities) == 0 {
+		return fmt.Errorf("network not found: %s: %w", ssid, wifi.ErrNotFound)
+	}
+	slices.SortStableFunc(priorities, func(a, b networkPriority) int {
+		if a.Priority != b.Priority {
+			return b.Priority - a.Priority
+		}
+		return strings.Compare(a.SSID, b.SSID)
+	})
+
+	if jsonOut {
+		return writeJSON(w, priorities)
+	}
+	for _, p := range priorities {
+		fmt.Fprintf(w, "%d\t%s", p.Priority, p.SSID)
+		if !p.AutoConnect {
+			fmt.Fprint(w, "\t(autoconnect off)")
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func runHotspotStart(w io.Writer, config wifi.HotspotConfig, b wifi.Backend) error {
+	fmt.Fprintf(w, "Starting hotspot %q...\n", config.SSID)
+	if err := b.StartHotspot(config); err != nil {
+		return fmt.Errorf("failed to start hotspot: %w", err)
+	}
+	status, err := b.HotspotStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get hotspot status: %w", err)
+	}
+	// Not every backend can read the passphrase back.
+	if status.Password == "" {
+		status.Password = config.Password
+	}
+	return writeHotspotDetails(w, status)
+}
+
+func runHotspotStop(w io.Writer, b wifi.Backend) error {
+	if err := b.StopHotspot(); err != nil {
+		return fmt.Errorf("failed to stop hotspot: %w", err)
+	}
+	fmt.Fprintln(w, "Hotspot stopped")
+	return nil
+}
+
+func runHotspotStatus(w io.Writer, jsonOut bool, b wifi.Backend) error {
+	status, err := b.HotspotStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get hotspot status: %w", err)
+	}
+	if jsonOut {
+		return writeJSON(w, status)
+	}
+	if !status.Active {
+		_, err := fmt.Fprintln(w, "Hotspot is off")
+		return err
+	}
+	return writeHotspotDetails(w, status)
+}
+
+// writeHotspotDetails prints a running hotspot with a QR code to join it,
+// when the passphrase is known.
+func writeHotspotDetails(w io.Writer, status wifi.HotspotStatus) error {
+	var writeErr error
+	write := func(format string, args ...any) {
+		if writeErr != nil {
+			return
+		}
+		_, writeErr = fmt.Fprintf(w, format, args...)
+	}
+	write("SSID: %s\n", status.SSID)
+	write("Passphrase: %s\n", status.Password)
+	write("Security: %s\n", status.Security)
+	if status.Band != wifi.BandUnknown {
+		write("Band: %s\n", status.Band)
+	}
+	if status.Interface != "" {
+		write("Interface: %s\n", status.Interface)
+	}
+	isSecure := status.Security != wifi.SecurityOpen
+	if !isSecure || status.Password != "" {
+		if qr, err := qrwifi.GenerateWifiQRCode(status.SSID, status.Password, isSecure, false); err == nil {
+			write("\n%s", qr)
+		}
+	}
+	return writeErr
+}
+
+// watchFilter selects which events runWatch prints. Empty fields match
+// everything.
+type watchFilter struct {
+	SSIDs  []string
+	Events []wifi.EventType
+}
+
+// match reports whether e passes the filter. Events that are not about a
+// particular network, such as radio toggles, are never excluded by SSID.
+func (f watchFilter) match(e wifi.Event) bool {
+	if len(f.Events) > 0 && !slices.Contains(f.Events, e.Type) {
+		return false
+	}
+	if len(f.SSIDs) > 0 && e.SSID != "" && !slices.Contains(f.SSIDs, e.SSID) {
+		return false
+	}
+	return true
+}
+
+// watchEvent is the JSON line written for each event.
+type watchEvent struct {
+	Type         wifi.EventType       `json:"type"`
+	Time         time.Time            `json:"time"`
+	SSID         string               `json:"ssid,omitempty"`
+	BSSID        string               `json:"bssid,omitempty"`
+	Strength     *uint8               `json:"strength,omitempty"`
+	State        wifi.ConnectionState `json:"state,omitempty"`
+	RadioEnabled *bool                `json:"radio_enabled,omitempty"`
+}
+
+func newWatchEvent(e wifi.Event) watchEvent {
+	out := watchEvent{
+		Type:  e.Type,
+		Time:  e.Time,
+		SSID:  e.SSID,
+		BSSID: e.BSSID,
+		State: e.State,
+	}
+	switch e.Type {
+	case wifi.EventNetworkAppeared, wifi.EventSignalChanged:
+		out.Strength = &e.Strength
+	case wifi.EventRadioToggled:
+		out.RadioEnabled = &e.RadioEnabled
+	}
+	return out
+}
+
+// listCachedNetworks lists networks without scanning, treating a disabled
+// radio as an empty list so watching can continue until it is enabled.
+func listCachedNetworks(b wifi.Backend) ([]wifi.Network, error) {
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if errors.Is(err, wifi.ErrWirelessDisabled) {
+		return nil, nil
+	}
+	return result.Networks, err
+}
+
+// runWatch writes one JSON object per line for each event until ctx is
+// cancelled. Backend events are used as a trigger to diff successive cached
+// network lists, so network and signal events look the same on every backend.
+// Connection, radio and scan events are passed through as reported.
+func runWatch(ctx context.Context, w io.Writer, filter watchFilter, b wifi.Backend) error {
+	networks, err := listCachedNetworks(b)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	events, err := b.WatchEvents(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to watch events: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	write := func(e wifi.Event) error {
+		if !filter.match(e) {
+			return nil
+		}
+		if err := enc.Encode(newWatchEvent(e)); err != nil {
+			return fmt.Errorf("failed to write event: %w", err)
+		}
+		return nil
+	}
+
+	for {
+		var e wifi.Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			e = event
+		}
+
+		// Collect the burst of events a single scan usually produces so the
+		// network list is only read once for all of them.
+		pending := []wifi.Event{e}
+	drain:
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					break drain
+				}
+				pending = append(pending, event)
+			default:
+				break drain
+			}
+		}
+
+		for _, e := range pending {
+			switch e.Type {
+			case wifi.EventConnectionStateChanged, wifi.EventRadioToggled, wifi.EventScanCompleted:
+				if err := write(e); err != nil {
+					return err
+				}
+			}
+		}
+
+		next, err := listCachedNetworks(b)
+		if err != nil {
+			return fmt.Errorf("failed to list networks: %w", err)
+		}
+		for _, e := range wifi.DiffNetworks(networks, next) {
+			if e.Type == wifi.EventConnectionStateChanged {
+				// Reported by the backend with intermediate states.
+				continue
+			}
+			if err := write(e); err != nil {
+				return err
+			}
+		}
+		networks = next
+	}
+}
+
+// runServe serves b on a Unix socket until ctx is cancelled.
+func runServe(ctx context.Context, w io.Writer, socket, group string, b wifi.Backend) error {
+	l, err := remote.Listen(socket, group)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	server := &http.Server{Handler: remote.NewHandler(b)}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	fmt.Fprintf(w, "Serving on %s\n", socket)
+	if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// collectProfiles gathers the saved networks and their passphrases for
+// export. Enterprise networks are skipped since their certificates and keys
+// live in files of their own, and missing passphrases are reported on errW.
+func collectProfiles(errW io.Writer, b wifi.Backend) ([]profiles.Profile, error) {
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	var exported []profiles.Profile
+	for _, c := range result.Networks {
+		if !c.IsKnown {
+			continue
+		}
+		security := c.ProfileSecurity()
+		if security.IsEnterprise() {
+			fmt.Fprintf(errW, "Skipping enterprise network %q\n", c.SSID)
+			continue
+		}
+		p := profiles.Profile{SSID: c.SSID, Security: security, AutoConnect: c.AutoConnect, Hidden: c.IsHidden}
+		if security != wifi.SecurityOpen && security != wifi.SecurityOWE {
+			if p.Passphrase, err = b.GetSecrets(c.SSID); err != nil {
+				fmt.Fprintf(errW, "Exporting %q without its passphrase: %v\n", c.SSID, err)
+			}
+		}
+		exported = append(exported, p)
+	}
+	return exported, nil
+}
+
+func runExport(w io.Writer, errW io.Writer, format profiles.Format, passphrase string, b wifi.Backend) error {
+	exported, err := collectProfiles(errW, b)
+	if err != nil {
+		return err
+	}
+	if err := profiles.Encode(w, format, exported, passphrase); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	fmt.Fprintf(errW, "Exported %d networks\n", len(exported))
+	return nil
+}
+
+// conflictPolicy decides what importing does with networks that are already
+// saved.
+type conflictPolicy string
+
+const (
+	conflictSkip      conflictPolicy = "skip"
+	conflictOverwrite conflictPolicy = "overwrite"
+	conflictFail      conflictPolicy = "fail"
+)
+
+// errAlreadySaved is returned when importing a network that is already saved
+// with the fail conflict policy.
+var errAlreadySaved = errors.New("already saved")
+
+// planAction is what importing or applying does with one network.
+type planAction string
+
+const (
+	planAdd    planAction = "add"
+	planUpdate planAction = "update"
+	// planReplace forgets and adds a saved network, because its security
+	// or hidden flag can't be changed in place.
+	planReplace planAction = "replace"
+	planSkip    planAction = "skip"
+	planForget  planAction = "forget"
+)
+
+type planStep struct {
+	action  planAction
+	profile profiles.Profile
+	// changes describes how a saved network differs from the profile, for
+	// updates and replacements made by apply.
+	changes []string
+}
+
+// planImport decides what to do with each profile, given the saved networks.
+// Nothing is planned if the fail policy meets a saved network.
+func planImport(imported []profiles.Profile, networks []wifi.Network, policy conflictPolicy) ([]planStep, error) {
+	saved := make(map[string]wifi.Network)
+	for _, c := range networks {
+		if c.IsKnown {
+			saved[c.SSID] = c
+		}
+	}
+	var steps []planStep
+	var conflicts []string
+	for _, p := range imported {
+		if p.Security.IsEnterprise() {
+			return nil, fmt.Errorf("importing enterprise network %q: %w", p.SSID, wifi.ErrNotSupported)
+		}
+		existing, ok := saved[p.SSID]
+		step := planStep{action: planAdd, profile: p}
+		switch {
+		case !ok:
+		case policy == conflictFail:
+			conflicts = append(conflicts, p.SSID)
+		case policy == conflictOverwrite && existing.ProfileSecurity() != p.Security:
+			step.action = planReplace
+		case policy == conflictOverwrite:
+			step.action = planUpdate
+		default:
+			step.action = planSkip
+		}
+		steps = append(steps, step)
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%s: %w", strings.Join(conflicts, ", "), errAlreadySaved)
+	}
+	return steps, nil
+}
+
+// skipEnterprise drops enterprise networks, whose certificates and keys
+// can't be carried over, and reports them on errW.
+func skipEnterprise(errW io.Writer, imported []profiles.Profile) []profiles.Profile {
+	var result []profiles.Profile
+	for _, p := range imported {
+		if p.Security.IsEnterprise() {
+			fmt.Fprintf(errW, "Skipping enterprise network %q\n", p.SSID)
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// runImport saves the imported networks without connecting to them. The plan
+// is printed first, and with dryRun nothing else happens.
+func runImport(w io.Writer, imported []profiles.Profile, policy conflictPolicy, dryRun bool, b wifi.Backend) error {
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	steps, err := planImport(imported, result.Networks, policy)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		fmt.Fprintf(w, "%s\t%s\n", step.action, step.profile.SSID)
+	}
+	if dryRun {
+		fmt.Fprintln(w, "Dry run, nothing was changed")
+		return nil
+	}
+
+	count := 0
+	for _, step := range steps {
+		if err := applyPlanStep(step, b); err != nil {
+			return fmt.Errorf("failed to import %q: %w", step.profile.SSID, err)
+		}
+		if step.action != planSkip {
+			count++
+		}
+	}
+	fmt.Fprintf(w, "Imported %d networks\n", count)
+	return nil
+}
+
+func applyPlanStep(step planStep, b wifi.Backend) error {
+	p := step.profile
+	switch step.action {
+	case planSkip:
+		return nil
+	case planForget:
+		return b.ForgetNetwork(p.SSID)
+	case planUpdate:
+		opts := wifi.UpdateOptions{AutoConnect: &p.AutoConnect}
+		if p.Passphrase != "" {
+			opts.Password = &p.Passphrase
+		}
+		return b.UpdateNetwork(p.SSID, opts)
+	case planReplace:
+		if err := b.ForgetNetwork(p.SSID); err != nil {
+			return err
+		}
+	}
+	err := b.JoinNetwork(p.SSID, wifi.JoinOptions{
+		Password: p.Passphrase,
+		Security: p.Security,
+		IsHidden: p.Hidden,
+		SaveOnly: true,
+	})
+	if err != nil {
+		return err
+	}
+	if !p.AutoConnect {
+		return b.UpdateNetwork(p.SSID, wifi.UpdateOptions{AutoConnect: &p.AutoConnect})
+	}
+	return nil
+}
+
+// planApply compares the desired networks with the saved ones. Saved
+// networks that aren't desired are forgotten with prune. Passphrases are
+// only compared when the backend can tell what they are.
+func planApply(desired []profiles.Profile, networks []wifi.Network, prune bool, b wifi.Backend) ([]planStep, error) {
+	saved := make(map[string]wifi.Network)
+	for _, c := range networks {
+		if c.IsKnown {
+			saved[c.SSID] = c
+		}
+	}
+	wanted := make(map[string]bool)
+	var steps []planStep
+	for _, p := range desired {
+		wanted[p.SSID] = true
+		if p.Security.IsEnterprise() {
+			return nil, fmt.Errorf("applying enterprise network %q: %w", p.SSID, wifi.ErrNotSupported)
+		}
+		existing, ok := saved[p.SSID]
+		if !ok {
+			steps = append(steps, planStep{action: planAdd, profile: p})
+			continue
+		}
+
+		step := planStep{action: planSkip, profile: p}
+		if security := existing.ProfileSecurity(); security != p.Security {
+			step.changes = append(step.changes, fmt.Sprintf("security: %s -> %s", security, p.Security))
+		}
+		if existing.IsHidden != p.Hidden {
+			step.changes = append(step.changes, fmt.Sprintf("hidden: %t -> %t", existing.IsHidden, p.Hidden))
+		}
+		if len(step.changes) > 0 {
+			step.action = planReplace
+			steps = append(steps, step)
+			continue
+		}
+		if existing.AutoConnect != p.AutoConnect {
+			step.changes = append(step.changes, fmt.Sprintf("autoconnect: %t -> %t", existing.AutoConnect, p.AutoConnect))
+		}
+		if p.Passphrase != "" {
+			if secret, err := b.GetSecrets(p.SSID); err == nil && secret != p.Passphrase {
+				step.changes = append(step.changes, "passphrase: (changed)")
+			} else {
+				// Leave the passphrase alone, some backends can't update it.
+				step.profile.Passphrase = ""
+			}
+		}
+		if len(step.changes) > 0 {
+			step.action = planUpdate
+		}
+		steps = append(steps, step)
+	}
+
+	if prune {
+		for _, c := range networks {
+			if c.IsKnown && !wanted[c.SSID] {
+				wanted[c.SSID] = true
+				steps = append(steps, planStep{action: planForget, profile: profiles.Profile{SSID: c.SSID}})
+			}
+		}
+	}
+	return steps, nil
+}
+
+// writePlan prints the changes of an apply plan, and returns how many there
+// are.
+func writePlan(w io.Writer, steps []planStep) int {
+	symbols := map[planAction]string{
+		planAdd:     "+",
+		planUpdate:  "~",
+		planReplace: "-/+",
+		planForget:  "-",
+	}
+	counts := make(map[planAction]int)
+	for _, step := range steps {
+		if step.action == planSkip {
+			continue
+		}
+		counts[step.action]++
+		fmt.Fprintf(w, "%3s %s %s\n", symbols[step.action], step.action, step.profile.SSID)
+		for _, change := range step.changes {
+			fmt.Fprintf(w, "      %s\n", change)
+		}
+	}
+	total := counts[planAdd] + counts[planUpdate] + counts[planReplace] + counts[planForget]
+	if total == 0 {
+		fmt.Fprintln(w, "No changes, the saved networks match the state file")
+		return 0
+	}
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to update, %d to replace, %d to forget\n",
+		counts[planAdd], counts[planUpdate], counts[planReplace], counts[planForget])
+	return total
+}
+
+// errApplyCancelled is returned when the plan of apply isn't confirmed.
+var errApplyCancelled = errors.New("apply cancelled")
+
+// runApply makes the saved networks match the desired ones. The plan is
+// printed first and, unless autoApprove is set, has to be confirmed by
+// typing yes on in. With dryRun only the plan is printed.
+func runApply(w io.Writer, in io.Reader, desired []profiles.Profile, prune bool, dryRun bool, autoApprove bool, b wifi.Backend) error {
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	steps, err := planApply(desired, result.Networks, prune, b)
+	if err != nil {
+		return err
+	}
+	if writePlan(w, steps) == 0 || dryRun {
+		return nil
+	}
+	if !autoApprove {
+		fmt.Fprint(w, "\nApply these changes? Only 'yes' will be accepted: ")
+		answer, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil {
+			// Nothing was typed, end the prompt's line.
+			fmt.Fprintln(w)
+		}
+		if strings.TrimSpace(answer) != "yes" {
+			return errApplyCancelled
+		}
+	}
+
+	for _, step := range steps {
+		if err := applyPlanStep(step, b); err != nil {
+			return fmt.Errorf("failed to %s %q: %w", step.action, step.profile.SSID, err)
+		}
+	}
+	fmt.Fprintln(w, "Apply complete")
+	return nil
+}
@@ -1,17 +1,30 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/shazow/wifitui/internal/profiles"
+	"github.com/shazow/wifitui/qrwifi"
 	"github.com/shazow/wifitui/wifi"
 	"github.com/shazow/wifitui/wifi/mock"
+	"github.com/shazow/wifitui/wifi/remote"
+	qrcode "github.com/skip2/go-qrcode"
 )
 
 func TestRunListAll(t *testing.T) {
@@ -22,7 +35,7 @@ func TestRunListAll(t *testing.T) {
 	var buf bytes.Buffer
 
 	// Test with all=true (should list invisible known networks)
-	if err := runList(&buf, io.Discard, false, true, false, mockBackend); err != nil {
+	if err := runList(&buf, io.Discard, false, true, false, nil, mockBackend); err != nil {
 		t.Fatalf("runList() failed: %v", err)
 	}
 
@@ -43,7 +56,7 @@ func TestRunListDefault(t *testing.T) {
 	var buf bytes.Buffer
 
 	// Default behavior (all=false)
-	if err := runList(&buf, io.Discard, false, false, false, mockBackend); err != nil {
+	if err := runList(&buf, io.Discard, false, false, false, nil, mockBackend); err != nil {
 		t.Fatalf("runList() failed: %v", err)
 	}
 
@@ -67,7 +80,7 @@ func TestRunListShowsScanWarningWithCachedResults(t *testing.T) {
 	backend := scanFailureBackend{
 		Backend: mockBackend,
 	}
-	if err := runList(&buf, &errBuf, false, false, true, backend); err != nil {
+	if err := runList(&buf, &errBuf, false, false, true, nil, backend); err != nil {
 		t.Fatalf("runList() failed: %v", err)
 	}
 
@@ -91,7 +104,7 @@ func TestRunListReturnsScanWarningWriteError(t *testing.T) {
 	wantErr := errors.New("write failed")
 	backend := scanFailureBackend{Backend: mockBackend}
 
-	err = runList(io.Discard, errorWriter{err: wantErr}, false, false, true, backend)
+	err = runList(io.Discard, errorWriter{err: wantErr}, false, false, true, nil, backend)
 	if !errors.Is(err, wantErr) {
 		t.Fatalf("runList() error = %v, want an error wrapping %v", err, wantErr)
 	}
@@ -107,7 +120,7 @@ func TestRunListScanForcesRefresh(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	if err := runList(&buf, io.Discard, false, false, true, &backend); err != nil {
+	if err := runList(&buf, io.Discard, false, false, true, nil, &backend); err != nil {
 		t.Fatalf("runList() failed: %v", err)
 	}
 	if len(backend.listScans) != 1 || backend.listScans[0] != wifi.ScanForce {
@@ -115,6 +128,32 @@ func TestRunListScanForcesRefresh(t *testing.T) {
 	}
 }
 
+func TestRunListFiltersByBand(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := runList(&buf, io.Discard, false, false, false, []wifi.Band{wifi.Band5GHz}, mockBackend); err != nil {
+		t.Fatalf("runList() failed: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "Mesh Network") || !strings.Contains(output, "2.4/5GHz") {
+		t.Errorf("runList() output missing 5GHz network with its bands. got=%q", output)
+	}
+	if strings.Contains(output, "Unencrypted_Honeypot") {
+		t.Errorf("runList() output contains a network without 5GHz access points. got=%q", output)
+	}
+
+	buf.Reset()
+	if err := runList(&buf, io.Discard, false, false, false, []wifi.Band{wifi.Band6GHz}, mockBackend); err != nil {
+		t.Fatalf("runList() failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("runList() with 6GHz = %q, want no networks", buf.String())
+	}
+}
+
 func TestRunShow(t *testing.T) {
 	mockBackend, err := mock.New()
 	if err != nil {
@@ -163,6 +202,55 @@ func TestRunShow(t *testing.T) {
 	}
 }
 
+func TestRunShowIncludesConnectionDetails(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	if err := mockBackend.ActivateNetwork("Mesh Network"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runShow(&buf, false, "Mesh Network", mockBackend); err != nil {
+		t.Fatalf("runShow() failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"BSSID: AA:BB:CC:DD:EE:03",
+		"Channel: 48 (5GHz, 5240 MHz)",
+		"IPv4 Address: 192.168.1.100/24",
+		"IPv4 Gateway: 192.168.1.1",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("runShow() output missing %q. got=%q", want, output)
+		}
+	}
+
+	buf.Reset()
+	if err := runShow(&buf, true, "Mesh Network", mockBackend); err != nil {
+		t.Fatalf("runShow() with JSON failed: %v", err)
+	}
+	var shown struct {
+		Connection *wifi.ConnectionDetails `json:"connection"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &shown); err != nil {
+		t.Fatalf("failed to decode JSON output: %v", err)
+	}
+	if shown.Connection == nil || shown.Connection.BSSID != "AA:BB:CC:DD:EE:03" {
+		t.Errorf("runShow() JSON connection = %+v, want details for the active access point", shown.Connection)
+	}
+
+	// Inactive networks have no connection details.
+	buf.Reset()
+	if err := runShow(&buf, true, "Password is password", mockBackend); err != nil {
+		t.Fatalf("runShow() with JSON failed: %v", err)
+	}
+	if strings.Contains(buf.String(), `"connection"`) {
+		t.Errorf("runShow() JSON for an inactive network has connection details: %s", buf.String())
+	}
+}
+
 func TestRunShowDoesNotRequestScan(t *testing.T) {
 	mockBackend, err := mock.New()
 	if err != nil {
@@ -237,7 +325,7 @@ func TestAttemptConnectIncludesScanFailureWhenActivationFails(t *testing.T) {
 		activationErr: activationErr,
 	}
 
-	err = attemptConnect("Cafe", "", wifi.SecurityWPA, false, true, backend)
+	err = attemptConnect("Cafe", wifi.JoinOptions{Security: wifi.SecurityWPA}, true, backend)
 	if !errors.Is(err, activationErr) {
 		t.Fatalf("attemptConnect() error %v does not retain activation failure", err)
 	}
@@ -259,7 +347,7 @@ func TestRunListJSON(t *testing.T) {
 	}
 	var buf bytes.Buffer
 
-	if err := runList(&buf, io.Discard, true, true, false, mockBackend); err != nil {
+	if err := runList(&buf, io.Discard, true, true, false, nil, mockBackend); err != nil {
 		t.Fatalf("runList() failed: %v", err)
 	}
 
@@ -285,6 +373,52 @@ func TestRunListJSON(t *testing.T) {
 	}
 }
 
+func TestRunListAllInterfacesJSON(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	if err := selectInterface("wlan1", mockBackend); err != nil {
+		t.Fatalf("selectInterface() failed: %v", err)
+	}
+	var buf bytes.Buffer
+
+	if err := runListAllInterfaces(&buf, io.Discard, true, false, false, nil, mockBackend); err != nil {
+		t.Fatalf("runListAllInterfaces() failed: %v", err)
+	}
+
+	var results []deviceNetworks
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil {
+		t.Fatalf("runListAllInterfaces() output is not valid JSON: %v. got=%q", err, buf.String())
+	}
+	if len(results) != 2 || results[0].Interface != "wlan0" || results[1].Interface != "wlan1" {
+		t.Fatalf("runListAllInterfaces() devices = %+v, want wlan0 and wlan1", results)
+	}
+	for _, result := range results {
+		if len(result.Networks) == 0 {
+			t.Errorf("runListAllInterfaces() listed no networks for %s", result.Interface)
+		}
+	}
+
+	devices, err := mockBackend.Devices()
+	if err != nil {
+		t.Fatalf("Devices() failed: %v", err)
+	}
+	if selected, _ := wifi.SelectedDevice(devices); selected.Interface != "wlan1" {
+		t.Errorf("runListAllInterfaces() left %q selected, want wlan1", selected.Interface)
+	}
+}
+
+func TestSelectInterfaceUnknown(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	if err := selectInterface("wlan9", mockBackend); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("selectInterface(wlan9) = %v, want ErrNotFound", err)
+	}
+}
+
 func TestRunListJSONReportsScanFailureOnStderr(t *testing.T) {
 	mockBackend, err := mock.New()
 	if err != nil {
@@ -293,7 +427,7 @@ func TestRunListJSONReportsScanFailureOnStderr(t *testing.T) {
 	var output bytes.Buffer
 	var diagnostics bytes.Buffer
 
-	err = runList(&output, &diagnostics, true, true, true, scanFailureBackend{Backend: mockBackend})
+	err = runList(&output, &diagnostics, true, true, true, nil, scanFailureBackend{Backend: mockBackend})
 	if err != nil {
 		t.Fatalf("runList() failed: %v", err)
 	}
@@ -365,7 +499,7 @@ func TestRunConnect(t *testing.T) {
 	var buf bytes.Buffer
 
 	// Test case: connect to a new network with a passphrase
-	if err := runConnect(&buf, "new-network", "new-password", wifi.SecurityWPA, false, RetryConfig{Interval: time.Second}, mockBackend); err != nil {
+	if err := runConnect(&buf, "new-network", wifi.JoinOptions{Password: "new-password", Security: wifi.SecurityWPA}, RetryConfig{Interval: time.Second}, mockBackend); err != nil {
 		t.Fatalf("runConnect() with passphrase failed: %v", err)
 	}
 
@@ -388,7 +522,7 @@ func TestRunConnect(t *testing.T) {
 
 	// Test case: connect to a known network without a passphrase
 	buf.Reset()
-	if err := runConnect(&buf, "Password is password", "", wifi.SecurityWPA, false, RetryConfig{Interval: time.Second}, mockBackend); err != nil {
+	if err := runConnect(&buf, "Password is password", wifi.JoinOptions{Security: wifi.SecurityWPA}, RetryConfig{Interval: time.Second}, mockBackend); err != nil {
 		t.Fatalf("runConnect() without passphrase failed: %v", err)
 	}
 
@@ -412,6 +546,257 @@ func TestRunConnect(t *testing.T) {
 	}
 }
 
+func TestRunConnectivityCheck(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	if err := mockBackend.ActivateNetwork("Password is password"); err != nil {
+		t.Fatalf("failed to activate network: %v", err)
+	}
+	var buf bytes.Buffer
+
+	if err := runConnectivityCheck(&buf, "", false, mockBackend, &connectResult{}); err != nil {
+		t.Fatalf("runConnectivityCheck() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Connected to the internet") {
+		t.Errorf("runConnectivityCheck() output = %q, want full connectivity", buf.String())
+	}
+
+	// The mock backend cannot find the login page, so probe a fake portal.
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://portal.example/login", http.StatusFound)
+	}))
+	defer portal.Close()
+	mockBackend.(*mock.MockBackend).ConnectivityState = wifi.ConnectivityPortal
+	buf.Reset()
+	err = runConnectivityCheck(&buf, portal.URL, false, mockBackend, &connectResult{})
+	if !errors.Is(err, errCaptivePortal) {
+		t.Fatalf("runConnectivityCheck() = %v, want %v", err, errCaptivePortal)
+	}
+	if !strings.Contains(buf.String(), "http://portal.example/login") {
+		t.Errorf("runConnectivityCheck() output = %q, want the portal URL", buf.String())
+	}
+	if got := exitCode(err); got != 3 {
+		t.Errorf("exitCode() = %d, want 3", got)
+	}
+
+	mockBackend.(*mock.MockBackend).ConnectivityState = wifi.ConnectivityLimited
+	err = runConnectivityCheck(&buf, portal.URL, false, mockBackend, &connectResult{})
+	if !errors.Is(err, errLimitedConnectivity) {
+		t.Fatalf("runConnectivityCheck() = %v, want %v", err, errLimitedConnectivity)
+	}
+	if got := exitCode(err); got != 4 {
+		t.Errorf("exitCode() = %d, want 4", got)
+	}
+}
+
+// connectivityFailureBackend fails to tell whether the internet is reachable.
+type connectivityFailureBackend struct {
+	wifi.Backend
+}
+
+func (b connectivityFailureBackend) Connectivity() (wifi.Connectivity, error) {
+	return wifi.ConnectivityUnknown, fmt.Errorf("failed to check connectivity: %w", wifi.ErrOperationFailed)
+}
+
+func TestRunConnectivityCheckFailure(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	if err := mockBackend.ActivateNetwork("Password is password"); err != nil {
+		t.Fatalf("failed to activate network: %v", err)
+	}
+	var buf bytes.Buffer
+	result := connectResult{}
+	if err := runConnectivityCheck(&buf, "", false, connectivityFailureBackend{mockBackend}, &result); err != nil {
+		t.Fatalf("runConnectivityCheck() = %v, want nil when connectivity cannot be checked", err)
+	}
+	if result.Connectivity != "" || buf.Len() != 0 {
+		t.Errorf("runConnectivityCheck() reported %q and %q, want nothing", result.Connectivity, buf.String())
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("boom"), exitFailure},
+		{fmt.Errorf("failed to activate connection: %w", wifi.ErrIncorrectPassphrase), exitIncorrectPassphrase},
+		{fmt.Errorf("network %q: %w", "Cafe", wifi.ErrNotFound), exitNotFound},
+		{wifi.ErrWirelessDisabled, exitWirelessDisabled},
+		{&wifi.ScanFailure{Backend: "iwd", Cause: wifi.ErrScanPermissionDenied}, exitPermission},
+		{fmt.Errorf("error: %w", wifi.ErrNotAvailable), exitNotAvailable},
+		{wifi.ErrInvalidBSSID, exitUsage},
+		{errCaptivePortal, exitCaptivePortal},
+		{errWaitOnlineTimeout, exitTimeout},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRunConnectCommandJSON(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	var buf bytes.Buffer
+	check := ConnectCheck{WaitOnline: time.Second}
+	if err := runConnectCommand(&buf, true, "Password is password", wifi.JoinOptions{Security: wifi.SecurityWPA}, RetryConfig{}, check, mockBackend); err != nil {
+		t.Fatalf("runConnectCommand() failed: %v", err)
+	}
+	var result connectResult
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("runConnectCommand() output is not JSON: %v\n%s", err, buf.String())
+	}
+	if !result.Connected || result.Connectivity != wifi.ConnectivityFull || result.ExitCode != 0 || len(result.IPv4Addresses) == 0 {
+		t.Errorf("runConnectCommand() = %+v, want connected with full connectivity and an address", result)
+	}
+
+	mockBackend.(*mock.MockBackend).JoinError = fmt.Errorf("activation failed: %w", wifi.ErrIncorrectPassphrase)
+	buf.Reset()
+	err = runConnectCommand(&buf, true, "new-network", wifi.JoinOptions{Password: "wrong-password", Security: wifi.SecurityWPA}, RetryConfig{}, check, mockBackend)
+	if !errors.Is(err, wifi.ErrIncorrectPassphrase) {
+		t.Fatalf("runConnectCommand() = %v, want %v", err, wifi.ErrIncorrectPassphrase)
+	}
+	result = connectResult{}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("runConnectCommand() output is not JSON: %v\n%s", err, buf.String())
+	}
+	if result.Connected || result.ExitCode != exitIncorrectPassphrase || result.Error == "" {
+		t.Errorf("runConnectCommand() = %+v, want a failure with exit code %d", result, exitIncorrectPassphrase)
+	}
+}
+
+// linkLocalBackend never gets past a link-local address, as when DHCP does
+// not answer.
+type linkLocalBackend struct {
+	wifi.Backend
+}
+
+func (b linkLocalBackend) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	return &wifi.ConnectionDetails{
+		SSID:          "Password is password",
+		IPv6Addresses: []netip.Prefix{netip.MustParsePrefix("fe80::1/64")},
+	}, nil
+}
+
+func TestRunWaitOnlineTimeout(t *testing.T) {
+	defer func(interval time.Duration) { waitOnlineInterval = interval }(waitOnlineInterval)
+	waitOnlineInterval = 10 * time.Millisecond
+
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	var buf bytes.Buffer
+	check := ConnectCheck{WaitOnline: 50 * time.Millisecond}
+	err = runWaitOnline(&buf, "Password is password", check, linkLocalBackend{mockBackend}, &connectResult{})
+	if !errors.Is(err, errWaitOnlineTimeout) {
+		t.Errorf("runWaitOnline() = %v, want %v", err, errWaitOnlineTimeout)
+	}
+
+	// An address is enough when connectivity is not checked.
+	if err := mockBackend.ActivateNetwork("Password is password"); err != nil {
+		t.Fatalf("failed to activate network: %v", err)
+	}
+	mockBackend.(*mock.MockBackend).ConnectivityState = wifi.ConnectivityLimited
+	if err := runWaitOnline(&buf, "Password is password", ConnectCheck{Skip: true, WaitOnline: check.WaitOnline}, mockBackend, &connectResult{}); err != nil {
+		t.Errorf("runWaitOnline() without a connectivity check failed: %v", err)
+	}
+	err = runWaitOnline(&buf, "Password is password", check, mockBackend, &connectResult{})
+	if !errors.Is(err, errLimitedConnectivity) {
+		t.Errorf("runWaitOnline() = %v, want %v", err, errLimitedConnectivity)
+	}
+}
+
+func TestReadWifiURI(t *testing.T) {
+	config, err := readWifiURI(`WIFI:S:Guest;T:WPA;P:battery staple;;`, "")
+	if err != nil {
+		t.Fatalf("readWifiURI() failed: %v", err)
+	}
+	if config.SSID != "Guest" || config.Password != "battery staple" {
+		t.Errorf("readWifiURI() = %+v, want the Guest network", config)
+	}
+
+	png, err := qrcode.Encode(`WIFI:S:Placard;T:nopass;;`, qrcode.Medium, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "placard.png")
+	if err := os.WriteFile(path, png, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err = readWifiURI("", path)
+	if err != nil {
+		t.Fatalf("readWifiURI() of a QR image failed: %v", err)
+	}
+	if config.SSID != "Placard" || config.Type != qrwifi.TypeNoPass {
+		t.Errorf("readWifiURI() of a QR image = %+v, want the open Placard network", config)
+	}
+
+	_, err = readWifiURI("Placard", "")
+	if got := exitCode(err); got != exitUsage {
+		t.Errorf("exitCode() of an invalid URI = %d, want %d", got, exitUsage)
+	}
+}
+
+func TestRunQR(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	var buf bytes.Buffer
+
+	if err := runQR(&buf, "Password is password", "text", 0, 80, 40, mockBackend); err != nil {
+		t.Fatalf("runQR() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "█") {
+		t.Errorf("runQR() =\n%s\nwant a QR code", buf.String())
+	}
+
+	buf.Reset()
+	if err := runQR(&buf, "Password is password", "text", 0, 20, 10, mockBackend); err != nil {
+		t.Fatalf("runQR() in a small terminal failed: %v", err)
+	}
+	if got, want := buf.String(), "WIFI:S:Password is password;T:WPA;P:password;;\n"; got != want {
+		t.Errorf("runQR() in a small terminal = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := runQR(&buf, "Password is password", "png", 256, 0, 0, mockBackend); err != nil {
+		t.Fatalf("runQR() as PNG failed: %v", err)
+	}
+	decoded, err := qrwifi.DecodeQRCode(&buf)
+	if err != nil {
+		t.Fatalf("runQR() did not write a readable PNG: %v", err)
+	}
+	if !strings.Contains(decoded, "P:password;") {
+		t.Errorf("runQR() PNG holds %q, want the passphrase", decoded)
+	}
+
+	buf.Reset()
+	if err := runQR(&buf, "Password is password", "svg", 0, 0, 0, mockBackend); err != nil {
+		t.Fatalf("runQR() as SVG failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "<svg") {
+		t.Errorf("runQR() as SVG = %q, want an SVG image", buf.String())
+	}
+
+	if err := runQR(&buf, "nonexistent", "text", 0, 0, 0, mockBackend); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("runQR() of an unknown network = %v, want %v", err, wifi.ErrNotFound)
+	}
+	if err := runQR(&buf, "Corporate Synergy", "text", 0, 0, 0, mockBackend); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("runQR() of an enterprise network = %v, want %v", err, wifi.ErrNotSupported)
+	}
+}
+
 func TestRunRadio(t *testing.T) {
 	mockBackend, err := mock.New()
 	if err != nil {
@@ -497,12 +882,12 @@ func (f *flakyBackend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, er
 	return f.MockBackend.ListNetworks(scan)
 }
 
-func (f *flakyBackend) JoinNetwork(ssid, passphrase string, security wifi.SecurityType, isHidden bool) error {
+func (f *flakyBackend) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
 	if f.failCount < f.maxFails {
 		f.failCount++
 		return errors.New("transient failure")
 	}
-	return f.MockBackend.JoinNetwork(ssid, passphrase, security, isHidden)
+	return f.MockBackend.JoinNetwork(ssid, opts)
 }
 
 func TestRunConnectRetry(t *testing.T) {
@@ -534,7 +919,7 @@ func TestRunConnectRetry(t *testing.T) {
 
 	start := time.Now()
 	// Using passphrase triggers JoinNetwork which we overrode
-	if err := runConnect(&buf, "retry-network", "password", wifi.SecurityWPA, false, RetryConfig{Total: retryTotal, Interval: retryInterval}, fb); err != nil {
+	if err := runConnect(&buf, "retry-network", wifi.JoinOptions{Password: "password", Security: wifi.SecurityWPA}, RetryConfig{Total: retryTotal, Interval: retryInterval}, fb); err != nil {
 		t.Fatalf("runConnect() with retry failed: %v", err)
 	}
 	duration := time.Since(start)
@@ -592,7 +977,7 @@ func TestRunConnectFastRetry(t *testing.T) {
 
 	start := time.Now()
 	// Using passphrase triggers JoinNetwork which we overrode
-	if err := runConnect(&buf, "retry-network", "password", wifi.SecurityWPA, false, RetryConfig{Total: retryTotal, Interval: retryInterval}, fb); err != nil {
+	if err := runConnect(&buf, "retry-network", wifi.JoinOptions{Password: "password", Security: wifi.SecurityWPA}, RetryConfig{Total: retryTotal, Interval: retryInterval}, fb); err != nil {
 		t.Fatalf("runConnect() with fast retry failed: %v", err)
 	}
 	duration := time.Since(start)
@@ -638,7 +1023,7 @@ func TestRunConnectCustomRetryInterval(t *testing.T) {
 	fb.MockBackend.ActionSleep = 0
 
 	start := time.Now()
-	if err := runConnect(&buf, "retry-network", "password", wifi.SecurityWPA, false, RetryConfig{Total: retryTotal, Interval: retryInterval}, fb); err != nil {
+	if err := runConnect(&buf, "retry-network", wifi.JoinOptions{Password: "password", Security: wifi.SecurityWPA}, RetryConfig{Total: retryTotal, Interval: retryInterval}, fb); err != nil {
 		t.Fatalf("runConnect() failed: %v", err)
 	}
 	duration := time.Since(start)
@@ -669,6 +1054,11 @@ func TestParseSecurityType(t *testing.T) {
 		{"open", wifi.SecurityOpen, false},
 		{"wep", wifi.SecurityWEP, false},
 		{"wpa", wifi.SecurityWPA, false},
+		{"enterprise", wifi.SecurityEnterprise, false},
+		{"wpa3", wifi.SecuritySAE, false},
+		{"wpa2-wpa3", wifi.SecurityWPA2WPA3, false},
+		{"owe", wifi.SecurityOWE, false},
+		{"wpa3-enterprise", wifi.SecurityEnterpriseWPA3, false},
 		{"", wifi.SecurityUnknown, true},
 		{"WPA", wifi.SecurityUnknown, true},
 		{"invalid", wifi.SecurityUnknown, true},
@@ -724,6 +1114,120 @@ func TestParseRetryConfig(t *testing.T) {
 	}
 }
 
+func TestParseIPFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    IPFlags
+		wantIPv4 *wifi.IPConfig
+		wantIPv6 *wifi.IPConfig
+		wantErr  bool
+	}{
+		{
+			name: "no flags",
+		},
+		{
+			name: "static IPv4 implies manual",
+			flags: IPFlags{
+				IPv4Address: []string{"192.168.1.10/24"},
+				IPv4Gateway: "192.168.1.1",
+				DNS:         []string{"1.1.1.1", "2606:4700:4700::1111"},
+				DNSSearch:   []string{"lab.example.com"},
+			},
+			wantIPv4: &wifi.IPConfig{
+				Method:    wifi.IPMethodManual,
+				Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+				Gateway:   netip.MustParseAddr("192.168.1.1"),
+				DNS:       []netip.Addr{netip.MustParseAddr("1.1.1.1")},
+				DNSSearch: []string{"lab.example.com"},
+			},
+			wantIPv6: &wifi.IPConfig{
+				Method: wifi.IPMethodAuto,
+				DNS:    []netip.Addr{netip.MustParseAddr("2606:4700:4700::1111")},
+			},
+		},
+		{
+			name:     "disable IPv6",
+			flags:    IPFlags{IPv6Method: "disabled"},
+			wantIPv6: &wifi.IPConfig{Method: wifi.IPMethodDisabled},
+		},
+		{
+			name:    "manual without address",
+			flags:   IPFlags{IPv4Method: "manual"},
+			wantErr: true,
+		},
+		{
+			name:    "address from the wrong family",
+			flags:   IPFlags{IPv6Address: []string{"192.168.1.10/24"}},
+			wantErr: true,
+		},
+		{
+			name:    "address without prefix",
+			flags:   IPFlags{IPv4Address: []string{"192.168.1.10"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		ipv4, ipv6, err := parseIPFlags(tt.flags)
+		if tt.wantErr {
+			if !errors.Is(err, wifi.ErrInvalidIPConfig) {
+				t.Errorf("%s: parseIPFlags() error = %v, want ErrInvalidIPConfig", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parseIPFlags() unexpected error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(ipv4, tt.wantIPv4) || !reflect.DeepEqual(ipv6, tt.wantIPv6) {
+			t.Errorf("%s: parseIPFlags() = %#v, %#v, want %#v, %#v", tt.name, ipv4, ipv6, tt.wantIPv4, tt.wantIPv6)
+		}
+	}
+}
+
+func TestAttemptConnectUpdatesIPConfigOfKnownNetwork(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	ipv4 := &wifi.IPConfig{Method: wifi.IPMethodManual, Addresses: []netip.Prefix{netip.MustParsePrefix("10.0.0.5/24")}}
+	if err := attemptConnect("Password is password", wifi.JoinOptions{IPv4: ipv4}, false, mockBackend); err != nil {
+		t.Fatalf("attemptConnect() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runShow(&buf, false, "Password is password", mockBackend); err != nil {
+		t.Fatalf("runShow() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "IPv4: manual 10.0.0.5/24") {
+		t.Fatalf("runShow() output missing IP configuration:\n%s", buf.String())
+	}
+}
+
+func TestAttemptConnectLocksAccessPoint(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	opts := wifi.JoinOptions{BSSID: "AA:BB:CC:DD:EE:02", LockBSSID: true}
+	if err := attemptConnect("Mesh Network", opts, false, mockBackend); err != nil {
+		t.Fatalf("attemptConnect() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runShow(&buf, false, "Mesh Network", mockBackend); err != nil {
+		t.Fatalf("runShow() failed: %v", err)
+	}
+	for _, want := range []string{"Locked BSSID: AA:BB:CC:DD:EE:02", "BSSID: AA:BB:CC:DD:EE:02", "Channel: 36 (5GHz, 5180 MHz)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("runShow() output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	if err := attemptConnect("Mesh Network", wifi.JoinOptions{BSSID: "AA:BB:CC:DD:EE:99"}, false, mockBackend); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("attemptConnect() with unknown BSSID error = %v, want ErrNotFound", err)
+	}
+}
+
 func TestFilterVisibleNetworks(t *testing.T) {
 	networks := []wifi.Network{
 		{SSID: "visible1", IsVisible: true},
@@ -773,3 +1277,432 @@ func TestFindNetworkBySSID(t *testing.T) {
 		t.Error("findNetworkBySSID() returned true for empty slice")
 	}
 }
+
+type eventsBackend struct {
+	wifi.Backend
+	events  chan wifi.Event
+	started chan struct{}
+}
+
+func (b *eventsBackend) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
+	close(b.started)
+	return b.events, nil
+}
+
+func TestRunWatch(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	b := &eventsBackend{
+		Backend: mockBackend,
+		events:  make(chan wifi.Event),
+		started: make(chan struct{}),
+	}
+	filter := watchFilter{
+		SSIDs:  []string{"Unencrypted_Honeypot"},
+		Events: []wifi.EventType{wifi.EventRadioToggled, wifi.EventNetworkDisappeared},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- runWatch(ctx, w, filter, b)
+		w.Close()
+	}()
+
+	<-b.started
+	if err := mockBackend.SetWireless(false); err != nil {
+		t.Fatalf("SetWireless failed: %v", err)
+	}
+	b.events <- wifi.Event{Type: wifi.EventRadioToggled, Time: time.Now(), RadioEnabled: false}
+
+	scanner := bufio.NewScanner(r)
+	var got []map[string]any
+	for len(got) < 2 && scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("runWatch() wrote invalid JSON %q: %v", scanner.Text(), err)
+		}
+		got = append(got, line)
+	}
+	cancel()
+	go io.Copy(io.Discard, r)
+	if err := <-done; err != nil {
+		t.Fatalf("runWatch() failed: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("runWatch() wrote %d events, want 2: %v", len(got), got)
+	}
+	if got[0]["type"] != "radio-toggled" || got[0]["radio_enabled"] != false {
+		t.Errorf("first event = %v, want radio-toggled with radio_enabled=false", got[0])
+	}
+	if got[1]["type"] != "network-disappeared" || got[1]["ssid"] != "Unencrypted_Honeypot" {
+		t.Errorf("second event = %v, want Unencrypted_Honeypot to disappear", got[1])
+	}
+}
+
+func TestWatchFilter(t *testing.T) {
+	filter := watchFilter{SSIDs: []string{"Home"}, Events: []wifi.EventType{wifi.EventSignalChanged, wifi.EventScanCompleted}}
+	tests := []struct {
+		event wifi.Event
+		want  bool
+	}{
+		{wifi.Event{Type: wifi.EventSignalChanged, SSID: "Home"}, true},
+		{wifi.Event{Type: wifi.EventSignalChanged, SSID: "Cafe"}, false},
+		{wifi.Event{Type: wifi.EventNetworkAppeared, SSID: "Home"}, false},
+		{wifi.Event{Type: wifi.EventScanCompleted}, true},
+	}
+	for _, tt := range tests {
+		if got := filter.match(tt.event); got != tt.want {
+			t.Errorf("match(%+v) = %v, want %v", tt.event, got, tt.want)
+		}
+	}
+	if !(watchFilter{}).match(wifi.Event{Type: wifi.EventRadioToggled}) {
+		t.Error("empty filter did not match every event")
+	}
+}
+
+func TestRunHotspot(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	var buf bytes.Buffer
+
+	if err := runHotspotStatus(&buf, false, mockBackend); err != nil {
+		t.Fatalf("runHotspotStatus() failed: %v", err)
+	}
+	if got := buf.String(); got != "Hotspot is off\n" {
+		t.Errorf("runHotspotStatus() = %q, want the hotspot to be off", got)
+	}
+
+	buf.Reset()
+	config := wifi.HotspotConfig{SSID: "Event", Password: "correct horse"}
+	if err := runHotspotStart(&buf, config, mockBackend); err != nil {
+		t.Fatalf("runHotspotStart() failed: %v", err)
+	}
+	for _, want := range []string{"SSID: Event\n", "Passphrase: correct horse\n", "Security: WPA/WPA2\n", "█"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("runHotspotStart() output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := runHotspotStatus(&buf, true, mockBackend); err != nil {
+		t.Fatalf("runHotspotStatus() failed: %v", err)
+	}
+	var status wifi.HotspotStatus
+	if err := json.Unmarshal(buf.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if !status.Active || status.SSID != "Event" || status.Security != wifi.SecurityWPA {
+		t.Errorf("status = %+v, want the running hotspot", status)
+	}
+
+	buf.Reset()
+	if err := runHotspotStop(&buf, mockBackend); err != nil {
+		t.Fatalf("runHotspotStop() failed: %v", err)
+	}
+	if err := runHotspotStop(&buf, mockBackend); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("runHotspotStop() without a hotspot = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRunExportImport(t *testing.T) {
+	source, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	var file, errBuf bytes.Buffer
+	if err := runExport(&file, &errBuf, profiles.FormatJSON, "", source); err != nil {
+		t.Fatalf("runExport() failed: %v", err)
+	}
+	exported, err := profiles.Decode(bytes.NewReader(file.Bytes()), profiles.FormatJSON, "")
+	if err != nil {
+		t.Fatalf("runExport() wrote an unreadable file: %v\n%s", err, file.String())
+	}
+	var lan *profiles.Profile
+	for i, p := range exported {
+		if p.SSID == "Password is password" && p.Passphrase != "password" {
+			t.Errorf("exported %+v, want its passphrase", p)
+		}
+		if p.SSID == "GET off my LAN" {
+			lan = &exported[i]
+		}
+	}
+	if lan == nil || lan.AutoConnect {
+		t.Fatalf("exported GET off my LAN as %+v, want a network without autoconnect", lan)
+	}
+
+	target, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	if err := target.ForgetNetwork("GET off my LAN"); err != nil {
+		t.Fatalf("ForgetNetwork() failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runImport(&out, exported, conflictSkip, true, target); err != nil {
+		t.Fatalf("runImport(dry run) failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "add\tGET off my LAN") || !strings.Contains(out.String(), "skip\tPassword is password") {
+		t.Errorf("runImport(dry run) plan = %q", out.String())
+	}
+	result, _ := target.ListNetworks(wifi.ScanNever)
+	if c, _ := findNetworkBySSID(result.Networks, "GET off my LAN"); c.IsKnown {
+		t.Fatal("runImport(dry run) saved a network")
+	}
+
+	out.Reset()
+	if err := runImport(&out, exported, conflictSkip, false, target); err != nil {
+		t.Fatalf("runImport() failed: %v", err)
+	}
+	result, _ = target.ListNetworks(wifi.ScanNever)
+	c, _ := findNetworkBySSID(result.Networks, "GET off my LAN")
+	if !c.IsKnown || c.IsActive || c.AutoConnect {
+		t.Errorf("imported network = %+v, want it saved, inactive and without autoconnect", c)
+	}
+	if !strings.Contains(out.String(), "Imported 1 networks") {
+		t.Errorf("runImport() output = %q", out.String())
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	imported := []profiles.Profile{
+		{SSID: "New", Security: wifi.SecurityWPA, Passphrase: "password"},
+		{SSID: "Same", Security: wifi.SecurityWPA, Passphrase: "password"},
+		{SSID: "Upgraded", Security: wifi.SecuritySAE, Passphrase: "password"},
+		{SSID: "Transition", Security: wifi.SecurityWPA, Passphrase: "password"},
+	}
+	networks := []wifi.Network{
+		{SSID: "Same", Security: wifi.SecurityWPA, IsKnown: true},
+		{SSID: "Upgraded", Security: wifi.SecurityWPA, IsKnown: true},
+		{SSID: "New", Security: wifi.SecurityWPA, IsVisible: true},
+		// A WPA2 profile in range of a transition mode access point.
+		{SSID: "Transition", Security: wifi.SecurityWPA2WPA3, SavedSecurity: wifi.SecurityWPA, IsKnown: true, IsVisible: true},
+	}
+
+	actions := func(steps []planStep) []planAction {
+		var actions []planAction
+		for _, step := range steps {
+			actions = append(actions, step.action)
+		}
+		return actions
+	}
+	steps, err := planImport(imported, networks, conflictSkip)
+	if err != nil {
+		t.Fatalf("planImport(skip) failed: %v", err)
+	}
+	if got, want := actions(steps), []planAction{planAdd, planSkip, planSkip, planSkip}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planImport(skip) = %v, want %v", got, want)
+	}
+	steps, err = planImport(imported, networks, conflictOverwrite)
+	if err != nil {
+		t.Fatalf("planImport(overwrite) failed: %v", err)
+	}
+	if got, want := actions(steps), []planAction{planAdd, planUpdate, planReplace, planUpdate}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planImport(overwrite) = %v, want %v", got, want)
+	}
+	if _, err := planImport(imported, networks, conflictFail); !errors.Is(err, errAlreadySaved) {
+		t.Errorf("planImport(fail) = %v, want errAlreadySaved", err)
+	}
+	enterprise := []profiles.Profile{{SSID: "Corp", Security: wifi.SecurityEnterprise}}
+	if _, err := planImport(enterprise, nil, conflictSkip); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("planImport(enterprise) = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestSkipEnterprise(t *testing.T) {
+	var errBuf bytes.Buffer
+	got := skipEnterprise(&errBuf, []profiles.Profile{
+		{SSID: "Home", Security: wifi.SecurityWPA},
+		{SSID: "Corp", Security: wifi.SecurityEnterprise},
+	})
+	if len(got) != 1 || got[0].SSID != "Home" {
+		t.Errorf("skipEnterprise() = %+v, want only Home", got)
+	}
+	if !strings.Contains(errBuf.String(), `"Corp"`) {
+		t.Errorf("skipEnterprise() did not report Corp, got %q", errBuf.String())
+	}
+}
+
+func TestRunApply(t *testing.T) {
+	b, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	if err := b.JoinNetwork("Lab", wifi.JoinOptions{Security: wifi.SecurityWPA, Password: "password", SaveOnly: true}); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	desired := []profiles.Profile{
+		{SSID: "Password is password", Security: wifi.SecurityWPA, Passphrase: "password", AutoConnect: true},
+		{SSID: "HideYoKidsHideYoWiFi", Security: wifi.SecurityWPA, Passphrase: "changed", AutoConnect: false},
+		{SSID: "GET off my LAN", Security: wifi.SecurityWPA, AutoConnect: true},
+		{SSID: "Lab", Security: wifi.SecuritySAE, Passphrase: "password", AutoConnect: true},
+		{SSID: "New Office", Security: wifi.SecurityWPA, Passphrase: "password", AutoConnect: true, Hidden: true},
+	}
+
+	var out bytes.Buffer
+	if err := runApply(&out, strings.NewReader(""), desired, true, true, false, b); err != nil {
+		t.Fatalf("runApply(dry run) failed: %v", err)
+	}
+	for _, want := range []string{
+		"  + add New Office\n",
+		"  ~ update HideYoKidsHideYoWiFi\n      autoconnect: true -> false\n      passphrase: (changed)\n",
+		"  ~ update GET off my LAN\n      autoconnect: false -> true\n",
+		"-/+ replace Lab\n      security: WPA/WPA2 -> WPA3\n",
+		"  - forget Mesh Network\n",
+		"Plan: 1 to add, 2 to update, 1 to replace, 1 to forget\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("runApply(dry run) plan is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Password is password") {
+		t.Errorf("runApply(dry run) planned a network that matches:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runApply(&out, strings.NewReader("no\n"), desired, true, false, false, b); !errors.Is(err, errApplyCancelled) {
+		t.Fatalf("runApply(no) = %v, want errApplyCancelled", err)
+	}
+	result, _ := b.ListNetworks(wifi.ScanNever)
+	if c, _ := findNetworkBySSID(result.Networks, "New Office"); c.IsKnown {
+		t.Fatal("runApply() changed networks without confirmation")
+	}
+
+	out.Reset()
+	if err := runApply(&out, strings.NewReader("yes\n"), desired, true, false, false, b); err != nil {
+		t.Fatalf("runApply(yes) failed: %v", err)
+	}
+	result, _ = b.ListNetworks(wifi.ScanNever)
+	for _, p := range desired {
+		c, _ := findNetworkBySSID(result.Networks, p.SSID)
+		if !c.IsKnown || c.Security != p.Security || c.AutoConnect != p.AutoConnect || c.IsHidden != p.Hidden {
+			t.Errorf("after apply %q = %+v, want %+v", p.SSID, c, p)
+		}
+		if secret, _ := b.GetSecrets(p.SSID); secret != p.Passphrase {
+			t.Errorf("after apply %q has passphrase %q, want %q", p.SSID, secret, p.Passphrase)
+		}
+	}
+	if c, _ := findNetworkBySSID(result.Networks, "Mesh Network"); c.IsKnown {
+		t.Error("runApply(prune) did not forget Mesh Network")
+	}
+
+	out.Reset()
+	if err := runApply(&out, strings.NewReader(""), desired, true, false, false, b); err != nil {
+		t.Fatalf("runApply() after applying failed: %v", err)
+	}
+	if got := out.String(); got != "No changes, the saved networks match the state file\n" {
+		t.Errorf("runApply() after applying = %q, want no changes", got)
+	}
+}
+
+func TestPlanApplyTransitionMode(t *testing.T) {
+	desired := []profiles.Profile{{SSID: "Transition", Security: wifi.SecurityWPA, AutoConnect: true}}
+	networks := []wifi.Network{
+		{SSID: "Transition", Security: wifi.SecurityWPA2WPA3, SavedSecurity: wifi.SecurityWPA, IsKnown: true, IsVisible: true, AutoConnect: true},
+	}
+	steps, err := planApply(desired, networks, false, nil)
+	if err != nil {
+		t.Fatalf("planApply() failed: %v", err)
+	}
+	if len(steps) != 1 || steps[0].action != planSkip {
+		t.Errorf("planApply() = %+v, want the saved WPA profile kept", steps)
+	}
+}
+
+func TestRunPriority(t *testing.T) {
+	b, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+
+	var out bytes.Buffer
+	priority := 10
+	if err := runPriority(&out, false, "Password is password", &priority, b); err != nil {
+		t.Fatalf("runPriority(set) failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `Priority of "Password is password" is now 10`) {
+		t.Errorf("runPriority(set) output = %q", out.String())
+	}
+
+	out.Reset()
+	if err := runPriority(&out, false, "", nil, b); err != nil {
+		t.Fatalf("runPriority(list) failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if lines[0] != "10\tPassword is password" {
+		t.Errorf("runPriority(list) first line = %q, want Password is password with priority 10", lines[0])
+	}
+	if !slices.Contains(lines, "0\tGET off my LAN\t(autoconnect off)") {
+		t.Errorf("runPriority(list) = %q, want GET off my LAN marked without autoconnect", lines)
+	}
+
+	out.Reset()
+	if err := runPriority(&out, true, "Password is password", nil, b); err != nil {
+		t.Fatalf("runPriority(json) failed: %v", err)
+	}
+	var got []networkPriority
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("runPriority(json) wrote invalid JSON: %v", err)
+	}
+	if want := []networkPriority{{SSID: "Password is password", Priority: 10, AutoConnect: true}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("runPriority(json) = %+v, want %+v", got, want)
+	}
+
+	if err := runPriority(&out, false, "Not Saved", nil, b); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("runPriority() for an unknown network = %v, want ErrNotFound", err)
+	}
+	priority = wifi.MaxPriority + 1
+	if err := runPriority(&out, false, "Password is password", &priority, b); !errors.Is(err, wifi.ErrInvalidPriority) {
+		t.Errorf("runPriority() out of range = %v, want ErrInvalidPriority", err)
+	}
+}
+
+func TestRunServe(t *testing.T) {
+	mockBackend, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	socket := filepath.Join(t.TempDir(), "wifitui.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- runServe(ctx, io.Discard, socket, "", mockBackend)
+	}()
+
+	var client *remote.Client
+	for start := time.Now(); client == nil; {
+		if client, err = remote.Dial(socket); err != nil {
+			if time.Since(start) > 2*time.Second {
+				t.Fatalf("daemon did not start: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if err := client.ActivateNetwork("Password is password"); err != nil {
+		t.Fatalf("ActivateNetwork() through the daemon failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := runShow(&buf, false, "Password is password", client); err != nil {
+		t.Fatalf("runShow() through the daemon failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Active: true") {
+		t.Errorf("runShow() through the daemon printed:\n%s", buf.String())
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("runServe() = %v", err)
+	}
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket left behind after stopping: %v", err)
+	}
+}
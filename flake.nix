@@ -22,7 +22,7 @@
           version = "0.0.0"; # Development version is always 0.0.0
           src = ./.;
           # Updated by `make vendorHash`
-          vendorHash = "sha256-2smXAK3mRweg0yKDerKgu3fcT3ulDjRSbbkMCSe+nVs=";
+          vendorHash = "sha256-XyicU5GI8lxj3Thz62b3Pvlny50Ky9IrlorN53sEpro=";
           env.CGO_ENABLED = if pkgs.stdenv.isDarwin then "1" else "0";
           ldflags = [
             "-s"
@@ -43,6 +43,7 @@
           buildInputs = [
             pkgs.go
             pkgs.go-tools
+            pkgs.dbus # dbus-daemon for the backend integration tests
           ];
         };
       }
@@ -0,0 +1,113 @@
+// Package connectivity finds out whether a connection reaches the internet
+// or is held back by a captive portal, as on many hotel and airport networks.
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// DefaultURL answers with 204 No Content when the internet is reachable.
+const DefaultURL = "http://connectivitycheck.gstatic.com/generate_204"
+
+// Timeout bounds a single probe. Portals usually answer quickly, and a probe
+// that hangs means the network does not reach the internet.
+var Timeout = 5 * time.Second
+
+// Result is the outcome of a connectivity check.
+type Result struct {
+	State wifi.Connectivity
+	// PortalURL is the login page of a captive portal, if it could be found.
+	PortalURL string
+}
+
+// Probe requests url, which is expected to answer with 204 No Content. A
+// redirect or a page in its place means a captive portal intercepted the
+// request, and failing to reach it means connectivity is limited. Only an
+// invalid url is returned as an error.
+func Probe(ctx context.Context, url string) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid connectivity check URL: %w", err)
+	}
+	client := &http.Client{
+		// Portals redirect to their login page, which is what we want to
+		// find rather than follow.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{State: wifi.ConnectivityLimited}, nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1))
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent,
+		resp.StatusCode == http.StatusOK && len(body) == 0:
+		return Result{State: wifi.ConnectivityFull}, nil
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		location, err := resp.Location()
+		if err != nil {
+			return Result{State: wifi.ConnectivityPortal}, nil
+		}
+		return Result{State: wifi.ConnectivityPortal, PortalURL: location.String()}, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// The portal served its login page in place of the check.
+		return Result{State: wifi.ConnectivityPortal, PortalURL: url}, nil
+	}
+	return Result{State: wifi.ConnectivityLimited}, nil
+}
+
+// Check asks the backend first and probes url when the backend cannot tell.
+// A portal reported by the backend is probed too, to find its login page. An
+// empty url disables probing.
+func Check(ctx context.Context, b wifi.Backend, url string) (Result, error) {
+	state, err := b.Connectivity()
+	if err != nil && !errors.Is(err, wifi.ErrNotSupported) {
+		return Result{}, err
+	}
+	switch state {
+	case wifi.ConnectivityNone, wifi.ConnectivityLimited, wifi.ConnectivityFull:
+		return Result{State: state}, nil
+	}
+	if url == "" {
+		return Result{State: state}, nil
+	}
+	result, err := Probe(ctx, url)
+	if err != nil {
+		return Result{State: state}, err
+	}
+	if state == wifi.ConnectivityPortal {
+		// The backend knows better, the probe may have raced a login.
+		result.State = wifi.ConnectivityPortal
+	}
+	return result, nil
+}
+
+// OpenURL opens url in the default browser.
+func OpenURL(url string) error {
+	name := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		name = "open"
+	}
+	cmd := exec.Command(name, url)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", url, err)
+	}
+	// Don't leave a zombie behind while the TUI keeps running.
+	go cmd.Wait()
+	return nil
+}
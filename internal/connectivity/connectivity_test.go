@@ -0,0 +1,123 @@
+package connectivity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/mock"
+)
+
+func TestProbe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate_204", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login?from=probe", http.StatusFound)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>Welcome to Airport WiFi</html>"))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want Result
+	}{
+		{"/generate_204", Result{State: wifi.ConnectivityFull}},
+		{"/redirect", Result{State: wifi.ConnectivityPortal, PortalURL: server.URL + "/login?from=probe"}},
+		{"/page", Result{State: wifi.ConnectivityPortal, PortalURL: server.URL + "/page"}},
+		{"/broken", Result{State: wifi.ConnectivityLimited}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := Probe(context.Background(), server.URL+tt.path)
+			if err != nil {
+				t.Fatalf("Probe() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Probe() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	server.Close()
+	got, err := Probe(context.Background(), server.URL+"/generate_204")
+	if err != nil {
+		t.Fatalf("Probe() on a closed server failed: %v", err)
+	}
+	if got.State != wifi.ConnectivityLimited {
+		t.Errorf("Probe() on a closed server = %+v, want limited", got)
+	}
+
+	if _, err := Probe(context.Background(), "://"); err == nil {
+		t.Error("Probe() with an invalid URL succeeded")
+	}
+}
+
+// unsupportedBackend is a backend that cannot check connectivity itself,
+// like iwd and darwin.
+type unsupportedBackend struct {
+	wifi.Backend
+}
+
+func (unsupportedBackend) Connectivity() (wifi.Connectivity, error) {
+	return wifi.ConnectivityUnknown, wifi.ErrNotSupported
+}
+
+func TestCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://portal.example/login", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	got, err := Check(context.Background(), unsupportedBackend{}, server.URL)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	want := Result{State: wifi.ConnectivityPortal, PortalURL: "https://portal.example/login"}
+	if got != want {
+		t.Errorf("Check() = %+v, want %+v", got, want)
+	}
+
+	got, err = Check(context.Background(), unsupportedBackend{}, "")
+	if err != nil {
+		t.Fatalf("Check() without a URL failed: %v", err)
+	}
+	if got.State != wifi.ConnectivityUnknown {
+		t.Errorf("Check() without a URL = %+v, want unknown", got)
+	}
+
+	// The backend's answer wins without probing.
+	mock.DefaultActionSleep = 0
+	b, err := mock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ActivateNetwork("Password is password"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = Check(context.Background(), b, server.URL)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if got.State != wifi.ConnectivityFull {
+		t.Errorf("Check() = %+v, want full", got)
+	}
+
+	b.(*mock.MockBackend).ConnectivityState = wifi.ConnectivityPortal
+	got, err = Check(context.Background(), b, server.URL)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Check() with a portal = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+// Package dbustest runs a private D-Bus daemon for tests, so backends that
+// talk to system services can be exercised against fakes.
+package dbustest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// config allows everything, there is nothing to protect on a private bus.
+const config = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// Bus is a private dbus-daemon.
+type Bus struct {
+	Address string
+}
+
+// New starts a private dbus-daemon for the rest of the test. The test is
+// skipped if dbus-daemon is not installed.
+func New(t testing.TB) *Bus {
+	t.Helper()
+	b, cmd := start(t)
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	return b
+}
+
+// NewSystemBus is like New, and also points dbus.SystemBus at the bus for
+// code that cannot be handed a connection. Tests using it cannot run in
+// parallel, since the system bus is shared by the whole process.
+func NewSystemBus(t testing.TB) *Bus {
+	t.Helper()
+	b, cmd := start(t)
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", b.Address)
+	// Registered after Setenv so that it runs first: the shared connection
+	// must be closed while it still points at this bus, or the next test
+	// would be handed a dead connection.
+	t.Cleanup(func() {
+		if conn, err := dbus.SystemBus(); err == nil {
+			conn.Close()
+		}
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	return b
+}
+
+func start(t testing.TB) (*Bus, *exec.Cmd) {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(configPath, []byte(fmt.Sprintf(config, filepath.Join(dir, "socket"))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(daemon, "--config-file="+configPath, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dbus-daemon warns when it cannot raise its file descriptor limit, so
+	// its output is only shown when it fails.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start dbus-daemon: %v", err)
+	}
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		t.Fatalf("failed to read the address of dbus-daemon: %v\n%s", err, stderr.String())
+	}
+	return &Bus{Address: strings.TrimSpace(address)}, cmd
+}
+
+// Connect opens a connection to the bus, separate from the shared system bus
+// connection. It is closed at the end of the test.
+func (b *Bus) Connect(t testing.TB) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(b.Address)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", b.Address, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
@@ -0,0 +1,28 @@
+package dbustest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestNewSystemBus(t *testing.T) {
+	bus := NewSystemBus(t)
+	service := bus.Connect(t)
+	if reply, err := service.RequestName("org.example.Test", dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("RequestName() = %v, %v", reply, err)
+	}
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		t.Fatalf("SystemBus() failed: %v", err)
+	}
+	var names []string
+	if err := conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(names, "org.example.Test") {
+		t.Errorf("system bus names = %v, want the private bus", names)
+	}
+}
@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// FormatBands formats a list of bands compactly, such as "2.4/5GHz".
+func FormatBands(bands []wifi.Band) string {
+	names := make([]string, 0, len(bands))
+	for _, band := range bands {
+		names = append(names, strings.TrimSuffix(string(band), "GHz"))
+	}
+	return strings.Join(names, "/") + "GHz"
+}
+
+// FormatBandPreference formats a band preference, such as "5GHz, channel 36".
+func FormatBandPreference(band wifi.Band, channel int) string {
+	if band == wifi.BandUnknown {
+		return "any"
+	}
+	if channel == 0 {
+		return string(band)
+	}
+	return fmt.Sprintf("%s, channel %d", band, channel)
+}
@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func TestFormatBands(t *testing.T) {
+	if got, want := FormatBands([]wifi.Band{wifi.Band2GHz, wifi.Band5GHz}), "2.4/5GHz"; got != want {
+		t.Errorf("FormatBands() = %q, want %q", got, want)
+	}
+	if got, want := FormatBands([]wifi.Band{wifi.Band6GHz}), "6GHz"; got != want {
+		t.Errorf("FormatBands() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatBandPreference(t *testing.T) {
+	tests := []struct {
+		band    wifi.Band
+		channel int
+		want    string
+	}{
+		{wifi.BandUnknown, 0, "any"},
+		{wifi.Band5GHz, 0, "5GHz"},
+		{wifi.Band5GHz, 36, "5GHz, channel 36"},
+	}
+	for _, tt := range tests {
+		if got := FormatBandPreference(tt.band, tt.channel); got != tt.want {
+			t.Errorf("FormatBandPreference(%q, %d) = %q, want %q", tt.band, tt.channel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// FormatBitrate formats a bitrate in kbit/s, such as "866.7 Mbit/s".
+func FormatBitrate(kbps uint32) string {
+	if kbps < 1000 {
+		return fmt.Sprintf("%d kbit/s", kbps)
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(kbps)/1000), ".0") + " Mbit/s"
+}
+
+// Field is a labelled value for display.
+type Field struct {
+	Label string
+	Value string
+}
+
+// ConnectionDetailFields returns the fields of d that the backend reported,
+// in display order.
+func ConnectionDetailFields(d wifi.ConnectionDetails) []Field {
+	var fields []Field
+	add := func(label, value string) {
+		if value != "" {
+			fields = append(fields, Field{Label: label, Value: value})
+		}
+	}
+	add("BSSID", d.BSSID)
+	if channel := d.Channel(); channel != 0 {
+		add("Channel", fmt.Sprintf("%d (%s, %d MHz)", channel, d.Band(), d.Frequency))
+	}
+	if d.Bitrate > 0 {
+		add("Bitrate", FormatBitrate(d.Bitrate))
+	}
+	add("Interface", d.Interface)
+	add("IPv4 Address", joinStrings(d.IPv4Addresses))
+	if d.IPv4Gateway.IsValid() {
+		add("IPv4 Gateway", d.IPv4Gateway.String())
+	}
+	add("IPv6 Address", joinStrings(d.IPv6Addresses))
+	if d.IPv6Gateway.IsValid() {
+		add("IPv6 Gateway", d.IPv6Gateway.String())
+	}
+	add("DNS", joinStrings(d.DNS))
+	return fields
+}
+
+func joinStrings[T fmt.Stringer](values []T) string {
+	s := make([]string, 0, len(values))
+	for _, v := range values {
+		s = append(s, v.String())
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func TestFormatBitrate(t *testing.T) {
+	tests := map[uint32]string{
+		600:     "600 kbit/s",
+		54000:   "54 Mbit/s",
+		866700:  "866.7 Mbit/s",
+		1200000: "1200 Mbit/s",
+	}
+	for kbps, want := range tests {
+		if got := FormatBitrate(kbps); got != want {
+			t.Errorf("FormatBitrate(%d) = %q, want %q", kbps, got, want)
+		}
+	}
+}
+
+func TestConnectionDetailFields(t *testing.T) {
+	got := ConnectionDetailFields(wifi.ConnectionDetails{
+		SSID:          "Home",
+		BSSID:         "AA:BB:CC:DD:EE:01",
+		Frequency:     5180,
+		Bitrate:       866700,
+		IPv4Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+		IPv4Gateway:   netip.MustParseAddr("192.168.1.1"),
+		DNS:           []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("2606:4700:4700::1111")},
+	})
+	want := []Field{
+		{"BSSID", "AA:BB:CC:DD:EE:01"},
+		{"Channel", "36 (5GHz, 5180 MHz)"},
+		{"Bitrate", "866.7 Mbit/s"},
+		{"IPv4 Address", "192.168.1.10/24"},
+		{"IPv4 Gateway", "192.168.1.1"},
+		{"DNS", "1.1.1.1 2606:4700:4700::1111"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConnectionDetailFields() = %v, want %v", got, want)
+	}
+}
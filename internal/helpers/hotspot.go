@@ -0,0 +1,29 @@
+package helpers
+
+import "os"
+
+// HotspotPassphraseLength is the length of generated hotspot passphrases.
+const HotspotPassphraseLength = 12
+
+// passphraseAlphabet leaves out characters that are easily confused when
+// read off a screen, such as 0/O and 1/l/I.
+const passphraseAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// RandomPassphrase returns a random passphrase of length characters that is
+// easy to type on a phone.
+func RandomPassphrase(length int) (string, error) {
+	return randomString(passphraseAlphabet, length)
+}
+
+// DefaultHotspotSSID names a hotspot after the machine's hostname.
+func DefaultHotspotSSID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "wifitui"
+	}
+	ssid := hostname + "-hotspot"
+	if len(ssid) > 32 {
+		ssid = ssid[:32]
+	}
+	return ssid
+}
@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandomPassphrase(t *testing.T) {
+	a, err := RandomPassphrase(12)
+	if err != nil {
+		t.Fatalf("RandomPassphrase() failed: %v", err)
+	}
+	if len(a) != 12 {
+		t.Errorf("RandomPassphrase(12) = %q, want 12 characters", a)
+	}
+	for _, r := range a {
+		if !strings.ContainsRune(passphraseAlphabet, r) {
+			t.Errorf("RandomPassphrase() = %q, contains %q", a, r)
+		}
+	}
+	if b, _ := RandomPassphrase(12); a == b {
+		t.Errorf("RandomPassphrase() returned %q twice", a)
+	}
+}
+
+func TestDefaultHotspotSSID(t *testing.T) {
+	if ssid := DefaultHotspotSSID(); ssid == "" || len(ssid) > 32 {
+		t.Errorf("DefaultHotspotSSID() = %q, want 1 to 32 bytes", ssid)
+	}
+}
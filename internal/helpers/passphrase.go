@@ -0,0 +1,233 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/sethvargo/go-diceware/diceware"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// PassphraseStyle selects how GeneratePassphrase builds a passphrase.
+type PassphraseStyle int
+
+const (
+	// PassphraseRandom picks random characters.
+	PassphraseRandom PassphraseStyle = iota
+	// PassphraseWords joins random words from the EFF diceware list with
+	// hyphens.
+	PassphraseWords
+)
+
+// Default lengths of generated passphrases.
+const (
+	DefaultPassphraseLength = 16
+	DefaultPassphraseWords  = 4
+)
+
+const (
+	minPassphraseWords = 3
+	maxPassphraseWords = 7
+)
+
+// Character classes of random passphrases. Symbols leave out quotes,
+// backslashes and the characters that WIFI: URIs escape.
+const (
+	lowercaseChars = "abcdefghijklmnopqrstuvwxyz"
+	uppercaseChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars     = "0123456789"
+	symbolChars    = "!#$%&*+-=?@^_~"
+)
+
+// PassphraseOptions configures GeneratePassphrase.
+type PassphraseOptions struct {
+	Style PassphraseStyle
+	// Length is the number of characters of a random passphrase, or the
+	// number of words. It is adjusted to what the security type allows, and
+	// zero picks the default.
+	Length int
+	// Lowercase letters are always used. Words are capitalized by Uppercase
+	// and followed by a digit with Digits; Symbols only applies to random
+	// passphrases.
+	Uppercase bool
+	Digits    bool
+	Symbols   bool
+}
+
+// GeneratePassphrase returns a random passphrase that is valid for security.
+// WEP keys are 5 or 13 characters, whichever is closest to the requested
+// length, and cannot be made of words.
+func GeneratePassphrase(security wifi.SecurityType, opts PassphraseOptions) (string, error) {
+	if opts.Style == PassphraseWords {
+		if security == wifi.SecurityWEP {
+			return "", fmt.Errorf("WEP keys cannot be made of words: %w", wifi.ErrNotSupported)
+		}
+		return generateWords(security, opts)
+	}
+
+	length := opts.Length
+	if length == 0 {
+		length = DefaultPassphraseLength
+	}
+	if security == wifi.SecurityWEP {
+		if length <= (wifi.WEP40PassphraseLength+wifi.WEP104PassphraseLength)/2 {
+			length = wifi.WEP40PassphraseLength
+		} else {
+			length = wifi.WEP104PassphraseLength
+		}
+	} else {
+		length = max(wifi.MinWPAPassphraseLength, min(length, wifi.MaxWPAPassphraseLength))
+	}
+
+	classes := []string{lowercaseChars}
+	if opts.Uppercase {
+		classes = append(classes, uppercaseChars)
+	}
+	if opts.Digits {
+		classes = append(classes, digitChars)
+	}
+	if opts.Symbols {
+		classes = append(classes, symbolChars)
+	}
+	alphabet := strings.Join(classes, "")
+	// Draw again until every class is used, which rarely takes more than one
+	// attempt at these lengths.
+	for {
+		p, err := randomString(alphabet, length)
+		if err != nil {
+			return "", err
+		}
+		if usesAll(p, classes) {
+			return p, nil
+		}
+	}
+}
+
+func generateWords(security wifi.SecurityType, opts PassphraseOptions) (string, error) {
+	count := opts.Length
+	if count == 0 {
+		count = DefaultPassphraseWords
+	}
+	count = max(minPassphraseWords, min(count, maxPassphraseWords))
+	// Long words can push a passphrase over the WPA limit, so draw again
+	// until it fits.
+	for range 100 {
+		words, err := diceware.Generate(count)
+		if err != nil {
+			return "", err
+		}
+		if opts.Uppercase {
+			for i, w := range words {
+				words[i] = strings.ToUpper(w[:1]) + w[1:]
+			}
+		}
+		if opts.Digits {
+			digit, err := randomString(digitChars, 1)
+			if err != nil {
+				return "", err
+			}
+			words[len(words)-1] += digit
+		}
+		p := strings.Join(words, "-")
+		if wifi.ValidatePassphrase(security, p) == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a passphrase of %d words", count)
+}
+
+// randomString returns length characters picked uniformly from alphabet with
+// crypto/rand.
+func randomString(alphabet string, length int) (string, error) {
+	var b strings.Builder
+	size := big.NewInt(int64(len(alphabet)))
+	for range length {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(alphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+func usesAll(s string, classes []string) bool {
+	for _, class := range classes {
+		if !strings.ContainsAny(s, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// PassphraseStrength is a rough rating of how hard a passphrase is to guess.
+type PassphraseStrength int
+
+const (
+	StrengthWeak PassphraseStrength = iota
+	StrengthFair
+	StrengthStrong
+	StrengthVeryStrong
+)
+
+func (s PassphraseStrength) String() string {
+	switch s {
+	case StrengthFair:
+		return "fair"
+	case StrengthStrong:
+		return "strong"
+	case StrengthVeryStrong:
+		return "very strong"
+	}
+	return "weak"
+}
+
+// EstimatePassphraseStrength rates a passphrase by the entropy it would have
+// if its characters were picked at random from the classes it uses, which
+// flatters passphrases made of words.
+func EstimatePassphraseStrength(passphrase string) PassphraseStrength {
+	pool := 0
+	var lower, upper, digit, other bool
+	for _, r := range passphrase {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	if lower {
+		pool += len(lowercaseChars)
+	}
+	if upper {
+		pool += len(uppercaseChars)
+	}
+	if digit {
+		pool += len(digitChars)
+	}
+	if other {
+		pool += 33 // printable ASCII punctuation and space
+	}
+	if pool == 0 {
+		return StrengthWeak
+	}
+	bits := float64(len([]rune(passphrase))) * math.Log2(float64(pool))
+	switch {
+	case bits < 40:
+		return StrengthWeak
+	case bits < 60:
+		return StrengthFair
+	case bits < 80:
+		return StrengthStrong
+	}
+	return StrengthVeryStrong
+}
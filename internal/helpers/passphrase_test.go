@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func TestGeneratePassphrase(t *testing.T) {
+	tests := []struct {
+		security wifi.SecurityType
+		opts     PassphraseOptions
+		length   int
+	}{
+		{wifi.SecurityWPA, PassphraseOptions{}, DefaultPassphraseLength},
+		{wifi.SecurityWPA, PassphraseOptions{Length: 4}, 8},
+		{wifi.SecuritySAE, PassphraseOptions{Length: 100, Uppercase: true, Digits: true, Symbols: true}, 63},
+		{wifi.SecurityWEP, PassphraseOptions{Length: 8}, 5},
+		{wifi.SecurityWEP, PassphraseOptions{Length: 20, Digits: true}, 13},
+	}
+	for _, tt := range tests {
+		p, err := GeneratePassphrase(tt.security, tt.opts)
+		if err != nil {
+			t.Fatalf("GeneratePassphrase(%v, %+v) failed: %v", tt.security, tt.opts, err)
+		}
+		if len(p) != tt.length {
+			t.Errorf("GeneratePassphrase(%v, %+v) = %q, want %d characters", tt.security, tt.opts, p, tt.length)
+		}
+		if err := wifi.ValidatePassphrase(tt.security, p); err != nil {
+			t.Errorf("GeneratePassphrase(%v, %+v) = %q, which is invalid: %v", tt.security, tt.opts, p, err)
+		}
+		if tt.opts.Digits && !strings.ContainsAny(p, digitChars) {
+			t.Errorf("GeneratePassphrase(%v, %+v) = %q, want a digit", tt.security, tt.opts, p)
+		}
+		if !tt.opts.Symbols && strings.ContainsAny(p, symbolChars) {
+			t.Errorf("GeneratePassphrase(%v, %+v) = %q, want no symbols", tt.security, tt.opts, p)
+		}
+	}
+}
+
+func TestGeneratePassphraseWords(t *testing.T) {
+	p, err := GeneratePassphrase(wifi.SecurityWPA, PassphraseOptions{Style: PassphraseWords, Length: 5, Uppercase: true, Digits: true})
+	if err != nil {
+		t.Fatalf("GeneratePassphrase() failed: %v", err)
+	}
+	words := strings.Split(p, "-")
+	if len(words) != 5 {
+		t.Errorf("GeneratePassphrase() = %q, want 5 words", p)
+	}
+	for _, w := range words {
+		if w[0] < 'A' || w[0] > 'Z' {
+			t.Errorf("GeneratePassphrase() = %q, want capitalized words", p)
+		}
+	}
+	if !strings.ContainsAny(words[4], digitChars) {
+		t.Errorf("GeneratePassphrase() = %q, want a digit", p)
+	}
+
+	if _, err := GeneratePassphrase(wifi.SecurityWEP, PassphraseOptions{Style: PassphraseWords}); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("GeneratePassphrase() of a WEP key = %v, want %v", err, wifi.ErrNotSupported)
+	}
+}
+
+func TestEstimatePassphraseStrength(t *testing.T) {
+	tests := []struct {
+		passphrase string
+		want       PassphraseStrength
+	}{
+		{"", StrengthWeak},
+		{"password", StrengthWeak},
+		{"Tr0ub4dor", StrengthFair},
+		{"hunter2hunter2", StrengthStrong},
+		{"correct-horse-battery-staple", StrengthVeryStrong},
+	}
+	for _, tt := range tests {
+		if got := EstimatePassphraseStrength(tt.passphrase); got != tt.want {
+			t.Errorf("EstimatePassphraseStrength(%q) = %v, want %v", tt.passphrase, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package profiles
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// ParseIWD reads an iwd network file. The SSID and security come from the
+// file name, such as "Home.psk", where SSIDs that aren't plain alphanumeric
+// are written as "=" followed by their bytes in hex.
+func ParseIWD(name string, r io.Reader) (Profile, error) {
+	ext := filepath.Ext(name)
+	var p Profile
+	switch ext {
+	case ".psk":
+		// iwd uses the same file for WPA2 and WPA3 and picks whichever the
+		// access point offers.
+		p.Security = wifi.SecurityWPA
+	case ".open":
+		p.Security = wifi.SecurityOpen
+	case ".8021x":
+		p.Security = wifi.SecurityEnterprise
+	default:
+		return Profile{}, fmt.Errorf("unknown iwd network type %q", ext)
+	}
+	ssid, err := iwdSSID(strings.TrimSuffix(name, ext))
+	if err != nil {
+		return Profile{}, err
+	}
+	p.SSID = ssid
+
+	file, err := parseINI(r)
+	if err != nil {
+		return Profile{}, err
+	}
+	p.AutoConnect = file["Settings"]["AutoConnect"] != "false"
+	p.Hidden = file["Settings"]["Hidden"] == "true"
+	if p.Security == wifi.SecurityWPA {
+		// iwd saves the derived key next to the passphrase, and only the key
+		// when it was given one.
+		p.Passphrase = file["Security"]["Passphrase"]
+		if p.Passphrase == "" {
+			p.Passphrase = file["Security"]["PreSharedKey"]
+		}
+	}
+	return p, nil
+}
+
+func iwdSSID(name string) (string, error) {
+	if !strings.HasPrefix(name, "=") {
+		return name, nil
+	}
+	decoded, err := hex.DecodeString(name[1:])
+	if err != nil {
+		return "", fmt.Errorf("invalid encoded SSID %q: %w", name, err)
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,116 @@
+package profiles
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// ParseNMConnection reads a NetworkManager keyfile. It returns false for
+// profiles that aren't wireless stations, such as wired connections or
+// hotspots.
+func ParseNMConnection(r io.Reader) (Profile, bool, error) {
+	file, err := parseINI(r)
+	if err != nil {
+		return Profile{}, false, err
+	}
+	// Keyfiles may use either the setting names or their aliases.
+	section := func(name, alias string) map[string]string {
+		if s, ok := file[name]; ok {
+			return s
+		}
+		return file[alias]
+	}
+	connection := file["connection"]
+	wireless := section("wifi", "802-11-wireless")
+	security := section("wifi-security", "802-11-wireless-security")
+
+	if typ := connection["type"]; typ != "wifi" && typ != "802-11-wireless" {
+		return Profile{}, false, nil
+	}
+	if mode := wireless["mode"]; mode != "" && mode != "infrastructure" {
+		return Profile{}, false, nil
+	}
+
+	var p Profile
+	if p.SSID, err = nmSSID(wireless["ssid"]); err != nil {
+		return Profile{}, false, err
+	}
+	if p.SSID == "" {
+		return Profile{}, false, fmt.Errorf("missing ssid: %w", wifi.ErrInvalidCredentials)
+	}
+	p.AutoConnect = connection["autoconnect"] != "false"
+	p.Hidden = wireless["hidden"] == "true"
+
+	switch keyMgmt := security["key-mgmt"]; keyMgmt {
+	case "":
+		p.Security = wifi.SecurityOpen
+	case "none":
+		p.Security = wifi.SecurityWEP
+		p.Passphrase = nmUnescape(security["wep-key0"])
+	case "owe":
+		p.Security = wifi.SecurityOWE
+	case "wpa-psk":
+		p.Security = wifi.SecurityWPA
+		p.Passphrase = nmUnescape(security["psk"])
+	case "sae":
+		p.Security = wifi.SecuritySAE
+		p.Passphrase = nmUnescape(security["psk"])
+	case "wpa-eap", "ieee8021x":
+		p.Security = wifi.SecurityEnterprise
+	case "wpa-eap-suite-b-192":
+		p.Security = wifi.SecurityEnterpriseWPA3
+	default:
+		return Profile{}, false, fmt.Errorf("key-mgmt %q: %w", keyMgmt, wifi.ErrNotSupported)
+	}
+	return p, true, nil
+}
+
+// nmSSID decodes an SSID, which is written as a string or, when it isn't
+// valid UTF-8, as a list of byte values such as "72;111;109;101;".
+func nmSSID(value string) (string, error) {
+	if !strings.HasSuffix(value, ";") {
+		return nmUnescape(value), nil
+	}
+	var ssid []byte
+	for _, field := range strings.Split(strings.TrimSuffix(value, ";"), ";") {
+		b, err := strconv.ParseUint(field, 10, 8)
+		if err != nil {
+			// A string that happens to end in a semicolon.
+			return nmUnescape(value), nil
+		}
+		ssid = append(ssid, byte(b))
+	}
+	return string(ssid), nil
+}
+
+// nmUnescape undoes the escaping of keyfile string values.
+func nmUnescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			sb.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 's':
+			sb.WriteByte(' ')
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(value[i])
+		}
+	}
+	return sb.String()
+}
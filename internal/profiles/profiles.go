@@ -0,0 +1,254 @@
+// Package profiles reads and writes files of saved network profiles, for
+// moving them between machines.
+package profiles
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/shazow/wifitui/wifi"
+)
+
+// Version is the current file format version. Files with a newer version are
+// rejected.
+const Version = 1
+
+// ErrPassphraseRequired is returned when decoding an encrypted file without a
+// passphrase.
+var ErrPassphraseRequired = errors.New("file is encrypted, a passphrase is required")
+
+// ErrDecrypt is returned when an encrypted file can't be decrypted, usually
+// because the passphrase is wrong.
+var ErrDecrypt = errors.New("failed to decrypt, wrong passphrase or corrupted file")
+
+// ErrUnsupportedVersion is returned when a file was written by a newer
+// version of wifitui.
+var ErrUnsupportedVersion = errors.New("unsupported file version")
+
+// Format is the encoding of a profiles file.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+// FormatFromPath guesses the format from a file extension, defaulting to
+// JSON.
+func FormatFromPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// Profile is a saved network.
+type Profile struct {
+	SSID        string            `json:"ssid" toml:"ssid"`
+	Security    wifi.SecurityType `json:"security" toml:"security"`
+	Passphrase  string            `json:"passphrase,omitempty" toml:"passphrase,omitempty"`
+	AutoConnect bool              `json:"autoconnect" toml:"autoconnect"`
+	Hidden      bool              `json:"hidden,omitempty" toml:"hidden,omitempty"`
+}
+
+// File is the contents of a profiles file. When Encryption is set, Networks
+// is empty and the encrypted payload holds another File with the networks.
+type File struct {
+	Version    int         `json:"version" toml:"version"`
+	Encryption *Encryption `json:"encryption,omitempty" toml:"encryption,omitempty"`
+	Networks   []Profile   `json:"networks,omitempty" toml:"networks,omitempty"`
+}
+
+// Encryption describes how the payload of a file was encrypted. Binary
+// values are base64 encoded so they fit both formats.
+type Encryption struct {
+	Cipher     string `json:"cipher" toml:"cipher"`
+	KDF        string `json:"kdf" toml:"kdf"`
+	Iterations int    `json:"iterations" toml:"iterations"`
+	Salt       string `json:"salt" toml:"salt"`
+	Nonce      string `json:"nonce" toml:"nonce"`
+	Data       string `json:"data" toml:"data"`
+}
+
+const (
+	cipherAESGCM = "aes-256-gcm"
+	kdfPBKDF2    = "pbkdf2-sha256"
+)
+
+// kdfIterations is the PBKDF2 work factor for new files. Tests lower it.
+var kdfIterations = 600_000
+
+// Encode writes networks in the given format, encrypted with passphrase
+// unless it is empty.
+func Encode(w io.Writer, format Format, networks []Profile, passphrase string) error {
+	f := File{Version: Version, Networks: networks}
+	if passphrase != "" {
+		payload, err := marshal(format, f)
+		if err != nil {
+			return err
+		}
+		encryption, err := encrypt(payload, passphrase)
+		if err != nil {
+			return err
+		}
+		f = File{Version: Version, Encryption: encryption}
+	}
+	data, err := marshal(format, f)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Decode reads networks in the given format, decrypting them with passphrase
+// if the file is encrypted.
+func Decode(r io.Reader, format Format, passphrase string) ([]Profile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	f, err := unmarshal(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if f.Encryption != nil {
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		payload, err := decrypt(f.Encryption, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if f, err = unmarshal(format, payload); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range f.Networks {
+		if p.SSID == "" {
+			return nil, fmt.Errorf("network without an SSID: %w", wifi.ErrInvalidCredentials)
+		}
+	}
+	return f.Networks, nil
+}
+
+func marshal(format Format, f File) ([]byte, error) {
+	switch format {
+	case FormatTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(f); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(f, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func unmarshal(format Format, data []byte) (File, error) {
+	var f File
+	var err error
+	switch format {
+	case FormatTOML:
+		_, err = toml.Decode(string(data), &f)
+	case FormatJSON:
+		err = json.Unmarshal(data, &f)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+	if f.Version < 1 || f.Version > Version {
+		return File{}, fmt.Errorf("version %d: %w", f.Version, ErrUnsupportedVersion)
+	}
+	return f, nil
+}
+
+// encrypt seals payload with AES-GCM, using a key derived from passphrase
+// with a random salt.
+func encrypt(payload []byte, passphrase string) (*Encryption, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &Encryption{
+		Cipher:     cipherAESGCM,
+		KDF:        kdfPBKDF2,
+		Iterations: kdfIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Data:       base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, payload, nil)),
+	}, nil
+}
+
+func decrypt(e *Encryption, passphrase string) ([]byte, error) {
+	if e.Cipher != cipherAESGCM || e.KDF != kdfPBKDF2 {
+		return nil, fmt.Errorf("encryption %s with %s: %w", e.Cipher, e.KDF, ErrUnsupportedVersion)
+	}
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", ErrDecrypt)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(e.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", ErrDecrypt)
+	}
+	data, err := base64.StdEncoding.DecodeString(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", ErrDecrypt)
+	}
+	aead, err := newAEAD(passphrase, salt, e.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce: %w", ErrDecrypt)
+	}
+	payload, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return payload, nil
+}
+
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("invalid iteration count %d: %w", iterations, ErrDecrypt)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,96 @@
+package profiles
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func init() {
+	// Keep the tests fast, the work factor doesn't change the format.
+	kdfIterations = 1000
+}
+
+var testProfiles = []Profile{
+	{SSID: "Home", Security: wifi.SecurityWPA2WPA3, Passphrase: "correct horse", AutoConnect: true},
+	{SSID: "Cafe", Security: wifi.SecurityOpen},
+	{SSID: "Lab", Security: wifi.SecurityWPA, Passphrase: "battery staple", Hidden: true},
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatTOML} {
+		for _, passphrase := range []string{"", "s3cret"} {
+			var buf bytes.Buffer
+			if err := Encode(&buf, format, testProfiles, passphrase); err != nil {
+				t.Fatalf("Encode(%s) failed: %v", format, err)
+			}
+			if passphrase != "" && strings.Contains(buf.String(), "correct horse") {
+				t.Errorf("encrypted %s file contains a passphrase in the clear:\n%s", format, buf.String())
+			}
+			got, err := Decode(&buf, format, passphrase)
+			if err != nil {
+				t.Fatalf("Decode(%s) failed: %v", format, err)
+			}
+			if !reflect.DeepEqual(got, testProfiles) {
+				t.Errorf("Decode(%s) = %+v, want %+v", format, got, testProfiles)
+			}
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	input := `{"version": 1, "networks": [{"ssid": "Home", "security": "wpa3", "passphrase": "pw", "autoconnect": true}]}`
+	got, err := Decode(strings.NewReader(input), FormatJSON, "")
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	want := []Profile{{SSID: "Home", Security: wifi.SecuritySAE, Passphrase: "pw", AutoConnect: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var encrypted bytes.Buffer
+	if err := Encode(&encrypted, FormatJSON, testProfiles, "s3cret"); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		passphrase string
+		want       error
+	}{
+		{"missing passphrase", encrypted.String(), "", ErrPassphraseRequired},
+		{"wrong passphrase", encrypted.String(), "guess", ErrDecrypt},
+		{"newer version", `{"version": 2, "networks": []}`, "", ErrUnsupportedVersion},
+		{"missing version", `{"networks": []}`, "", ErrUnsupportedVersion},
+		{"missing SSID", `{"version": 1, "networks": [{"security": "open"}]}`, "", wifi.ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tt.input), FormatJSON, tt.passphrase)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Decode() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]Format{
+		"networks.toml": FormatTOML,
+		"NETWORKS.TOML": FormatTOML,
+		"networks.json": FormatJSON,
+		"networks":      FormatJSON,
+	} {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package profiles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Source is the configuration of another network manager that networks can
+// be imported from.
+type Source string
+
+const (
+	SourceWPASupplicant Source = "wpa_supplicant"
+	SourceIWD           Source = "iwd"
+	SourceNM            Source = "nm"
+)
+
+// DefaultPath returns where the source usually keeps its networks.
+func (s Source) DefaultPath() string {
+	switch s {
+	case SourceWPASupplicant:
+		return "/etc/wpa_supplicant/wpa_supplicant.conf"
+	case SourceIWD:
+		return "/var/lib/iwd"
+	case SourceNM:
+		return "/etc/NetworkManager/system-connections"
+	}
+	return ""
+}
+
+// ReadSource reads the networks at path, which may be a single file or, for
+// iwd and NetworkManager, a directory of them.
+func ReadSource(source Source, path string) ([]Profile, error) {
+	var parseFile func(path string) ([]Profile, error)
+	var exts []string
+	switch source {
+	case SourceWPASupplicant:
+		parseFile = func(path string) ([]Profile, error) {
+			return readFile(path, ParseWPASupplicant)
+		}
+	case SourceIWD:
+		exts = []string{".psk", ".open", ".8021x"}
+		parseFile = func(path string) ([]Profile, error) {
+			return readFile(path, func(r io.Reader) ([]Profile, error) {
+				p, err := ParseIWD(filepath.Base(path), r)
+				if err != nil {
+					return nil, err
+				}
+				return []Profile{p}, nil
+			})
+		}
+	case SourceNM:
+		exts = []string{".nmconnection"}
+		parseFile = func(path string) ([]Profile, error) {
+			return readFile(path, func(r io.Reader) ([]Profile, error) {
+				p, ok, err := ParseNMConnection(r)
+				if err != nil || !ok {
+					return nil, err
+				}
+				return []Profile{p}, nil
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unknown source %q", source)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return parseFile(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []Profile
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(exts, filepath.Ext(entry.Name())) {
+			continue
+		}
+		found, err := parseFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, found...)
+	}
+	return result, nil
+}
+
+func readFile(path string, parse func(io.Reader) ([]Profile, error)) ([]Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	found, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return found, nil
+}
+
+// iniFile maps section names to their keys and values.
+type iniFile map[string]map[string]string
+
+// parseINI reads the key files used by iwd and NetworkManager. Values are
+// returned as written, without unescaping.
+func parseINI(r io.Reader) (iniFile, error) {
+	file := iniFile{}
+	var section map[string]string
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := line[1 : len(line)-1]
+			if file[name] == nil {
+				file[name] = map[string]string{}
+			}
+			section = file[name]
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || section == nil {
+				return nil, fmt.Errorf("line %d: expected key=value in a section", lineNum)
+			}
+			section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return file, scanner.Err()
+}
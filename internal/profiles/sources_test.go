@@ -0,0 +1,200 @@
+package profiles
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func TestParseWPASupplicant(t *testing.T) {
+	conf := `ctrl_interface=/run/wpa_supplicant
+update_config=1
+
+network={
+	ssid="Home"
+	psk="correct horse" # quoted, with a comment
+}
+
+network={
+	ssid=4c6162
+	scan_ssid=1
+	key_mgmt=SAE WPA-PSK
+	psk="battery#staple"
+	disabled=1
+}
+
+network={
+	ssid="Cafe"
+	key_mgmt=NONE
+}
+
+network={
+	ssid="Retro"
+	key_mgmt=NONE
+	wep_key0="abcde"
+}
+
+network={
+	ssid="Corp"
+	key_mgmt=WPA-EAP
+	eap=PEAP
+	identity="user"
+}
+
+network={
+	ssid="Raw"
+	psk=0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+}
+`
+	got, err := ParseWPASupplicant(strings.NewReader(conf))
+	if err != nil {
+		t.Fatalf("ParseWPASupplicant() failed: %v", err)
+	}
+	want := []Profile{
+		{SSID: "Home", Security: wifi.SecurityWPA, Passphrase: "correct horse", AutoConnect: true},
+		{SSID: "Lab", Security: wifi.SecurityWPA2WPA3, Passphrase: "battery#staple", Hidden: true},
+		{SSID: "Cafe", Security: wifi.SecurityOpen, AutoConnect: true},
+		{SSID: "Retro", Security: wifi.SecurityWEP, Passphrase: "abcde", AutoConnect: true},
+		{SSID: "Corp", Security: wifi.SecurityEnterprise, AutoConnect: true},
+		{SSID: "Raw", Security: wifi.SecurityWPA, Passphrase: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", AutoConnect: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseWPASupplicant() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if _, err := ParseWPASupplicant(strings.NewReader("network={\n\tssid=\"Home\"\n")); err == nil {
+		t.Error("ParseWPASupplicant() accepted an unterminated block")
+	}
+	if _, err := ParseWPASupplicant(strings.NewReader("network={\n\tpsk=\"pw\"\n}\n")); !errors.Is(err, wifi.ErrInvalidCredentials) {
+		t.Errorf("ParseWPASupplicant() without ssid = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestParseIWD(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Profile
+	}{
+		{
+			name:    "Home.psk",
+			content: "[Security]\nPreSharedKey=abcdef\nPassphrase=correct horse\n",
+			want:    Profile{SSID: "Home", Security: wifi.SecurityWPA, Passphrase: "correct horse", AutoConnect: true},
+		},
+		{
+			name:    "=436166c3a9.open",
+			content: "[Settings]\nAutoConnect=false\nHidden=true\n",
+			want:    Profile{SSID: "Café", Security: wifi.SecurityOpen, Hidden: true},
+		},
+		{
+			name:    "KeyOnly.psk",
+			content: "[Security]\nPreSharedKey=abcdef\n",
+			want:    Profile{SSID: "KeyOnly", Security: wifi.SecurityWPA, Passphrase: "abcdef", AutoConnect: true},
+		},
+		{
+			name:    "Corp.8021x",
+			content: "[Security]\nEAP-Method=PEAP\n",
+			want:    Profile{SSID: "Corp", Security: wifi.SecurityEnterprise, AutoConnect: true},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseIWD(tt.name, strings.NewReader(tt.content))
+		if err != nil {
+			t.Errorf("ParseIWD(%q) failed: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseIWD(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseNMConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Profile
+		ok      bool
+	}{
+		{
+			name: "wpa",
+			content: `[connection]
+id=Home
+uuid=0d0a4b3e-0d2c-4c59-8a3f-2f4f0d5a8d3e
+type=wifi
+autoconnect=false
+
+[wifi]
+mode=infrastructure
+ssid=Home Sweet Home
+
+[wifi-security]
+key-mgmt=wpa-psk
+psk=\scorrect horse
+`,
+			want: Profile{SSID: "Home Sweet Home", Security: wifi.SecurityWPA, Passphrase: " correct horse"},
+			ok:   true,
+		},
+		{
+			name: "byte list SSID",
+			content: `[connection]
+type=802-11-wireless
+
+[802-11-wireless]
+ssid=76;97;98;
+hidden=true
+`,
+			want: Profile{SSID: "Lab", Security: wifi.SecurityOpen, AutoConnect: true, Hidden: true},
+			ok:   true,
+		},
+		{
+			name:    "wired",
+			content: "[connection]\ntype=ethernet\n",
+		},
+		{
+			name:    "hotspot",
+			content: "[connection]\ntype=wifi\n\n[wifi]\nmode=ap\nssid=Hotspot\n",
+		},
+	}
+	for _, tt := range tests {
+		got, ok, err := ParseNMConnection(strings.NewReader(tt.content))
+		if err != nil {
+			t.Errorf("ParseNMConnection(%s) failed: %v", tt.name, err)
+			continue
+		}
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseNMConnection(%s) = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReadSourceDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Home.psk":  "[Security]\nPassphrase=correct horse\n",
+		"Cafe.open": "",
+		"notes.txt": "not a network",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ReadSource(SourceIWD, dir)
+	if err != nil {
+		t.Fatalf("ReadSource() failed: %v", err)
+	}
+	want := []Profile{
+		{SSID: "Cafe", Security: wifi.SecurityOpen, AutoConnect: true},
+		{SSID: "Home", Security: wifi.SecurityWPA, Passphrase: "correct horse", AutoConnect: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadSource() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/shazow/wifitui/wifi"
+)
+
+// State lists the networks a machine should have saved, for checking into
+// version control. Passphrases can be kept out of the file by referring to
+// an environment variable or a file instead.
+type State struct {
+	Version  int            `json:"version" toml:"version"`
+	Networks []StateNetwork `json:"networks" toml:"networks"`
+}
+
+// StateNetwork is a network of a State. At most one of Passphrase,
+// PassphraseEnv and PassphraseFile may be set.
+type StateNetwork struct {
+	SSID string `json:"ssid" toml:"ssid"`
+	// Security defaults to WPA if the network has a passphrase, and open
+	// otherwise.
+	Security       wifi.SecurityType `json:"security" toml:"security"`
+	Passphrase     string            `json:"passphrase" toml:"passphrase"`
+	PassphraseEnv  string            `json:"passphrase_env" toml:"passphrase_env"`
+	PassphraseFile string            `json:"passphrase_file" toml:"passphrase_file"`
+	// AutoConnect defaults to true.
+	AutoConnect *bool `json:"autoconnect" toml:"autoconnect"`
+	Hidden      bool  `json:"hidden" toml:"hidden"`
+}
+
+// ReadState reads a state file and resolves the passphrases it refers to.
+// Relative passphrase files are relative to dir, usually the directory of
+// the state file.
+func ReadState(r io.Reader, format Format, dir string) ([]Profile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	switch format {
+	case FormatTOML:
+		_, err = toml.Decode(string(data), &state)
+	case FormatJSON:
+		err = json.Unmarshal(data, &state)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", format, err)
+	}
+	if state.Version != Version {
+		return nil, fmt.Errorf("version %d: %w", state.Version, ErrUnsupportedVersion)
+	}
+
+	seen := make(map[string]bool)
+	result := make([]Profile, 0, len(state.Networks))
+	for _, n := range state.Networks {
+		if n.SSID == "" {
+			return nil, fmt.Errorf("network without an SSID: %w", wifi.ErrInvalidCredentials)
+		}
+		if seen[n.SSID] {
+			return nil, fmt.Errorf("network %q is listed twice", n.SSID)
+		}
+		seen[n.SSID] = true
+		p, err := n.resolve(dir)
+		if err != nil {
+			return nil, fmt.Errorf("network %q: %w", n.SSID, err)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func (n StateNetwork) resolve(dir string) (Profile, error) {
+	p := Profile{
+		SSID:        n.SSID,
+		Security:    n.Security,
+		Passphrase:  n.Passphrase,
+		AutoConnect: n.AutoConnect == nil || *n.AutoConnect,
+		Hidden:      n.Hidden,
+	}
+	sources := 0
+	for _, s := range []string{n.Passphrase, n.PassphraseEnv, n.PassphraseFile} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return Profile{}, fmt.Errorf("only one of passphrase, passphrase_env and passphrase_file can be set: %w", wifi.ErrInvalidCredentials)
+	}
+	switch {
+	case n.PassphraseEnv != "":
+		value, ok := os.LookupEnv(n.PassphraseEnv)
+		if !ok {
+			return Profile{}, fmt.Errorf("environment variable %s is not set: %w", n.PassphraseEnv, wifi.ErrInvalidCredentials)
+		}
+		p.Passphrase = value
+	case n.PassphraseFile != "":
+		path := n.PassphraseFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Profile{}, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		p.Passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+	if p.Security == wifi.SecurityUnknown {
+		p.Security = wifi.SecurityOpen
+		if p.Passphrase != "" {
+			p.Security = wifi.SecurityWPA
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,74 @@
+package profiles
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func TestReadState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lab.psk"), []byte("battery staple\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OFFICE_PSK", "correct horse")
+
+	state := `version = 1
+
+[[networks]]
+ssid = "Office"
+security = "wpa3"
+passphrase_env = "OFFICE_PSK"
+
+[[networks]]
+ssid = "Lab"
+passphrase_file = "lab.psk"
+autoconnect = false
+hidden = true
+
+[[networks]]
+ssid = "Cafe"
+`
+	got, err := ReadState(strings.NewReader(state), FormatTOML, dir)
+	if err != nil {
+		t.Fatalf("ReadState() failed: %v", err)
+	}
+	want := []Profile{
+		{SSID: "Office", Security: wifi.SecuritySAE, Passphrase: "correct horse", AutoConnect: true},
+		{SSID: "Lab", Security: wifi.SecurityWPA, Passphrase: "battery staple", Hidden: true},
+		{SSID: "Cafe", Security: wifi.SecurityOpen, AutoConnect: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadState() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReadStateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		want  error
+	}{
+		{"missing version", `{"networks": []}`, ErrUnsupportedVersion},
+		{"missing env", `{"version": 1, "networks": [{"ssid": "A", "passphrase_env": "WIFITUI_TEST_UNSET"}]}`, wifi.ErrInvalidCredentials},
+		{"two passphrases", `{"version": 1, "networks": [{"ssid": "A", "passphrase": "x", "passphrase_env": "HOME"}]}`, wifi.ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadState(strings.NewReader(tt.state), FormatJSON, "")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ReadState() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	_, err := ReadState(strings.NewReader(`{"version": 1, "networks": [{"ssid": "A"}, {"ssid": "A"}]}`), FormatJSON, "")
+	if err == nil || !strings.Contains(err.Error(), "twice") {
+		t.Errorf("ReadState() with a duplicate network = %v, want an error", err)
+	}
+}
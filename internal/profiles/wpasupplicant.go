@@ -0,0 +1,155 @@
+package profiles
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// ParseWPASupplicant reads the network blocks of a wpa_supplicant.conf file.
+func ParseWPASupplicant(r io.Reader) ([]Profile, error) {
+	var result []Profile
+	var block map[string]string
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		switch {
+		case line == "":
+		case block == nil && strings.ReplaceAll(line, " ", "") == "network={":
+			block = map[string]string{}
+		case block == nil:
+			// Global settings such as ctrl_interface.
+		case line == "}":
+			p, err := wpaSupplicantProfile(block)
+			if err != nil {
+				return nil, fmt.Errorf("network block ending on line %d: %w", lineNum, err)
+			}
+			result = append(result, p)
+			block = nil
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value", lineNum)
+			}
+			block[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if block != nil {
+		return nil, fmt.Errorf("unterminated network block")
+	}
+	return result, nil
+}
+
+func wpaSupplicantProfile(block map[string]string) (Profile, error) {
+	ssid, ok := block["ssid"]
+	if !ok {
+		return Profile{}, fmt.Errorf("missing ssid: %w", wifi.ErrInvalidCredentials)
+	}
+	var p Profile
+	var err error
+	if p.SSID, err = wpaSupplicantString(ssid); err != nil {
+		return Profile{}, fmt.Errorf("ssid: %w", err)
+	}
+	p.Hidden = block["scan_ssid"] == "1"
+	p.AutoConnect = block["disabled"] != "1"
+
+	if psk, ok := block["psk"]; ok {
+		if p.Passphrase, err = wpaSupplicantKey(psk); err != nil {
+			return Profile{}, fmt.Errorf("psk: %w", err)
+		}
+	}
+	wepKey, hasWEP := block["wep_key0"]
+
+	// wpa_supplicant defaults to "WPA-PSK WPA-EAP" when key_mgmt is not set.
+	keyMgmt := strings.Fields(block["key_mgmt"])
+	has := func(name string) bool { return slices.Contains(keyMgmt, name) }
+	switch {
+	case len(keyMgmt) == 0 && p.Passphrase != "":
+		p.Security = wifi.SecurityWPA
+	case len(keyMgmt) == 0:
+		p.Security = wifi.SecurityEnterprise
+	case has("WPA-EAP-SUITE-B-192"):
+		p.Security = wifi.SecurityEnterpriseWPA3
+	case has("WPA-EAP") || has("IEEE8021X") || has("WPA-EAP-SHA256"):
+		p.Security = wifi.SecurityEnterprise
+	case has("SAE") && (has("WPA-PSK") || has("WPA-PSK-SHA256")):
+		p.Security = wifi.SecurityWPA2WPA3
+	case has("SAE"):
+		p.Security = wifi.SecuritySAE
+	case has("WPA-PSK") || has("WPA-PSK-SHA256"):
+		p.Security = wifi.SecurityWPA
+	case has("OWE"):
+		p.Security = wifi.SecurityOWE
+	case has("NONE") && hasWEP:
+		p.Security = wifi.SecurityWEP
+		if p.Passphrase, err = wpaSupplicantKey(wepKey); err != nil {
+			return Profile{}, fmt.Errorf("wep_key0: %w", err)
+		}
+	case has("NONE"):
+		p.Security = wifi.SecurityOpen
+	default:
+		return Profile{}, fmt.Errorf("key_mgmt %q: %w", block["key_mgmt"], wifi.ErrNotSupported)
+	}
+	if sae, ok := block["sae_password"]; ok && p.Passphrase == "" {
+		if p.Passphrase, err = wpaSupplicantString(sae); err != nil {
+			return Profile{}, fmt.Errorf("sae_password: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// wpaSupplicantString decodes a quoted string or hex bytes.
+func wpaSupplicantString(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) {
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	}
+	if strings.HasPrefix(value, `P"`) {
+		return strconv.Unquote(value[1:])
+	}
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("expected a quoted string or hex: %w", err)
+	}
+	return string(decoded), nil
+}
+
+// wpaSupplicantKey decodes a key, which is a quoted passphrase or the raw
+// key in hex. Raw keys are kept in hex, the form other backends accept them
+// in.
+func wpaSupplicantKey(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) {
+		return wpaSupplicantString(value)
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return "", fmt.Errorf("expected a quoted passphrase or hex key: %w", err)
+	}
+	return value, nil
+}
+
+// stripComment removes a # comment, unless it is inside quotes.
+func stripComment(line string) string {
+	inQuotes := false
+	for i, r := range line {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case '#':
+			if !inQuotes {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
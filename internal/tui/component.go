@@ -8,6 +8,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/shazow/wifitui/internal/connectivity"
 	"github.com/shazow/wifitui/internal/helpers"
 	"github.com/shazow/wifitui/wifi"
 )
@@ -71,7 +72,24 @@ type (
 	networkSavedMsg struct {
 		forgottenSSID string
 	}
-	errorMsg struct{ err error }
+	// connectedMsg is sent instead of networkSavedMsg when a network was
+	// activated, to check its connectivity as well.
+	connectedMsg struct {
+		ssid string
+	}
+	connectivityCheckedMsg struct {
+		ssid string
+		connectivity.Result
+	}
+	connectionDetailsLoadedMsg struct {
+		details *wifi.ConnectionDetails
+	}
+	hotspotStatusMsg struct {
+		status wifi.HotspotStatus
+	}
+	devicesLoadedMsg  []wifi.Device
+	deviceSelectedMsg struct{ devices []wifi.Device }
+	errorMsg          struct{ err error }
 
 	// To main model
 	scanMsg struct {
@@ -80,20 +98,27 @@ type (
 	connectMsg struct {
 		item        networkItem
 		autoConnect bool
+		// bssid selects the access point to connect through, if set.
+		bssid string
+		// lockBSSID changes the access point lock before connecting.
+		lockBSSID *string
 	}
 	joinNetworkMsg struct {
-		ssid     string
-		password string
-		security wifi.SecurityType
-		isHidden bool
+		ssid string
+		wifi.JoinOptions
 	}
-	loadSecretsMsg  struct{ item networkItem }
-	updateSecretMsg struct {
+	loadSecretsMsg           struct{ item networkItem }
+	loadConnectionDetailsMsg struct{}
+	updateSecretMsg          struct {
 		item        networkItem
 		newPassword string
 		autoConnect bool
 	}
 	forgetNetworkMsg struct{ item networkItem }
+	loadHotspotMsg   struct{}
+	startHotspotMsg  struct{ wifi.HotspotConfig }
+	stopHotspotMsg   struct{}
+	selectDeviceMsg  struct{ iface string }
 )
 
 // --- Checkbox ---
@@ -156,6 +181,7 @@ type ChoiceComponent struct {
 	options  []string
 	selected int
 	focused  bool
+	vertical bool
 }
 
 func NewChoiceComponent(label string, options []string) *ChoiceComponent {
@@ -165,6 +191,14 @@ func NewChoiceComponent(label string, options []string) *ChoiceComponent {
 	}
 }
 
+// NewChoiceListComponent creates a ChoiceComponent that shows one option per
+// line, for options too long to fit side by side.
+func NewChoiceListComponent(label string, options []string) *ChoiceComponent {
+	c := NewChoiceComponent(label, options)
+	c.vertical = true
+	return c
+}
+
 func (c *ChoiceComponent) Focus() tea.Cmd {
 	c.focused = true
 	return nil
@@ -176,7 +210,16 @@ func (c *ChoiceComponent) Blur() {
 func (c *ChoiceComponent) Update(msg tea.Msg) (Focusable, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
+		key := msg.String()
+		if c.vertical {
+			switch key {
+			case "down", "j":
+				key = "right"
+			case "up", "k":
+				key = "left"
+			}
+		}
+		switch key {
 		case "right", "l":
 			c.selected = (c.selected + 1) % len(c.options)
 		case "left", "h":
@@ -194,6 +237,17 @@ func (c *ChoiceComponent) View() string {
 		if c.focused && i == c.selected {
 			style = lipgloss.NewStyle().Foreground(CurrentTheme.Primary).Bold(true)
 		}
+		if c.vertical {
+			marker := "( ) "
+			if i == c.selected {
+				marker = "(•) "
+			}
+			if i > 0 {
+				s.WriteString("\n")
+			}
+			s.WriteString(style.Render(marker + option))
+			continue
+		}
 		s.WriteString(style.Render("[ " + option + " ]"))
 		s.WriteString("  ")
 	}
@@ -204,6 +258,13 @@ func (c *ChoiceComponent) Selected() int {
 	return c.selected
 }
 
+// SetSelected selects the option at index i.
+func (c *ChoiceComponent) SetSelected(i int) {
+	if i >= 0 && i < len(c.options) {
+		c.selected = i
+	}
+}
+
 // --- TextInput ---
 
 // TextInput wraps a textinput.Model to make it conform to the Focusable interface.
@@ -213,6 +274,8 @@ type TextInput struct {
 	focused bool
 	OnFocus func(*textinput.Model) tea.Cmd
 	OnBlur  func(*textinput.Model)
+	// Hint, if set, renders a note next to the label for the current value.
+	Hint func(value string) string
 }
 
 // Update wraps the textinput.Model's Update method.
@@ -247,7 +310,13 @@ func (a *TextInput) View() string {
 	if a.focused {
 		style = style.BorderForeground(CurrentTheme.Primary)
 	}
-	return a.label + "\n" + style.Render(a.Model.View())
+	label := a.label
+	if a.Hint != nil {
+		if hint := a.Hint(a.Model.Value()); hint != "" {
+			label += " " + hint
+		}
+	}
+	return label + "\n" + style.Render(a.Model.View())
 }
 
 // --- MultiButtonComponent ---
@@ -3,8 +3,13 @@ package tui
 import (
 	"errors"
 	"fmt"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -24,13 +29,21 @@ type EditModel struct {
 	ssidAdapter         *TextInput
 	passwordAdapter     *TextInput
 	securityGroup       *ChoiceComponent
+	enterprise          *enterpriseForm
+	generator           *generatorForm
+	ip                  *ipForm
 	autoConnectCheckbox *Checkbox
+	priority            *TextInput
+	accessPoint         *accessPointForm
+	band                *bandForm
 	buttonGroup         *MultiButtonComponent
 	passwordRevealed    bool
 	isForgetting        bool
 	secretsLoaded       bool
 	hasError            bool
 	selectedItem        networkItem
+	connection          *wifi.ConnectionDetails
+	history             *signalHistory
 	width               int
 	window              *WindowState
 }
@@ -41,6 +54,478 @@ const (
 	editInputFrameWidth     = 4 // text input border + horizontal padding
 )
 
+// newNetworkSecurity maps the security choices offered for new networks to
+// their security types.
+var newNetworkSecurity = []wifi.SecurityType{
+	wifi.SecurityOpen,
+	wifi.SecurityWEP,
+	wifi.SecurityWPA,
+	wifi.SecuritySAE,
+	wifi.SecurityEnterprise,
+}
+
+// enterpriseForm holds the 802.1X fields of the edit form.
+type enterpriseForm struct {
+	identity           *TextInput
+	anonymousIdentity  *TextInput
+	eapMethod          *ChoiceComponent
+	phase2Auth         *ChoiceComponent
+	caCert             *TextInput
+	clientCert         *TextInput
+	privateKey         *TextInput
+	privateKeyPassword *TextInput
+}
+
+var (
+	enterpriseEAPMethods = []wifi.EAPMethod{wifi.EAPMethodPEAP, wifi.EAPMethodTTLS, wifi.EAPMethodTLS}
+	enterprisePhase2Auth = []string{"mschapv2", "pap", "gtc"}
+)
+
+func newEnterpriseForm() *enterpriseForm {
+	newInput := func(label string, charLimit int) *TextInput {
+		ti := textinput.New()
+		ti.CharLimit = charLimit
+		ti.Width = 45
+		return &TextInput{Model: ti, label: label}
+	}
+	f := &enterpriseForm{
+		identity:           newInput("Identity:", 128),
+		anonymousIdentity:  newInput("Anonymous Identity:", 128),
+		eapMethod:          NewChoiceComponent("EAP Method:", []string{"PEAP", "TTLS", "TLS"}),
+		phase2Auth:         NewChoiceComponent("Phase 2 Auth:", []string{"MSCHAPv2", "PAP", "GTC"}),
+		caCert:             newInput("CA Certificate:", 256),
+		clientCert:         newInput("Client Certificate:", 256),
+		privateKey:         newInput("Private Key:", 256),
+		privateKeyPassword: newInput("Private Key Password:", 128),
+	}
+	f.privateKeyPassword.Model.EchoMode = textinput.EchoPassword
+	return f
+}
+
+// items returns the fields relevant to the selected EAP method.
+func (f *enterpriseForm) items() []Focusable {
+	items := []Focusable{f.identity, f.anonymousIdentity, f.eapMethod}
+	if f.method() == wifi.EAPMethodTLS {
+		return append(items, f.caCert, f.clientCert, f.privateKey, f.privateKeyPassword)
+	}
+	return append(items, f.phase2Auth, f.caCert)
+}
+
+func (f *enterpriseForm) inputs() []*TextInput {
+	return []*TextInput{f.identity, f.anonymousIdentity, f.caCert, f.clientCert, f.privateKey, f.privateKeyPassword}
+}
+
+func (f *enterpriseForm) method() wifi.EAPMethod {
+	return enterpriseEAPMethods[f.eapMethod.Selected()]
+}
+
+// Credentials returns the credentials described by the form.
+func (f *enterpriseForm) Credentials() *wifi.EnterpriseCredentials {
+	creds := &wifi.EnterpriseCredentials{
+		Identity:          f.identity.Model.Value(),
+		AnonymousIdentity: f.anonymousIdentity.Model.Value(),
+		EAPMethod:         f.method(),
+		CACert:            f.caCert.Model.Value(),
+	}
+	if creds.EAPMethod == wifi.EAPMethodTLS {
+		creds.ClientCert = f.clientCert.Model.Value()
+		creds.PrivateKey = f.privateKey.Model.Value()
+		creds.PrivateKeyPassword = f.privateKeyPassword.Model.Value()
+	} else {
+		creds.Phase2Auth = enterprisePhase2Auth[f.phase2Auth.Selected()]
+	}
+	return creds
+}
+
+// generatorForm configures the passphrase generator of the new network form.
+type generatorForm struct {
+	style     *ChoiceComponent
+	length    *TextInput
+	uppercase *Checkbox
+	digits    *Checkbox
+	symbols   *Checkbox
+}
+
+var generatorStyles = []helpers.PassphraseStyle{helpers.PassphraseRandom, helpers.PassphraseWords}
+
+func newGeneratorForm() *generatorForm {
+	ti := textinput.New()
+	ti.CharLimit = 2
+	ti.Width = 45
+	f := &generatorForm{
+		style:     NewChoiceComponent("Generate Passphrase (ctrl+g):", []string{"Random", "Words"}),
+		length:    &TextInput{Model: ti, label: "Length:"},
+		uppercase: NewCheckbox("Uppercase", true),
+		digits:    NewCheckbox("Digits", true),
+		symbols:   NewCheckbox("Symbols", false),
+	}
+	f.length.Hint = func(string) string {
+		if f.selected() == helpers.PassphraseWords {
+			return "(words)"
+		}
+		return "(characters)"
+	}
+	return f
+}
+
+func (f *generatorForm) selected() helpers.PassphraseStyle {
+	return generatorStyles[f.style.Selected()]
+}
+
+// items leaves out symbols for words, which are always joined by hyphens.
+func (f *generatorForm) items() []Focusable {
+	items := []Focusable{f.style, f.length, f.uppercase, f.digits}
+	if f.selected() == helpers.PassphraseRandom {
+		items = append(items, f.symbols)
+	}
+	return items
+}
+
+// Generate returns a new passphrase for security with the chosen options.
+func (f *generatorForm) Generate(security wifi.SecurityType) (string, error) {
+	opts := helpers.PassphraseOptions{
+		Style:     f.selected(),
+		Uppercase: f.uppercase.Checked(),
+		Digits:    f.digits.Checked(),
+		Symbols:   f.symbols.Checked(),
+	}
+	if s := strings.TrimSpace(f.length.Model.Value()); s != "" {
+		length, err := strconv.Atoi(s)
+		if err != nil || length < 1 {
+			return "", fmt.Errorf("invalid passphrase length %q", s)
+		}
+		opts.Length = length
+	}
+	return helpers.GeneratePassphrase(security, opts)
+}
+
+// ipForm holds the IP configuration fields of a known network.
+type ipForm struct {
+	ipv4Method    *ChoiceComponent
+	ipv4Addresses *TextInput
+	ipv4Gateway   *TextInput
+	ipv6Method    *ChoiceComponent
+	ipv6Addresses *TextInput
+	ipv6Gateway   *TextInput
+	dns           *TextInput
+	dnsSearch     *TextInput
+
+	// initial holds the configurations the form started with, so that only
+	// families the user changed are updated.
+	initialIPv4, initialIPv6 *wifi.IPConfig
+}
+
+var ipMethods = []wifi.IPMethod{wifi.IPMethodAuto, wifi.IPMethodManual, wifi.IPMethodDisabled}
+
+func newIPForm(ipv4, ipv6 *wifi.IPConfig) *ipForm {
+	newInput := func(label, placeholder string) *TextInput {
+		ti := textinput.New()
+		ti.CharLimit = 256
+		ti.Width = 45
+		ti.Placeholder = placeholder
+		return &TextInput{Model: ti, label: label}
+	}
+	methods := []string{"Automatic", "Manual", "Disabled"}
+	f := &ipForm{
+		ipv4Method:    NewChoiceComponent("IPv4:", methods),
+		ipv4Addresses: newInput("IPv4 Addresses:", "192.168.1.10/24"),
+		ipv4Gateway:   newInput("IPv4 Gateway:", "192.168.1.1"),
+		ipv6Method:    NewChoiceComponent("IPv6:", methods),
+		ipv6Addresses: newInput("IPv6 Addresses:", "fd00::10/64"),
+		ipv6Gateway:   newInput("IPv6 Gateway:", "fd00::1"),
+		dns:           newInput("DNS Servers:", "1.1.1.1, 2606:4700:4700::1111"),
+		dnsSearch:     newInput("Search Domains:", "lab.example.com"),
+	}
+
+	var dns, search []string
+	for _, family := range []struct {
+		config    *wifi.IPConfig
+		method    *ChoiceComponent
+		addresses *TextInput
+		gateway   *TextInput
+	}{
+		{ipv4, f.ipv4Method, f.ipv4Addresses, f.ipv4Gateway},
+		{ipv6, f.ipv6Method, f.ipv6Addresses, f.ipv6Gateway},
+	} {
+		if family.config == nil {
+			continue
+		}
+		family.method.SetSelected(slices.Index(ipMethods, family.config.Method))
+		var addresses []string
+		for _, prefix := range family.config.Addresses {
+			addresses = append(addresses, prefix.String())
+		}
+		family.addresses.Model.SetValue(strings.Join(addresses, ", "))
+		if family.config.Gateway.IsValid() {
+			family.gateway.Model.SetValue(family.config.Gateway.String())
+		}
+		for _, addr := range family.config.DNS {
+			dns = append(dns, addr.String())
+		}
+		for _, domain := range family.config.DNSSearch {
+			if !slices.Contains(search, domain) {
+				search = append(search, domain)
+			}
+		}
+	}
+	f.dns.Model.SetValue(strings.Join(dns, ", "))
+	f.dnsSearch.Model.SetValue(strings.Join(search, ", "))
+
+	f.initialIPv4, _ = f.config(false)
+	f.initialIPv6, _ = f.config(true)
+	return f
+}
+
+func (f *ipForm) method(ipv6 bool) wifi.IPMethod {
+	if ipv6 {
+		return ipMethods[f.ipv6Method.Selected()]
+	}
+	return ipMethods[f.ipv4Method.Selected()]
+}
+
+// items returns the fields relevant to the selected methods.
+func (f *ipForm) items() []Focusable {
+	items := []Focusable{f.ipv4Method}
+	if f.method(false) == wifi.IPMethodManual {
+		items = append(items, f.ipv4Addresses, f.ipv4Gateway)
+	}
+	items = append(items, f.ipv6Method)
+	if f.method(true) == wifi.IPMethodManual {
+		items = append(items, f.ipv6Addresses, f.ipv6Gateway)
+	}
+	if f.method(false) != wifi.IPMethodDisabled || f.method(true) != wifi.IPMethodDisabled {
+		items = append(items, f.dns, f.dnsSearch)
+	}
+	return items
+}
+
+func (f *ipForm) inputs() []*TextInput {
+	return []*TextInput{f.ipv4Addresses, f.ipv4Gateway, f.ipv6Addresses, f.ipv6Gateway, f.dns, f.dnsSearch}
+}
+
+// splitList splits a comma or space separated list.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// config returns the configuration of one IP family described by the form.
+// DNS servers are assigned to the family they belong to, and search domains
+// to IPv4 unless it is disabled.
+func (f *ipForm) config(ipv6 bool) (*wifi.IPConfig, error) {
+	addresses, gateway := f.ipv4Addresses, f.ipv4Gateway
+	if ipv6 {
+		addresses, gateway = f.ipv6Addresses, f.ipv6Gateway
+	}
+	c := &wifi.IPConfig{Method: f.method(ipv6)}
+	if c.Method == wifi.IPMethodManual {
+		for _, s := range splitList(addresses.Model.Value()) {
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q, expected address/prefix: %w", s, wifi.ErrInvalidIPConfig)
+			}
+			c.Addresses = append(c.Addresses, prefix)
+		}
+		if s := strings.TrimSpace(gateway.Model.Value()); s != "" {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gateway %q: %w", s, wifi.ErrInvalidIPConfig)
+			}
+			c.Gateway = addr
+		}
+	}
+	if c.Method == wifi.IPMethodDisabled {
+		return c, nil
+	}
+	for _, s := range splitList(f.dns.Model.Value()) {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNS server %q: %w", s, wifi.ErrInvalidIPConfig)
+		}
+		if addr.Is6() == ipv6 {
+			c.DNS = append(c.DNS, addr)
+		}
+	}
+	if ipv6 == (f.method(false) == wifi.IPMethodDisabled) {
+		c.DNSSearch = splitList(f.dnsSearch.Model.Value())
+	}
+	return c, nil
+}
+
+// UpdateOptions sets the IP configuration of opts for each family that
+// differs from the one the form started with.
+func (f *ipForm) UpdateOptions(opts *wifi.UpdateOptions) error {
+	ipv4, err := f.config(false)
+	if err != nil {
+		return err
+	}
+	ipv6, err := f.config(true)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(ipv4, f.initialIPv4) {
+		opts.IPv4 = ipv4
+	}
+	if !reflect.DeepEqual(ipv6, f.initialIPv6) {
+		opts.IPv6 = ipv6
+	}
+	return opts.Validate()
+}
+
+// accessPointForm picks the access point of a known network to connect
+// through, and whether the network is locked to it.
+type accessPointForm struct {
+	choice *ChoiceComponent
+	lock   *Checkbox
+	// bssids holds the BSSID of each choice after the first, which is "Any".
+	bssids []string
+	locked string
+}
+
+// newAccessPointForm returns nil if the network has no access points with a
+// BSSID to choose from.
+func newAccessPointForm(n wifi.Network) *accessPointForm {
+	f := &accessPointForm{locked: n.LockedBSSID}
+	options := []string{"Any"}
+	for _, ap := range n.AccessPoints {
+		if ap.BSSID == "" {
+			continue
+		}
+		f.bssids = append(f.bssids, ap.BSSID)
+		options = append(options, fmt.Sprintf("%s  %d%%  %dMHz", ap.BSSID, ap.Strength, ap.Frequency))
+	}
+	if f.locked != "" && !slices.ContainsFunc(f.bssids, func(bssid string) bool { return strings.EqualFold(bssid, f.locked) }) {
+		f.bssids = append(f.bssids, f.locked)
+		options = append(options, f.locked+"  (not visible)")
+	}
+	if len(f.bssids) == 0 {
+		return nil
+	}
+	f.choice = NewChoiceListComponent("Access Point:", options)
+	f.lock = NewCheckbox("Lock to access point", f.locked != "")
+	if f.locked != "" {
+		f.choice.SetSelected(1 + slices.IndexFunc(f.bssids, func(bssid string) bool { return strings.EqualFold(bssid, f.locked) }))
+	}
+	return f
+}
+
+func (f *accessPointForm) items() []Focusable {
+	return []Focusable{f.choice, f.lock}
+}
+
+// BSSID returns the selected access point, or "" for any.
+func (f *accessPointForm) BSSID() string {
+	if f == nil || f.choice.Selected() == 0 {
+		return ""
+	}
+	return f.bssids[f.choice.Selected()-1]
+}
+
+// Lock returns the new access point lock, or nil if it is unchanged. Locking
+// with "Any" selected removes the lock.
+func (f *accessPointForm) Lock() *string {
+	if f == nil {
+		return nil
+	}
+	var lock string
+	if f.lock.Checked() {
+		lock = f.BSSID()
+	}
+	if strings.EqualFold(lock, f.locked) {
+		return nil
+	}
+	return &lock
+}
+
+// bandForm holds the band and channel preference of a known network.
+type bandForm struct {
+	band    *ChoiceComponent
+	channel *TextInput
+
+	initialBand    wifi.Band
+	initialChannel int
+}
+
+var bandChoices = []wifi.Band{wifi.BandUnknown, wifi.Band2GHz, wifi.Band5GHz, wifi.Band6GHz}
+
+func newBandForm(band wifi.Band, channel int) *bandForm {
+	ti := textinput.New()
+	ti.CharLimit = 3
+	ti.Width = 45
+	ti.Placeholder = "any"
+	f := &bandForm{
+		band:           NewChoiceComponent("Band:", []string{"Any", "2.4 GHz", "5 GHz", "6 GHz"}),
+		channel:        &TextInput{Model: ti, label: "Channel:"},
+		initialBand:    band,
+		initialChannel: channel,
+	}
+	f.band.SetSelected(slices.Index(bandChoices, band))
+	if channel != 0 {
+		f.channel.Model.SetValue(strconv.Itoa(channel))
+	}
+	return f
+}
+
+func (f *bandForm) selected() wifi.Band {
+	return bandChoices[f.band.Selected()]
+}
+
+// items returns the channel field only when a band is selected.
+func (f *bandForm) items() []Focusable {
+	if f.selected() == wifi.BandUnknown {
+		return []Focusable{f.band}
+	}
+	return []Focusable{f.band, f.channel}
+}
+
+// UpdateOptions sets the band and channel of opts if they differ from the
+// ones the form started with.
+func (f *bandForm) UpdateOptions(opts *wifi.UpdateOptions) error {
+	band := f.selected()
+	channel := 0
+	if s := strings.TrimSpace(f.channel.Model.Value()); s != "" && band != wifi.BandUnknown {
+		var err error
+		if channel, err = strconv.Atoi(s); err != nil {
+			return fmt.Errorf("invalid channel %q: %w", s, wifi.ErrInvalidBand)
+		}
+	}
+	if band == f.initialBand && channel == f.initialChannel {
+		return nil
+	}
+	opts.Band = &band
+	opts.Channel = &channel
+	return opts.Validate()
+}
+
+func newPriorityInput(priority int) *TextInput {
+	ti := textinput.New()
+	ti.CharLimit = 4
+	ti.Width = 45
+	ti.Placeholder = "0"
+	if priority != 0 {
+		ti.SetValue(strconv.Itoa(priority))
+	}
+	return &TextInput{Model: ti, label: "Priority:"}
+}
+
+// priorityOptions sets the priority of opts if it differs from the one of the
+// network.
+func (m *EditModel) priorityOptions(opts *wifi.UpdateOptions) error {
+	priority := 0
+	if s := strings.TrimSpace(m.priority.Model.Value()); s != "" {
+		var err error
+		if priority, err = strconv.Atoi(s); err != nil {
+			return fmt.Errorf("invalid priority %q: %w", s, wifi.ErrInvalidPriority)
+		}
+	}
+	if priority == m.selectedItem.Priority {
+		return nil
+	}
+	opts.Priority = &priority
+	return opts.Validate()
+}
+
 func NewEditModel(item *networkItem) *EditModel {
 	return NewEditModelWithWindow(item, nil)
 }
@@ -50,7 +535,6 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 		item = &networkItem{}
 	}
 	isNew := item.SSID == ""
-	var items []Focusable
 
 	ssidInput := textinput.New()
 	ssidInput.Focus()
@@ -67,6 +551,15 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 	passwordInput.EchoMode = textinput.EchoPassword
 
 	m := EditModel{selectedItem: *item, window: window}
+	if isNew {
+		m.securityGroup = NewChoiceComponent("Security:", []string{"Open", "WEP", "WPA/WPA2", "WPA3", "Enterprise"})
+	}
+	if isNew || (!item.IsKnown && item.Security.IsEnterprise()) {
+		m.enterprise = newEnterpriseForm()
+	}
+	if isNew {
+		m.generator = newGeneratorForm()
+	}
 
 	m.ssidAdapter = &TextInput{
 		Model: ssidInput,
@@ -90,6 +583,7 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 		label:   "Passphrase:",
 		OnFocus: onPasswordFocus,
 		OnBlur:  onPasswordBlur,
+		Hint:    m.passphraseHint,
 	}
 
 	if m.window != nil && m.window.Width > 0 {
@@ -98,27 +592,12 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 		m.applyWindowWidth(defaultEditContentWidth + editHorizontalMargin*2)
 	}
 
-	if isNew {
-		items = append(items, m.ssidAdapter)
-	}
-
-	security := m.selectedItem.Security
-	if isNew {
-		m.securityGroup = NewChoiceComponent("Security:", []string{"Open", "WEP", "WPA/WPA2"})
-		security = wifi.SecurityType(m.securityGroup.Selected())
-	}
-
-	if ShouldDisplayPasswordField(security) {
-		items = append(items, m.passwordAdapter)
-	}
-
-	if isNew {
-		items = append(items, m.securityGroup)
-	}
-
 	if m.selectedItem.IsKnown {
 		m.autoConnectCheckbox = NewCheckbox("Auto Connect", m.selectedItem.AutoConnect)
-		items = append(items, m.autoConnectCheckbox)
+		m.priority = newPriorityInput(m.selectedItem.Priority)
+		m.ip = newIPForm(m.selectedItem.IPv4, m.selectedItem.IPv6)
+		m.accessPoint = newAccessPointForm(m.selectedItem.Network)
+		m.band = newBandForm(m.selectedItem.PreferredBand, m.selectedItem.PreferredChannel)
 	}
 
 	var buttons []string
@@ -135,11 +614,20 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 			switch index {
 			case 0: // Join
 				return func() tea.Msg {
+					opts := wifi.JoinOptions{
+						Password: m.passwordAdapter.Model.Value(),
+						Security: m.security(),
+						IsHidden: true,
+					}
+					if err := wifi.ValidatePassphrase(opts.Security, opts.Password); err != nil {
+						return statusMsg{status: err.Error()}
+					}
+					if opts.Security.IsEnterprise() {
+						opts.Enterprise = m.enterprise.Credentials()
+					}
 					return joinNetworkMsg{
-						ssid:     m.ssidAdapter.Model.Value(),
-						password: m.passwordAdapter.Model.Value(),
-						security: wifi.SecurityType(m.securityGroup.Selected()),
-						isHidden: true,
+						ssid:        m.ssidAdapter.Model.Value(),
+						JoinOptions: opts,
 					}
 				}
 			case 1: // Cancel
@@ -153,6 +641,8 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 					return connectMsg{
 						item:        m.selectedItem,
 						autoConnect: autoConnect,
+						bssid:       m.accessPoint.BSSID(),
+						lockBSSID:   m.accessPoint.Lock(),
 					}
 				}
 			case 1: // Save
@@ -161,10 +651,23 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 					autoConnect := m.autoConnectCheckbox.Checked()
 					opts := wifi.UpdateOptions{
 						AutoConnect: &autoConnect,
+						BSSID:       m.accessPoint.Lock(),
 					}
 					if newPassword != "" {
+						if err := wifi.ValidatePassphrase(m.selectedItem.Security, newPassword); err != nil {
+							return statusMsg{status: err.Error()}
+						}
 						opts.Password = &newPassword
 					}
+					if err := m.ip.UpdateOptions(&opts); err != nil {
+						return statusMsg{status: err.Error()}
+					}
+					if err := m.band.UpdateOptions(&opts); err != nil {
+						return statusMsg{status: err.Error()}
+					}
+					if err := m.priorityOptions(&opts); err != nil {
+						return statusMsg{status: err.Error()}
+					}
 					return updateNetworkMsg{
 						item:          m.selectedItem,
 						UpdateOptions: opts,
@@ -179,11 +682,20 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 			switch index {
 			case 0: // Join
 				return func() tea.Msg {
+					opts := wifi.JoinOptions{
+						Password: m.passwordAdapter.Model.Value(),
+						Security: m.selectedItem.Security,
+						IsHidden: m.selectedItem.IsHidden,
+					}
+					if err := wifi.ValidatePassphrase(opts.Security, opts.Password); err != nil {
+						return statusMsg{status: err.Error()}
+					}
+					if m.enterprise != nil {
+						opts.Enterprise = m.enterprise.Credentials()
+					}
 					return joinNetworkMsg{
-						ssid:     m.selectedItem.SSID,
-						password: m.passwordAdapter.Model.Value(),
-						security: m.selectedItem.Security,
-						isHidden: m.selectedItem.IsHidden,
+						ssid:        m.selectedItem.SSID,
+						JoinOptions: opts,
 					}
 				}
 			case 1: // Cancel
@@ -193,9 +705,8 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 		return nil
 	}
 	m.buttonGroup = NewMultiButtonComponent(buttons, buttonAction)
-	items = append(items, m.buttonGroup)
 
-	m.focusManager = NewFocusManager(items...)
+	m.focusManager = NewFocusManager(m.formItems()...)
 
 	if m.selectedItem.IsKnown {
 		m.focusManager.SetFocus(m.buttonGroup)
@@ -205,6 +716,111 @@ func NewEditModelWithWindow(item *networkItem, window *WindowState) *EditModel {
 	return &m
 }
 
+// security returns the security type selected in the form.
+func (m *EditModel) security() wifi.SecurityType {
+	if m.securityGroup != nil {
+		return newNetworkSecurity[m.securityGroup.Selected()]
+	}
+	return m.selectedItem.Security
+}
+
+// formItems returns the focusable fields for the current form state.
+func (m *EditModel) formItems() []Focusable {
+	var items []Focusable
+	isNew := m.selectedItem.SSID == ""
+	if isNew {
+		items = append(items, m.ssidAdapter)
+	}
+
+	// New networks always offer a passphrase, it is also used for PEAP/TTLS.
+	security := m.security()
+	if isNew || ShouldDisplayPasswordField(security) {
+		items = append(items, m.passwordAdapter)
+	}
+
+	if isNew {
+		items = append(items, m.securityGroup)
+	}
+	if m.generator != nil && security.RequiresPassphrase() {
+		items = append(items, m.generator.items()...)
+	}
+
+	if m.enterprise != nil && security.IsEnterprise() {
+		items = append(items, m.enterprise.items()...)
+	}
+
+	if m.autoConnectCheckbox != nil {
+		items = append(items, m.autoConnectCheckbox)
+	}
+	if m.priority != nil {
+		items = append(items, m.priority)
+	}
+	if m.accessPoint != nil {
+		items = append(items, m.accessPoint.items()...)
+	}
+	if m.band != nil {
+		items = append(items, m.band.items()...)
+	}
+	if m.ip != nil {
+		items = append(items, m.ip.items()...)
+	}
+	return append(items, m.buttonGroup)
+}
+
+func (m *EditModel) textInputs() []*TextInput {
+	inputs := []*TextInput{m.ssidAdapter, m.passwordAdapter}
+	if m.enterprise != nil {
+		inputs = append(inputs, m.enterprise.inputs()...)
+	}
+	if m.generator != nil {
+		inputs = append(inputs, m.generator.length)
+	}
+	if m.ip != nil {
+		inputs = append(inputs, m.ip.inputs()...)
+	}
+	if m.band != nil {
+		inputs = append(inputs, m.band.channel)
+	}
+	if m.priority != nil {
+		inputs = append(inputs, m.priority)
+	}
+	return inputs
+}
+
+// passphraseHint rates a passphrase, or explains why it is not valid for the
+// selected security type.
+func (m *EditModel) passphraseHint(value string) string {
+	security := m.security()
+	if value == "" || !security.RequiresPassphrase() {
+		return ""
+	}
+	if err := wifi.ValidatePassphrase(security, value); err != nil {
+		reason, _, _ := strings.Cut(err.Error(), ": ")
+		return lipgloss.NewStyle().Foreground(CurrentTheme.Error).Render("✗ " + reason)
+	}
+	strength := helpers.EstimatePassphraseStrength(value)
+	style := lipgloss.NewStyle().Foreground(CurrentTheme.Success)
+	switch strength {
+	case helpers.StrengthWeak:
+		style = style.Foreground(CurrentTheme.Error)
+	case helpers.StrengthFair:
+		style = style.Foreground(CurrentTheme.Normal)
+	}
+	return style.Render("✓ " + strength.String())
+}
+
+// generatePassphrase fills in the passphrase field with a new passphrase and
+// focuses it, which reveals it.
+func (m *EditModel) generatePassphrase() tea.Cmd {
+	password, err := m.generator.Generate(m.security())
+	if err != nil {
+		return func() tea.Msg { return statusMsg{status: err.Error()} }
+	}
+	m.passwordAdapter.Model.SetValue(password)
+	m.passwordAdapter.Model.CursorEnd()
+	return m.focusManager.SetFocus(m.passwordAdapter)
+}
+
 func (m *EditModel) SetPassword(password string) {
 	m.passwordAdapter.Model.SetValue(password)
 	m.passwordAdapter.Model.CursorEnd()
@@ -240,6 +856,11 @@ func (m *EditModel) Update(msg tea.Msg) (Component, tea.Cmd) {
 		m.secretsLoaded = true
 		m.SetPassword(msg.secret)
 		return m, nil
+	case connectionDetailsLoadedMsg:
+		if msg.details != nil && msg.details.SSID == m.selectedItem.SSID {
+			m.connection = msg.details
+		}
+		return m, nil
 	case startForgettingMsg:
 		m.isForgetting = true
 		return m, nil
@@ -261,6 +882,10 @@ func (m *EditModel) Update(msg tea.Msg) (Component, tea.Cmd) {
 			return m, m.focusManager.Prev()
 		case "esc":
 			return m, func() tea.Msg { return popViewMsg{} }
+		case "ctrl+g":
+			if m.generator != nil && m.security().RequiresPassphrase() {
+				return m, m.generatePassphrase()
+			}
 		case "enter":
 			if m.focusManager.Focused() == m.passwordAdapter {
 				return m, m.focusManager.Next()
@@ -271,6 +896,11 @@ func (m *EditModel) Update(msg tea.Msg) (Component, tea.Cmd) {
 	newFocusable, cmd := m.focusManager.Update(msg)
 	cmds = append(cmds, cmd)
 
+	// Choices can change which fields are relevant
+	if _, ok := msg.(tea.KeyMsg); ok && (m.enterprise != nil || m.ip != nil) {
+		cmds = append(cmds, m.focusManager.SetItems(m.formItems()...))
+	}
+
 	// FIXME: This is a hack to update the underlying models
 	if ta, ok := newFocusable.(*TextInput); ok {
 		if ta.label == "SSID:" {
@@ -283,8 +913,22 @@ func (m *EditModel) Update(msg tea.Msg) (Component, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// OnEnter requests the live details of the connection when the network is
+// active.
+func (m *EditModel) OnEnter() tea.Cmd {
+	if !m.selectedItem.IsActive || m.connection != nil {
+		return nil
+	}
+	return func() tea.Msg { return loadConnectionDetailsMsg{} }
+}
+
 func (m *EditModel) IsConsumingInput() bool {
-	return m.ssidAdapter.Model.Focused() || m.passwordAdapter.Model.Focused()
+	for _, input := range m.textInputs() {
+		if input.Model.Focused() {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *EditModel) availableContentWidth() int {
@@ -297,8 +941,9 @@ func (m *EditModel) applyWindowWidth(width int) {
 	if inputWidth < 10 {
 		inputWidth = 10
 	}
-	m.ssidAdapter.Model.Width = inputWidth
-	m.passwordAdapter.Model.Width = inputWidth
+	for _, input := range m.textInputs() {
+		input.Model.Width = inputWidth
+	}
 }
 
 func (m *EditModel) View() string {
@@ -314,15 +959,8 @@ func (m *EditModel) View() string {
 		var details strings.Builder
 		details.WriteString(formatLabel.Render("SSID: "))
 		details.WriteString(fmt.Sprintf("%s\n", m.selectedItem.SSID))
-		var security string
-		switch m.selectedItem.Security {
-		case wifi.SecurityOpen:
-			security = "Open"
-		case wifi.SecurityWEP:
-			security = "WEP"
-		case wifi.SecurityWPA:
-			security = "WPA/WPA2"
-		default:
+		security := m.selectedItem.Security.String()
+		if m.selectedItem.Security == wifi.SecurityUnknown {
 			if m.selectedItem.IsSecure {
 				security = "Secure"
 			} else {
@@ -348,6 +986,32 @@ func (m *EditModel) View() string {
 				details.WriteString("\n  ")
 				details.WriteString(CurrentTheme.FormatSignalStrength(ap.Strength))
 				details.WriteString(fmt.Sprintf("  %dMHz  %s", ap.Frequency, bssid))
+				if ap.BSSID != "" && strings.EqualFold(ap.BSSID, m.selectedItem.LockedBSSID) {
+					details.WriteString(" (locked)")
+				}
+				if samples := m.history.AccessPoint(ap.BSSID); len(samples) > 1 {
+					details.WriteString("\n")
+					details.WriteString(m.signalGraph(samples))
+				}
+			}
+		}
+		if !hasBSSID(m.selectedItem.AccessPoints) {
+			if samples := m.history.Network(m.selectedItem.SSID); len(samples) > 1 {
+				details.WriteString("\n\n")
+				details.WriteString(formatLabel.Render("Signal History:"))
+				details.WriteString("\n")
+				details.WriteString(m.signalGraph(samples))
+			}
+		}
+		if m.connection != nil {
+			if fields := helpers.ConnectionDetailFields(*m.connection); len(fields) > 0 {
+				details.WriteString("\n\n")
+				details.WriteString(formatLabel.Render("Connection:"))
+				for _, field := range fields {
+					details.WriteString("\n  ")
+					details.WriteString(formatLabel.Render(field.Label + ": "))
+					details.WriteString(field.Value)
+				}
 			}
 		}
 		if m.selectedItem.IsKnown && m.selectedItem.LastConnected != nil {
@@ -381,10 +1045,15 @@ func (m *EditModel) View() string {
 	if m.selectedItem.IsKnown {
 		password := m.passwordAdapter.Model.Value()
 		if m.passwordRevealed && password != "" {
-			qrCodeString, err := qrwifi.GenerateWifiQRCode(m.selectedItem.SSID, password, m.selectedItem.IsSecure, m.selectedItem.IsHidden)
+			security := m.selectedItem.Security
+			if security == wifi.SecurityUnknown && m.selectedItem.IsSecure {
+				security = wifi.SecurityWPA
+			}
+			content, err := qrwifi.WifiStringForSecurity(m.selectedItem.SSID, password, security, m.selectedItem.IsHidden)
 			if err == nil {
 				s.WriteString("\n\n")
-				s.WriteString(qrCodeString)
+				// Leave room for the status line.
+				s.WriteString(renderQRCode(content, m.availableContentWidth(), m.window.BaseHeight(0)-2))
 			}
 		}
 	}
@@ -392,8 +1061,30 @@ func (m *EditModel) View() string {
 	return s.String()
 }
 
+// signalGraph renders samples as an indented graph that fits the details box.
+func (m *EditModel) signalGraph(samples []uint8) string {
+	width := m.availableContentWidth() - 8 // border, padding and indent
+	if width > signalHistoryLength {
+		width = signalHistoryLength
+	}
+	if width < sparklineWidth {
+		width = sparklineWidth
+	}
+	lines := strings.Split(renderSignalGraph(samples, width, 3), "\n")
+	return "  " + strings.Join(lines, "\n  ")
+}
+
+func hasBSSID(aps []wifi.AccessPoint) bool {
+	for _, ap := range aps {
+		if ap.BSSID != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func ShouldDisplayPasswordField(security wifi.SecurityType) bool {
-	return security != wifi.SecurityOpen
+	return security != wifi.SecurityOpen && security != wifi.SecurityOWE
 }
 
 // forgetHandler handles the key presses for the forget confirmation.
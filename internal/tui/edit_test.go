@@ -2,6 +2,10 @@ package tui
 
 import (
 	"fmt"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -172,6 +176,237 @@ func TestEditModel_SaveAutoConnectDoesNotOverwriteUnloadedPassword(t *testing.T)
 	}
 }
 
+func TestEditModel_SaveIPConfig(t *testing.T) {
+	item := &networkItem{
+		Network: wifi.Network{
+			SSID:     "LabNet",
+			IsKnown:  true,
+			IsSecure: true,
+			Security: wifi.SecurityWPA,
+			IPv4: &wifi.IPConfig{
+				Method:    wifi.IPMethodManual,
+				Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+				Gateway:   netip.MustParseAddr("192.168.1.1"),
+				DNS:       []netip.Addr{netip.MustParseAddr("1.1.1.1")},
+			},
+			IPv6: &wifi.IPConfig{Method: wifi.IPMethodAuto},
+		},
+	}
+	m := NewEditModel(item)
+	if got := m.ip.ipv4Addresses.Model.Value(); got != "192.168.1.10/24" {
+		t.Fatalf("IPv4 addresses = %q, want the saved address", got)
+	}
+	if got := m.ip.dns.Model.Value(); got != "1.1.1.1" {
+		t.Fatalf("DNS servers = %q, want the saved server", got)
+	}
+	if !slices.Contains(m.focusManager.items, Focusable(m.ip.ipv4Gateway)) {
+		t.Fatal("manual IPv4 configuration does not show the gateway field")
+	}
+
+	save := func() tea.Msg {
+		m.buttonGroup.selected = 1 // Save
+		m.focusManager.SetFocus(m.buttonGroup)
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatal("Save did not return a command")
+		}
+		return cmd()
+	}
+
+	m.ip.ipv4Addresses.Model.SetValue("10.0.0.5/8, 10.0.0.6/8")
+	m.ip.dnsSearch.Model.SetValue("lab.example.com")
+	msg, ok := save().(updateNetworkMsg)
+	if !ok {
+		t.Fatal("Save did not return updateNetworkMsg")
+	}
+	want := &wifi.IPConfig{
+		Method:    wifi.IPMethodManual,
+		Addresses: []netip.Prefix{netip.MustParsePrefix("10.0.0.5/8"), netip.MustParsePrefix("10.0.0.6/8")},
+		Gateway:   netip.MustParseAddr("192.168.1.1"),
+		DNS:       []netip.Addr{netip.MustParseAddr("1.1.1.1")},
+		DNSSearch: []string{"lab.example.com"},
+	}
+	if !reflect.DeepEqual(msg.IPv4, want) {
+		t.Fatalf("Save IPv4 = %#v, want %#v", msg.IPv4, want)
+	}
+	if msg.IPv6 != nil {
+		t.Fatalf("Save IPv6 = %#v, want nil for an unchanged family", msg.IPv6)
+	}
+
+	m.ip.ipv4Gateway.Model.SetValue("not-an-address")
+	if status, ok := save().(statusMsg); !ok || !strings.Contains(status.status, "invalid gateway") {
+		t.Fatalf("Save with invalid gateway returned %#v, want a status message", status)
+	}
+}
+
+func TestEditModel_AccessPointLock(t *testing.T) {
+	item := &networkItem{
+		Network: wifi.Network{
+			SSID:        "Mesh",
+			IsKnown:     true,
+			IsSecure:    true,
+			Security:    wifi.SecurityWPA,
+			LockedBSSID: "AA:BB:CC:DD:EE:02",
+			AccessPoints: []wifi.AccessPoint{
+				{BSSID: "AA:BB:CC:DD:EE:01", Strength: 80, Frequency: 2412},
+				{BSSID: "AA:BB:CC:DD:EE:02", Strength: 40, Frequency: 5180},
+			},
+		},
+	}
+	m := NewEditModel(item)
+	if m.accessPoint == nil {
+		t.Fatal("known network with access points has no access point choice")
+	}
+	if got := m.accessPoint.BSSID(); got != "AA:BB:CC:DD:EE:02" {
+		t.Fatalf("initial access point = %q, want the locked one", got)
+	}
+	if !m.accessPoint.lock.Checked() {
+		t.Fatal("lock checkbox is not checked for a locked network")
+	}
+	if !strings.Contains(m.View(), "AA:BB:CC:DD:EE:02 (locked)") {
+		t.Error("View() does not mark the locked access point")
+	}
+
+	press := func(button int) tea.Msg {
+		m.buttonGroup.selected = button
+		m.focusManager.SetFocus(m.buttonGroup)
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatal("button did not return a command")
+		}
+		return cmd()
+	}
+
+	// Choosing another access point moves the lock with it.
+	m.focusManager.SetFocus(m.accessPoint.choice)
+	m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	connect, ok := press(0).(connectMsg)
+	if !ok {
+		t.Fatal("Connect did not return connectMsg")
+	}
+	if connect.bssid != "AA:BB:CC:DD:EE:01" {
+		t.Errorf("Connect bssid = %q, want AA:BB:CC:DD:EE:01", connect.bssid)
+	}
+	if connect.lockBSSID == nil || *connect.lockBSSID != "AA:BB:CC:DD:EE:01" {
+		t.Errorf("Connect lockBSSID = %v, want AA:BB:CC:DD:EE:01", connect.lockBSSID)
+	}
+
+	// Unchecking the lock clears it on save.
+	m.accessPoint.lock.checked = false
+	save, ok := press(1).(updateNetworkMsg)
+	if !ok {
+		t.Fatal("Save did not return updateNetworkMsg")
+	}
+	if save.BSSID == nil || *save.BSSID != "" {
+		t.Errorf("Save BSSID = %v, want an empty lock", save.BSSID)
+	}
+}
+
+func TestEditModel_SaveBandPreference(t *testing.T) {
+	item := &networkItem{
+		Network: wifi.Network{
+			SSID:          "Office",
+			IsKnown:       true,
+			IsSecure:      true,
+			Security:      wifi.SecurityWPA,
+			PreferredBand: wifi.Band5GHz,
+		},
+	}
+	m := NewEditModel(item)
+	if !slices.Contains(m.focusManager.items, Focusable(m.band.channel)) {
+		t.Fatal("band preference does not show the channel field")
+	}
+
+	save := func() tea.Msg {
+		m.buttonGroup.selected = 1 // Save
+		m.focusManager.SetFocus(m.buttonGroup)
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatal("Save did not return a command")
+		}
+		return cmd()
+	}
+
+	msg, ok := save().(updateNetworkMsg)
+	if !ok {
+		t.Fatal("Save did not return updateNetworkMsg")
+	}
+	if msg.Band != nil || msg.Channel != nil {
+		t.Fatalf("Save Band = %v, Channel = %v, want nil for an unchanged preference", msg.Band, msg.Channel)
+	}
+
+	m.band.channel.Model.SetValue("36")
+	msg, ok = save().(updateNetworkMsg)
+	if !ok {
+		t.Fatal("Save did not return updateNetworkMsg")
+	}
+	if msg.Band == nil || *msg.Band != wifi.Band5GHz || msg.Channel == nil || *msg.Channel != 36 {
+		t.Fatalf("Save Band = %v, Channel = %v, want 5GHz channel 36", msg.Band, msg.Channel)
+	}
+
+	m.band.channel.Model.SetValue("1")
+	if status, ok := save().(statusMsg); !ok || !strings.Contains(status.status, "channel 1") {
+		t.Fatalf("Save with a 2.4GHz channel returned %#v, want a status message", status)
+	}
+
+	// Choosing any band hides the channel and clears the preference.
+	m.focusManager.SetFocus(m.band.band)
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	m.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	if slices.Contains(m.focusManager.items, Focusable(m.band.channel)) {
+		t.Fatal("channel field is shown without a band")
+	}
+	msg, ok = save().(updateNetworkMsg)
+	if !ok {
+		t.Fatal("Save did not return updateNetworkMsg")
+	}
+	if msg.Band == nil || *msg.Band != wifi.BandUnknown || msg.Channel == nil || *msg.Channel != 0 {
+		t.Fatalf("Save Band = %v, Channel = %v, want any band", msg.Band, msg.Channel)
+	}
+}
+
+func TestEditModel_ShowsConnectionDetails(t *testing.T) {
+	item := &networkItem{
+		Network: wifi.Network{SSID: "Home", IsKnown: true, IsActive: true, Security: wifi.SecurityWPA},
+	}
+	m := NewEditModel(item)
+
+	cmd := m.OnEnter()
+	if cmd == nil {
+		t.Fatal("OnEnter() did not request connection details for an active network")
+	}
+	if _, ok := cmd().(loadConnectionDetailsMsg); !ok {
+		t.Fatalf("OnEnter() command returned %T, want loadConnectionDetailsMsg", cmd())
+	}
+
+	// Details for another network, such as after roaming, are ignored.
+	m.Update(connectionDetailsLoadedMsg{details: &wifi.ConnectionDetails{SSID: "Other", BSSID: "AA:BB:CC:DD:EE:09"}})
+	if strings.Contains(m.View(), "AA:BB:CC:DD:EE:09") {
+		t.Error("View() shows connection details for a different network")
+	}
+
+	m.Update(connectionDetailsLoadedMsg{details: &wifi.ConnectionDetails{
+		SSID:          "Home",
+		BSSID:         "AA:BB:CC:DD:EE:01",
+		Frequency:     5180,
+		IPv4Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+	}})
+	view := m.View()
+	for _, want := range []string{"AA:BB:CC:DD:EE:01", "36 (5GHz, 5180 MHz)", "192.168.1.10/24"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q", want)
+		}
+	}
+	if m.OnEnter() != nil {
+		t.Error("OnEnter() requested connection details again after they were loaded")
+	}
+
+	inactive := NewEditModel(&networkItem{Network: wifi.Network{SSID: "Away", IsKnown: true}})
+	if inactive.OnEnter() != nil {
+		t.Error("OnEnter() requested connection details for an inactive network")
+	}
+}
+
 func TestSecretLoadingLoop(t *testing.T) {
 	// Create a mock backend that fails to get secrets with ErrMissingPermission
 	b, err := mock.New()
@@ -281,3 +516,176 @@ func TestSecretLoadingLoop(t *testing.T) {
 		t.Log("No loadSecretsMsg found, loop broken")
 	}
 }
+
+func TestEditModel_JoinEnterpriseNetwork(t *testing.T) {
+	m := NewEditModel(nil) // New network
+	m.ssidAdapter.Model.SetValue("Corp")
+	m.passwordAdapter.Model.SetValue("hunter2")
+
+	if got := len(m.focusManager.items); got != 4 {
+		t.Fatalf("expected 4 form items before selecting enterprise, got %d", got)
+	}
+
+	// Focus the security choice and select Enterprise
+	for m.focusManager.Focused() != m.securityGroup {
+		m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	for m.security() != wifi.SecurityEnterprise {
+		m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	}
+	if m.focusManager.Focused() != m.securityGroup {
+		t.Fatal("expected focus to stay on the security choice after rebuilding the form")
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.focusManager.Focused() != m.enterprise.identity {
+		t.Fatalf("expected identity field after security choice, got %T", m.focusManager.Focused())
+	}
+	if !m.IsConsumingInput() {
+		t.Error("expected identity field to consume input")
+	}
+	m.enterprise.identity.Model.SetValue("alice")
+
+	m.buttonGroup.selected = 0 // Join
+	_, cmd := m.buttonGroup.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Join did not return a command")
+	}
+	msg, ok := cmd().(joinNetworkMsg)
+	if !ok {
+		t.Fatalf("Join returned %T, want joinNetworkMsg", msg)
+	}
+	if msg.ssid != "Corp" || msg.Password != "hunter2" || msg.Security != wifi.SecurityEnterprise {
+		t.Fatalf("unexpected join message: %+v", msg)
+	}
+	want := wifi.EnterpriseCredentials{
+		Identity:   "alice",
+		EAPMethod:  wifi.EAPMethodPEAP,
+		Phase2Auth: "mschapv2",
+	}
+	if msg.Enterprise == nil || *msg.Enterprise != want {
+		t.Fatalf("Enterprise = %+v, want %+v", msg.Enterprise, want)
+	}
+}
+
+func TestEditModel_NewNetworkSecurityChoices(t *testing.T) {
+	m := NewEditModel(nil)
+	m.securityGroup.selected = 2 // WPA/WPA2
+	if got := m.security(); got != wifi.SecurityWPA {
+		t.Errorf("security() = %v, want %v", got, wifi.SecurityWPA)
+	}
+	m.securityGroup.selected = 3 // WPA3
+	if got := m.security(); got != wifi.SecuritySAE {
+		t.Errorf("security() = %v, want %v", got, wifi.SecuritySAE)
+	}
+	m.securityGroup.selected = 0 // Open
+	if got := m.security(); got != wifi.SecurityOpen {
+		t.Errorf("security() = %v, want %v", got, wifi.SecurityOpen)
+	}
+}
+
+func TestEditModel_SavePriority(t *testing.T) {
+	item := &networkItem{
+		Network: wifi.Network{
+			SSID:     "Office",
+			IsKnown:  true,
+			IsSecure: true,
+			Security: wifi.SecurityWPA,
+			Priority: 5,
+		},
+	}
+	m := NewEditModel(item)
+	if got := m.priority.Model.Value(); got != "5" {
+		t.Fatalf("priority = %q, want the saved priority", got)
+	}
+
+	save := func() tea.Msg {
+		m.buttonGroup.selected = 1 // Save
+		m.focusManager.SetFocus(m.buttonGroup)
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatal("Save did not return a command")
+		}
+		return cmd()
+	}
+
+	msg, ok := save().(updateNetworkMsg)
+	if !ok {
+		t.Fatal("Save did not return updateNetworkMsg")
+	}
+	if msg.Priority != nil {
+		t.Fatalf("Save Priority = %v, want nil for an unchanged priority", *msg.Priority)
+	}
+
+	m.priority.Model.SetValue("-2")
+	msg, ok = save().(updateNetworkMsg)
+	if !ok {
+		t.Fatal("Save did not return updateNetworkMsg")
+	}
+	if msg.Priority == nil || *msg.Priority != -2 {
+		t.Fatalf("Save Priority = %v, want -2", msg.Priority)
+	}
+
+	m.priority.Model.SetValue("high")
+	if status, ok := save().(statusMsg); !ok || !strings.Contains(status.status, "invalid priority") {
+		t.Fatalf("Save with an invalid priority returned %#v, want a status message", status)
+	}
+}
+
+func TestEditModel_GeneratePassphrase(t *testing.T) {
+	m := NewEditModel(nil) // New network
+	m.ssidAdapter.Model.SetValue("Lab")
+	if slices.Contains(m.focusManager.items, Focusable(m.generator.style)) {
+		t.Fatal("expected no generator for an open network")
+	}
+
+	for m.focusManager.Focused() != m.securityGroup {
+		m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	for m.security() != wifi.SecurityWPA {
+		m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	}
+	if !slices.Contains(m.focusManager.items, Focusable(m.generator.style)) {
+		t.Fatal("expected the generator for a WPA network")
+	}
+
+	join := func() tea.Msg {
+		m.buttonGroup.selected = 0 // Join
+		_, cmd := m.buttonGroup.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatal("Join did not return a command")
+		}
+		return cmd()
+	}
+
+	m.passwordAdapter.Model.SetValue("short")
+	if hint := m.passphraseHint("short"); !strings.Contains(hint, "8 to 63") {
+		t.Errorf("passphraseHint() = %q, want the length limits", hint)
+	}
+	if status, ok := join().(statusMsg); !ok || !strings.Contains(status.status, "8 to 63") {
+		t.Fatalf("Join with a short passphrase returned %#v, want a status message", status)
+	}
+
+	m.generator.length.Model.SetValue("20")
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	password := m.passwordAdapter.Model.Value()
+	if len(password) != 20 {
+		t.Fatalf("generated passphrase %q, want 20 characters", password)
+	}
+	if m.focusManager.Focused() != m.passwordAdapter {
+		t.Error("expected the passphrase field to be focused after generating")
+	}
+	if hint := m.passphraseHint(password); !strings.Contains(hint, "very strong") {
+		t.Errorf("passphraseHint(%q) = %q, want very strong", password, hint)
+	}
+	if msg, ok := join().(joinNetworkMsg); !ok || msg.Password != password {
+		t.Fatalf("Join returned %#v, want the generated passphrase", msg)
+	}
+
+	m.securityGroup.selected = 1   // WEP
+	m.generator.style.selected = 1 // Words
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	if status, ok := cmd().(statusMsg); !ok || !strings.Contains(status.status, "WEP") {
+		t.Errorf("generating words for WEP returned %#v, want a status message", status)
+	}
+}
@@ -174,3 +174,24 @@ func (m *FocusManager) SetFocus(target Focusable) tea.Cmd {
 	}
 	return nil
 }
+
+// SetItems replaces the managed items. Focus stays on the previously focused
+// item if it is still present, otherwise it moves to the first item.
+func (m *FocusManager) SetItems(items ...Focusable) tea.Cmd {
+	focused := m.Focused()
+	m.items = items
+	for i, item := range items {
+		if item == focused {
+			m.focus = i
+			return nil
+		}
+	}
+	if focused != nil {
+		focused.Blur()
+	}
+	m.focus = 0
+	if len(items) > 0 {
+		return items[0].Focus()
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// signalHistoryLength is the number of samples kept per network and access
+// point, about a minute of scans at the fast scan interval.
+const signalHistoryLength = 30
+
+// signalHistory keeps recent signal strengths across list reloads, so the
+// trend is visible while moving around. Access points are keyed by BSSID.
+type signalHistory struct {
+	networks     map[string][]uint8
+	accessPoints map[string][]uint8
+}
+
+func newSignalHistory() *signalHistory {
+	return &signalHistory{
+		networks:     make(map[string][]uint8),
+		accessPoints: make(map[string][]uint8),
+	}
+}
+
+// Record appends a sample for every network and access point in a snapshot.
+// Entries missing from the snapshot get a zero sample, and are dropped once
+// they have been gone for the whole window.
+func (h *signalHistory) Record(networks []wifi.Network) {
+	seenNetworks := make(map[string]uint8)
+	seenAccessPoints := make(map[string]uint8)
+	for _, n := range networks {
+		if strength := n.Strength(); strength > seenNetworks[n.SSID] {
+			seenNetworks[n.SSID] = strength
+		}
+		for _, ap := range n.AccessPoints {
+			if ap.BSSID == "" {
+				continue
+			}
+			bssid := strings.ToUpper(ap.BSSID)
+			if ap.Strength > seenAccessPoints[bssid] {
+				seenAccessPoints[bssid] = ap.Strength
+			}
+		}
+	}
+	recordSamples(h.networks, seenNetworks)
+	recordSamples(h.accessPoints, seenAccessPoints)
+}
+
+func recordSamples(history map[string][]uint8, seen map[string]uint8) {
+	for key, samples := range history {
+		samples = append(samples, seen[key])
+		if len(samples) > signalHistoryLength {
+			samples = samples[len(samples)-signalHistoryLength:]
+		}
+		if isSilent(samples) {
+			delete(history, key)
+			continue
+		}
+		history[key] = samples
+	}
+	for key, strength := range seen {
+		if _, ok := history[key]; !ok && strength > 0 {
+			history[key] = []uint8{strength}
+		}
+	}
+}
+
+func isSilent(samples []uint8) bool {
+	for _, s := range samples {
+		if s > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Network returns the samples for a network, oldest first.
+func (h *signalHistory) Network(ssid string) []uint8 {
+	if h == nil {
+		return nil
+	}
+	return h.networks[ssid]
+}
+
+// AccessPoint returns the samples for an access point, oldest first.
+func (h *signalHistory) AccessPoint(bssid string) []uint8 {
+	if h == nil || bssid == "" {
+		return nil
+	}
+	return h.accessPoints[strings.ToUpper(bssid)]
+}
+
+var graphLevels = []rune("▁▂▃▄▅▆▇█")
+
+// graphRows draws the latest width samples as a bar graph height rows tall,
+// top row first. Missing samples on the left are blank, and any signal at all
+// gets at least the lowest bar so it can be told apart from no signal.
+func graphRows(samples []uint8, width, height int) [][]rune {
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	offset := width - len(samples)
+	steps := len(graphLevels)
+	rows := make([][]rune, height)
+	for r := range rows {
+		rows[r] = []rune(strings.Repeat(" ", width))
+	}
+	for i, s := range samples {
+		level := int(s) * height * steps / 100
+		if s > 0 && level == 0 {
+			level = 1
+		}
+		for r := range rows {
+			fill := level - (height-1-r)*steps
+			if fill <= 0 {
+				continue
+			}
+			if fill > steps {
+				fill = steps
+			}
+			rows[r][offset+i] = graphLevels[fill-1]
+		}
+	}
+	return rows
+}
+
+// renderSignalGraph renders graphRows with each column colored by its
+// strength, like FormatSignalStrength.
+func renderSignalGraph(samples []uint8, width, height int) string {
+	rows := graphRows(samples, width, height)
+	if len(samples) > width {
+		samples = samples[len(samples)-width:]
+	}
+	offset := width - len(samples)
+	lines := make([]string, len(rows))
+	for r, row := range rows {
+		var b strings.Builder
+		b.WriteString(string(row[:offset]))
+		for i, s := range samples {
+			b.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.signalColor(s)).Render(string(row[offset+i])))
+		}
+		lines[r] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
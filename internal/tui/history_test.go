@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/mock"
+)
+
+func TestSignalHistory_Record(t *testing.T) {
+	h := newSignalHistory()
+	snapshot := func(strengths ...uint8) []wifi.Network {
+		var aps []wifi.AccessPoint
+		for i, s := range strengths {
+			aps = append(aps, wifi.AccessPoint{BSSID: []string{"aa:00:00:00:00:01", "AA:00:00:00:00:02"}[i], Strength: s})
+		}
+		return []wifi.Network{{SSID: "Office", AccessPoints: aps}}
+	}
+
+	h.Record(snapshot(40, 70))
+	h.Record(snapshot(50, 60))
+	h.Record(snapshot(60))
+
+	if got, want := h.AccessPoint("AA:00:00:00:00:01"), []uint8{40, 50, 60}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AccessPoint(01) = %v, want %v", got, want)
+	}
+	if got, want := h.AccessPoint("aa:00:00:00:00:02"), []uint8{70, 60, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AccessPoint(02) = %v, want %v with a gap once it disappeared", got, want)
+	}
+	if got, want := h.Network("Office"), []uint8{70, 60, 60}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Network() = %v, want the strongest access point of each snapshot %v", got, want)
+	}
+
+	for i := 0; i < signalHistoryLength; i++ {
+		h.Record(snapshot(60))
+	}
+	if got := len(h.AccessPoint("AA:00:00:00:00:01")); got != signalHistoryLength {
+		t.Errorf("history length = %d, want it bounded to %d", got, signalHistoryLength)
+	}
+	if got := h.AccessPoint("AA:00:00:00:00:02"); got != nil {
+		t.Errorf("AccessPoint(02) = %v, want it dropped after being gone for the whole window", got)
+	}
+}
+
+func TestGraphRows(t *testing.T) {
+	rows := graphRows([]uint8{0, 1, 50, 100}, 6, 1)
+	if got, want := string(rows[0]), "   ▁▄█"; got != want {
+		t.Errorf("graphRows() height 1 = %q, want %q", got, want)
+	}
+
+	rows = graphRows([]uint8{25, 50, 100}, 3, 2)
+	got := []string{string(rows[0]), string(rows[1])}
+	want := []string{"  █", "▄██"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("graphRows() height 2 = %q, want %q", got, want)
+	}
+}
+
+func TestTuiModel_ListShowsSignalHistory(t *testing.T) {
+	backend, err := mock.New()
+	if err != nil {
+		t.Fatalf("mock.New() failed: %v", err)
+	}
+	m, err := NewModel(backend)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+
+	networks := func(strength uint8) []wifi.Network {
+		return []wifi.Network{{SSID: "Hallway", IsVisible: true, AccessPoints: []wifi.AccessPoint{
+			{BSSID: "AA:00:00:00:00:01", Strength: strength, Frequency: 2412},
+		}}}
+	}
+	m.Update(scanFinishedMsg{networks: networks(25)})
+	m.Update(networksLoadedMsg(networks(100)))
+
+	if view := m.View(); !strings.Contains(view, "▂█") {
+		t.Errorf("list view does not contain the signal sparkline in\n%s", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	edit, ok := m.stack.Top().(*EditModel)
+	if !ok {
+		t.Fatalf("expected the edit view, got %T", m.stack.Top())
+	}
+	if view := edit.View(); !strings.Contains(view, "▆█") {
+		t.Errorf("edit view does not contain the access point graph in\n%s", view)
+	}
+}
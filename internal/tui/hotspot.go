@@ -0,0 +1,175 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/shazow/wifitui/internal/helpers"
+	"github.com/shazow/wifitui/qrwifi"
+	"github.com/shazow/wifitui/wifi"
+)
+
+// hotspotBands are the bands offered when starting a hotspot, matching the
+// options of the band choice.
+var hotspotBands = []wifi.Band{wifi.BandUnknown, wifi.Band2GHz, wifi.Band5GHz}
+
+// HotspotModel starts and stops a hotspot. While it runs, the view shows how
+// to join it, with a QR code for phones.
+type HotspotModel struct {
+	focusManager *FocusManager
+	ssid         *TextInput
+	password     *TextInput
+	band         *ChoiceComponent
+	startButtons *MultiButtonComponent
+	stopButtons  *MultiButtonComponent
+
+	// status is nil until the backend has reported it.
+	status *wifi.HotspotStatus
+	// startedPassword is remembered after starting, for backends that can't
+	// read it back.
+	startedPassword string
+}
+
+func NewHotspotModel() *HotspotModel {
+	ssidInput := textinput.New()
+	ssidInput.CharLimit = 32
+	ssidInput.Width = 45
+	ssidInput.SetValue(helpers.DefaultHotspotSSID())
+
+	passwordInput := textinput.New()
+	passwordInput.CharLimit = 63
+	passwordInput.Width = 45
+	passwordInput.Placeholder = "open hotspot"
+	if password, err := helpers.RandomPassphrase(helpers.HotspotPassphraseLength); err == nil {
+		passwordInput.SetValue(password)
+	}
+
+	m := &HotspotModel{
+		ssid:     &TextInput{Model: ssidInput, label: "SSID:"},
+		password: &TextInput{Model: passwordInput, label: "Passphrase:"},
+		band:     NewChoiceComponent("Band:", []string{"Any", "2.4 GHz", "5 GHz"}),
+	}
+	m.startButtons = NewMultiButtonComponent([]string{"Start", "Cancel"}, func(index int) tea.Cmd {
+		if index == 1 {
+			return func() tea.Msg { return popViewMsg{} }
+		}
+		config := m.config()
+		if err := config.Validate(); err != nil {
+			return func() tea.Msg { return statusMsg{status: err.Error()} }
+		}
+		m.startedPassword = config.Password
+		return func() tea.Msg { return startHotspotMsg{config} }
+	})
+	m.stopButtons = NewMultiButtonComponent([]string{"Stop", "Close"}, func(index int) tea.Cmd {
+		if index == 1 {
+			return func() tea.Msg { return popViewMsg{} }
+		}
+		return func() tea.Msg { return stopHotspotMsg{} }
+	})
+	m.focusManager = NewFocusManager()
+	m.focusManager.SetItems(m.formItems()...)
+	return m
+}
+
+func (m *HotspotModel) config() wifi.HotspotConfig {
+	return wifi.HotspotConfig{
+		SSID:     strings.TrimSpace(m.ssid.Model.Value()),
+		Password: m.password.Model.Value(),
+		Band:     hotspotBands[m.band.Selected()],
+	}
+}
+
+func (m *HotspotModel) isActive() bool {
+	return m.status != nil && m.status.Active
+}
+
+// formItems returns the settings while the hotspot is off, and only the stop
+// button while it runs.
+func (m *HotspotModel) formItems() []Focusable {
+	if m.isActive() {
+		return []Focusable{m.stopButtons}
+	}
+	return []Focusable{m.ssid, m.password, m.band, m.startButtons}
+}
+
+// OnEnter requests the current hotspot, which may have been started outside
+// of wifitui.
+func (m *HotspotModel) OnEnter() tea.Cmd {
+	return func() tea.Msg { return loadHotspotMsg{} }
+}
+
+func (m *HotspotModel) Update(msg tea.Msg) (Component, tea.Cmd) {
+	switch msg := msg.(type) {
+	case hotspotStatusMsg:
+		m.status = &msg.status
+		if m.status.Password == "" && m.status.Security != wifi.SecurityOpen {
+			m.status.Password = m.startedPassword
+		}
+		return m, m.focusManager.SetItems(m.formItems()...)
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "ctrl+j":
+			return m, m.focusManager.Next()
+		case "shift+tab", "ctrl+k":
+			return m, m.focusManager.Prev()
+		case "esc":
+			return m, func() tea.Msg { return popViewMsg{} }
+		case "enter":
+			if _, ok := m.focusManager.Focused().(*TextInput); ok {
+				return m, m.focusManager.Next()
+			}
+		}
+	}
+	_, cmd := m.focusManager.Update(msg)
+	return m, cmd
+}
+
+func (m *HotspotModel) IsConsumingInput() bool {
+	return !m.isActive() && (m.ssid.Model.Focused() || m.password.Model.Focused())
+}
+
+func (m *HotspotModel) View() string {
+	var s strings.Builder
+	s.WriteString("\n  ")
+	s.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Primary).Bold(true).Render("Hotspot"))
+	s.WriteString("\n\n")
+
+	if m.isActive() {
+		formatLabel := lipgloss.NewStyle().Foreground(CurrentTheme.Subtle)
+		var details strings.Builder
+		details.WriteString(fmt.Sprintf("%s %s", formatLabel.Render("SSID:"), m.status.SSID))
+		password := m.status.Password
+		if password == "" && m.status.Security != wifi.SecurityOpen {
+			password = "(unavailable)"
+		}
+		details.WriteString(fmt.Sprintf("\n%s %s", formatLabel.Render("Passphrase:"), password))
+		details.WriteString(fmt.Sprintf("\n%s %s", formatLabel.Render("Security:"), m.status.Security))
+		if m.status.Band != wifi.BandUnknown {
+			details.WriteString(fmt.Sprintf("\n%s %s", formatLabel.Render("Band:"), m.status.Band))
+		}
+		if m.status.Interface != "" {
+			details.WriteString(fmt.Sprintf("\n%s %s", formatLabel.Render("Interface:"), m.status.Interface))
+		}
+		s.WriteString(lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(1, 2).Render(details.String()))
+		s.WriteString("\n\n")
+	}
+
+	for _, item := range m.focusManager.items {
+		s.WriteString(item.View())
+		s.WriteString("\n\n")
+	}
+
+	if m.isActive() {
+		if content, err := qrwifi.WifiStringForSecurity(m.status.SSID, m.status.Password, m.status.Security, false); err == nil {
+			s.WriteString("Scan to join:\n\n")
+			s.WriteString(renderQRCode(content, 0, 0))
+		}
+	} else {
+		s.WriteString("\n(tab to switch fields, arrows to navigate, enter to select)")
+	}
+	return s.String()
+}
@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/mock"
+)
+
+func TestHotspotModel_StartAndShow(t *testing.T) {
+	m := NewHotspotModel()
+	if m.ssid.Model.Value() == "" || len(m.password.Model.Value()) < 8 {
+		t.Fatalf("new hotspot form = %q/%q, want a default SSID and passphrase", m.ssid.Model.Value(), m.password.Model.Value())
+	}
+	m.Update(hotspotStatusMsg{})
+
+	start := func() tea.Msg {
+		m.startButtons.selected = 0
+		m.focusManager.SetFocus(m.startButtons)
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatal("Start did not return a command")
+		}
+		return cmd()
+	}
+
+	m.password.Model.SetValue("short")
+	if status, ok := start().(statusMsg); !ok || !strings.Contains(status.status, "8 to 63") {
+		t.Fatalf("Start with a short passphrase returned %#v, want a status message", status)
+	}
+
+	m.ssid.Model.SetValue("Event")
+	m.password.Model.SetValue("correct horse")
+	m.band.SetSelected(2)
+	msg, ok := start().(startHotspotMsg)
+	if !ok {
+		t.Fatal("Start did not return startHotspotMsg")
+	}
+	want := wifi.HotspotConfig{SSID: "Event", Password: "correct horse", Band: wifi.Band5GHz}
+	if msg.HotspotConfig != want {
+		t.Fatalf("startHotspotMsg = %+v, want %+v", msg.HotspotConfig, want)
+	}
+
+	// A backend that can't read the passphrase back still gets a QR code.
+	m.Update(hotspotStatusMsg{status: wifi.HotspotStatus{
+		Active:        true,
+		HotspotConfig: wifi.HotspotConfig{SSID: "Event"},
+		Security:      wifi.SecurityWPA,
+	}})
+	if m.focusManager.Focused() != m.stopButtons {
+		t.Fatal("running hotspot does not focus the stop button")
+	}
+	view := m.View()
+	for _, want := range []string{"Event", "correct horse", "Scan to join", "█"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q", want)
+		}
+	}
+
+	m.stopButtons.selected = 0
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if _, ok := cmd().(stopHotspotMsg); !ok {
+		t.Fatal("Stop did not return stopHotspotMsg")
+	}
+}
+
+func TestModel_StartHotspot(t *testing.T) {
+	b, err := mock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock backend: %v", err)
+	}
+	mb := b.(*mock.MockBackend)
+	mb.ActionSleep = 0
+
+	m, err := NewModel(mb)
+	if err != nil {
+		t.Fatalf("failed to create model: %v", err)
+	}
+	hotspot := NewHotspotModel()
+	m.stack.Push(hotspot)
+
+	_, cmd := m.Update(startHotspotMsg{wifi.HotspotConfig{SSID: "Event", Password: "correct horse"}})
+	var status *hotspotStatusMsg
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(hotspotStatusMsg); ok {
+			status = &msg
+		}
+	}
+	if status == nil || !status.status.Active || status.status.SSID != "Event" {
+		t.Fatalf("startHotspotMsg resulted in %+v, want the running hotspot", status)
+	}
+
+	m.Update(*status)
+	if !hotspot.isActive() {
+		t.Error("hotspot view did not receive the running hotspot")
+	}
+}
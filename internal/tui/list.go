@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -10,6 +11,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/shazow/wifitui/internal/helpers"
 	"github.com/shazow/wifitui/wifi"
 )
 
@@ -71,17 +73,36 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 		)
 	}
 
+	band := ""
+	if bands := i.Bands(); len(bands) > 0 {
+		band = "  " + lipgloss.NewStyle().Foreground(CurrentTheme.Subtle).Render(helpers.FormatBands(bands))
+	}
+
+	security := ""
+	if i.IsVisible && i.Security != wifi.SecurityUnknown {
+		security = "  " + lipgloss.NewStyle().Foreground(CurrentTheme.Subtle).Render(i.Security.String())
+	}
+
+	priority := ""
+	if i.IsKnown && i.Priority != 0 {
+		priority = "  " + lipgloss.NewStyle().Foreground(CurrentTheme.Subtle).Render(fmt.Sprintf("priority %d", i.Priority))
+	}
+
 	var desc string
 	var sb strings.Builder
 	if i.Strength() > 0 {
 		sb.WriteString(CurrentTheme.FormatSignalStrength(i.Strength()))
 		sb.WriteString(apCount)
+		sb.WriteString(band)
+		sb.WriteString(security)
+		sb.WriteString(priority)
 		sb.WriteString(connectedPart)
 		desc = sb.String()
 	} else {
 		// Networks that are not visible have Strength=0, show their time ago instead
 		sb.WriteString(strengthPart)
 		sb.WriteString(apCount)
+		sb.WriteString(priority)
 		sb.WriteString(connectedPart)
 		desc = lipgloss.NewStyle().Foreground(CurrentTheme.Subtle).Render(sb.String())
 	}
@@ -93,6 +114,11 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 		if d.listModel.isForgetting {
 			desc = lipgloss.NewStyle().Foreground(CurrentTheme.Error).Render("Forget? (Y/n)")
 		}
+	}
+	if d.listModel.showSparklines() {
+		desc = renderSignalGraph(d.listModel.history.Network(i.SSID), sparklineWidth, 1) + " " + desc
+	}
+	if index == m.Index() {
 		line = lipgloss.NewStyle().Foreground(CurrentTheme.Primary).Render("▶ ") + title + padding + " " + desc
 	} else {
 		// Normal item
@@ -111,6 +137,13 @@ type ListModel struct {
 	window             *WindowState
 	ssidColumnWidth    int
 	desiredColumnWidth int
+	history            *signalHistory
+	// networks is the latest snapshot, before filtering by bandFilter.
+	networks   []wifi.Network
+	bandFilter wifi.Band
+	// devices are the wireless devices, which can be switched between when
+	// there is more than one.
+	devices []wifi.Device
 }
 
 const (
@@ -118,8 +151,15 @@ const (
 	maxSSIDColumnWidth     = 60
 	listContentOverhead    = 33
 	minWindowWidth         = 70
+	sparklineWidth         = 8
 )
 
+// showSparklines returns whether the window leaves room for a signal history
+// column next to the SSID column.
+func (m *ListModel) showSparklines() bool {
+	return m.availableWidth()-listContentOverhead-m.ssidColumnWidth > sparklineWidth
+}
+
 // IsConsumingInput returns whether the model is focused on a text input.
 func (m *ListModel) IsConsumingInput() bool {
 	// The list model does not have any text inputs.
@@ -148,13 +188,14 @@ func NewListModelWithWindow(window *WindowState) *ListModel {
 		ssidColumnWidth:    defaultSSIDColumnWidth,
 		desiredColumnWidth: defaultSSIDColumnWidth + 2,
 		window:             window,
+		history:            newSignalHistory(),
 	}
 	m.scanner = NewScanSchedule(func() tea.Msg { return scanMsg{mode: wifi.ScanAuto} })
 	delegate := itemDelegate{
 		listModel: m,
 	}
 	l := list.New([]list.Item{}, delegate, 0, 0)
-	l.Title = fmt.Sprintf("%-29s %s", CurrentTheme.TitleIcon+"WiFi Network", "Signal")
+	l.Title = fmt.Sprintf("%-29s %s", m.titlePrefix(), "Signal")
 	l.SetShowStatusBar(false)
 	l.SetShowHelp(false)
 	l.Help.Styles.ShortKey = lipgloss.NewStyle().Foreground(CurrentTheme.Primary)
@@ -172,11 +213,17 @@ func NewListModelWithWindow(window *WindowState) *ListModel {
 	l.KeyMap.Quit = key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit"))
 	l.KeyMap.ShowFullHelp.SetEnabled(false)
 	l.KeyMap.CloseFullHelp.SetEnabled(false)
+	// 'b' filters by band and 'h' opens the hotspot, so leave them out of the paging keys
+	l.KeyMap.PrevPage = key.NewBinding(key.WithKeys("left", "pgup", "u"), key.WithHelp("←/pgup", "prev page"))
 	l.AdditionalFullHelpKeys = func() []key.Binding {
 		return append([]key.Binding{
 			key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new network")),
 			key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "active scan")),
 			key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "disable radio")),
+			key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "filter by band")),
+			key.NewBinding(key.WithKeys("h"), key.WithHelp("h", "hotspot")),
+			key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "switch interface")),
+			key.NewBinding(key.WithKeys("+", "-"), key.WithHelp("+/-", "raise/lower priority")),
 		}, l.AdditionalShortHelpKeys()...)
 	}
 
@@ -257,7 +304,7 @@ func (m *ListModel) updateListSize() {
 	m.ssidColumnWidth = targetSSIDWidth
 
 	// Update list title to match the new column width
-	titlePrefix := CurrentTheme.TitleIcon + "WiFi Network"
+	titlePrefix := m.titlePrefix()
 	gap := m.ssidColumnWidth - lipgloss.Width(titlePrefix)
 	if gap < 0 {
 		gap = 0
@@ -277,16 +324,67 @@ func (m *ListModel) updateListSize() {
 	m.list.SetSize(availableWidth, windowHeight-extraVerticalSpace-helpHeight)
 }
 
+// setNetworks replaces the list items with the networks that pass the band
+// filter.
+func (m *ListModel) setNetworks(networks []wifi.Network) {
+	m.networks = networks
+	m.refreshColumns(networks)
+	var items []list.Item
+	for _, c := range networks {
+		if m.bandFilter == wifi.BandUnknown || c.HasBand(m.bandFilter) {
+			items = append(items, networkItem{Network: c})
+		}
+	}
+	m.list.SetItems(items)
+	m.updateListSize()
+}
+
+// nextBandFilter cycles the band filter through all bands and back to none.
+func (m *ListModel) nextBandFilter() {
+	i := slices.Index(bandChoices, m.bandFilter)
+	m.bandFilter = bandChoices[(i+1)%len(bandChoices)]
+	m.setNetworks(m.networks)
+}
+
+// titlePrefix names the list, along with the selected device when there is
+// more than one to choose from.
+func (m *ListModel) titlePrefix() string {
+	title := CurrentTheme.TitleIcon + "WiFi Network"
+	if selected, ok := wifi.SelectedDevice(m.devices); ok && len(m.devices) > 1 {
+		title += " on " + selected.Interface
+	}
+	return title
+}
+
+// setDevices updates the wireless devices shown in the title.
+func (m *ListModel) setDevices(devices []wifi.Device) {
+	m.devices = devices
+	m.updateListSize()
+}
+
+// nextDevice returns the device after the selected one, or false if there
+// is nothing to switch to.
+func (m *ListModel) nextDevice() (wifi.Device, bool) {
+	if len(m.devices) < 2 {
+		return wifi.Device{}, false
+	}
+	i := slices.IndexFunc(m.devices, func(d wifi.Device) bool { return d.Selected })
+	return m.devices[(i+1)%len(m.devices)], true
+}
+
 func (m *ListModel) SetItems(items []list.Item) {
 	m.list.SetItems(items)
 }
 
 func (m *ListModel) newEditModel(item *networkItem) *EditModel {
+	var editModel *EditModel
 	if m.window != nil {
-		return NewEditModelWithWindow(item, m.window)
+		editModel = NewEditModelWithWindow(item, m.window)
+	} else {
+		editModel = NewEditModel(item)
+		editModel.applyWindowWidth(m.width)
 	}
-	editModel := NewEditModel(item)
-	editModel.applyWindowWidth(m.width)
+	editModel.history = m.history
 	return editModel
 }
 
@@ -324,23 +422,11 @@ func (m *ListModel) Update(msg tea.Msg) (Component, tea.Cmd) {
 		m.updateListSize()
 		return m, nil
 	case networksLoadedMsg:
-		m.refreshColumns(msg)
-		items := make([]list.Item, len(msg))
-		for i, c := range msg {
-			items[i] = networkItem{Network: c}
-		}
-		m.list.SetItems(items)
-		m.updateListSize()
+		m.setNetworks(msg)
 		return m, nil
 	case scanFinishedMsg:
-		m.refreshColumns(msg.networks)
-		items := make([]list.Item, len(msg.networks))
-		for i, c := range msg.networks {
-			items[i] = networkItem{Network: c}
-		}
-		m.list.SetItems(items)
-		m.updateListSize()
-		if len(items) > 0 {
+		m.setNetworks(msg.networks)
+		if len(msg.networks) > 0 {
 			m.numScans++
 		}
 		if m.numScans == 3 {
@@ -366,6 +452,21 @@ func (m *ListModel) Update(msg tea.Msg) (Component, tea.Cmd) {
 			return editModel, nil
 		case "s":
 			return m, func() tea.Msg { return scanMsg{mode: wifi.ScanForce} }
+		case "h":
+			return NewHotspotModel(), nil
+		case "i":
+			device, ok := m.nextDevice()
+			if !ok {
+				return m, func() tea.Msg { return statusMsg{status: "No other wireless interface"} }
+			}
+			return m, func() tea.Msg { return selectDeviceMsg{iface: device.Interface} }
+		case "b":
+			m.nextBandFilter()
+			status := "Showing all bands"
+			if m.bandFilter != wifi.BandUnknown {
+				status = fmt.Sprintf("Showing %s networks", m.bandFilter)
+			}
+			return m, func() tea.Msg { return statusMsg{status: status} }
 		case "S":
 			enabled, cmd := m.scanner.Toggle()
 			var msg string
@@ -377,6 +478,19 @@ func (m *ListModel) Update(msg tea.Msg) (Component, tea.Cmd) {
 			return m, tea.Batch(cmd, func() tea.Msg {
 				return statusMsg{status: msg}
 			})
+		case "+", "-":
+			selected, ok := m.list.SelectedItem().(networkItem)
+			if !ok || !selected.IsKnown {
+				break
+			}
+			priority := selected.Priority + 1
+			if msg.String() == "-" {
+				priority = selected.Priority - 1
+			}
+			priority = max(wifi.MinPriority, min(priority, wifi.MaxPriority))
+			return m, func() tea.Msg {
+				return updateNetworkMsg{item: selected, UpdateOptions: wifi.UpdateOptions{Priority: &priority}}
+			}
 		case "f":
 			if len(m.list.Items()) > 0 {
 				selected, ok := m.list.SelectedItem().(networkItem)
@@ -439,6 +553,9 @@ func (m *ListModel) View() string {
 	if len(m.list.Items()) > 0 {
 		statusText = fmt.Sprintf("%d/%d", m.list.Index()+1, len(m.list.Items()))
 	}
+	if m.bandFilter != wifi.BandUnknown {
+		statusText += lipgloss.NewStyle().Foreground(CurrentTheme.Subtle).Render(fmt.Sprintf("  (%s only)", m.bandFilter))
+	}
 	viewBuilder.WriteString("\n")
 	viewBuilder.WriteString(statusText)
 	return lipgloss.NewStyle().Margin(1, 2).Render(viewBuilder.String())
@@ -2,9 +2,11 @@ package tui
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/shazow/wifitui/wifi"
@@ -136,3 +138,134 @@ func TestItemDelegate_RenderNoAnnotationForSingleAP(t *testing.T) {
 		t.Fatalf("expected no AP count annotation for a single AP, got: %q", out)
 	}
 }
+
+func TestListModel_BandFilter(t *testing.T) {
+	m := NewListModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m.Update(networksLoadedMsg{
+		{SSID: "Dual", IsVisible: true, AccessPoints: []wifi.AccessPoint{{Strength: 80, Frequency: 2412}, {Strength: 60, Frequency: 5180}}},
+		{SSID: "Legacy", IsVisible: true, AccessPoints: []wifi.AccessPoint{{Strength: 70, Frequency: 2437}}},
+		{SSID: "Saved", IsKnown: true},
+	})
+	if got := len(m.list.Items()); got != 3 {
+		t.Fatalf("unfiltered list has %d items, want 3", got)
+	}
+
+	bKeyMsg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")}
+	m.Update(bKeyMsg) // 2.4GHz
+	if got := len(m.list.Items()); got != 2 {
+		t.Fatalf("2.4GHz list has %d items, want 2", got)
+	}
+	m.Update(bKeyMsg) // 5GHz
+	items := m.list.Items()
+	if len(items) != 1 || items[0].(networkItem).SSID != "Dual" {
+		t.Fatalf("5GHz list = %v, want only Dual", items)
+	}
+	if !strings.Contains(m.View(), "5GHz only") {
+		t.Error("View() does not show the band filter")
+	}
+
+	// The filter applies to later snapshots too.
+	m.Update(networksLoadedMsg{{SSID: "Legacy", IsVisible: true, AccessPoints: []wifi.AccessPoint{{Strength: 70, Frequency: 2437}}}})
+	if got := len(m.list.Items()); got != 0 {
+		t.Fatalf("5GHz list after reload has %d items, want 0", got)
+	}
+
+	m.Update(bKeyMsg) // 6GHz
+	m.Update(bKeyMsg) // any
+	if m.bandFilter != wifi.BandUnknown || len(m.list.Items()) != 1 {
+		t.Fatalf("band filter = %q with %d items, want no filter", m.bandFilter, len(m.list.Items()))
+	}
+}
+
+func TestListModel_Paging(t *testing.T) {
+	m := NewListModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 12})
+	var networks networksLoadedMsg
+	for i := 0; i < 20; i++ {
+		networks = append(networks, wifi.Network{SSID: fmt.Sprintf("Network%d", i), IsKnown: true})
+	}
+	m.Update(networks)
+	if m.list.Paginator.TotalPages < 2 {
+		t.Fatalf("list has %d pages, want several", m.list.Paginator.TotalPages)
+	}
+
+	for _, k := range []tea.KeyMsg{{Type: tea.KeyPgUp}, {Type: tea.KeyLeft}} {
+		m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+		if m.list.Paginator.Page != 1 {
+			t.Fatalf("page after pgdown = %d, want 1", m.list.Paginator.Page)
+		}
+		m.Update(k)
+		if m.list.Paginator.Page != 0 {
+			t.Errorf("page after %s = %d, want 0", k, m.list.Paginator.Page)
+		}
+	}
+
+	// 'b' filters by band instead of paging back.
+	m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	if m.bandFilter != wifi.Band2GHz {
+		t.Errorf("band filter after b = %q, want %q", m.bandFilter, wifi.Band2GHz)
+	}
+	for _, r := range []string{"b", "h"} {
+		if key.Matches(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(r)}, m.list.KeyMap.PrevPage) {
+			t.Errorf("%s is still bound to the previous page", r)
+		}
+	}
+}
+
+func TestItemDelegate_RenderBands(t *testing.T) {
+	originalTheme := CurrentTheme
+	CurrentTheme = NewDefaultTheme()
+	t.Cleanup(func() {
+		CurrentTheme = originalTheme
+	})
+
+	d := itemDelegate{listModel: &ListModel{ssidColumnWidth: 30}}
+	m := list.New([]list.Item{}, d, 80, 5)
+
+	item := networkItem{Network: wifi.Network{
+		SSID:         "Dual",
+		IsVisible:    true,
+		AccessPoints: []wifi.AccessPoint{{Strength: 80, Frequency: 5180}, {Strength: 60, Frequency: 2412}},
+	}}
+
+	var buf bytes.Buffer
+	d.Render(&buf, m, 0, item)
+	if out := buf.String(); !strings.Contains(out, "2.4/5GHz") {
+		t.Fatalf("expected rendered output to contain the bands, got: %q", out)
+	}
+}
+
+func TestListModel_PriorityKeys(t *testing.T) {
+	m := NewListModel()
+	m.list.SetItems([]list.Item{
+		networkItem{Network: wifi.Network{SSID: "Known", IsKnown: true, Priority: 3}},
+		networkItem{Network: wifi.Network{SSID: "Unknown"}},
+	})
+
+	for key, want := range map[string]int{"+": 4, "-": 2} {
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		if cmd == nil {
+			t.Fatalf("%q returned no command", key)
+		}
+		msg, ok := cmd().(updateNetworkMsg)
+		if !ok || msg.item.SSID != "Known" || msg.Priority == nil || *msg.Priority != want {
+			t.Errorf("%q = %+v, want priority %d for Known", key, msg, want)
+		}
+	}
+
+	m.list.Select(1)
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")}); cmd != nil {
+		if _, ok := cmd().(updateNetworkMsg); ok {
+			t.Error("+ changed the priority of an unknown network")
+		}
+	}
+
+	d := itemDelegate{listModel: &ListModel{ssidColumnWidth: 30}}
+	var buf bytes.Buffer
+	d.Render(&buf, list.New([]list.Item{}, d, 80, 5), 0, m.list.Items()[0])
+	if out := buf.String(); !strings.Contains(out, "priority 3") {
+		t.Errorf("expected rendered output to contain the priority, got: %q", out)
+	}
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/shazow/wifitui/qrwifi"
+)
+
+// renderQRCode renders a compact QR code for a WIFI: string in the theme's QR
+// colors. When the code does not fit in width by height cells, the string is
+// shown instead so it can still be typed in. Zero sizes are not checked.
+func renderQRCode(content string, width, height int) string {
+	modules, err := qrwifi.Modules(content)
+	if err != nil {
+		return content
+	}
+	w, h := qrwifi.HalfBlockSize(modules)
+	if (width > 0 && w > width) || (height > 0 && h > height) {
+		fallback := lipgloss.NewStyle().Foreground(CurrentTheme.Subtle).Render("Too small for a QR code, join with:")
+		if width > 0 {
+			content = lipgloss.NewStyle().Width(width).Render(content)
+		}
+		return fallback + "\n" + content
+	}
+
+	style := lipgloss.NewStyle()
+	// Themes written before QR colors existed leave them unset.
+	if CurrentTheme.QRLight.TerminalColor != nil {
+		style = style.Foreground(CurrentTheme.QRLight)
+	}
+	if CurrentTheme.QRDark.TerminalColor != nil {
+		style = style.Background(CurrentTheme.QRDark)
+	}
+	return style.Render(qrwifi.RenderHalfBlocks(modules))
+}
@@ -0,0 +1,25 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestRenderQRCode(t *testing.T) {
+	const content = `WIFI:S:Guest;T:WPA;P:battery staple;;`
+
+	got := renderQRCode(content, 80, 40)
+	if strings.Contains(got, "WIFI:") || !strings.Contains(got, "█") {
+		t.Errorf("renderQRCode() in a large terminal =\n%s\nwant a QR code", got)
+	}
+	if width := lipgloss.Width(got); width > 80 {
+		t.Errorf("renderQRCode() is %d cells wide, want at most 80", width)
+	}
+
+	got = renderQRCode(content, 20, 10)
+	if !strings.Contains(got, "Too small for a QR code") || !strings.Contains(strings.ReplaceAll(got, "\n", ""), "P:battery") {
+		t.Errorf("renderQRCode() in a small terminal =\n%s\nwant the WIFI: string", got)
+	}
+}
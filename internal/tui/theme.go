@@ -60,6 +60,10 @@ type Theme struct {
 	SignalHigh Color
 	SignalLow  Color
 	Saved      Color
+	// QR codes are drawn light on dark. Scanners need good contrast, so
+	// these are not adaptive by default.
+	QRLight Color
+	QRDark  Color
 
 	// Icons
 	TitleIcon          string
@@ -86,6 +90,8 @@ var EmptyTheme = Theme{
 	SignalHigh: Color{lipgloss.NoColor{}},
 	SignalLow:  Color{lipgloss.NoColor{}},
 	Saved:      Color{lipgloss.NoColor{}},
+	QRLight:    Color{lipgloss.NoColor{}},
+	QRDark:     Color{lipgloss.NoColor{}},
 }
 
 // NewDefaultTheme creates a new default theme.
@@ -101,6 +107,8 @@ func NewDefaultTheme() Theme {
 		SignalHigh: Color{lipgloss.AdaptiveColor{Light: "#00B300", Dark: "#00FF00"}},
 		SignalLow:  Color{lipgloss.AdaptiveColor{Light: "#D05F00", Dark: "#BC3C00"}},
 		Saved:      Color{lipgloss.AdaptiveColor{Light: "#00459E", Dark: "#54A5F6"}},
+		QRLight:    Color{lipgloss.Color("#FFFFFF")},
+		QRDark:     Color{lipgloss.Color("#000000")},
 
 		TitleIcon:          "🛜 ",
 		NetworkSecureIcon:  "🔒 ",
@@ -132,6 +140,11 @@ func LoadTheme(r io.Reader) (Theme, error) {
 
 // FormatSignalStrength returns a color based on the signal strength.
 func (theme *Theme) FormatSignalStrength(strength uint8) string {
+	return lipgloss.NewStyle().Foreground(theme.signalColor(strength)).Render(fmt.Sprintf("%d%%", strength))
+}
+
+// signalColor blends between SignalLow and SignalHigh by strength.
+func (theme *Theme) signalColor(strength uint8) lipgloss.Color {
 	var signalHigh, signalLow string
 	if adaptiveHigh, ok := theme.SignalHigh.TerminalColor.(lipgloss.AdaptiveColor); ok {
 		if adaptiveLow, ok := theme.SignalLow.TerminalColor.(lipgloss.AdaptiveColor); ok {
@@ -148,6 +161,5 @@ func (theme *Theme) FormatSignalStrength(strength uint8) string {
 	end, _ := colorful.Hex(signalHigh)
 	p := float64(strength) / 100.0
 	blend := start.BlendRgb(end, p)
-	c := lipgloss.Color(blend.Hex())
-	return lipgloss.NewStyle().Foreground(c).Render(fmt.Sprintf("%d%%", strength))
+	return lipgloss.Color(blend.Hex())
 }
@@ -11,10 +11,15 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/shazow/wifitui/internal/connectivity"
 	"github.com/shazow/wifitui/internal/helpers"
 	"github.com/shazow/wifitui/wifi"
 )
 
+// ConnectivityURL is probed after connecting, where the backend cannot check
+// connectivity itself. An empty URL disables probing.
+var ConnectivityURL = connectivity.DefaultURL
+
 // The main model for our TUI application
 type model struct {
 	stack *ComponentStack
@@ -26,21 +31,20 @@ type model struct {
 
 	listModel *ListModel
 
+	// connectivity is the result of checking the last connected network,
+	// named by connectivitySSID.
+	connectivity     connectivity.Result
+	connectivitySSID string
+
 	networkChangeCancel   context.CancelFunc
 	networkRefreshPending bool
 }
 
-// NetworkManager can send several AP/device signals for one scan update.
-// Debounce them so AP property churn triggers one cached refresh instead of
-// repeated D-Bus list reads and redraws.
+// Backends can send several events for one scan update. Debounce them so
+// access point churn triggers one cached refresh instead of repeated list
+// reads and redraws.
 const networkChangeDebounce = 150 * time.Millisecond
 
-// TODO: Consider adding a general change-watching interface to wifi.Backend so
-// every backend can expose network-change hints through one TUI code path.
-type networkChangeWatcher interface {
-	WatchNetworkChanges(context.Context) (<-chan struct{}, error)
-}
-
 // NewModel creates the starting state of our application
 func NewModel(b wifi.Backend) (*model, error) {
 	s := spinner.New()
@@ -61,11 +65,12 @@ func NewModel(b wifi.Backend) (*model, error) {
 
 type radioEnabledMsg struct{}
 type networkWatchStartedMsg struct {
-	changes <-chan struct{}
-	cancel  context.CancelFunc
+	events <-chan wifi.Event
+	cancel context.CancelFunc
 }
 type networkChangedMsg struct {
-	changes <-chan struct{}
+	events <-chan wifi.Event
+	event  wifi.Event
 }
 type networkDebouncedMsg struct{}
 type updateNetworkMsg struct {
@@ -82,6 +87,7 @@ func (m *model) Init() tea.Cmd {
 	}
 
 	cmds = append(cmds, startNetworkChangeWatcher(m.backend))
+	cmds = append(cmds, loadDevices(m.backend))
 	cmds = append(cmds, m.spinner.Tick)
 	return tea.Batch(cmds...)
 }
@@ -104,9 +110,13 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	case networkWatchStartedMsg:
 		m.networkChangeCancel = msg.cancel
-		return m, waitForNetworkChange(msg.changes)
+		return m, waitForNetworkChange(msg.events)
 	case networkChangedMsg:
-		cmds = append(cmds, waitForNetworkChange(msg.changes))
+		cmds = append(cmds, waitForNetworkChange(msg.events))
+		if e := msg.event; e.Type == wifi.EventConnectionStateChanged && e.SSID == m.connectivitySSID &&
+			(e.State == wifi.ConnectionDeactivated || e.State == wifi.ConnectionFailed) {
+			m.connectivity = connectivity.Result{}
+		}
 		if !m.networkRefreshPending {
 			m.networkRefreshPending = true
 			cmds = append(cmds, tea.Tick(networkChangeDebounce, func(time.Time) tea.Msg {
@@ -169,6 +179,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 	case connectMsg:
+		m.connectivity = connectivity.Result{}
 		var batch []tea.Cmd = []tea.Cmd{
 			func() tea.Msg {
 				return statusMsg{status: fmt.Sprintf("Connecting to %q...", msg.item.SSID), loading: true}
@@ -184,24 +195,52 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			})
 		}
 		batch = append(batch, func() tea.Msg {
-			err := m.backend.ActivateNetwork(msg.item.SSID)
+			// The lock has to be in place before activating, so the new
+			// connection uses it.
+			if msg.lockBSSID != nil {
+				err := m.backend.UpdateNetwork(msg.item.SSID, wifi.UpdateOptions{BSSID: msg.lockBSSID})
+				if err != nil {
+					return errorMsg{fmt.Errorf("failed to update access point lock: %w", err)}
+				}
+			}
+			var err error
+			if msg.bssid != "" {
+				err = m.backend.ActivateAccessPoint(msg.item.SSID, msg.bssid)
+			} else {
+				err = m.backend.ActivateNetwork(msg.item.SSID)
+			}
 			if err != nil {
 				return errorMsg{fmt.Errorf("failed to activate connection: %w", err)}
 			}
-			return networkSavedMsg{} // Re-use this to trigger a refresh
+			return connectedMsg{ssid: msg.item.SSID}
 		})
 		return m, tea.Batch(batch...)
 	case joinNetworkMsg:
+		if !msg.SaveOnly {
+			m.connectivity = connectivity.Result{}
+		}
 		return m, tea.Batch(
 			func() tea.Msg { return statusMsg{status: fmt.Sprintf("Joining %q...", msg.ssid), loading: true} },
 			func() tea.Msg {
-				err := m.backend.JoinNetwork(msg.ssid, msg.password, msg.security, msg.isHidden)
+				err := m.backend.JoinNetwork(msg.ssid, msg.JoinOptions)
 				if err != nil {
 					return errorMsg{fmt.Errorf("failed to join network: %w", err)}
 				}
-				return networkSavedMsg{}
+				if msg.SaveOnly {
+					return networkSavedMsg{}
+				}
+				return connectedMsg{ssid: msg.ssid}
 			},
 		)
+	case connectedMsg:
+		return m, tea.Batch(
+			func() tea.Msg { return networkSavedMsg{} }, // Re-use this to trigger a refresh
+			checkConnectivity(m.backend, msg.ssid),
+		)
+	case connectivityCheckedMsg:
+		m.connectivity = msg.Result
+		m.connectivitySSID = msg.ssid
+		return m, nil
 	case loadSecretsMsg:
 		return m, tea.Batch(
 			func() tea.Msg { return statusMsg{status: fmt.Sprintf("Loading %q...", msg.item.SSID), loading: true} },
@@ -213,6 +252,15 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return secretsLoadedMsg{item: msg.item, secret: secret}
 			},
 		)
+	case loadConnectionDetailsMsg:
+		return m, func() tea.Msg {
+			// Details are supplementary, so a failure just leaves them out.
+			details, err := m.backend.ActiveConnection()
+			if err != nil {
+				return nil
+			}
+			return connectionDetailsLoadedMsg{details: details}
+		}
 	case updateNetworkMsg:
 		return m, tea.Batch(
 			func() tea.Msg { return statusMsg{status: fmt.Sprintf("Saving %q...", msg.item.SSID), loading: true} },
@@ -237,6 +285,76 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return networkSavedMsg{forgottenSSID: msg.item.SSID} // Re-use this to trigger a refresh
 			},
 		)
+	case loadHotspotMsg:
+		return m, func() tea.Msg {
+			status, err := m.backend.HotspotStatus()
+			if err != nil {
+				return errorMsg{fmt.Errorf("failed to get hotspot status: %w", err)}
+			}
+			return hotspotStatusMsg{status: status}
+		}
+	case startHotspotMsg:
+		return m, tea.Batch(
+			func() tea.Msg {
+				return statusMsg{status: fmt.Sprintf("Starting hotspot %q...", msg.SSID), loading: true}
+			},
+			func() tea.Msg {
+				if err := m.backend.StartHotspot(msg.HotspotConfig); err != nil {
+					return errorMsg{fmt.Errorf("failed to start hotspot: %w", err)}
+				}
+				status, err := m.backend.HotspotStatus()
+				if err != nil {
+					return errorMsg{fmt.Errorf("failed to get hotspot status: %w", err)}
+				}
+				return hotspotStatusMsg{status: status}
+			},
+		)
+	case stopHotspotMsg:
+		return m, tea.Batch(
+			func() tea.Msg { return statusMsg{status: "Stopping hotspot...", loading: true} },
+			func() tea.Msg {
+				if err := m.backend.StopHotspot(); err != nil {
+					return errorMsg{fmt.Errorf("failed to stop hotspot: %w", err)}
+				}
+				return hotspotStatusMsg{}
+			},
+		)
+	case devicesLoadedMsg:
+		m.listModel.setDevices(msg)
+		return m, nil
+	case selectDeviceMsg:
+		return m, tea.Batch(
+			func() tea.Msg { return statusMsg{status: fmt.Sprintf("Switching to %s...", msg.iface), loading: true} },
+			func() tea.Msg {
+				if err := m.backend.SelectDevice(msg.iface); err != nil {
+					return errorMsg{fmt.Errorf("failed to switch interface: %w", err)}
+				}
+				devices, err := m.backend.Devices()
+				if err != nil {
+					return errorMsg{fmt.Errorf("failed to list interfaces: %w", err)}
+				}
+				return deviceSelectedMsg{devices: devices}
+			},
+		)
+	case deviceSelectedMsg:
+		// Events and signal history belong to the previous device, so start
+		// over and scan on the new one.
+		if m.networkChangeCancel != nil {
+			m.networkChangeCancel()
+			m.networkChangeCancel = nil
+		}
+		m.listModel.history = newSignalHistory()
+		m.listModel.setDevices(msg.devices)
+		m.listModel.setNetworks(nil)
+		m.loading = false
+		m.statusMessage = ""
+		return m, tea.Batch(
+			startNetworkChangeWatcher(m.backend),
+			func() tea.Msg { return scanMsg{mode: wifi.ScanAuto} },
+		)
+	case hotspotStatusMsg:
+		// Clear loading status
+		cmds = append(cmds, func() tea.Msg { return statusMsg{} })
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+c" {
 			if m.networkChangeCancel != nil {
@@ -251,6 +369,14 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		switch msg.String() {
+		case "o":
+			if m.connectivity.State != wifi.ConnectivityPortal || m.connectivity.PortalURL == "" {
+				break
+			}
+			if err := connectivity.OpenURL(m.connectivity.PortalURL); err != nil {
+				return m, func() tea.Msg { return errorMsg{err} }
+			}
+			return m, nil
 		case "r":
 			// This is a global keybinding to toggle the radio.
 			// We only handle it here if the radio is currently enabled.
@@ -280,9 +406,13 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Clear loading status
 		cmds = append(cmds, func() tea.Msg { return statusMsg{} })
 	case networksLoadedMsg:
+		// Record history here rather than in the list, which does not get
+		// messages while another view is on top of it.
+		m.listModel.history.Record(msg)
 		// Clear loading status
 		cmds = append(cmds, func() tea.Msg { return statusMsg{} })
 	case scanFinishedMsg:
+		m.listModel.history.Record(msg.networks)
 		m.loading = false
 		m.statusMessage = ""
 		if msg.scanErr != nil {
@@ -335,35 +465,55 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func startNetworkChangeWatcher(b wifi.Backend) tea.Cmd {
-	watcher, ok := b.(networkChangeWatcher)
-	if !ok {
-		return nil
-	}
-
 	return func() tea.Msg {
 		ctx, cancel := context.WithCancel(context.Background())
-		changes, err := watcher.WatchNetworkChanges(ctx)
+		events, err := b.WatchEvents(ctx)
 		if err != nil {
 			cancel()
 			return nil
 		}
 		return networkWatchStartedMsg{
-			changes: changes,
-			cancel:  cancel,
+			events: events,
+			cancel: cancel,
 		}
 	}
 }
 
-func waitForNetworkChange(changes <-chan struct{}) tea.Cmd {
-	if changes == nil {
+// loadDevices lists the wireless devices, so the list can offer switching
+// between them. Failing to list them just leaves the switcher out.
+func loadDevices(b wifi.Backend) tea.Cmd {
+	return func() tea.Msg {
+		devices, err := b.Devices()
+		if err != nil {
+			return nil
+		}
+		return devicesLoadedMsg(devices)
+	}
+}
+
+// checkConnectivity reports whether the network that was just connected
+// reaches the internet. Failing to check is not worth an error view, the
+// connection itself succeeded.
+func checkConnectivity(b wifi.Backend, ssid string) tea.Cmd {
+	return func() tea.Msg {
+		result, err := connectivity.Check(context.Background(), b, ConnectivityURL)
+		if err != nil {
+			return nil
+		}
+		return connectivityCheckedMsg{ssid: ssid, Result: result}
+	}
+}
+
+func waitForNetworkChange(events <-chan wifi.Event) tea.Cmd {
+	if events == nil {
 		return nil
 	}
 	return func() tea.Msg {
-		_, ok := <-changes
+		event, ok := <-events
 		if !ok {
 			return nil
 		}
-		return networkChangedMsg{changes: changes}
+		return networkChangedMsg{events: events, event: event}
 	}
 }
 
@@ -377,7 +527,28 @@ func (m model) View() string {
 	if m.loading {
 		s.WriteString(m.spinner.View())
 	}
+	if m.statusMessage == "" && !m.loading {
+		s.WriteString(m.connectivityStatus())
+	}
 	s.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Primary).Render(m.statusMessage))
 
 	return s.String()
 }
+
+// connectivityStatus describes the connectivity of the last connected
+// network, if it was checked.
+func (m model) connectivityStatus() string {
+	switch m.connectivity.State {
+	case wifi.ConnectivityFull:
+		return lipgloss.NewStyle().Foreground(CurrentTheme.Success).Render(fmt.Sprintf("%s is connected to the internet", m.connectivitySSID))
+	case wifi.ConnectivityPortal:
+		status := fmt.Sprintf("%s needs a captive portal login", m.connectivitySSID)
+		if m.connectivity.PortalURL != "" {
+			status += ", press o to open it"
+		}
+		return lipgloss.NewStyle().Foreground(CurrentTheme.Error).Render(status)
+	case wifi.ConnectivityLimited, wifi.ConnectivityNone:
+		return lipgloss.NewStyle().Foreground(CurrentTheme.Error).Render(fmt.Sprintf("%s does not reach the internet", m.connectivitySSID))
+	}
+	return ""
+}
@@ -140,10 +140,10 @@ func TestStartNetworkChangeWatcher(t *testing.T) {
 	if err != nil {
 		t.Fatalf("mock.New() failed: %v", err)
 	}
-	changes := make(chan struct{})
+	events := make(chan wifi.Event)
 	watch := &watchBackend{
 		Backend: backend,
-		changes: changes,
+		events:  events,
 	}
 
 	cmd := startNetworkChangeWatcher(watch)
@@ -157,10 +157,10 @@ func TestStartNetworkChangeWatcher(t *testing.T) {
 		t.Fatalf("watch command returned %T, want networkWatchStartedMsg", msg)
 	}
 	if !watch.watchCalled {
-		t.Fatal("watch command did not call WatchNetworkChanges")
+		t.Fatal("watch command did not call WatchEvents")
 	}
-	if started.changes != (<-chan struct{})(changes) {
-		t.Fatal("watch command returned a different changes channel")
+	if started.events != (<-chan wifi.Event)(events) {
+		t.Fatal("watch command returned a different events channel")
 	}
 	if started.cancel == nil {
 		t.Fatal("watch command returned a nil cancel function")
@@ -179,10 +179,10 @@ func TestTuiModel_NetworkChangeDebouncesRefresh(t *testing.T) {
 	if err != nil {
 		t.Fatalf("mock.New() failed: %v", err)
 	}
-	changes := make(chan struct{})
+	events := make(chan wifi.Event)
 	watch := &watchBackend{
 		Backend: backend,
-		changes: changes,
+		events:  events,
 		networks: []wifi.Network{
 			{SSID: "UpdatedNet", IsVisible: true},
 		},
@@ -195,7 +195,7 @@ func TestTuiModel_NetworkChangeDebouncesRefresh(t *testing.T) {
 	updatedModel, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
 	m = updatedModel.(*model)
 
-	updatedModel, firstCmd := m.Update(networkChangedMsg{changes: changes})
+	updatedModel, firstCmd := m.Update(networkChangedMsg{events: events})
 	m = updatedModel.(*model)
 	if firstCmd == nil {
 		t.Fatal("first networkChangedMsg did not schedule a debounce command")
@@ -212,7 +212,7 @@ func TestTuiModel_NetworkChangeDebouncesRefresh(t *testing.T) {
 		t.Fatal("first networkChangedMsg did not mark a refresh as pending")
 	}
 
-	updatedModel, secondCmd := m.Update(networkChangedMsg{changes: changes})
+	updatedModel, secondCmd := m.Update(networkChangedMsg{events: events})
 	m = updatedModel.(*model)
 	if secondCmd == nil {
 		t.Fatal("second networkChangedMsg did not keep waiting for watcher changes")
@@ -324,17 +324,17 @@ func (b scanFailureBackend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResul
 
 type watchBackend struct {
 	wifi.Backend
-	changes     chan struct{}
+	events      chan wifi.Event
 	watchCalled bool
 	ctx         context.Context
 	listScans   []wifi.ScanMode
 	networks    []wifi.Network
 }
 
-func (b *watchBackend) WatchNetworkChanges(ctx context.Context) (<-chan struct{}, error) {
+func (b *watchBackend) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
 	b.watchCalled = true
 	b.ctx = ctx
-	return b.changes, nil
+	return b.events, nil
 }
 
 func (b *watchBackend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error) {
@@ -368,3 +368,91 @@ func runTUITestCommand(t *testing.T, m *model, cmd tea.Cmd) *model {
 	}
 	return runTUITestCommand(t, updated, nextCmd)
 }
+
+func TestTuiModel_SwitchDevice(t *testing.T) {
+	backend, err := mock.New()
+	if err != nil {
+		t.Fatalf("mock.New() failed: %v", err)
+	}
+	mb := backend.(*mock.MockBackend)
+	mb.ActionSleep = 0
+
+	m, err := NewModel(backend)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 24})
+	m.Update(loadDevices(backend)())
+	if view := m.View(); !strings.Contains(view, "WiFi Network on wlan0") {
+		t.Fatalf("View does not name the selected interface in\n%s", view)
+	}
+
+	_, cmd := m.listModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	msg, ok := cmd().(selectDeviceMsg)
+	if !ok || msg.iface != "wlan1" {
+		t.Fatalf("'i' returned %#v, want selectDeviceMsg for wlan1", msg)
+	}
+
+	_, cmd = m.Update(msg)
+	var selected *deviceSelectedMsg
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(deviceSelectedMsg); ok {
+			selected = &msg
+		}
+	}
+	if selected == nil {
+		t.Fatal("selectDeviceMsg did not result in deviceSelectedMsg")
+	}
+	m.listModel.setNetworks([]wifi.Network{{SSID: "Stale"}})
+	m.Update(*selected)
+	if m.loading {
+		t.Error("model is still loading after switching interface")
+	}
+	view := m.View()
+	if !strings.Contains(view, "WiFi Network on wlan1") {
+		t.Errorf("View does not name the new interface in\n%s", view)
+	}
+	if strings.Contains(view, "Stale") {
+		t.Errorf("View still lists networks of the previous interface in\n%s", view)
+	}
+}
+
+func TestTuiModel_ConnectivityStatus(t *testing.T) {
+	backend, err := mock.New()
+	if err != nil {
+		t.Fatalf("mock.New() failed: %v", err)
+	}
+	mb := backend.(*mock.MockBackend)
+	mb.ActionSleep = 0
+	mb.ConnectivityState = wifi.ConnectivityPortal
+	defer func(url string) { ConnectivityURL = url }(ConnectivityURL)
+	ConnectivityURL = ""
+
+	m, err := NewModel(backend)
+	if err != nil {
+		t.Fatalf("NewModel failed: %v", err)
+	}
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 24})
+	if err := backend.ActivateNetwork("Password is password"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cmd := m.Update(connectedMsg{ssid: "Password is password"})
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(connectivityCheckedMsg); ok {
+			m.Update(msg)
+		}
+	}
+	if view := m.View(); !strings.Contains(view, "Password is password needs a captive portal login") {
+		t.Errorf("View does not report the captive portal in\n%s", view)
+	}
+
+	m.Update(networkChangedMsg{event: wifi.Event{
+		Type:  wifi.EventConnectionStateChanged,
+		SSID:  "Password is password",
+		State: wifi.ConnectionDeactivated,
+	}})
+	if view := m.View(); strings.Contains(view, "captive portal") {
+		t.Errorf("View still reports the captive portal after disconnecting in\n%s", view)
+	}
+}
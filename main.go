@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/netip"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/charmbracelet/x/term"
 	flags "github.com/jessevdk/go-flags"
+	"github.com/shazow/wifitui/internal/connectivity"
+	"github.com/shazow/wifitui/internal/helpers"
+	"github.com/shazow/wifitui/internal/profiles"
 	"github.com/shazow/wifitui/internal/tui"
+	"github.com/shazow/wifitui/qrwifi"
 	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/remote"
+	"github.com/shazow/wifitui/wifi/trace"
 )
 
 var (
@@ -19,7 +33,7 @@ var (
 	defaultRetryInterval = 10 * time.Second
 )
 
-// parseSecurityType converts a security string (open, wep, wpa) to a wifi.SecurityType.
+// parseSecurityType converts a security string (open, wep, wpa, enterprise) to a wifi.SecurityType.
 func parseSecurityType(s string) (wifi.SecurityType, error) {
 	switch s {
 	case "open":
@@ -28,6 +42,16 @@ func parseSecurityType(s string) (wifi.SecurityType, error) {
 		return wifi.SecurityWEP, nil
 	case "wpa":
 		return wifi.SecurityWPA, nil
+	case "enterprise":
+		return wifi.SecurityEnterprise, nil
+	case "wpa3":
+		return wifi.SecuritySAE, nil
+	case "wpa2-wpa3":
+		return wifi.SecurityWPA2WPA3, nil
+	case "owe":
+		return wifi.SecurityOWE, nil
+	case "wpa3-enterprise":
+		return wifi.SecurityEnterpriseWPA3, nil
 	default:
 		return wifi.SecurityUnknown, fmt.Errorf("invalid security type: %s", s)
 	}
@@ -60,14 +84,35 @@ func parseRetryConfig(s string) (RetryConfig, error) {
 
 // Options defines the root-level flags
 type Options struct {
-	Theme   string `long:"theme" description:"path to theme toml file" env:"WIFITUI_THEME"`
-	Version bool   `long:"version" description:"display version"`
+	Theme           string  `long:"theme" description:"path to theme toml file" env:"WIFITUI_THEME"`
+	Interface       string  `long:"interface" description:"wireless device to manage (e.g. wlan1), defaults to the first one" env:"WIFITUI_INTERFACE" value-name:"NAME"`
+	ConnectivityURL *string `long:"connectivity-url" description:"URL to probe for internet access when the backend cannot tell, empty to disable" env:"WIFITUI_CONNECTIVITY_URL" value-name:"URL"`
+	Record          string  `long:"record" description:"record every backend call to FILE for a bug report, with secrets redacted" value-name:"FILE"`
+	Remote          string  `long:"remote" description:"use the backend of a wifitui daemon listening on SOCKET (see serve)" env:"WIFITUI_REMOTE" value-name:"SOCKET"`
+	Version         bool    `long:"version" description:"display version"`
 
-	Tui     TuiCommand     `command:"tui" description:"Run the TUI (default)"`
-	List    ListCommand    `command:"list" description:"List wifi networks"`
-	Show    ShowCommand    `command:"show" description:"Show a wifi network"`
-	Connect ConnectCommand `command:"connect" description:"Connect to a wifi network"`
-	Radio   RadioCommand   `command:"radio" description:"Control the wifi radio (on|off|toggle)"`
+	Tui      TuiCommand      `command:"tui" description:"Run the TUI (default)"`
+	List     ListCommand     `command:"list" description:"List wifi networks"`
+	Show     ShowCommand     `command:"show" description:"Show a wifi network"`
+	QR       QRCommand       `command:"qr" description:"Show a QR code to join a network, or save it as PNG or SVG"`
+	Connect  ConnectCommand  `command:"connect" description:"Connect to a wifi network"`
+	Radio    RadioCommand    `command:"radio" description:"Control the wifi radio (on|off|toggle)"`
+	Priority PriorityCommand `command:"priority" description:"Show or set the autoconnect priority of saved networks"`
+	Watch    WatchCommand    `command:"watch" description:"Stream network events as JSON lines"`
+	Hotspot  HotspotCommand  `command:"hotspot" description:"Share this machine's connection over a wifi hotspot"`
+	Export   ExportCommand   `command:"export" description:"Export saved networks to a file"`
+	Import   ImportCommand   `command:"import" description:"Import saved networks from a file"`
+	Apply    ApplyCommand    `command:"apply" description:"Make the saved networks match a state file"`
+	Serve    ServeCommand    `command:"serve" description:"Let other programs control wifi through an API on a Unix socket"`
+}
+
+// connectivityURL returns the URL given with --connectivity-url, or the
+// default one if the option was not set. An empty URL disables the probe.
+func (o *Options) connectivityURL() string {
+	if o.ConnectivityURL == nil {
+		return connectivity.DefaultURL
+	}
+	return *o.ConnectivityURL
 }
 
 // TuiCommand defines the handler for the "tui" subcommand
@@ -75,9 +120,11 @@ type TuiCommand struct{}
 
 // ListCommand defines the flags and arguments for the "list" subcommand
 type ListCommand struct {
-	JSON bool `long:"json" description:"output in JSON format"`
-	All  bool `long:"all" description:"list all saved and visible networks"`
-	Scan bool `long:"scan" description:"scan for new visible networks"`
+	JSON          bool     `long:"json" description:"output in JSON format"`
+	All           bool     `long:"all" description:"list all saved and visible networks"`
+	Scan          bool     `long:"scan" description:"scan for new visible networks"`
+	Band          []string `long:"band" description:"only list networks seen on a band, can be repeated" choice:"2.4" choice:"5" choice:"6"`
+	AllInterfaces bool     `long:"all-interfaces" description:"list the networks of every wireless device, grouped by device"`
 }
 
 // ShowCommand defines the flags and arguments for the "show" subcommand
@@ -88,17 +135,156 @@ type ShowCommand struct {
 	} `positional-args:"yes"`
 }
 
+// QRCommand defines the flags and arguments for the "qr" subcommand
+type QRCommand struct {
+	Output string `short:"o" long:"output" description:"file to write, standard output if omitted or -" value-name:"FILE"`
+	Format string `long:"format" description:"output format, guessed from the file name by default" choice:"text" choice:"png" choice:"svg"`
+	Size   int    `long:"size" default:"512" description:"width and height of PNG images in pixels"`
+	Args   struct {
+		SSID string `positional-arg-name:"ssid" required:"true"`
+	} `positional-args:"yes"`
+}
+
+// PriorityCommand defines the flags and arguments for the "priority" subcommand
+type PriorityCommand struct {
+	JSON bool `long:"json" description:"output in JSON format"`
+	Args struct {
+		SSID     string `positional-arg-name:"ssid" description:"network to show or set, all saved networks if omitted"`
+		Priority string `positional-arg-name:"priority" description:"new priority, higher is tried first (put -- before negative values)"`
+	} `positional-args:"yes"`
+}
+
 // ConnectCommand defines the flags and arguments for the "connect" subcommand
 type ConnectCommand struct {
-	Passphrase string `long:"passphrase" description:"passphrase for the network"`
-	Security   string `long:"security" default:"wpa" description:"security type" choice:"open" choice:"wep" choice:"wpa"`
+	Passphrase string `long:"passphrase" description:"passphrase for the network, or the user password for enterprise networks"`
+	Security   string `long:"security" default:"wpa" description:"security type" choice:"open" choice:"wep" choice:"wpa" choice:"wpa3" choice:"wpa2-wpa3" choice:"owe" choice:"enterprise" choice:"wpa3-enterprise"`
 	Hidden     bool   `long:"hidden" description:"network is hidden"`
 	RetryFor   string `long:"retry-for" description:"duration to retry connection (e.g. 60s or 2m:20s)" value-name:"DURATION[:INTERVAL]"`
-	Args       struct {
-		SSID string `positional-arg-name:"ssid" required:"true"`
+	BSSID      string `long:"bssid" description:"connect through a specific access point" value-name:"BSSID"`
+	LockBSSID  bool   `long:"lock-bssid" description:"lock the saved network to the access point given with --bssid"`
+	URI        string `long:"uri" description:"join the network described by a WIFI: URI instead of an SSID" value-name:"WIFI:..."`
+	QRImage    string `long:"qr-image" description:"join the network of the WIFI: QR code in a PNG, JPEG or GIF image, - for stdin" value-name:"FILE"`
+
+	NoConnectivityCheck bool          `long:"no-connectivity-check" description:"don't check for internet access after connecting"`
+	OpenPortal          bool          `long:"open-portal" description:"open the login page in a browser if a captive portal is found"`
+	WaitOnline          time.Duration `long:"wait-online" description:"wait until the network has an IP address and reaches the internet (e.g. 30s)" value-name:"DURATION"`
+	JSON                bool          `long:"json" description:"print the outcome as a JSON object"`
+
+	Enterprise EnterpriseFlags `group:"Enterprise (802.1X) Options"`
+	IP         IPFlags         `group:"IP Options"`
+
+	Args struct {
+		SSID string `positional-arg-name:"ssid" description:"network to connect to, required unless --uri or --qr-image is given"`
 	} `positional-args:"yes"`
 }
 
+// EnterpriseFlags defines the 802.1X flags used with --security=enterprise
+type EnterpriseFlags struct {
+	Identity           string `long:"identity" description:"identity (username) for enterprise networks"`
+	AnonymousIdentity  string `long:"anonymous-identity" description:"outer identity sent before the tunnel is established"`
+	EAP                string `long:"eap" default:"peap" description:"EAP method" choice:"peap" choice:"ttls" choice:"tls"`
+	Phase2Auth         string `long:"phase2-auth" default:"mschapv2" description:"inner authentication for peap and ttls (e.g. mschapv2, pap, gtc)"`
+	CACert             string `long:"ca-cert" description:"path to the CA certificate" value-name:"FILE"`
+	ClientCert         string `long:"client-cert" description:"path to the client certificate (tls)" value-name:"FILE"`
+	PrivateKey         string `long:"private-key" description:"path to the client private key (tls)" value-name:"FILE"`
+	PrivateKeyPassword string `long:"private-key-password" description:"password for an encrypted private key"`
+}
+
+// Credentials converts the flags to wifi.EnterpriseCredentials.
+func (f EnterpriseFlags) Credentials() *wifi.EnterpriseCredentials {
+	return &wifi.EnterpriseCredentials{
+		Identity:           f.Identity,
+		AnonymousIdentity:  f.AnonymousIdentity,
+		EAPMethod:          wifi.EAPMethod(f.EAP),
+		Phase2Auth:         f.Phase2Auth,
+		CACert:             f.CACert,
+		ClientCert:         f.ClientCert,
+		PrivateKey:         f.PrivateKey,
+		PrivateKeyPassword: f.PrivateKeyPassword,
+	}
+}
+
+// IPFlags defines the addressing flags used with connect. They apply to new
+// networks and update the saved configuration of known ones.
+type IPFlags struct {
+	IPv4Method  string   `long:"ipv4-method" description:"IPv4 method, manual is implied by --ipv4-address" choice:"auto" choice:"manual" choice:"disabled"`
+	IPv4Address []string `long:"ipv4-address" description:"static IPv4 address with prefix length (repeatable)" value-name:"CIDR"`
+	IPv4Gateway string   `long:"ipv4-gateway" description:"IPv4 default gateway" value-name:"ADDR"`
+	IPv6Method  string   `long:"ipv6-method" description:"IPv6 method, manual is implied by --ipv6-address" choice:"auto" choice:"manual" choice:"disabled"`
+	IPv6Address []string `long:"ipv6-address" description:"static IPv6 address with prefix length (repeatable)" value-name:"CIDR"`
+	IPv6Gateway string   `long:"ipv6-gateway" description:"IPv6 default gateway" value-name:"ADDR"`
+	DNS         []string `long:"dns" description:"DNS server, replacing those from DHCP (repeatable)" value-name:"ADDR"`
+	DNSSearch   []string `long:"dns-search" description:"DNS search domain (repeatable)" value-name:"DOMAIN"`
+}
+
+// parseIPFlags converts the flags to IP configurations. A family is nil when
+// none of its flags are set, which leaves its configuration unchanged.
+func parseIPFlags(f IPFlags) (ipv4, ipv6 *wifi.IPConfig, err error) {
+	family := func(method string, addresses []string, gateway string) (*wifi.IPConfig, error) {
+		if method == "" && len(addresses) == 0 && gateway == "" {
+			return nil, nil
+		}
+		c := &wifi.IPConfig{Method: wifi.IPMethod(method)}
+		if c.Method == "" {
+			c.Method = wifi.IPMethodAuto
+			if len(addresses) > 0 || gateway != "" {
+				c.Method = wifi.IPMethodManual
+			}
+		}
+		for _, s := range addresses {
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q, expected address/prefix: %w", s, wifi.ErrInvalidIPConfig)
+			}
+			c.Addresses = append(c.Addresses, prefix)
+		}
+		if gateway != "" {
+			addr, err := netip.ParseAddr(gateway)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gateway %q: %w", gateway, wifi.ErrInvalidIPConfig)
+			}
+			c.Gateway = addr
+		}
+		return c, nil
+	}
+	if ipv4, err = family(f.IPv4Method, f.IPv4Address, f.IPv4Gateway); err != nil {
+		return nil, nil, err
+	}
+	if ipv6, err = family(f.IPv6Method, f.IPv6Address, f.IPv6Gateway); err != nil {
+		return nil, nil, err
+	}
+
+	for _, s := range f.DNS {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid DNS server %q: %w", s, wifi.ErrInvalidIPConfig)
+		}
+		target := &ipv4
+		if addr.Is6() {
+			target = &ipv6
+		}
+		if *target == nil {
+			*target = &wifi.IPConfig{Method: wifi.IPMethodAuto}
+		}
+		(*target).DNS = append((*target).DNS, addr)
+	}
+	if len(f.DNSSearch) > 0 {
+		switch {
+		case ipv4 != nil && ipv4.Method != wifi.IPMethodDisabled:
+			ipv4.DNSSearch = f.DNSSearch
+		case ipv6 != nil:
+			ipv6.DNSSearch = f.DNSSearch
+		default:
+			ipv4 = &wifi.IPConfig{Method: wifi.IPMethodAuto, DNSSearch: f.DNSSearch}
+		}
+	}
+
+	if err := (wifi.UpdateOptions{IPv4: ipv4, IPv6: ipv6}).Validate(); err != nil {
+		return nil, nil, err
+	}
+	return ipv4, ipv6, nil
+}
+
 // RadioCommand defines the argument for the "radio" subcommand
 // Action may be one of: on, off, toggle.
 type RadioCommand struct {
@@ -107,6 +293,82 @@ type RadioCommand struct {
 	} `positional-args:"yes"`
 }
 
+// WatchCommand defines the flags for the "watch" subcommand
+type WatchCommand struct {
+	SSID  []string `long:"ssid" description:"only show events for this network (repeatable); radio and scan events are always shown"`
+	Event []string `long:"event" description:"only show events of this type (repeatable)" choice:"network-appeared" choice:"network-disappeared" choice:"signal-changed" choice:"connection-state-changed" choice:"radio-toggled" choice:"scan-completed"`
+}
+
+// ServeCommand defines the flags for the "serve" subcommand
+type ServeCommand struct {
+	Socket string `long:"socket" description:"path of the Unix socket (default: $XDG_RUNTIME_DIR/wifitui.sock)" value-name:"PATH"`
+	Group  string `long:"group" description:"let members of GROUP connect too, only the owner can by default" value-name:"GROUP"`
+}
+
+// HotspotCommand groups the "hotspot" subcommands
+type HotspotCommand struct {
+	Start  HotspotStartCommand  `command:"start" description:"Start a hotspot"`
+	Stop   HotspotStopCommand   `command:"stop" description:"Stop the hotspot"`
+	Status HotspotStatusCommand `command:"status" description:"Show the hotspot"`
+}
+
+// HotspotStartCommand defines the flags for the "hotspot start" subcommand
+type HotspotStartCommand struct {
+	SSID       string `long:"ssid" description:"network name, defaults to the hostname"`
+	Passphrase string `long:"passphrase" description:"WPA2 passphrase, generated if not given"`
+	Open       bool   `long:"open" description:"start an open hotspot without a passphrase"`
+	Band       string `long:"band" description:"band to broadcast on" choice:"2.4" choice:"5"`
+}
+
+// HotspotStopCommand defines the handler for the "hotspot stop" subcommand
+type HotspotStopCommand struct{}
+
+// HotspotStatusCommand defines the flags for the "hotspot status" subcommand
+type HotspotStatusCommand struct {
+	JSON bool `long:"json" description:"output in JSON format"`
+}
+
+// ExportCommand defines the flags and arguments for the "export" subcommand
+type ExportCommand struct {
+	Format     string `long:"format" description:"file format, guessed from the file name by default" choice:"json" choice:"toml"`
+	Passphrase string `long:"passphrase" description:"encrypt the file with this passphrase" env:"WIFITUI_PROFILES_PASSPHRASE"`
+	Args       struct {
+		File string `positional-arg-name:"file" description:"file to write, standard output if omitted or -"`
+	} `positional-args:"yes"`
+}
+
+// ImportCommand defines the flags and arguments for the "import" subcommand
+type ImportCommand struct {
+	Format     string `long:"format" description:"file format, guessed from the file name by default" choice:"json" choice:"toml"`
+	Passphrase string `long:"passphrase" description:"passphrase of an encrypted file" env:"WIFITUI_PROFILES_PASSPHRASE"`
+	DryRun     bool   `long:"dry-run" description:"show what would be imported without changing anything"`
+	OnConflict string `long:"on-conflict" default:"skip" description:"what to do with networks that are already saved" choice:"skip" choice:"overwrite" choice:"fail"`
+	From       string `long:"from" description:"read the networks of another network manager, from its usual location unless a file or directory is given" choice:"wpa_supplicant" choice:"iwd" choice:"nm"`
+	Args       struct {
+		File string `positional-arg-name:"file" description:"file to read, - for standard input"`
+	} `positional-args:"yes"`
+}
+
+// ApplyCommand defines the flags and arguments for the "apply" subcommand
+type ApplyCommand struct {
+	Format string `long:"format" description:"file format, guessed from the file name by default" choice:"json" choice:"toml"`
+	Prune  bool   `long:"prune" description:"forget saved networks that aren't in the state file"`
+	DryRun bool   `long:"dry-run" description:"show the plan without changing anything"`
+	Yes    bool   `short:"y" long:"yes" description:"apply the plan without asking for confirmation"`
+	Args   struct {
+		File string `positional-arg-name:"file" description:"state file to read, - for standard input" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+// profilesFormat returns the format chosen with --format, or guesses it from
+// the file name.
+func profilesFormat(format, path string) profiles.Format {
+	if format != "" {
+		return profiles.Format(format)
+	}
+	return profiles.FormatFromPath(path)
+}
+
 // We need a global backend to be accessible by the command handlers.
 var b wifi.Backend
 var opts Options
@@ -129,12 +391,24 @@ func (c *TuiCommand) Execute(args []string) error {
 		}
 		tui.CurrentTheme = loadedTheme
 	}
+	tui.ConnectivityURL = opts.connectivityURL()
 	return runTUI(b)
 }
 
 // Execute is the handler for the "list" subcommand
 func (c *ListCommand) Execute(args []string) error {
-	return runList(os.Stdout, os.Stderr, c.JSON, c.All, c.Scan, b)
+	var bands []wifi.Band
+	for _, name := range c.Band {
+		band, err := wifi.ParseBand(name)
+		if err != nil {
+			return err
+		}
+		bands = append(bands, band)
+	}
+	if c.AllInterfaces {
+		return runListAllInterfaces(os.Stdout, os.Stderr, c.JSON, c.All, c.Scan, bands, b)
+	}
+	return runList(os.Stdout, os.Stderr, c.JSON, c.All, c.Scan, bands, b)
 }
 
 // Execute is the handler for the "show" subcommand
@@ -142,19 +416,85 @@ func (c *ShowCommand) Execute(args []string) error {
 	return runShow(os.Stdout, c.JSON, c.Args.SSID, b)
 }
 
+// Execute is the handler for the "priority" subcommand
+func (c *PriorityCommand) Execute(args []string) error {
+	if c.Args.Priority == "" {
+		return runPriority(os.Stdout, c.JSON, c.Args.SSID, nil, b)
+	}
+	priority, err := strconv.Atoi(c.Args.Priority)
+	if err != nil {
+		return fmt.Errorf("invalid priority %q: %w", c.Args.Priority, wifi.ErrInvalidPriority)
+	}
+	return runPriority(os.Stdout, c.JSON, c.Args.SSID, &priority, b)
+}
+
 // Execute is the handler for the "connect" subcommand
 func (c *ConnectCommand) Execute(args []string) error {
+	ssid := c.Args.SSID
 	security, err := parseSecurityType(c.Security)
 	if err != nil {
 		return err
 	}
+	password, hidden := c.Passphrase, c.Hidden
+	if c.URI != "" || c.QRImage != "" {
+		if ssid != "" || (c.URI != "" && c.QRImage != "") {
+			return &flags.Error{Type: flags.ErrDuplicatedFlag, Message: "only one of an SSID, --uri and --qr-image can be given"}
+		}
+		config, err := readWifiURI(c.URI, c.QRImage)
+		if err != nil {
+			return err
+		}
+		uriOpts, err := config.JoinOptions()
+		if err != nil {
+			return err
+		}
+		ssid, security, password, hidden = config.SSID, uriOpts.Security, uriOpts.Password, uriOpts.IsHidden
+	} else if ssid == "" {
+		return &flags.Error{Type: flags.ErrRequired, Message: "an SSID, --uri or --qr-image is required"}
+	}
 
 	retry, err := parseRetryConfig(c.RetryFor)
 	if err != nil {
 		return err
 	}
 
-	return runConnect(os.Stdout, c.Args.SSID, c.Passphrase, security, c.Hidden, retry, b)
+	ipv4, ipv6, err := parseIPFlags(c.IP)
+	if err != nil {
+		return err
+	}
+
+	joinOpts := wifi.JoinOptions{
+		Password: password,
+		Security: security,
+		IsHidden: hidden,
+		IPv4:     ipv4,
+		IPv6:     ipv6,
+	}
+	if c.BSSID != "" {
+		if joinOpts.BSSID, err = wifi.ParseBSSID(c.BSSID); err != nil {
+			return err
+		}
+	}
+	if c.LockBSSID {
+		if joinOpts.BSSID == "" {
+			return fmt.Errorf("--lock-bssid requires --bssid: %w", wifi.ErrInvalidBSSID)
+		}
+		joinOpts.LockBSSID = true
+	}
+	if security.IsEnterprise() {
+		joinOpts.Enterprise = c.Enterprise.Credentials()
+		if err := joinOpts.Enterprise.Validate(); err != nil {
+			return err
+		}
+	}
+
+	check := ConnectCheck{
+		Skip:       c.NoConnectivityCheck,
+		URL:        opts.connectivityURL(),
+		OpenPortal: c.OpenPortal,
+		WaitOnline: c.WaitOnline,
+	}
+	return runConnectCommand(os.Stdout, c.JSON, ssid, joinOpts, retry, check, b)
 }
 
 // Execute is the handler for the "radio" subcommand
@@ -162,8 +502,209 @@ func (c *RadioCommand) Execute(args []string) error {
 	return runRadio(os.Stdout, c.Args.Action, b)
 }
 
+// Execute is the handler for the "watch" subcommand
+func (c *WatchCommand) Execute(args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	filter := watchFilter{SSIDs: c.SSID}
+	for _, event := range c.Event {
+		filter.Events = append(filter.Events, wifi.EventType(event))
+	}
+	return runWatch(ctx, os.Stdout, filter, b)
+}
+
+// Execute is the handler for the "serve" subcommand
+func (c *ServeCommand) Execute(args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	socket := c.Socket
+	if socket == "" {
+		socket = remote.DefaultSocket()
+	}
+	return runServe(ctx, os.Stderr, socket, c.Group, b)
+}
+
+// Execute is the handler for the "hotspot start" subcommand
+func (c *HotspotStartCommand) Execute(args []string) error {
+	if c.Open && c.Passphrase != "" {
+		return fmt.Errorf("--open cannot be used with --passphrase: %w", wifi.ErrInvalidCredentials)
+	}
+	band, err := wifi.ParseBand(c.Band)
+	if err != nil {
+		return err
+	}
+	config := wifi.HotspotConfig{SSID: c.SSID, Password: c.Passphrase, Band: band}
+	if config.SSID == "" {
+		config.SSID = helpers.DefaultHotspotSSID()
+	}
+	if config.Password == "" && !c.Open {
+		if config.Password, err = helpers.RandomPassphrase(helpers.HotspotPassphraseLength); err != nil {
+			return fmt.Errorf("failed to generate passphrase: %w", err)
+		}
+	}
+	return runHotspotStart(os.Stdout, config, b)
+}
+
+// Execute is the handler for the "hotspot stop" subcommand
+func (c *HotspotStopCommand) Execute(args []string) error {
+	return runHotspotStop(os.Stdout, b)
+}
+
+// Execute is the handler for the "hotspot status" subcommand
+func (c *HotspotStatusCommand) Execute(args []string) error {
+	return runHotspotStatus(os.Stdout, c.JSON, b)
+}
+
+// Execute is the handler for the "qr" subcommand
+func (c *QRCommand) Execute(args []string) error {
+	format := c.Format
+	if format == "" {
+		switch ext := strings.ToLower(filepath.Ext(c.Output)); ext {
+		case ".png", ".svg":
+			format = ext[1:]
+		default:
+			format = "text"
+		}
+	}
+	if c.Output == "" || c.Output == "-" {
+		var width, height int
+		if term.IsTerminal(os.Stdout.Fd()) {
+			if format != "text" {
+				return fmt.Errorf("refusing to write a %s image to a terminal, use --output", strings.ToUpper(format))
+			}
+			width, height, _ = term.GetSize(os.Stdout.Fd())
+		}
+		return runQR(os.Stdout, c.Args.SSID, format, c.Size, width, height, b)
+	}
+	// The code holds the passphrase, so keep it private.
+	f, err := os.OpenFile(c.Output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := runQR(f, c.Args.SSID, format, c.Size, 0, 0, b); err != nil {
+		f.Close()
+		os.Remove(c.Output)
+		return err
+	}
+	return f.Close()
+}
+
+// Execute is the handler for the "export" subcommand
+func (c *ExportCommand) Execute(args []string) error {
+	format := profilesFormat(c.Format, c.Args.File)
+	if c.Args.File == "" || c.Args.File == "-" {
+		return runExport(os.Stdout, os.Stderr, format, c.Passphrase, b)
+	}
+	// The file holds passphrases, so keep it private.
+	f, err := os.OpenFile(c.Args.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := runExport(f, os.Stderr, format, c.Passphrase, b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Execute is the handler for the "import" subcommand
+func (c *ImportCommand) Execute(args []string) error {
+	if c.From != "" {
+		source := profiles.Source(c.From)
+		path := c.Args.File
+		if path == "" {
+			path = source.DefaultPath()
+		}
+		imported, err := profiles.ReadSource(source, path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s networks: %w", source, err)
+		}
+		return runImport(os.Stdout, skipEnterprise(os.Stderr, imported), conflictPolicy(c.OnConflict), c.DryRun, b)
+	}
+	if c.Args.File == "" {
+		return fmt.Errorf("import needs a file, or --from to read the networks of another network manager")
+	}
+
+	r := os.Stdin
+	if c.Args.File != "-" {
+		f, err := os.Open(c.Args.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	imported, err := profiles.Decode(r, profilesFormat(c.Format, c.Args.File), c.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", c.Args.File, err)
+	}
+	return runImport(os.Stdout, imported, conflictPolicy(c.OnConflict), c.DryRun, b)
+}
+
+// Execute is the handler for the "apply" subcommand
+func (c *ApplyCommand) Execute(args []string) error {
+	r := os.Stdin
+	dir := "."
+	if c.Args.File != "-" {
+		f, err := os.Open(c.Args.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+		dir = filepath.Dir(c.Args.File)
+	}
+	desired, err := profiles.ReadState(r, profilesFormat(c.Format, c.Args.File), dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", c.Args.File, err)
+	}
+	answers := os.Stdin
+	if c.Args.File == "-" && !c.Yes && !c.DryRun {
+		// The state file used up standard input, so confirmation is read
+		// from the terminal instead.
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return fmt.Errorf("reading the state file from standard input without a terminal requires --yes: %w", err)
+		}
+		defer tty.Close()
+		answers = tty
+	}
+	return runApply(os.Stdout, answers, desired, c.Prune, c.DryRun, c.Yes, b)
+}
+
+// selectInterface points the backend at the device named by --interface, if
+// one was given.
+func selectInterface(iface string, b wifi.Backend) error {
+	if iface == "" {
+		return nil
+	}
+	return b.SelectDevice(iface)
+}
+
+// startRecording wraps the backend in a recorder writing to path, when
+// --record gave one. The returned function finishes the recording.
+func startRecording(path string, b wifi.Backend) (wifi.Backend, func() error, error) {
+	if path == "" {
+		return b, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start recording: %w", err)
+	}
+	recorder := trace.NewRecorder(b, f)
+	stop := func() error {
+		if err := errors.Join(recorder.Close(), f.Close()); err != nil {
+			return fmt.Errorf("failed to record %s: %w", path, err)
+		}
+		return nil
+	}
+	return recorder, stop, nil
+}
+
 // run is the main entry point that returns an error instead of calling os.Exit directly.
-func run() error {
+func run() (err error) {
 	// Manually check for --version flag before parsing to avoid unnecessary backend init.
 	for _, arg := range os.Args[1:] {
 		if arg == "--version" {
@@ -172,16 +713,39 @@ func run() error {
 		}
 	}
 
-	// Initialize the backend before parsing, so it's available to Execute methods.
-	var err error
-	b, err = GetBackend()
-	if err != nil {
-		return fmt.Errorf("error: %w", err)
+	stopRecording := func() error { return nil }
+	defer func() {
+		err = errors.Join(err, stopRecording())
+	}()
+	// setup initializes the backend once the root flags are parsed, so it's
+	// available to Execute methods.
+	setup := func() error {
+		var err error
+		if opts.Remote != "" {
+			b, err = remote.Dial(opts.Remote)
+		} else {
+			b, err = GetBackend()
+		}
+		if err != nil {
+			return fmt.Errorf("error: %w", err)
+		}
+		recorded, stop, err := startRecording(opts.Record, b)
+		if err != nil {
+			return err
+		}
+		b, stopRecording = recorded, stop
+		return selectInterface(opts.Interface, b)
 	}
 
 	parser := flags.NewParser(&opts, flags.HelpFlag)
 	parser.ShortDescription = "A simple TUI for managing wifi connections."
 	parser.LongDescription = "wifitui is a TUI and CLI for managing wifi connections."
+	parser.CommandHandler = func(command flags.Commander, args []string) error {
+		if err := setup(); err != nil {
+			return err
+		}
+		return command.Execute(args)
+	}
 
 	// Parse arguments.
 	_, err = parser.Parse()
@@ -192,8 +756,11 @@ func run() error {
 				parser.WriteHelp(os.Stdout)
 				return nil
 			}
-			if flagsErr.Type == flags.ErrCommandRequired {
+			if flagsErr.Type == flags.ErrCommandRequired && parser.Active == nil {
 				// No command was specified, so run the TUI by default.
+				if err := setup(); err != nil {
+					return err
+				}
 				return opts.Tui.Execute(nil)
 			}
 		}
@@ -209,6 +776,66 @@ func run() error {
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// Exit statuses, so scripts can tell failures apart. These are documented in
+// the README, keep them stable.
+const (
+	exitFailure             = 1
+	exitUsage               = 2
+	exitCaptivePortal       = 3
+	exitNoInternet          = 4
+	exitIncorrectPassphrase = 5
+	exitNotFound            = 6
+	exitWirelessDisabled    = 7
+	exitPermission          = 8
+	exitNotAvailable        = 9
+	exitNotSupported        = 10
+	exitTimeout             = 11
+)
+
+// exitCodes maps errors to their exit status. The first match wins, so the
+// outcome of a connection comes before the errors it may wrap.
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{errCaptivePortal, exitCaptivePortal},
+	{errLimitedConnectivity, exitNoInternet},
+	{errWaitOnlineTimeout, exitTimeout},
+	{wifi.ErrIncorrectPassphrase, exitIncorrectPassphrase},
+	{wifi.ErrNotFound, exitNotFound},
+	{wifi.ErrWirelessDisabled, exitWirelessDisabled},
+	{wifi.ErrMissingPermission, exitPermission},
+	{wifi.ErrScanPermissionDenied, exitPermission},
+	{wifi.ErrScanAuthRequired, exitPermission},
+	{wifi.ErrNotAvailable, exitNotAvailable},
+	{wifi.ErrScanDeviceUnavailable, exitNotAvailable},
+	{wifi.ErrNotSupported, exitNotSupported},
+	{wifi.ErrScanTimeout, exitTimeout},
+	{wifi.ErrInvalidCredentials, exitUsage},
+	{wifi.ErrInvalidIPConfig, exitUsage},
+	{wifi.ErrInvalidBSSID, exitUsage},
+	{wifi.ErrInvalidBand, exitUsage},
+	{wifi.ErrInvalidPriority, exitUsage},
+	{qrwifi.ErrInvalidURI, exitUsage},
+}
+
+// exitCode returns the exit status for err, 0 if it is nil.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var flagsErr *flags.Error
+	if errors.As(err, &flagsErr) {
+		return exitUsage
+	}
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
 	}
+	return exitFailure
 }
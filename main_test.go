@@ -1,6 +1,12 @@
 package main
 
-import "time"
+import (
+	"testing"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/shazow/wifitui/internal/connectivity"
+)
 
 // shortDurationValue and longDurationValue can be overridden at compile time,
 // for example:
@@ -20,3 +26,33 @@ func mustParseTestDuration(value string) time.Duration {
 	}
 	return d
 }
+
+func TestConnectivityURLOption(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  *string
+		want string
+	}{
+		{"unset", nil, nil, connectivity.DefaultURL},
+		{"flag", []string{"--connectivity-url=http://example.com/probe"}, nil, "http://example.com/probe"},
+		{"disabled by flag", []string{"--connectivity-url="}, nil, ""},
+		{"disabled by env", nil, new(string), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != nil {
+				t.Setenv("WIFITUI_CONNECTIVITY_URL", *tt.env)
+			}
+			var o Options
+			parser := flags.NewParser(&o, flags.None)
+			parser.SubcommandsOptional = true
+			if _, err := parser.ParseArgs(tt.args); err != nil {
+				t.Fatalf("ParseArgs(%q) failed: %v", tt.args, err)
+			}
+			if got := o.connectivityURL(); got != tt.want {
+				t.Errorf("connectivityURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package qrwifi
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// DecodeQRCode returns the text of the QR code in a PNG, JPEG or GIF image,
+// such as a photo of a guest network placard.
+func DecodeQRCode(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	// Photos are rarely as clean as generated codes, so take the slower
+	// path that copes with them.
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+	if err != nil {
+		return "", fmt.Errorf("no QR code found: %w", err)
+	}
+	return result.GetText(), nil
+}
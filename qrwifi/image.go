@@ -0,0 +1,57 @@
+package qrwifi
+
+import (
+	"fmt"
+	"io"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// WritePNG writes the QR code for content as a black on white PNG image of
+// size by size pixels, with the standard quiet zone for printing.
+func WritePNG(w io.Writer, content string, size int) error {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+	return q.Write(size, w)
+}
+
+// WriteSVG writes the QR code for content as a black on white SVG image, with
+// the standard quiet zone for printing. The image is sized in modules and
+// scales without blurring.
+func WriteSVG(w io.Writer, content string) error {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+	modules := q.Bitmap()
+	size := len(modules)
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", size, size); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, `<rect width="100%" height="100%" fill="#fff"/>`); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `<path fill="#000" d="`); err != nil {
+		return err
+	}
+	// One subpath per run of dark modules keeps the file small.
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			if _, err := fmt.Fprintf(w, "M%d %dh%dv1h-%dz", start, y, x-start, x-start); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = io.WriteString(w, "\"/>\n</svg>\n")
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/shazow/wifitui/wifi"
 	qrcode "github.com/skip2/go-qrcode"
 )
 
@@ -20,8 +21,21 @@ func EscapeWifiString(s string) string {
 	return r.Replace(s)
 }
 
-// GenerateWifiQRCode builds the correctly formatted Wi-Fi connection string and returns the TUI-friendly QR code string.
-func GenerateWifiQRCode(ssid, password string, isSecure, isHidden bool) (string, error) {
+// WifiString builds the correctly formatted Wi-Fi connection string that QR
+// codes carry. Secure networks are described as WPA; use
+// WifiStringForSecurity to describe other security types.
+func WifiString(ssid, password string, isSecure, isHidden bool) (string, error) {
+	security := wifi.SecurityOpen
+	if isSecure {
+		security = wifi.SecurityWPA
+	}
+	return WifiStringForSecurity(ssid, password, security, isHidden)
+}
+
+// WifiStringForSecurity builds the Wi-Fi connection string for a network with
+// the given security. Enterprise networks return wifi.ErrNotSupported, since
+// their credentials are an account rather than a shared passphrase.
+func WifiStringForSecurity(ssid, password string, security wifi.SecurityType, isHidden bool) (string, error) {
 	var b strings.Builder
 
 	// Start with the required prefix and SSID
@@ -30,17 +44,21 @@ func GenerateWifiQRCode(ssid, password string, isSecure, isHidden bool) (string,
 	b.WriteString(";")
 
 	// Set Authentication Type and Password
-	if isSecure {
-		if password != "" {
-			b.WriteString("T:WPA;P:")
-			b.WriteString(EscapeWifiString(password))
-			b.WriteString(";")
-		} else {
+	switch security {
+	case wifi.SecurityOpen, wifi.SecurityOWE:
+		b.WriteString("T:nopass;")
+	case wifi.SecurityEnterprise, wifi.SecurityEnterpriseWPA3:
+		return "", fmt.Errorf("%s networks cannot be shared with a QR code: %w", security, wifi.ErrNotSupported)
+	default:
+		if password == "" {
 			// Handle case where it's secure but no password is provided yet
 			return "", fmt.Errorf("secure network requires a password")
 		}
-	} else {
-		b.WriteString("T:nopass;")
+		b.WriteString("T:")
+		b.WriteString(uriType(security))
+		b.WriteString(";P:")
+		b.WriteString(EscapeWifiString(password))
+		b.WriteString(";")
 	}
 
 	// Add hidden flag if necessary
@@ -48,14 +66,93 @@ func GenerateWifiQRCode(ssid, password string, isSecure, isHidden bool) (string,
 		b.WriteString("H:true;")
 	}
 
-	// Add the final terminator
-	b.WriteString(";;")
+	// Every field ends with a semicolon, an empty field terminates
+	b.WriteString(";")
+	return b.String(), nil
+}
+
+// uriType returns the authentication type of a WIFI: URI for a network that
+// takes a password. Transition mode networks are WPA, so that clients without
+// WPA3 can join them too.
+func uriType(security wifi.SecurityType) string {
+	switch security {
+	case wifi.SecurityWEP:
+		return TypeWEP
+	case wifi.SecuritySAE:
+		return TypeSAE
+	default:
+		return TypeWPA
+	}
+}
 
-	// Generate the QR code string for the terminal
-	q, err := qrcode.New(b.String(), qrcode.Medium)
+// GenerateWifiQRCode builds the correctly formatted Wi-Fi connection string and returns the TUI-friendly QR code string.
+func GenerateWifiQRCode(ssid, password string, isSecure, isHidden bool) (string, error) {
+	content, err := WifiString(ssid, password, isSecure, isHidden)
+	if err != nil {
+		return "", err
+	}
+	modules, err := Modules(content)
 	if err != nil {
 		return "", err
 	}
+	return RenderHalfBlocks(modules), nil
+}
+
+// QuietZone is the light border around a QR code in modules. The standard
+// asks for 4, but scanners cope with 2 and terminal space is scarce.
+const QuietZone = 2
+
+// Modules returns the modules of the QR code for content, true for dark
+// ones, surrounded by a QuietZone.
+func Modules(content string) ([][]bool, error) {
+	q, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = true
+	code := q.Bitmap()
+
+	size := len(code) + 2*QuietZone
+	modules := make([][]bool, size)
+	for y := range modules {
+		modules[y] = make([]bool, size)
+		if y >= QuietZone && y < size-QuietZone {
+			copy(modules[y][QuietZone:], code[y-QuietZone])
+		}
+	}
+	return modules, nil
+}
+
+// RenderHalfBlocks draws modules with two rows per line of text, so a QR code
+// is as tall as it is wide in most terminal fonts. Light modules are drawn
+// and dark ones left blank, which reads correctly on dark terminals or when
+// the caller colors the text as light on dark.
+func RenderHalfBlocks(modules [][]bool) string {
+	var b strings.Builder
+	for y := 0; y < len(modules); y += 2 {
+		for x := range modules[y] {
+			top := !modules[y][x]
+			bottom := y+1 < len(modules) && !modules[y+1][x]
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		if y+2 < len(modules) {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
 
-	return q.ToSmallString(false), nil
+// HalfBlockSize returns the width and height in cells that RenderHalfBlocks
+// needs for modules.
+func HalfBlockSize(modules [][]bool) (width, height int) {
+	return len(modules), (len(modules) + 1) / 2
 }
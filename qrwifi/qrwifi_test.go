@@ -0,0 +1,158 @@
+package qrwifi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+const testWifiString = `WIFI:S:Guest;T:WPA;P:battery staple;;`
+
+func TestRenderHalfBlocks(t *testing.T) {
+	modules, err := Modules(testWifiString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := RenderHalfBlocks(modules)
+	lines := strings.Split(rendered, "\n")
+	width, height := HalfBlockSize(modules)
+	if len(lines) != height {
+		t.Fatalf("RenderHalfBlocks() has %d lines, want %d", len(lines), height)
+	}
+
+	// Read the modules back from the blocks and check the code still scans
+	// with the reduced quiet zone.
+	img := image.NewGray(image.Rect(0, 0, width*4, height*8))
+	for y, line := range lines {
+		for x, r := range []rune(line) {
+			if len([]rune(line)) != width {
+				t.Fatalf("line %d is %d cells wide, want %d", y, len([]rune(line)), width)
+			}
+			top := r == '█' || r == '▀'
+			bottom := r == '█' || r == '▄'
+			for py := 0; py < 8; py++ {
+				light := top
+				if py >= 4 {
+					light = bottom
+				}
+				for px := 0; px < 4; px++ {
+					c := color.Gray{}
+					if light {
+						c.Y = 255
+					}
+					img.SetGray(x*4+px, y*8+py, c)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeQRCode(&buf)
+	if err != nil {
+		t.Fatalf("DecodeQRCode() of the rendered blocks failed: %v", err)
+	}
+	if got != testWifiString {
+		t.Errorf("DecodeQRCode() = %q, want %q", got, testWifiString)
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, testWifiString, 300); err != nil {
+		t.Fatalf("WritePNG() failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("WritePNG() did not write a PNG: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 300 || size.Y != 300 {
+		t.Errorf("WritePNG() image is %v, want 300x300", size)
+	}
+	got, err := DecodeQRCode(&buf)
+	if err != nil {
+		t.Fatalf("DecodeQRCode() failed: %v", err)
+	}
+	if got != testWifiString {
+		t.Errorf("DecodeQRCode() = %q, want %q", got, testWifiString)
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, testWifiString); err != nil {
+		t.Fatalf("WriteSVG() failed: %v", err)
+	}
+	var svg struct {
+		XMLName xml.Name `xml:"svg"`
+		ViewBox string   `xml:"viewBox,attr"`
+		Path    struct {
+			D string `xml:"d,attr"`
+		} `xml:"path"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+		t.Fatalf("WriteSVG() did not write valid XML: %v\n%s", err, buf.String())
+	}
+	if !strings.HasPrefix(svg.ViewBox, "0 0 ") || !strings.HasPrefix(svg.Path.D, "M") {
+		t.Errorf("WriteSVG() = %s, want a view box and a path", buf.String())
+	}
+}
+
+func TestWifiString(t *testing.T) {
+	got, err := WifiString("Semi;colon", `back\slash`, true, true)
+	if err != nil {
+		t.Fatalf("WifiString() failed: %v", err)
+	}
+	if want := `WIFI:S:Semi\;colon;T:WPA;P:back\\slash;H:true;;`; got != want {
+		t.Errorf("WifiString() = %q, want %q", got, want)
+	}
+	config, err := ParseWifiURI(got)
+	if err != nil {
+		t.Fatalf("ParseWifiURI() failed: %v", err)
+	}
+	if config.SSID != "Semi;colon" || config.Password != `back\slash` || !config.Hidden {
+		t.Errorf("ParseWifiURI(WifiString()) = %+v, want the original network", config)
+	}
+
+	if _, err := WifiString("Secure", "", true, false); err == nil {
+		t.Error("WifiString() of a secure network without a password succeeded")
+	}
+}
+
+func TestWifiStringForSecurity(t *testing.T) {
+	tests := []struct {
+		security wifi.SecurityType
+		want     string
+	}{
+		{wifi.SecurityOpen, `WIFI:S:Net;T:nopass;;`},
+		{wifi.SecurityOWE, `WIFI:S:Net;T:nopass;;`},
+		{wifi.SecurityWEP, `WIFI:S:Net;T:WEP;P:secret;;`},
+		{wifi.SecurityWPA, `WIFI:S:Net;T:WPA;P:secret;;`},
+		{wifi.SecurityWPA2WPA3, `WIFI:S:Net;T:WPA;P:secret;;`},
+		{wifi.SecuritySAE, `WIFI:S:Net;T:SAE;P:secret;;`},
+	}
+	for _, tt := range tests {
+		got, err := WifiStringForSecurity("Net", "secret", tt.security, false)
+		if err != nil {
+			t.Errorf("WifiStringForSecurity(%v) failed: %v", tt.security, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("WifiStringForSecurity(%v) = %q, want %q", tt.security, got, tt.want)
+		}
+	}
+
+	for _, security := range []wifi.SecurityType{wifi.SecurityEnterprise, wifi.SecurityEnterpriseWPA3} {
+		if got, err := WifiStringForSecurity("Corp", "account password", security, false); !errors.Is(err, wifi.ErrNotSupported) {
+			t.Errorf("WifiStringForSecurity(%v) = %q, %v, want ErrNotSupported", security, got, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package qrwifi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// ErrInvalidURI is returned when a string is not a valid WIFI: URI.
+var ErrInvalidURI = errors.New("invalid WIFI: URI")
+
+// Authentication types of a WIFI: URI.
+const (
+	TypeWPA    = "WPA"
+	TypeSAE    = "SAE"
+	TypeWEP    = "WEP"
+	TypeNoPass = "nopass"
+	TypeWPAEAP = "WPA2-EAP"
+)
+
+// WifiConfig is a network described by a WIFI: URI, as found in QR codes on
+// guest network placards.
+type WifiConfig struct {
+	SSID string
+	// Type is one of the Type constants. WPA covers WPA2 and WPA3 networks.
+	Type     string
+	Password string
+	Hidden   bool
+	// TransitionDisable is the R: bitmask of the WPA3 specification. Bit 0
+	// means the network only accepts WPA3-Personal (SAE).
+	TransitionDisable uint64
+	// PasswordID is the SAE password identifier (I:).
+	PasswordID string
+	// PublicKey is the base64 encoded SAE-PK public key (K:).
+	PublicKey string
+}
+
+// UnescapeWifiString reverses EscapeWifiString.
+func UnescapeWifiString(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ParseWifiURI parses a WIFI:S:...;T:...;P:...;H:...;; payload. Fields may
+// come in any order and unknown fields are ignored. DPP: URIs of Wi-Fi Easy
+// Connect are recognized but not supported, since they need a configurator
+// rather than a passphrase.
+func ParseWifiURI(uri string) (WifiConfig, error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(uri), ":")
+	if ok && strings.EqualFold(scheme, "DPP") {
+		return WifiConfig{}, fmt.Errorf("Wi-Fi Easy Connect (DPP) URIs need a configurator: %w", wifi.ErrNotSupported)
+	}
+	if !ok || !strings.EqualFold(scheme, "WIFI") {
+		return WifiConfig{}, fmt.Errorf("missing WIFI: prefix: %w", ErrInvalidURI)
+	}
+
+	var c WifiConfig
+	for _, field := range splitFields(rest) {
+		if field == "" {
+			// The URI ends with an empty field.
+			break
+		}
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			return WifiConfig{}, fmt.Errorf("field %q has no value: %w", field, ErrInvalidURI)
+		}
+		value = UnescapeWifiString(value)
+		switch strings.ToUpper(key) {
+		case "S":
+			c.SSID = value
+		case "T":
+			c.Type = value
+		case "P":
+			c.Password = value
+		case "H":
+			c.Hidden = strings.EqualFold(value, "true")
+		case "R":
+			bits, err := strconv.ParseUint(value, 16, 64)
+			if err != nil {
+				return WifiConfig{}, fmt.Errorf("transition disable %q is not hexadecimal: %w", value, ErrInvalidURI)
+			}
+			c.TransitionDisable = bits
+		case "I":
+			c.PasswordID = value
+		case "K":
+			c.PublicKey = value
+		}
+	}
+	if c.SSID == "" {
+		return WifiConfig{}, fmt.Errorf("missing SSID: %w", ErrInvalidURI)
+	}
+	if c.Type == "" {
+		c.Type = TypeNoPass
+		if c.Password != "" {
+			c.Type = TypeWPA
+		}
+	}
+	return c, nil
+}
+
+// splitFields splits s at the semicolons that are not escaped.
+func splitFields(s string) []string {
+	var fields []string
+	start := 0
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ';':
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(fields, s[start:])
+}
+
+// Security returns the security type to join the network with.
+func (c WifiConfig) Security() (wifi.SecurityType, error) {
+	switch strings.ToUpper(c.Type) {
+	case strings.ToUpper(TypeNoPass):
+		return wifi.SecurityOpen, nil
+	case TypeWEP:
+		return wifi.SecurityWEP, nil
+	case TypeSAE:
+		return wifi.SecuritySAE, nil
+	case TypeWPA:
+		if c.TransitionDisable&1 != 0 || c.PublicKey != "" {
+			return wifi.SecuritySAE, nil
+		}
+		return wifi.SecurityWPA, nil
+	case TypeWPAEAP:
+		return wifi.SecurityUnknown, fmt.Errorf("enterprise networks need more than a WIFI: URI: %w", wifi.ErrNotSupported)
+	}
+	return wifi.SecurityUnknown, fmt.Errorf("unknown authentication type %q: %w", c.Type, ErrInvalidURI)
+}
+
+// JoinOptions returns the options to join the network with. The SAE password
+// identifier and public key are not used, since no backend supports them.
+func (c WifiConfig) JoinOptions() (wifi.JoinOptions, error) {
+	security, err := c.Security()
+	if err != nil {
+		return wifi.JoinOptions{}, err
+	}
+	return wifi.JoinOptions{
+		Password: c.Password,
+		Security: security,
+		IsHidden: c.Hidden,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package qrwifi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func TestParseWifiURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want WifiConfig
+	}{
+		{
+			uri:  `WIFI:S:Guest;T:WPA;P:correct horse;;`,
+			want: WifiConfig{SSID: "Guest", Type: TypeWPA, Password: "correct horse"},
+		},
+		{
+			uri:  `WIFI:T:nopass;S:Lobby;H:true;;`,
+			want: WifiConfig{SSID: "Lobby", Type: TypeNoPass, Hidden: true},
+		},
+		{
+			uri:  `wifi:S:Semi\;colon\:\\;P:a\,b\"c;;`,
+			want: WifiConfig{SSID: `Semi;colon:\`, Type: TypeWPA, Password: `a,b"c`},
+		},
+		{
+			uri:  `WIFI:T:WPA;R:1;S:Office;P:hunter22;I:alice;K:MDkwEwYHKoZIzj0CAQ==;;`,
+			want: WifiConfig{SSID: "Office", Type: TypeWPA, Password: "hunter22", TransitionDisable: 1, PasswordID: "alice", PublicKey: "MDkwEwYHKoZIzj0CAQ=="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			got, err := ParseWifiURI(tt.uri)
+			if err != nil {
+				t.Fatalf("ParseWifiURI() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWifiURI() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWifiURIErrors(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want error
+	}{
+		{"https://example.com", ErrInvalidURI},
+		{"WIFI:T:WPA;P:secret;;", ErrInvalidURI},
+		{"WIFI:S:Office;R:xyz;;", ErrInvalidURI},
+		{"DPP:V:2;K:MDkwEwYHKoZIzj0CAQ==;;", wifi.ErrNotSupported},
+	}
+	for _, tt := range tests {
+		if _, err := ParseWifiURI(tt.uri); !errors.Is(err, tt.want) {
+			t.Errorf("ParseWifiURI(%q) = %v, want %v", tt.uri, err, tt.want)
+		}
+	}
+}
+
+func TestWifiConfigSecurity(t *testing.T) {
+	tests := []struct {
+		config WifiConfig
+		want   wifi.SecurityType
+	}{
+		{WifiConfig{Type: TypeNoPass}, wifi.SecurityOpen},
+		{WifiConfig{Type: "wep"}, wifi.SecurityWEP},
+		{WifiConfig{Type: TypeWPA}, wifi.SecurityWPA},
+		{WifiConfig{Type: TypeWPA, TransitionDisable: 1}, wifi.SecuritySAE},
+		{WifiConfig{Type: TypeSAE}, wifi.SecuritySAE},
+	}
+	for _, tt := range tests {
+		got, err := tt.config.Security()
+		if err != nil {
+			t.Errorf("%+v.Security() failed: %v", tt.config, err)
+		} else if got != tt.want {
+			t.Errorf("%+v.Security() = %v, want %v", tt.config, got, tt.want)
+		}
+	}
+	if _, err := (WifiConfig{Type: TypeWPAEAP}).Security(); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("Security() of an enterprise network = %v, want %v", err, wifi.ErrNotSupported)
+	}
+}
+
+func TestDecodeQRCode(t *testing.T) {
+	uri := `WIFI:S:Guest\;Net;T:WPA;P:battery staple;H:true;;`
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeQRCode(bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("DecodeQRCode() failed: %v", err)
+	}
+	if got != uri {
+		t.Errorf("DecodeQRCode() = %q, want %q", got, uri)
+	}
+	config, err := ParseWifiURI(got)
+	if err != nil {
+		t.Fatalf("ParseWifiURI() failed: %v", err)
+	}
+	if config.SSID != "Guest;Net" || config.Password != "battery staple" || !config.Hidden {
+		t.Errorf("ParseWifiURI() = %+v, want the encoded network", config)
+	}
+
+	if _, err := DecodeQRCode(bytes.NewReader([]byte("not an image"))); err == nil {
+		t.Error("DecodeQRCode() of garbage succeeded")
+	}
+}
@@ -1,17 +1,144 @@
 package wifi
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
 
 // SecurityType represents the security protocol of a network.
 type SecurityType int
 
 const (
-	SecurityUnknown SecurityType = iota
-	SecurityOpen
-	SecurityWEP
-	SecurityWPA
+	SecurityUnknown        SecurityType = iota
+	SecurityOpen                        // No encryption
+	SecurityWEP                         // Legacy WEP
+	SecurityWPA                         // WPA/WPA2-Personal (PSK)
+	SecurityEnterprise                  // WPA/WPA2-Enterprise (802.1X)
+	SecuritySAE                         // WPA3-Personal (SAE) only
+	SecurityWPA2WPA3                    // WPA2/WPA3-Personal transition mode, PSK and SAE
+	SecurityOWE                         // Enhanced Open (OWE), encrypted without a passphrase
+	SecurityEnterpriseWPA3              // WPA3-Enterprise 192-bit mode (EAP Suite B)
+)
+
+var securityNames = map[SecurityType]string{
+	SecurityUnknown:        "unknown",
+	SecurityOpen:           "open",
+	SecurityWEP:            "wep",
+	SecurityWPA:            "wpa",
+	SecurityEnterprise:     "enterprise",
+	SecuritySAE:            "wpa3",
+	SecurityWPA2WPA3:       "wpa2-wpa3",
+	SecurityOWE:            "owe",
+	SecurityEnterpriseWPA3: "wpa3-enterprise",
+}
+
+// String returns a human-readable name for the security type.
+func (s SecurityType) String() string {
+	switch s {
+	case SecurityOpen:
+		return "Open"
+	case SecurityWEP:
+		return "WEP"
+	case SecurityWPA:
+		return "WPA/WPA2"
+	case SecurityEnterprise:
+		return "WPA2-Enterprise"
+	case SecuritySAE:
+		return "WPA3"
+	case SecurityWPA2WPA3:
+		return "WPA2/WPA3"
+	case SecurityOWE:
+		return "OWE"
+	case SecurityEnterpriseWPA3:
+		return "WPA3-Enterprise"
+	default:
+		return "Unknown"
+	}
+}
+
+// MarshalText encodes the security type as a short lowercase name, such as
+// "wpa2-wpa3", which is also accepted by UnmarshalText.
+func (s SecurityType) MarshalText() ([]byte, error) {
+	name, ok := securityNames[s]
+	if !ok {
+		return nil, fmt.Errorf("unknown security type: %d", int(s))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes a name produced by MarshalText.
+func (s *SecurityType) UnmarshalText(text []byte) error {
+	for security, name := range securityNames {
+		if name == string(text) {
+			*s = security
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid security type: %q", text)
+}
+
+// IsEnterprise returns true for security types that authenticate with 802.1X.
+func (s SecurityType) IsEnterprise() bool {
+	return s == SecurityEnterprise || s == SecurityEnterpriseWPA3
+}
+
+// RequiresPassphrase returns true if joining needs a passphrase or key.
+// Enterprise networks may also take a password, depending on the EAP method.
+func (s SecurityType) RequiresPassphrase() bool {
+	switch s {
+	case SecurityWEP, SecurityWPA, SecuritySAE, SecurityWPA2WPA3:
+		return true
+	}
+	return false
+}
+
+// EAPMethod is the outer authentication method used by an 802.1X network.
+type EAPMethod string
+
+const (
+	EAPMethodPEAP EAPMethod = "peap"
+	EAPMethodTTLS EAPMethod = "ttls"
+	EAPMethodTLS  EAPMethod = "tls"
 )
 
+// EnterpriseCredentials configures WPA2/WPA3-Enterprise (802.1X) authentication.
+// Certificate and key fields are paths to files on the local filesystem.
+type EnterpriseCredentials struct {
+	Identity           string
+	AnonymousIdentity  string
+	EAPMethod          EAPMethod
+	Phase2Auth         string // Inner authentication for PEAP and TTLS, e.g. "mschapv2"
+	CACert             string
+	ClientCert         string
+	PrivateKey         string
+	PrivateKeyPassword string
+}
+
+// Validate returns ErrInvalidCredentials if the credentials are missing fields
+// required by the selected EAP method.
+func (c *EnterpriseCredentials) Validate() error {
+	if c == nil {
+		return fmt.Errorf("enterprise network requires credentials: %w", ErrInvalidCredentials)
+	}
+	switch c.EAPMethod {
+	case EAPMethodPEAP, EAPMethodTTLS:
+		if c.Identity == "" {
+			return fmt.Errorf("%s requires an identity: %w", c.EAPMethod, ErrInvalidCredentials)
+		}
+	case EAPMethodTLS:
+		if c.Identity == "" || c.ClientCert == "" || c.PrivateKey == "" {
+			return fmt.Errorf("tls requires an identity, client certificate and private key: %w", ErrInvalidCredentials)
+		}
+	default:
+		return fmt.Errorf("unsupported EAP method %q: %w", c.EAPMethod, ErrInvalidCredentials)
+	}
+	return nil
+}
+
 // AccessPoint represents a single access point for a network.
 type AccessPoint struct {
 	SSID      string
@@ -20,18 +147,55 @@ type AccessPoint struct {
 	Frequency uint  // MHz
 }
 
+// ParseBSSID parses a MAC address such as aa:bb:cc:dd:ee:ff and returns it in
+// the uppercase, colon separated form that backends report.
+func ParseBSSID(s string) (string, error) {
+	mac, err := net.ParseMAC(s)
+	if err != nil || len(mac) != 6 {
+		return "", fmt.Errorf("%q is not a BSSID: %w", s, ErrInvalidBSSID)
+	}
+	return strings.ToUpper(mac.String()), nil
+}
+
 // Network represents a single Wi-Fi network, visible or known.
 type Network struct {
-	SSID          string
-	IsActive      bool
-	IsKnown       bool
-	IsSecure      bool
-	IsVisible     bool
-	IsHidden      bool
-	AccessPoints  []AccessPoint
-	Security      SecurityType
+	SSID         string
+	IsActive     bool
+	IsKnown      bool
+	IsSecure     bool
+	IsVisible    bool
+	IsHidden     bool
+	AccessPoints []AccessPoint
+	Security     SecurityType
+	// SavedSecurity is the security a known network was saved with, when the
+	// backend reports it. It can differ from Security, which its access points
+	// advertise: a WPA2 profile also joins a WPA2/WPA3 transition network.
+	SavedSecurity SecurityType
 	LastConnected *time.Time
 	AutoConnect   bool
+	// IPv4 and IPv6 are the saved addressing of a known network, or nil if
+	// the backend does not report them.
+	IPv4 *IPConfig
+	IPv6 *IPConfig
+	// LockedBSSID is the access point a known network is restricted to, or
+	// empty if it may roam between all of its access points.
+	LockedBSSID string
+	// PreferredBand and PreferredChannel restrict a known network to a band,
+	// and optionally a channel within it. BandUnknown and 0 allow any.
+	PreferredBand    Band
+	PreferredChannel int
+	// Priority orders known networks for autoconnect, higher values are
+	// tried first. Networks that were never ordered have priority 0.
+	Priority int
+}
+
+// ProfileSecurity returns the security a known network was saved with,
+// falling back to the advertised security for backends that do not report it.
+func (c Network) ProfileSecurity() SecurityType {
+	if c.SavedSecurity != SecurityUnknown {
+		return c.SavedSecurity
+	}
+	return c.Security
 }
 
 // Strength returns the strength of the strongest access point, or 0 if none.
@@ -71,9 +235,28 @@ func (c *Network) AddAccessPoint(other Network) error {
 	if other.IsKnown {
 		c.IsKnown = true
 		c.AutoConnect = other.AutoConnect
+		if other.SavedSecurity != SecurityUnknown {
+			c.SavedSecurity = other.SavedSecurity
+		}
 		if other.LastConnected != nil {
 			c.LastConnected = other.LastConnected
 		}
+		if other.IPv4 != nil {
+			c.IPv4 = other.IPv4
+		}
+		if other.IPv6 != nil {
+			c.IPv6 = other.IPv6
+		}
+		if other.LockedBSSID != "" {
+			c.LockedBSSID = other.LockedBSSID
+		}
+		if other.PreferredBand != BandUnknown {
+			c.PreferredBand = other.PreferredBand
+			c.PreferredChannel = other.PreferredChannel
+		}
+		if other.Priority != 0 {
+			c.Priority = other.Priority
+		}
 	}
 	return nil
 }
@@ -83,6 +266,77 @@ func (c *Network) AddAccessPoint(other Network) error {
 type UpdateOptions struct {
 	Password    *string
 	AutoConnect *bool
+	// IPv4 and IPv6 replace the whole configuration of their IP family.
+	IPv4 *IPConfig
+	IPv6 *IPConfig
+	// BSSID locks the network to one access point. An empty string removes
+	// the lock.
+	BSSID *string
+	// Band restricts the network to a band, BandUnknown removes the
+	// restriction. Channel picks a channel within Band, and can only be set
+	// together with it; 0 allows any channel.
+	Band    *Band
+	Channel *int
+	// Priority orders the network for autoconnect, between MinPriority and
+	// MaxPriority.
+	Priority *int
+}
+
+// MinPriority and MaxPriority bound the autoconnect priority of a network.
+const (
+	MinPriority = -999
+	MaxPriority = 999
+)
+
+// Validate returns ErrInvalidIPConfig if either IP configuration is invalid,
+// ErrInvalidBSSID if the BSSID is malformed, ErrInvalidBand if the band or
+// channel is unknown, or ErrInvalidPriority if the priority is out of range.
+func (o UpdateOptions) Validate() error {
+	if err := o.IPv4.Validate(false); err != nil {
+		return err
+	}
+	if err := o.IPv6.Validate(true); err != nil {
+		return err
+	}
+	if o.BSSID != nil && *o.BSSID != "" {
+		if _, err := ParseBSSID(*o.BSSID); err != nil {
+			return err
+		}
+	}
+	if o.Band != nil && *o.Band != BandUnknown && !slices.Contains(bands, *o.Band) {
+		return fmt.Errorf("unknown band %q: %w", *o.Band, ErrInvalidBand)
+	}
+	if o.Channel != nil && *o.Channel != 0 {
+		if o.Band == nil || ChannelFrequency(*o.Band, *o.Channel) == 0 {
+			return fmt.Errorf("channel %d requires a band that contains it: %w", *o.Channel, ErrInvalidBand)
+		}
+	}
+	if o.Priority != nil && (*o.Priority < MinPriority || *o.Priority > MaxPriority) {
+		return fmt.Errorf("priority %d is not between %d and %d: %w", *o.Priority, MinPriority, MaxPriority, ErrInvalidPriority)
+	}
+	return nil
+}
+
+// JoinOptions specifies how to connect to a new network.
+type JoinOptions struct {
+	// Password is the passphrase for personal networks, or the user password
+	// for enterprise networks that use an inner authentication method.
+	Password string
+	Security SecurityType
+	IsHidden bool
+	// Enterprise is required when Security.IsEnterprise() is true.
+	Enterprise *EnterpriseCredentials
+	// IPv4 and IPv6 configure addressing for the new network. Nil uses
+	// automatic configuration.
+	IPv4 *IPConfig
+	IPv6 *IPConfig
+	// BSSID joins through a specific access point instead of the strongest
+	// one. With LockBSSID the new network is also locked to it.
+	BSSID     string
+	LockBSSID bool
+	// SaveOnly saves the network without connecting to it. The system may
+	// still connect to it later, as with any network that autoconnects.
+	SaveOnly bool
 }
 
 // ScanMode controls whether listing networks should request a scan first.
@@ -111,17 +365,47 @@ type Backend interface {
 	ListNetworks(scan ScanMode) (NetworksResult, error)
 	// ActivateNetwork activates a known network.
 	ActivateNetwork(ssid string) error
+	// ActivateAccessPoint activates a known network through the access point
+	// with the given BSSID, without locking the network to it.
+	ActivateAccessPoint(ssid, bssid string) error
 	// ForgetNetwork removes a known network configuration.
 	ForgetNetwork(ssid string) error
 	// JoinNetwork connects to a new network, potentially creating a new configuration.
-	JoinNetwork(ssid string, password string, security SecurityType, isHidden bool) error
+	JoinNetwork(ssid string, opts JoinOptions) error
 	// GetSecrets retrieves the password for a known network.
 	GetSecrets(ssid string) (string, error)
+	// ActiveConnection returns live details of the active connection, or nil
+	// if there is none.
+	ActiveConnection() (*ConnectionDetails, error)
 	// UpdateNetwork updates a known network.
 	UpdateNetwork(ssid string, opts UpdateOptions) error
 
+	// Devices lists the wireless devices, marking the selected one. Until
+	// SelectDevice is called, the backend picks the first usable device.
+	Devices() ([]Device, error)
+	// SelectDevice makes every other method act on the device with the given
+	// interface name. It returns ErrNotFound if there is no such device.
+	SelectDevice(iface string) error
+
 	// IsWirelessEnabled checks if the wireless radio is enabled.
 	IsWirelessEnabled() (bool, error)
 	// SetWireless enables or disables the wireless radio.
 	SetWireless(enabled bool) error
+
+	// Connectivity checks whether the active connection reaches the
+	// internet. Backends that cannot tell return ErrNotSupported.
+	Connectivity() (Connectivity, error)
+
+	// StartHotspot turns the wireless device into an access point that
+	// shares this machine's other connections.
+	StartHotspot(config HotspotConfig) error
+	// StopHotspot stops the hotspot and returns the device to station mode.
+	StopHotspot() error
+	// HotspotStatus returns the current hotspot. The passphrase is empty if
+	// the backend cannot read it back.
+	HotspotStatus() (HotspotStatus, error)
+
+	// WatchEvents streams changes as the backend observes them. The channel
+	// is closed when ctx is cancelled or the backend stops reporting changes.
+	WatchEvents(ctx context.Context) (<-chan Event, error)
 }
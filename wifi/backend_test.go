@@ -0,0 +1,94 @@
+package wifi
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSecurityTypeJSONRoundTrip(t *testing.T) {
+	for security := range securityNames {
+		data, err := json.Marshal(security)
+		if err != nil {
+			t.Fatalf("json.Marshal(%v) failed: %v", security, err)
+		}
+		var got SecurityType
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("json.Unmarshal(%s) failed: %v", data, err)
+		}
+		if got != security {
+			t.Errorf("round trip of %v = %v", security, got)
+		}
+	}
+}
+
+func TestSecurityTypeMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(Network{SSID: "Home", Security: SecurityWPA2WPA3})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if got := decoded["Security"]; got != "wpa2-wpa3" {
+		t.Errorf("Security = %#v, want %q", got, "wpa2-wpa3")
+	}
+}
+
+func TestSecurityTypeUnmarshalTextRejectsUnknownNames(t *testing.T) {
+	var security SecurityType
+	if err := security.UnmarshalText([]byte("wpa4")); err == nil {
+		t.Fatal("UnmarshalText(wpa4) returned nil error")
+	}
+}
+
+func TestParseBSSID(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "aa:bb:cc:dd:ee:ff", want: "AA:BB:CC:DD:EE:FF"},
+		{in: "AA-BB-CC-DD-EE-01", want: "AA:BB:CC:DD:EE:01"},
+		{in: "", wantErr: true},
+		{in: "aa:bb:cc", wantErr: true},
+		{in: "00:00:5e:00:53:01:02:03", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBSSID(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidBSSID) {
+				t.Errorf("ParseBSSID(%q) error = %v, want ErrInvalidBSSID", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBSSID(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestUpdateOptionsValidateBSSID(t *testing.T) {
+	unlock := ""
+	if err := (UpdateOptions{BSSID: &unlock}).Validate(); err != nil {
+		t.Errorf("Validate() with an empty BSSID = %v, want nil", err)
+	}
+	invalid := "not-a-mac"
+	if err := (UpdateOptions{BSSID: &invalid}).Validate(); !errors.Is(err, ErrInvalidBSSID) {
+		t.Errorf("Validate() with %q = %v, want ErrInvalidBSSID", invalid, err)
+	}
+}
+
+func TestUpdateOptionsValidatePriority(t *testing.T) {
+	for _, priority := range []int{MinPriority, 0, MaxPriority} {
+		if err := (UpdateOptions{Priority: &priority}).Validate(); err != nil {
+			t.Errorf("Validate() with priority %d = %v, want nil", priority, err)
+		}
+	}
+	for _, priority := range []int{MinPriority - 1, MaxPriority + 1} {
+		if err := (UpdateOptions{Priority: &priority}).Validate(); !errors.Is(err, ErrInvalidPriority) {
+			t.Errorf("Validate() with priority %d = %v, want ErrInvalidPriority", priority, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package wifi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bands lists the known bands in ascending frequency order.
+var bands = []Band{Band2GHz, Band5GHz, Band6GHz}
+
+// ParseBand parses a band such as "5", "5ghz" or "2.4GHz". An empty string or
+// "any" returns BandUnknown, which means no band preference.
+func ParseBand(s string) (Band, error) {
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "ghz")
+	switch strings.TrimSpace(name) {
+	case "", "any":
+		return BandUnknown, nil
+	case "2.4", "2":
+		return Band2GHz, nil
+	case "5":
+		return Band5GHz, nil
+	case "6":
+		return Band6GHz, nil
+	}
+	return BandUnknown, fmt.Errorf("unknown band %q: %w", s, ErrInvalidBand)
+}
+
+// ChannelFrequency returns the center frequency in MHz of a channel within a
+// band, or 0 if the band has no such channel.
+func ChannelFrequency(band Band, channel int) uint {
+	if channel <= 0 {
+		return 0
+	}
+	var frequency int
+	switch band {
+	case Band2GHz:
+		if channel == 14 {
+			return 2484
+		}
+		frequency = 2407 + channel*5
+	case Band5GHz:
+		frequency = 5000 + channel*5
+	case Band6GHz:
+		if channel == 2 {
+			return 5935
+		}
+		// 20 MHz channels are every fourth channel number.
+		if channel%4 != 1 {
+			return 0
+		}
+		frequency = 5950 + channel*5
+	default:
+		return 0
+	}
+	if FrequencyBand(uint(frequency)) != band || FrequencyChannel(uint(frequency)) != channel {
+		return 0
+	}
+	return uint(frequency)
+}
+
+// Bands returns the bands the network's access points were seen on, in
+// ascending frequency order.
+func (c Network) Bands() []Band {
+	var seen []Band
+	for _, band := range bands {
+		for _, ap := range c.AccessPoints {
+			if FrequencyBand(ap.Frequency) == band {
+				seen = append(seen, band)
+				break
+			}
+		}
+	}
+	return seen
+}
+
+// HasBand returns whether any of the network's access points use band.
+func (c Network) HasBand(band Band) bool {
+	for _, ap := range c.AccessPoints {
+		if FrequencyBand(ap.Frequency) == band {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package wifi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseBand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Band
+	}{
+		{"", BandUnknown},
+		{"any", BandUnknown},
+		{"2.4", Band2GHz},
+		{"2.4GHz", Band2GHz},
+		{"5", Band5GHz},
+		{"5ghz", Band5GHz},
+		{"6 GHz", Band6GHz},
+	}
+	for _, tt := range tests {
+		got, err := ParseBand(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBand(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseBand("60"); !errors.Is(err, ErrInvalidBand) {
+		t.Errorf("ParseBand(60) error = %v, want ErrInvalidBand", err)
+	}
+}
+
+func TestChannelFrequency(t *testing.T) {
+	tests := []struct {
+		band    Band
+		channel int
+		want    uint
+	}{
+		{Band2GHz, 1, 2412},
+		{Band2GHz, 14, 2484},
+		{Band5GHz, 36, 5180},
+		{Band6GHz, 1, 5955},
+		{Band6GHz, 33, 6115},
+		{Band6GHz, 36, 0},
+		{Band6GHz, 2, 5935},
+		{Band2GHz, 36, 0},
+		{Band5GHz, 1, 0},
+		{BandUnknown, 6, 0},
+		{Band5GHz, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := ChannelFrequency(tt.band, tt.channel); got != tt.want {
+			t.Errorf("ChannelFrequency(%q, %d) = %d, want %d", tt.band, tt.channel, got, tt.want)
+		}
+	}
+}
+
+func TestNetworkBands(t *testing.T) {
+	n := Network{AccessPoints: []AccessPoint{{Frequency: 5180}, {Frequency: 2412}, {Frequency: 5240}, {}}}
+	if got, want := n.Bands(), []Band{Band2GHz, Band5GHz}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bands() = %v, want %v", got, want)
+	}
+	if !n.HasBand(Band5GHz) || n.HasBand(Band6GHz) {
+		t.Errorf("HasBand() does not match Bands() %v", n.Bands())
+	}
+}
+
+func TestUpdateOptionsValidateBand(t *testing.T) {
+	band5, band6, anyBand := Band5GHz, Band6GHz, BandUnknown
+	channel36, channel0 := 36, 0
+	tests := []struct {
+		name    string
+		opts    UpdateOptions
+		wantErr bool
+	}{
+		{"band only", UpdateOptions{Band: &band5}, false},
+		{"band and channel", UpdateOptions{Band: &band5, Channel: &channel36}, false},
+		{"any band clears channel", UpdateOptions{Band: &anyBand, Channel: &channel0}, false},
+		{"channel outside band", UpdateOptions{Band: &band6, Channel: &channel36}, true},
+		{"channel without band", UpdateOptions{Channel: &channel36}, true},
+		{"channel with any band", UpdateOptions{Band: &anyBand, Channel: &channel36}, true},
+	}
+	for _, tt := range tests {
+		err := tt.opts.Validate()
+		if tt.wantErr != errors.Is(err, ErrInvalidBand) {
+			t.Errorf("%s: Validate() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+	invalid := Band("60GHz")
+	if err := (UpdateOptions{Band: &invalid}).Validate(); !errors.Is(err, ErrInvalidBand) {
+		t.Errorf("Validate() with band %q = %v, want ErrInvalidBand", invalid, err)
+	}
+}
@@ -0,0 +1,20 @@
+package wifi
+
+// Connectivity describes how far the active connection reaches beyond the
+// local network.
+type Connectivity string
+
+const (
+	// ConnectivityUnknown means the backend could not check.
+	ConnectivityUnknown Connectivity = "unknown"
+	// ConnectivityNone means there is no network connection at all.
+	ConnectivityNone Connectivity = "none"
+	// ConnectivityPortal means a captive portal intercepts traffic until the
+	// user logs in, as on many hotel and airport networks.
+	ConnectivityPortal Connectivity = "portal"
+	// ConnectivityLimited means the network is connected but does not reach
+	// the internet.
+	ConnectivityLimited Connectivity = "limited"
+	// ConnectivityFull means the internet is reachable.
+	ConnectivityFull Connectivity = "full"
+)
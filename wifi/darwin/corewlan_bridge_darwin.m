@@ -16,6 +16,12 @@ static void wifitui_set_error(char **destination, NSString *message) {
 }
 
 static NSString *wifitui_security(CWNetwork *network) {
+    if ([network supportsSecurity:kCWSecurityOWE]) {
+        return @"owe";
+    }
+    if ([network supportsSecurity:kCWSecurityOWETransition]) {
+        return @"owe-transition";
+    }
     if ([network supportsSecurity:kCWSecurityNone]) {
         return @"open";
     }
@@ -26,6 +32,28 @@ static NSString *wifitui_security(CWNetwork *network) {
     if ([network supportsSecurity:kCWSecurityUnknown]) {
         return @"unknown";
     }
+    if ([network supportsSecurity:kCWSecurityWPA3Transitional]) {
+        return @"wpa2-wpa3";
+    }
+    BOOL wpa2Personal = [network supportsSecurity:kCWSecurityWPAPersonal] ||
+        [network supportsSecurity:kCWSecurityWPA2Personal];
+    BOOL wpa3Personal = [network supportsSecurity:kCWSecurityWPA3Personal];
+    if (wpa2Personal && wpa3Personal) {
+        return @"wpa2-wpa3";
+    }
+    if (wpa3Personal) {
+        return @"wpa3";
+    }
+    BOOL personal = wpa2Personal || [network supportsSecurity:kCWSecurityPersonal];
+    BOOL enterprise = [network supportsSecurity:kCWSecurityWPAEnterprise] ||
+        [network supportsSecurity:kCWSecurityWPA2Enterprise] ||
+        [network supportsSecurity:kCWSecurityEnterprise];
+    if ([network supportsSecurity:kCWSecurityWPA3Enterprise] && !personal) {
+        return @"wpa3-enterprise";
+    }
+    if (enterprise && !personal) {
+        return @"enterprise";
+    }
     return @"wpa";
 }
 
@@ -3,9 +3,11 @@
 package darwin
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/shazow/wifitui/wifi"
 )
@@ -35,6 +37,12 @@ func (b *Backend) ActivateNetwork(ssid string) error {
 	return runOnly(cmd)
 }
 
+// ActivateAccessPoint is not supported, networksetup always picks the access
+// point itself.
+func (b *Backend) ActivateAccessPoint(ssid, bssid string) error {
+	return fmt.Errorf("connecting to a specific access point is not supported on darwin: %w", wifi.ErrNotSupported)
+}
+
 // ForgetNetwork removes a known network configuration.
 func (b *Backend) ForgetNetwork(ssid string) error {
 	cmd := exec.Command("networksetup", "-removepreferredwirelessnetwork", b.WifiInterface, ssid)
@@ -63,24 +71,56 @@ func (b *Backend) SetWireless(enabled bool) error {
 	return runOnly(cmd)
 }
 
+// Connectivity is not supported on darwin, which only shows its captive
+// portal assistant without reporting the state.
+func (b *Backend) Connectivity() (wifi.Connectivity, error) {
+	return wifi.ConnectivityUnknown, fmt.Errorf("connectivity checks are not supported on darwin: %w", wifi.ErrNotSupported)
+}
+
+// StartHotspot is not supported, since macOS only shares connections through
+// Internet Sharing in System Settings.
+func (b *Backend) StartHotspot(config wifi.HotspotConfig) error {
+	return fmt.Errorf("hotspot is not supported on darwin: %w", wifi.ErrNotSupported)
+}
+
+// StopHotspot is not supported on darwin.
+func (b *Backend) StopHotspot() error {
+	return fmt.Errorf("hotspot is not supported on darwin: %w", wifi.ErrNotSupported)
+}
+
+// HotspotStatus is not supported on darwin.
+func (b *Backend) HotspotStatus() (wifi.HotspotStatus, error) {
+	return wifi.HotspotStatus{}, fmt.Errorf("hotspot is not supported on darwin: %w", wifi.ErrNotSupported)
+}
+
 // JoinNetwork connects to a new network, potentially creating a new configuration.
-func (b *Backend) JoinNetwork(ssid string, password string, security wifi.SecurityType, isHidden bool) error {
-	cmd := exec.Command("networksetup", "-setairportnetwork", b.WifiInterface, ssid, password)
+func (b *Backend) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
+	if opts.Security.IsEnterprise() {
+		// networksetup has no way to supply 802.1X credentials; enterprise
+		// networks must be configured with a profile or System Settings.
+		return fmt.Errorf("joining enterprise networks is not supported on darwin: %w", wifi.ErrNotSupported)
+	}
+	if opts.IPv4 != nil || opts.IPv6 != nil {
+		return fmt.Errorf("per-network IP configuration is not supported on darwin: %w", wifi.ErrNotSupported)
+	}
+	if opts.BSSID != "" {
+		return fmt.Errorf("joining a specific access point is not supported on darwin: %w", wifi.ErrNotSupported)
+	}
+	args := []string{"-addpreferredwirelessnetworkatindex", b.WifiInterface, ssid, "0", networksetupSecurity(opts.Security)}
+	if opts.SaveOnly {
+		// Without joining first, the passphrase has to be stored along with
+		// the preferred network.
+		if opts.Password != "" {
+			args = append(args, opts.Password)
+		}
+		return runOnly(exec.Command("networksetup", args...))
+	}
+	cmd := exec.Command("networksetup", "-setairportnetwork", b.WifiInterface, ssid, opts.Password)
 	if err := runOnly(cmd); err != nil {
 		return err
 	}
 	// Add to preferred networks so it becomes "known"
-	var securityType string
-	switch security {
-	case wifi.SecurityOpen:
-		securityType = "OPEN"
-	case wifi.SecurityWEP:
-		securityType = "WEP"
-	default:
-		securityType = "WPA2" // Default to WPA2 for WPA/WPA2
-	}
-	cmd = exec.Command("networksetup", "-addpreferredwirelessnetworkatindex", b.WifiInterface, ssid, "0", securityType)
-	return runOnly(cmd)
+	return runOnly(exec.Command("networksetup", args...))
 }
 
 // GetSecrets retrieves the password for a known connection.
@@ -95,6 +135,19 @@ func (b *Backend) GetSecrets(ssid string) (string, error) {
 
 // UpdateNetwork updates a known network.
 func (b *Backend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
+	if opts.IPv4 != nil || opts.IPv6 != nil {
+		// networksetup configures addressing per network service, not per SSID.
+		return fmt.Errorf("per-network IP configuration is not supported on darwin: %w", wifi.ErrNotSupported)
+	}
+	if opts.BSSID != nil {
+		return fmt.Errorf("locking a network to an access point is not supported on darwin: %w", wifi.ErrNotSupported)
+	}
+	if opts.Band != nil || opts.Channel != nil {
+		return fmt.Errorf("band and channel preferences are not supported on darwin: %w", wifi.ErrNotSupported)
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 	if opts.Password != nil {
 		// In macOS, we need to delete the old password and add a new one.
 		// The -U flag in add-generic-password updates the item if it exists,
@@ -119,5 +172,20 @@ func (b *Backend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
 		}
 	}
 
+	if opts.Priority != nil {
+		if err := b.setPriority(ssid, *opts.Priority); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// eventPollInterval is how often WatchEvents compares cached scan results.
+const eventPollInterval = 5 * time.Second
+
+// WatchEvents reports changes by polling, since CoreWLAN event monitoring
+// needs a delegate on a running run loop that the CLI does not have.
+func (b *Backend) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
+	return wifi.PollEvents(ctx, b, eventPollInterval)
+}
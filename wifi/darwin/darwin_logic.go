@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/netip"
 	"os/exec"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -99,6 +103,20 @@ func decodeCoreWLANScan(output []byte) ([]scannedNetwork, error) {
 			security = wifi.SecurityWEP
 		case "wpa":
 			security = wifi.SecurityWPA
+		case "wpa3":
+			security = wifi.SecuritySAE
+		case "wpa2-wpa3":
+			security = wifi.SecurityWPA2WPA3
+		case "owe":
+			security = wifi.SecurityOWE
+		case "owe-transition":
+			// Like NetworkManager, report the open half of an OWE transition
+			// pair as open, the system switches to OWE on its own.
+			security = wifi.SecurityOpen
+		case "enterprise":
+			security = wifi.SecurityEnterprise
+		case "wpa3-enterprise":
+			security = wifi.SecurityEnterpriseWPA3
 		}
 		networks = append(networks, scannedNetwork{
 			ssid:      network.SSID,
@@ -132,14 +150,20 @@ type Backend struct {
 
 var currentNetworkRE = regexp.MustCompile(`Current Wi-Fi Network: (.+)`)
 
+// commandRunner returns the injected command runner, or one that executes
+// commands on the system.
+func (b *Backend) commandRunner() outputRunner {
+	if b.runOutput != nil {
+		return b.runOutput
+	}
+	return func(name string, args ...string) ([]byte, error) {
+		return runWithOutput(exec.Command(name, args...))
+	}
+}
+
 // ListNetworks returns the current network list and optionally scans first.
 func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error) {
-	run := b.runOutput
-	if run == nil {
-		run = func(name string, args ...string) ([]byte, error) {
-			return runWithOutput(exec.Command(name, args...))
-		}
-	}
+	run := b.commandRunner()
 
 	out, err := run("networksetup", "-getairportpower", b.WifiInterface)
 	if err != nil {
@@ -165,10 +189,10 @@ func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error)
 	if err != nil {
 		return wifi.NetworksResult{}, fmt.Errorf("failed to list preferred networks: %w: %w", wifi.ErrOperationFailed, err)
 	}
-	knownSSIDs := parsePreferredNetworks(string(preferredOut))
+	preferred := parsePreferredNetworks(string(preferredOut))
 
 	if scan == wifi.ScanNever {
-		return wifi.NetworksResult{Networks: mergeNetworks(b.cachedNetworks(), knownSSIDs, currentSSID)}, nil
+		return wifi.NetworksResult{Networks: mergeNetworks(b.cachedNetworks(), preferred, currentSSID)}, nil
 	}
 
 	scanner := b.scanNetworks
@@ -185,19 +209,66 @@ func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error)
 		if currentErr != nil {
 			cause = fmt.Errorf("scan failed: %w; current network query also failed: %w", err, currentErr)
 		}
-		return b.scanFallback(b.cachedNetworks(), knownSSIDs, currentSSID, stage, cause), nil
+		return b.scanFallback(b.cachedNetworks(), preferred, currentSSID, stage, cause), nil
 	}
 	visible := visibleNetworks(scanned)
 	if len(scanned) > 0 && len(visible) == 0 {
 		cause := fmt.Errorf("%w: scan returned no networks with an SSID", wifi.ErrScanProtocol)
-		return b.scanFallback(b.cachedNetworks(), knownSSIDs, currentSSID, wifi.ScanStageCompletion, cause), nil
+		return b.scanFallback(b.cachedNetworks(), preferred, currentSSID, wifi.ScanStageCompletion, cause), nil
 	}
 	b.storeNetworks(visible)
-	return wifi.NetworksResult{Networks: mergeNetworks(visible, knownSSIDs, currentSSID)}, nil
+	return wifi.NetworksResult{Networks: mergeNetworks(visible, preferred, currentSSID)}, nil
 }
 
-func parsePreferredNetworks(output string) map[string]bool {
-	knownSSIDs := make(map[string]bool)
+// ActiveConnection returns live details of the current network. The BSSID,
+// channel and bitrate are not available without CoreWLAN location access, so
+// only the addressing reported by ipconfig is filled in.
+func (b *Backend) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	run := b.commandRunner()
+	out, err := run("networksetup", "-getairportnetwork", b.WifiInterface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current network: %w", err)
+	}
+	matches := currentNetworkRE.FindStringSubmatch(string(out))
+	if len(matches) < 2 {
+		return nil, nil
+	}
+	details := &wifi.ConnectionDetails{
+		SSID:      strings.TrimSpace(matches[1]),
+		Interface: b.WifiInterface,
+	}
+
+	// ipconfig exits with an error for options the DHCP server did not
+	// provide, so each lookup is best effort.
+	option := func(args ...string) string {
+		out, err := run("ipconfig", args...)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if addr, err := netip.ParseAddr(option("getifaddr", b.WifiInterface)); err == nil {
+		bits := 32
+		if mask, err := netip.ParseAddr(option("getoption", b.WifiInterface, "subnet_mask")); err == nil && mask.Is4() {
+			m := mask.As4()
+			bits, _ = net.IPv4Mask(m[0], m[1], m[2], m[3]).Size()
+		}
+		details.IPv4Addresses = []netip.Prefix{netip.PrefixFrom(addr, bits)}
+	}
+	details.IPv4Gateway, _ = netip.ParseAddr(option("getoption", b.WifiInterface, "router"))
+	for _, field := range strings.Fields(option("getoption", b.WifiInterface, "domain_name_server")) {
+		if addr, err := netip.ParseAddr(field); err == nil {
+			details.DNS = append(details.DNS, addr)
+		}
+	}
+	return details, nil
+}
+
+// parsePreferredNetworks maps the preferred networks to their priority. The
+// first network is tried first, so it gets the highest priority and the last
+// one gets 1.
+func parsePreferredNetworks(output string) map[string]int {
+	var ssids []string
 	scanner := bufio.NewScanner(strings.NewReader(output))
 	firstNonEmpty := true
 	for scanner.Scan() {
@@ -211,14 +282,24 @@ func parsePreferredNetworks(output string) map[string]bool {
 				continue
 			}
 		}
-		knownSSIDs[line] = true
+		ssids = append(ssids, line)
+	}
+	preferred := make(map[string]int, len(ssids))
+	for i, ssid := range ssids {
+		preferred[ssid] = len(ssids) - i
 	}
-	return knownSSIDs
+	return preferred
 }
 
-func (b *Backend) scanFallback(cached []wifi.Network, knownSSIDs map[string]bool, currentSSID string, stage wifi.ScanStage, cause error) wifi.NetworksResult {
+// preferredIndex returns where a network with the given priority goes in a
+// preferred list of count networks, including itself.
+func preferredIndex(priority, count int) int {
+	return max(0, min(count-priority, count-1))
+}
+
+func (b *Backend) scanFallback(cached []wifi.Network, preferred map[string]int, currentSSID string, stage wifi.ScanStage, cause error) wifi.NetworksResult {
 	return wifi.NetworksResult{
-		Networks: mergeNetworks(cached, knownSSIDs, currentSSID),
+		Networks: mergeNetworks(cached, preferred, currentSSID),
 		ScanError: &wifi.ScanFailure{
 			Backend: "macOS",
 			Stage:   stage,
@@ -257,8 +338,8 @@ func visibleNetworks(scanned []scannedNetwork) []wifi.Network {
 	return sortedNetworks(networksByKey)
 }
 
-func mergeNetworks(visible []wifi.Network, knownSSIDs map[string]bool, currentSSID string) []wifi.Network {
-	networksByKey := make(map[darwinNetworkKey]wifi.Network, len(visible)+len(knownSSIDs)+1)
+func mergeNetworks(visible []wifi.Network, preferred map[string]int, currentSSID string) []wifi.Network {
+	networksByKey := make(map[darwinNetworkKey]wifi.Network, len(visible)+len(preferred)+1)
 	variantCount := make(map[string]int, len(visible))
 	for _, network := range visible {
 		variantCount[network.SSID]++
@@ -271,8 +352,11 @@ func mergeNetworks(visible []wifi.Network, knownSSIDs map[string]bool, currentSS
 		// scan found more than one; doing so could mark an open evil twin known.
 		unambiguous := variantCount[network.SSID] == 1
 		network.IsActive = unambiguous && network.SSID == currentSSID
-		network.IsKnown = unambiguous && knownSSIDs[network.SSID]
+		network.IsKnown = unambiguous && preferred[network.SSID] > 0
 		network.AutoConnect = network.IsKnown
+		if network.IsKnown {
+			network.Priority = preferred[network.SSID]
+		}
 		key := darwinNetworkKey{ssid: network.SSID, security: network.Security}
 		networksByKey[key] = network
 		visibleSSIDs[network.SSID] = true
@@ -284,14 +368,15 @@ func mergeNetworks(visible []wifi.Network, knownSSIDs map[string]bool, currentSS
 			SSID:        currentSSID,
 			IsActive:    true,
 			IsVisible:   true,
-			IsKnown:     knownSSIDs[currentSSID],
-			AutoConnect: knownSSIDs[currentSSID],
+			IsKnown:     preferred[currentSSID] > 0,
+			AutoConnect: preferred[currentSSID] > 0,
+			Priority:    preferred[currentSSID],
 			Security:    wifi.SecurityUnknown,
 		}
 		visibleSSIDs[currentSSID] = true
 	}
 
-	for ssid := range knownSSIDs {
+	for ssid, priority := range preferred {
 		if !visibleSSIDs[ssid] {
 			key := darwinNetworkKey{ssid: ssid, security: wifi.SecurityUnknown}
 			networksByKey[key] = wifi.Network{
@@ -299,6 +384,7 @@ func mergeNetworks(visible []wifi.Network, knownSSIDs map[string]bool, currentSS
 				IsKnown:     true,
 				AutoConnect: true,
 				Security:    wifi.SecurityUnknown,
+				Priority:    priority,
 			}
 		}
 	}
@@ -353,27 +439,122 @@ func rssiToStrength(rssi int) uint8 {
 
 // findWifiDevice parses the output of `networksetup -listallhardwareports` to find the Wi-Fi device.
 func findWifiDevice(output string) (string, error) {
+	devices := findWifiDevices(output)
+	if len(devices) == 0 {
+		return "", fmt.Errorf("no Wi-Fi interface found: %w", wifi.ErrNotFound)
+	}
+	return devices[0].Interface, nil
+}
+
+// findWifiDevices parses the output of `networksetup -listallhardwareports`
+// and returns every Wi-Fi port, in the order listed.
+func findWifiDevices(output string) []wifi.Device {
+	var devices []wifi.Device
 	// The output is a series of stanzas, separated by blank lines.
 	// Each stanza describes a hardware port.
 	stanzas := strings.Split(output, "\n\n")
 	for _, stanza := range stanzas {
-		var hardwarePort, device string
+		var device wifi.Device
 		isWifiPort := false
 		lines := strings.Split(stanza, "\n")
 		for _, line := range lines {
 			if strings.HasPrefix(line, "Hardware Port: ") {
-				hardwarePort = strings.TrimPrefix(line, "Hardware Port: ")
+				hardwarePort := strings.TrimPrefix(line, "Hardware Port: ")
 				if strings.Contains(hardwarePort, "Wi-Fi") || strings.Contains(hardwarePort, "AirPort") {
 					isWifiPort = true
 				}
 			}
 			if strings.HasPrefix(line, "Device: ") {
-				device = strings.TrimPrefix(line, "Device: ")
+				device.Interface = strings.TrimPrefix(line, "Device: ")
+			}
+			if strings.HasPrefix(line, "Ethernet Address: ") {
+				device.HardwareAddress = strings.TrimPrefix(line, "Ethernet Address: ")
 			}
 		}
-		if isWifiPort && device != "" {
-			return device, nil
+		if isWifiPort && device.Interface != "" {
+			devices = append(devices, device)
 		}
 	}
-	return "", fmt.Errorf("no Wi-Fi interface found: %w", wifi.ErrNotFound)
+	return devices
+}
+
+// Devices lists the Wi-Fi hardware ports.
+func (b *Backend) Devices() ([]wifi.Device, error) {
+	out, err := b.commandRunner()("networksetup", "-listallhardwareports")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hardware ports: %w: %w", wifi.ErrOperationFailed, err)
+	}
+	devices := findWifiDevices(string(out))
+	for i := range devices {
+		devices[i].Selected = devices[i].Interface == b.WifiInterface
+	}
+	return devices, nil
+}
+
+// SelectDevice switches to another Wi-Fi hardware port. The cached scan
+// results belong to the previous port, so they are dropped.
+func (b *Backend) SelectDevice(iface string) error {
+	devices, err := b.Devices()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(devices, func(d wifi.Device) bool { return d.Interface == iface }) {
+		return fmt.Errorf("no Wi-Fi interface %s found: %w", iface, wifi.ErrNotFound)
+	}
+	b.WifiInterface = iface
+	b.storeNetworks(nil)
+	return nil
+}
+
+// setPriority moves a preferred network to the position of the given
+// priority. networksetup can only reorder by removing the network and adding
+// it again, which needs its security type, so the network has to have been
+// seen in a scan.
+func (b *Backend) setPriority(ssid string, priority int) error {
+	run := b.commandRunner()
+	out, err := run("networksetup", "-listpreferredwirelessnetworks", b.WifiInterface)
+	if err != nil {
+		return fmt.Errorf("failed to list preferred networks: %w: %w", wifi.ErrOperationFailed, err)
+	}
+	preferred := parsePreferredNetworks(string(out))
+	if preferred[ssid] == 0 {
+		return fmt.Errorf("network %s is not a preferred network: %w", ssid, wifi.ErrNotFound)
+	}
+
+	var variants []wifi.SecurityType
+	for _, c := range b.cachedNetworks() {
+		if c.SSID == ssid {
+			variants = append(variants, c.Security)
+		}
+	}
+	if len(variants) != 1 || variants[0] == wifi.SecurityUnknown || variants[0].IsEnterprise() {
+		return fmt.Errorf("reordering %s needs its security type from a scan: %w", ssid, wifi.ErrNotSupported)
+	}
+
+	args := []string{"-addpreferredwirelessnetworkatindex", b.WifiInterface, ssid,
+		strconv.Itoa(preferredIndex(priority, len(preferred))), networksetupSecurity(variants[0])}
+	if secret, err := run("security", "find-generic-password", "-wa", ssid); err == nil {
+		args = append(args, strings.TrimSpace(string(secret)))
+	}
+	if _, err := run("networksetup", "-removepreferredwirelessnetwork", b.WifiInterface, ssid); err != nil {
+		return err
+	}
+	_, err = run("networksetup", args...)
+	return err
+}
+
+// networksetupSecurity returns the security type name that networksetup
+// expects when adding a preferred network.
+func networksetupSecurity(security wifi.SecurityType) string {
+	switch security {
+	case wifi.SecurityOpen, wifi.SecurityOWE:
+		// OWE is negotiated by the system when joining an open network.
+		return "OPEN"
+	case wifi.SecurityWEP:
+		return "WEP"
+	case wifi.SecuritySAE, wifi.SecurityWPA2WPA3:
+		return "WPA3"
+	default:
+		return "WPA2" // Default to WPA2 for WPA/WPA2
+	}
 }
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"os/exec"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -384,7 +386,7 @@ func TestMergeNetworksDoesNotTrustAmbiguousSSIDMetadata(t *testing.T) {
 		{ssid: "Cafe", bssid: "00:11:22:33:44:55", security: wifi.SecurityOpen, rssi: -60},
 		{ssid: "Cafe", bssid: "00:11:22:33:44:66", security: wifi.SecurityWPA, rssi: -50},
 	})
-	networks := mergeNetworks(visible, map[string]bool{"Cafe": true}, "Cafe")
+	networks := mergeNetworks(visible, map[string]int{"Cafe": 1}, "Cafe")
 
 	if len(networks) != 2 {
 		t.Fatalf("mergeNetworks returned %d networks, want 2 security variants: %#v", len(networks), networks)
@@ -410,6 +412,27 @@ func TestDecodeCoreWLANScan(t *testing.T) {
 	}
 }
 
+func TestDecodeCoreWLANScanSecurityTypes(t *testing.T) {
+	tests := map[string]wifi.SecurityType{
+		"wpa3":            wifi.SecuritySAE,
+		"wpa2-wpa3":       wifi.SecurityWPA2WPA3,
+		"owe":             wifi.SecurityOWE,
+		"owe-transition":  wifi.SecurityOpen,
+		"enterprise":      wifi.SecurityEnterprise,
+		"wpa3-enterprise": wifi.SecurityEnterpriseWPA3,
+	}
+	for name, want := range tests {
+		output := fmt.Sprintf(`[{"ssid":"Net","security":%q}]`, name)
+		networks, err := decodeCoreWLANScan([]byte(output))
+		if err != nil {
+			t.Fatalf("decodeCoreWLANScan(%s) returned error: %v", name, err)
+		}
+		if networks[0].security != want {
+			t.Errorf("decodeCoreWLANScan(%s) security = %v, want %v", name, networks[0].security, want)
+		}
+	}
+}
+
 func TestDecodeCoreWLANScanAllowsEmptyResults(t *testing.T) {
 	networks, err := decodeCoreWLANScan([]byte("[]"))
 	if err != nil || len(networks) != 0 {
@@ -457,11 +480,46 @@ func TestCoreWLANStatusErrorClassifiesKnownFailures(t *testing.T) {
 
 func TestParsePreferredNetworksKeepsSSIDStartingWithPreferred(t *testing.T) {
 	known := parsePreferredNetworks("Preferred networks on en0:\n\tHome\n\tPreferred Cafe\n")
-	if !known["Home"] || !known["Preferred Cafe"] || len(known) != 2 {
+	if known["Home"] != 2 || known["Preferred Cafe"] != 1 || len(known) != 2 {
 		t.Fatalf("parsePreferredNetworks = %#v", known)
 	}
 }
 
+func TestSetPriorityReordersPreferredNetworks(t *testing.T) {
+	results := map[string]commandResult{
+		"networksetup -listpreferredwirelessnetworks en0": {
+			output: "Preferred networks on en0:\n\tHome\n\tOffice\n\tCafe\n",
+		},
+		"security find-generic-password -wa Cafe":                                   {output: "password\n"},
+		"networksetup -removepreferredwirelessnetwork en0 Cafe":                     {},
+		"networksetup -addpreferredwirelessnetworkatindex en0 Cafe 0 WPA2 password": {},
+	}
+	runner := &fakeOutputRunner{t: t, results: results}
+	backend := &Backend{WifiInterface: "en0", runOutput: runner.run}
+	backend.storeNetworks([]wifi.Network{{SSID: "Cafe", Security: wifi.SecurityWPA, IsVisible: true}})
+
+	if err := backend.setPriority("Cafe", 3); err != nil {
+		t.Fatalf("setPriority() failed: %v", err)
+	}
+	if got := runner.commands[len(runner.commands)-1]; got != "networksetup -addpreferredwirelessnetworkatindex en0 Cafe 0 WPA2 password" {
+		t.Errorf("setPriority() ran %q last", got)
+	}
+	if err := backend.setPriority("Office", 3); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("setPriority() without a scan = %v, want ErrNotSupported", err)
+	}
+	if err := backend.setPriority("Elsewhere", 3); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("setPriority() for an unknown network = %v, want ErrNotFound", err)
+	}
+
+	for _, tt := range []struct{ priority, count, want int }{
+		{3, 3, 0}, {1, 3, 2}, {2, 3, 1}, {10, 3, 0}, {0, 3, 2}, {-5, 3, 2},
+	} {
+		if got := preferredIndex(tt.priority, tt.count); got != tt.want {
+			t.Errorf("preferredIndex(%d, %d) = %d, want %d", tt.priority, tt.count, got, tt.want)
+		}
+	}
+}
+
 func networkBySSID(networks []wifi.Network, ssid string) (wifi.Network, bool) {
 	for _, network := range networks {
 		if network.SSID == ssid {
@@ -493,6 +551,40 @@ Ethernet Address: a1:b2:c3:d4:e5:f8`
 	}
 }
 
+func TestDevicesAndSelectDevice(t *testing.T) {
+	runner := &fakeOutputRunner{t: t, results: map[string]commandResult{
+		"networksetup -listallhardwareports": {
+			output: "Hardware Port: Wi-Fi\nDevice: en0\nEthernet Address: a1:b2:c3:d4:e5:f6\n\n" +
+				"Hardware Port: Thunderbolt Bridge\nDevice: bridge0\nEthernet Address: a1:b2:c3:d4:e5:f8\n\n" +
+				"Hardware Port: USB Wi-Fi\nDevice: en7\nEthernet Address: a1:b2:c3:d4:e5:f9",
+		},
+	}}
+	backend := &Backend{WifiInterface: "en0", runOutput: runner.run}
+	backend.storeNetworks([]wifi.Network{{SSID: "Stale"}})
+
+	devices, err := backend.Devices()
+	if err != nil {
+		t.Fatalf("Devices() failed: %v", err)
+	}
+	want := []wifi.Device{
+		{Interface: "en0", HardwareAddress: "a1:b2:c3:d4:e5:f6", Selected: true},
+		{Interface: "en7", HardwareAddress: "a1:b2:c3:d4:e5:f9"},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Fatalf("Devices() = %+v, want %+v", devices, want)
+	}
+
+	if err := backend.SelectDevice("en7"); err != nil {
+		t.Fatalf("SelectDevice(en7) failed: %v", err)
+	}
+	if backend.WifiInterface != "en7" || len(backend.cachedNetworks()) != 0 {
+		t.Errorf("SelectDevice(en7) left interface %q and %d cached networks", backend.WifiInterface, len(backend.cachedNetworks()))
+	}
+	if err := backend.SelectDevice("bridge0"); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("SelectDevice(bridge0) = %v, want ErrNotFound", err)
+	}
+}
+
 func TestRssiToStrength(t *testing.T) {
 	tests := []struct {
 		rssi     int
@@ -514,3 +606,43 @@ func TestRssiToStrength(t *testing.T) {
 		}
 	}
 }
+
+func TestActiveConnectionReadsIPConfiguration(t *testing.T) {
+	runner := &fakeOutputRunner{t: t, results: map[string]commandResult{
+		"networksetup -getairportnetwork en0":       {output: "Current Wi-Fi Network: Home\n"},
+		"ipconfig getifaddr en0":                    {output: "192.168.1.10\n"},
+		"ipconfig getoption en0 subnet_mask":        {output: "255.255.255.0\n"},
+		"ipconfig getoption en0 router":             {output: "192.168.1.1\n"},
+		"ipconfig getoption en0 domain_name_server": {err: errors.New("exit status 1")},
+	}}
+	backend := &Backend{WifiInterface: "en0", runOutput: runner.run}
+
+	details, err := backend.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection returned an error: %v", err)
+	}
+	want := &wifi.ConnectionDetails{
+		SSID:          "Home",
+		Interface:     "en0",
+		IPv4Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+		IPv4Gateway:   netip.MustParseAddr("192.168.1.1"),
+	}
+	if !reflect.DeepEqual(details, want) {
+		t.Fatalf("ActiveConnection = %+v, want %+v", details, want)
+	}
+}
+
+func TestActiveConnectionNotAssociated(t *testing.T) {
+	runner := &fakeOutputRunner{t: t, results: map[string]commandResult{
+		"networksetup -getairportnetwork en0": {output: "You are not associated with an AirPort network.\n"},
+	}}
+	backend := &Backend{WifiInterface: "en0", runOutput: runner.run}
+
+	details, err := backend.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection returned an error: %v", err)
+	}
+	if details != nil {
+		t.Fatalf("ActiveConnection = %+v, want nil", details)
+	}
+}
@@ -0,0 +1,77 @@
+package wifi
+
+import "net/netip"
+
+// Band is a Wi-Fi frequency band.
+type Band string
+
+const (
+	BandUnknown Band = ""
+	Band2GHz    Band = "2.4GHz"
+	Band5GHz    Band = "5GHz"
+	Band6GHz    Band = "6GHz"
+)
+
+// FrequencyBand returns the band of a channel frequency in MHz.
+func FrequencyBand(frequency uint) Band {
+	switch {
+	case frequency >= 2401 && frequency <= 2495:
+		return Band2GHz
+	case frequency >= 5150 && frequency <= 5895:
+		return Band5GHz
+	case frequency >= 5925 && frequency <= 7125:
+		return Band6GHz
+	default:
+		return BandUnknown
+	}
+}
+
+// FrequencyChannel returns the channel number of a frequency in MHz, or 0 if
+// the frequency is not a Wi-Fi channel.
+func FrequencyChannel(frequency uint) int {
+	f := int(frequency)
+	switch FrequencyBand(frequency) {
+	case Band2GHz:
+		if f == 2484 {
+			return 14
+		}
+		return (f - 2407) / 5
+	case Band5GHz:
+		return (f - 5000) / 5
+	case Band6GHz:
+		if f == 5935 {
+			return 2
+		}
+		return (f - 5950) / 5
+	default:
+		return 0
+	}
+}
+
+// ConnectionDetails describes the live state of the active connection. Fields
+// a backend cannot report are left at their zero value.
+type ConnectionDetails struct {
+	SSID string
+	// BSSID is the access point the device is associated with.
+	BSSID     string
+	Frequency uint // MHz
+	// Bitrate is the current transmit rate in kbit/s.
+	Bitrate   uint32
+	Interface string
+
+	IPv4Addresses []netip.Prefix
+	IPv4Gateway   netip.Addr
+	IPv6Addresses []netip.Prefix
+	IPv6Gateway   netip.Addr
+	DNS           []netip.Addr
+}
+
+// Channel returns the channel the connection is using, or 0 if unknown.
+func (d ConnectionDetails) Channel() int {
+	return FrequencyChannel(d.Frequency)
+}
+
+// Band returns the band the connection is using.
+func (d ConnectionDetails) Band() Band {
+	return FrequencyBand(d.Frequency)
+}
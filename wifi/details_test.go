@@ -0,0 +1,30 @@
+package wifi
+
+import "testing"
+
+func TestFrequencyChannel(t *testing.T) {
+	tests := []struct {
+		frequency uint
+		channel   int
+		band      Band
+	}{
+		{2412, 1, Band2GHz},
+		{2462, 11, Band2GHz},
+		{2484, 14, Band2GHz},
+		{5180, 36, Band5GHz},
+		{5825, 165, Band5GHz},
+		{5935, 2, Band6GHz},
+		{5955, 1, Band6GHz},
+		{6115, 33, Band6GHz},
+		{0, 0, BandUnknown},
+		{60480, 0, BandUnknown},
+	}
+	for _, tt := range tests {
+		if got := FrequencyChannel(tt.frequency); got != tt.channel {
+			t.Errorf("FrequencyChannel(%d) = %d, want %d", tt.frequency, got, tt.channel)
+		}
+		if got := FrequencyBand(tt.frequency); got != tt.band {
+			t.Errorf("FrequencyBand(%d) = %q, want %q", tt.frequency, got, tt.band)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package wifi
+
+// Device is a wireless network interface that a backend can manage.
+type Device struct {
+	Interface       string
+	HardwareAddress string
+	// Selected is true for the device the backend's other methods act on.
+	Selected bool
+}
+
+// SelectedDevice returns the selected device, or false if none is.
+func SelectedDevice(devices []Device) (Device, bool) {
+	for _, d := range devices {
+		if d.Selected {
+			return d, true
+		}
+	}
+	return Device{}, false
+}
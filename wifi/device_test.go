@@ -0,0 +1,13 @@
+package wifi
+
+import "testing"
+
+func TestSelectedDevice(t *testing.T) {
+	if _, ok := SelectedDevice([]Device{{Interface: "wlan0"}}); ok {
+		t.Error("SelectedDevice() found a device when none is selected")
+	}
+	got, ok := SelectedDevice([]Device{{Interface: "wlan0"}, {Interface: "wlan1", Selected: true}})
+	if !ok || got.Interface != "wlan1" {
+		t.Errorf("SelectedDevice() = %+v, %v, want wlan1", got, ok)
+	}
+}
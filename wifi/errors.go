@@ -27,6 +27,25 @@ var ErrNotSupported = errors.New("not supported")
 // ErrAccessPointMismatch is returned when trying to merge connections with different SSID or security.
 var ErrAccessPointMismatch = errors.New("SSID or security mismatch")
 
+// ErrInvalidCredentials is returned when the supplied credentials are
+// incomplete for the requested security type.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrInvalidIPConfig is returned when an IP configuration is incomplete or
+// inconsistent.
+var ErrInvalidIPConfig = errors.New("invalid IP configuration")
+
+// ErrInvalidBSSID is returned when a BSSID is not a MAC address.
+var ErrInvalidBSSID = errors.New("invalid BSSID")
+
+// ErrInvalidBand is returned when a band or channel is unknown, or a channel
+// does not belong to its band.
+var ErrInvalidBand = errors.New("invalid band or channel")
+
+// ErrInvalidPriority is returned when an autoconnect priority is out of
+// range.
+var ErrInvalidPriority = errors.New("invalid priority")
+
 // ErrMissingPermission is returned when the user lacks necessary permissions.
 var ErrMissingPermission = errors.New("missing permission")
 
@@ -0,0 +1,203 @@
+package wifi
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// EventType identifies the kind of change an Event describes.
+type EventType string
+
+const (
+	// EventNetworkAppeared is sent when the first access point of an SSID becomes visible.
+	EventNetworkAppeared EventType = "network-appeared"
+	// EventNetworkDisappeared is sent when the last access point of an SSID is no longer visible.
+	EventNetworkDisappeared EventType = "network-disappeared"
+	// EventSignalChanged is sent when the strength of an access point changes.
+	EventSignalChanged EventType = "signal-changed"
+	// EventConnectionStateChanged is sent when the active connection changes state.
+	EventConnectionStateChanged EventType = "connection-state-changed"
+	// EventRadioToggled is sent when the wireless radio is enabled or disabled.
+	EventRadioToggled EventType = "radio-toggled"
+	// EventScanCompleted is sent when a scan finishes, whoever requested it.
+	EventScanCompleted EventType = "scan-completed"
+)
+
+// ConnectionState is the state of the active wireless connection.
+type ConnectionState string
+
+const (
+	ConnectionActivating   ConnectionState = "activating"
+	ConnectionActivated    ConnectionState = "activated"
+	ConnectionDeactivating ConnectionState = "deactivating"
+	ConnectionDeactivated  ConnectionState = "deactivated"
+	ConnectionFailed       ConnectionState = "failed"
+)
+
+// Event describes a single change reported by a backend. Only the fields
+// relevant to the Type are set.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// SSID is set for network, signal and connection state events, when known.
+	SSID string
+	// BSSID and Strength are set for signal events, and for appearance
+	// events when the backend reports access points.
+	BSSID    string
+	Strength uint8
+
+	// State is set for EventConnectionStateChanged.
+	State ConnectionState
+	// RadioEnabled is set for EventRadioToggled.
+	RadioEnabled bool
+}
+
+// DiffNetworks returns the events that turn prev into next: networks that
+// appeared or disappeared, access points whose strength changed, and networks
+// that became active or inactive. Only visible networks are compared for
+// appearance and signal changes.
+func DiffNetworks(prev, next []Network) []Event {
+	now := time.Now()
+	before := indexNetworks(prev)
+	after := indexNetworks(next)
+
+	var events []Event
+	for _, ssid := range after.order {
+		cur := after.networks[ssid]
+		old, existed := before.networks[ssid]
+		switch {
+		case cur.visible && (!existed || !old.visible):
+			events = append(events, Event{Type: EventNetworkAppeared, Time: now, SSID: ssid, Strength: cur.strength})
+		case cur.visible && old.visible:
+			for _, bssid := range cur.order {
+				strength := cur.accessPoints[bssid]
+				if oldStrength, ok := old.accessPoints[bssid]; ok && oldStrength != strength {
+					events = append(events, Event{Type: EventSignalChanged, Time: now, SSID: ssid, BSSID: bssid, Strength: strength})
+				}
+			}
+		}
+		if cur.active && !old.active {
+			events = append(events, Event{Type: EventConnectionStateChanged, Time: now, SSID: ssid, State: ConnectionActivated})
+		}
+	}
+	for _, ssid := range before.order {
+		old := before.networks[ssid]
+		cur, exists := after.networks[ssid]
+		if old.visible && (!exists || !cur.visible) {
+			events = append(events, Event{Type: EventNetworkDisappeared, Time: now, SSID: ssid})
+		}
+		if old.active && !cur.active {
+			events = append(events, Event{Type: EventConnectionStateChanged, Time: now, SSID: ssid, State: ConnectionDeactivated})
+		}
+	}
+	return events
+}
+
+type networkSnapshot struct {
+	visible  bool
+	active   bool
+	strength uint8
+	// accessPoints maps BSSIDs to strength. Backends that do not report
+	// access points use the SSID as the key.
+	accessPoints map[string]uint8
+	order        []string
+}
+
+type networkIndex struct {
+	networks map[string]networkSnapshot
+	order    []string
+}
+
+// indexNetworks merges networks by SSID, since the same SSID can be listed
+// once per security variant.
+func indexNetworks(networks []Network) networkIndex {
+	index := networkIndex{networks: make(map[string]networkSnapshot)}
+	for _, n := range networks {
+		s, ok := index.networks[n.SSID]
+		if !ok {
+			s.accessPoints = make(map[string]uint8)
+			index.order = append(index.order, n.SSID)
+		}
+		s.visible = s.visible || n.IsVisible
+		s.active = s.active || n.IsActive
+		if n.Strength() > s.strength {
+			s.strength = n.Strength()
+		}
+		if n.IsVisible {
+			for _, ap := range n.AccessPoints {
+				key := ap.BSSID
+				if key == "" {
+					key = n.SSID
+				}
+				if _, seen := s.accessPoints[key]; !seen {
+					s.order = append(s.order, key)
+				}
+				s.accessPoints[key] = ap.Strength
+			}
+		}
+		index.networks[n.SSID] = s
+	}
+	return index
+}
+
+// PollEvents emulates WatchEvents for backends without change notifications
+// by comparing cached network lists every interval. It never requests a scan.
+func PollEvents(ctx context.Context, b Backend, interval time.Duration) (<-chan Event, error) {
+	enabled, err := b.IsWirelessEnabled()
+	if err != nil {
+		return nil, err
+	}
+	var networks []Network
+	if enabled {
+		result, err := b.ListNetworks(ScanNever)
+		if err != nil {
+			return nil, err
+		}
+		networks = result.Networks
+	}
+
+	events := make(chan Event, 16)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		send := func(e Event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var pending []Event
+			if nowEnabled, err := b.IsWirelessEnabled(); err == nil && nowEnabled != enabled {
+				enabled = nowEnabled
+				pending = append(pending, Event{Type: EventRadioToggled, Time: time.Now(), RadioEnabled: enabled})
+			}
+			result, err := b.ListNetworks(ScanNever)
+			if errors.Is(err, ErrWirelessDisabled) {
+				result, err = NetworksResult{}, nil
+			}
+			if err == nil {
+				pending = append(pending, DiffNetworks(networks, result.Networks)...)
+				networks = result.Networks
+			}
+			for _, e := range pending {
+				if !send(e) {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
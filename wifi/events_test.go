@@ -0,0 +1,100 @@
+package wifi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDiffNetworks(t *testing.T) {
+	prev := []Network{
+		{SSID: "Home", IsVisible: true, IsActive: true, AccessPoints: []AccessPoint{{BSSID: "aa", Strength: 50}, {BSSID: "bb", Strength: 20}}},
+		{SSID: "Cafe", IsVisible: true, AccessPoints: []AccessPoint{{BSSID: "cc", Strength: 40}}},
+		{SSID: "Saved", IsKnown: true},
+	}
+	next := []Network{
+		{SSID: "Home", IsVisible: true, AccessPoints: []AccessPoint{{BSSID: "aa", Strength: 70}, {BSSID: "bb", Strength: 20}}},
+		{SSID: "Library", IsVisible: true, IsActive: true, AccessPoints: []AccessPoint{{BSSID: "dd", Strength: 30}}},
+		{SSID: "Saved", IsKnown: true},
+	}
+
+	got := DiffNetworks(prev, next)
+	want := []Event{
+		{Type: EventSignalChanged, SSID: "Home", BSSID: "aa", Strength: 70},
+		{Type: EventNetworkAppeared, SSID: "Library", Strength: 30},
+		{Type: EventConnectionStateChanged, SSID: "Library", State: ConnectionActivated},
+		{Type: EventConnectionStateChanged, SSID: "Home", State: ConnectionDeactivated},
+		{Type: EventNetworkDisappeared, SSID: "Cafe"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DiffNetworks() returned %d events, want %d: %#v", len(got), len(want), got)
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			g.Time = time.Time{}
+			if g == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("DiffNetworks() missing %#v in %#v", w, got)
+		}
+	}
+}
+
+func TestDiffNetworksUnchanged(t *testing.T) {
+	networks := []Network{
+		{SSID: "Home", IsVisible: true, IsActive: true, AccessPoints: []AccessPoint{{BSSID: "aa", Strength: 50}}},
+		{SSID: "Home", IsVisible: true, Security: SecurityOpen, AccessPoints: []AccessPoint{{BSSID: "bb", Strength: 10}}},
+	}
+	if got := DiffNetworks(networks, networks); len(got) != 0 {
+		t.Fatalf("DiffNetworks() of identical lists = %#v, want none", got)
+	}
+}
+
+type pollBackend struct {
+	Backend
+	networks chan []Network
+	scans    []ScanMode
+}
+
+func (b *pollBackend) IsWirelessEnabled() (bool, error) { return true, nil }
+
+func (b *pollBackend) ListNetworks(scan ScanMode) (NetworksResult, error) {
+	b.scans = append(b.scans, scan)
+	return NetworksResult{Networks: <-b.networks}, nil
+}
+
+func TestPollEvents(t *testing.T) {
+	b := &pollBackend{networks: make(chan []Network, 2)}
+	b.networks <- nil
+	b.networks <- []Network{{SSID: "Home", IsVisible: true}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := PollEvents(ctx, b, time.Millisecond)
+	if err != nil {
+		t.Fatalf("PollEvents() failed: %v", err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != EventNetworkAppeared || e.SSID != "Home" {
+			t.Fatalf("PollEvents() sent %#v, want Home to appear", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PollEvents() did not report the new network")
+	}
+
+	cancel()
+	close(b.networks)
+	for range events {
+	}
+	for _, scan := range b.scans {
+		if scan != ScanNever {
+			t.Fatalf("PollEvents() listed networks with %v, want ScanNever", scan)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package wifi
+
+import (
+	"fmt"
+	"slices"
+)
+
+// HotspotConfig describes an access point to share this machine's connection.
+type HotspotConfig struct {
+	SSID string
+	// Password is the WPA2 passphrase. An empty password starts an open
+	// hotspot.
+	Password string
+	// Band picks the band to broadcast on, BandUnknown lets the backend
+	// choose.
+	Band Band
+}
+
+// Validate returns ErrInvalidCredentials if the SSID or passphrase do not fit
+// WPA2, or ErrInvalidBand if the band is unknown.
+func (c HotspotConfig) Validate() error {
+	if len(c.SSID) == 0 || len(c.SSID) > 32 {
+		return fmt.Errorf("hotspot SSID must be 1 to 32 bytes: %w", ErrInvalidCredentials)
+	}
+	if c.Password != "" && (len(c.Password) < 8 || len(c.Password) > 63) {
+		return fmt.Errorf("hotspot passphrase must be 8 to 63 characters: %w", ErrInvalidCredentials)
+	}
+	if c.Band != BandUnknown && !slices.Contains(bands, c.Band) {
+		return fmt.Errorf("unknown band %q: %w", c.Band, ErrInvalidBand)
+	}
+	return nil
+}
+
+// HotspotStatus reports the state of the hotspot.
+type HotspotStatus struct {
+	Active bool
+	HotspotConfig
+	// Security is how clients join, which is reported separately since the
+	// passphrase may not be readable.
+	Security SecurityType
+	// Interface is the device broadcasting the hotspot, if known.
+	Interface string
+}
@@ -0,0 +1,32 @@
+package wifi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHotspotConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config HotspotConfig
+		want   error
+	}{
+		{"wpa2", HotspotConfig{SSID: "Event", Password: "12345678"}, nil},
+		{"open", HotspotConfig{SSID: "Event"}, nil},
+		{"band", HotspotConfig{SSID: "Event", Band: Band5GHz}, nil},
+		{"no ssid", HotspotConfig{Password: "12345678"}, ErrInvalidCredentials},
+		{"long ssid", HotspotConfig{SSID: strings.Repeat("x", 33)}, ErrInvalidCredentials},
+		{"short passphrase", HotspotConfig{SSID: "Event", Password: "1234567"}, ErrInvalidCredentials},
+		{"long passphrase", HotspotConfig{SSID: "Event", Password: strings.Repeat("x", 64)}, ErrInvalidCredentials},
+		{"unknown band", HotspotConfig{SSID: "Event", Band: "60GHz"}, ErrInvalidBand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
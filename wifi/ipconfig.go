@@ -0,0 +1,80 @@
+package wifi
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// IPMethod is how a connection obtains addresses for one IP family.
+type IPMethod string
+
+const (
+	// IPMethodAuto uses DHCP, or SLAAC and DHCPv6 for IPv6.
+	IPMethodAuto IPMethod = "auto"
+	// IPMethodManual uses the static addresses in IPConfig.
+	IPMethodManual IPMethod = "manual"
+	// IPMethodDisabled turns the IP family off for the connection.
+	IPMethodDisabled IPMethod = "disabled"
+)
+
+// IPConfig configures addressing for one IP family of a known network.
+type IPConfig struct {
+	Method IPMethod
+	// Addresses are the static addresses with their prefix length, such as
+	// 192.168.1.10/24. They are required when Method is IPMethodManual.
+	Addresses []netip.Prefix
+	// Gateway is the default gateway, if any. Only used with IPMethodManual.
+	Gateway netip.Addr
+	// DNS servers replace the ones learned from DHCP when set.
+	DNS       []netip.Addr
+	DNSSearch []string
+}
+
+// Validate returns ErrInvalidIPConfig if the configuration is incomplete or
+// has addresses from the wrong family. ipv6 selects the family c is for.
+func (c *IPConfig) Validate(ipv6 bool) error {
+	if c == nil {
+		return nil
+	}
+	family := "IPv4"
+	if ipv6 {
+		family = "IPv6"
+	}
+	inFamily := func(addr netip.Addr) bool {
+		if ipv6 {
+			return addr.Is6() && !addr.Is4In6()
+		}
+		return addr.Is4()
+	}
+
+	switch c.Method {
+	case IPMethodAuto, IPMethodDisabled:
+		if len(c.Addresses) > 0 || c.Gateway.IsValid() {
+			return fmt.Errorf("%s addresses and gateway require the manual method: %w", family, ErrInvalidIPConfig)
+		}
+	case IPMethodManual:
+		if len(c.Addresses) == 0 {
+			return fmt.Errorf("manual %s configuration requires an address: %w", family, ErrInvalidIPConfig)
+		}
+	default:
+		return fmt.Errorf("unsupported %s method %q: %w", family, c.Method, ErrInvalidIPConfig)
+	}
+	if c.Method == IPMethodDisabled && (len(c.DNS) > 0 || len(c.DNSSearch) > 0) {
+		return fmt.Errorf("%s is disabled but has DNS settings: %w", family, ErrInvalidIPConfig)
+	}
+
+	for _, prefix := range c.Addresses {
+		if !prefix.IsValid() || !inFamily(prefix.Addr()) {
+			return fmt.Errorf("%s is not an %s address: %w", prefix, family, ErrInvalidIPConfig)
+		}
+	}
+	if c.Gateway.IsValid() && !inFamily(c.Gateway) {
+		return fmt.Errorf("gateway %s is not an %s address: %w", c.Gateway, family, ErrInvalidIPConfig)
+	}
+	for _, addr := range c.DNS {
+		if !inFamily(addr) {
+			return fmt.Errorf("DNS server %s is not an %s address: %w", addr, family, ErrInvalidIPConfig)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package wifi
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIPConfigValidate(t *testing.T) {
+	v4 := netip.MustParsePrefix("192.168.1.10/24")
+	v6 := netip.MustParsePrefix("fd00::10/64")
+	tests := []struct {
+		name    string
+		config  *IPConfig
+		ipv6    bool
+		wantErr bool
+	}{
+		{"nil", nil, false, false},
+		{"auto", &IPConfig{Method: IPMethodAuto}, false, false},
+		{"auto with DNS", &IPConfig{Method: IPMethodAuto, DNS: []netip.Addr{netip.MustParseAddr("1.1.1.1")}}, false, false},
+		{"manual IPv4", &IPConfig{Method: IPMethodManual, Addresses: []netip.Prefix{v4}, Gateway: netip.MustParseAddr("192.168.1.1")}, false, false},
+		{"manual IPv6", &IPConfig{Method: IPMethodManual, Addresses: []netip.Prefix{v6}}, true, false},
+		{"manual without address", &IPConfig{Method: IPMethodManual}, false, true},
+		{"auto with address", &IPConfig{Method: IPMethodAuto, Addresses: []netip.Prefix{v4}}, false, true},
+		{"disabled with DNS", &IPConfig{Method: IPMethodDisabled, DNSSearch: []string{"example.com"}}, true, true},
+		{"IPv6 address for IPv4", &IPConfig{Method: IPMethodManual, Addresses: []netip.Prefix{v6}}, false, true},
+		{"IPv4 gateway for IPv6", &IPConfig{Method: IPMethodManual, Addresses: []netip.Prefix{v6}, Gateway: netip.MustParseAddr("192.168.1.1")}, true, true},
+		{"IPv6 DNS for IPv4", &IPConfig{Method: IPMethodAuto, DNS: []netip.Addr{netip.MustParseAddr("fd00::1")}}, false, true},
+		{"unknown method", &IPConfig{Method: "shared"}, false, true},
+	}
+	for _, tt := range tests {
+		err := tt.config.Validate(tt.ipv6)
+		if tt.wantErr && !errors.Is(err, ErrInvalidIPConfig) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidIPConfig", tt.name, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: Validate() = %v, want nil", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+//go:build linux
+
+package iwd
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shazow/wifitui/wifi"
+)
+
+const iwdStationDiagnosticIface = "net.connman.iwd.StationDiagnostic"
+
+// ActiveConnection returns live details of the station's connection. iwd
+// leaves IP configuration to another daemon, so addresses are read from the
+// interface and the gateway and DNS servers are not reported.
+func (b *Backend) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	conn, err := b.bus()
+	if err != nil {
+		return nil, err
+	}
+	station, err := getStationDevice(conn, b.iface)
+	if err != nil {
+		return nil, err
+	}
+	stationObj := conn.Object(iwdDest, station)
+
+	stateVar, err := stationObj.GetProperty(iwdStationIface + ".State")
+	if err != nil {
+		return nil, err
+	}
+	if state, _ := stateVar.Value().(string); state != "connected" && state != "roaming" {
+		return nil, nil
+	}
+	networkVar, err := stationObj.GetProperty(iwdStationIface + ".ConnectedNetwork")
+	if err != nil {
+		return nil, err
+	}
+	networkPath, ok := networkVar.Value().(dbus.ObjectPath)
+	if !ok {
+		return nil, nil
+	}
+
+	details := &wifi.ConnectionDetails{}
+	if nameVar, err := conn.Object(iwdDest, networkPath).GetProperty(iwdNetworkIface + ".Name"); err == nil {
+		details.SSID, _ = nameVar.Value().(string)
+	}
+	if nameVar, err := stationObj.GetProperty(iwdDeviceIface + ".Name"); err == nil {
+		details.Interface, _ = nameVar.Value().(string)
+	}
+
+	// StationDiagnostic is optional in iwd, so its absence is not an error.
+	var diagnostics map[string]dbus.Variant
+	if err := stationObj.Call(iwdStationDiagnosticIface+".GetDiagnostics", 0).Store(&diagnostics); err == nil {
+		applyDiagnostics(details, diagnostics)
+	}
+
+	if details.Interface != "" {
+		if iface, err := net.InterfaceByName(details.Interface); err == nil {
+			if addrs, err := iface.Addrs(); err == nil {
+				applyInterfaceAddrs(details, addrs)
+			}
+		}
+	}
+	return details, nil
+}
+
+// applyDiagnostics copies the fields of a StationDiagnostic.GetDiagnostics
+// result into details.
+func applyDiagnostics(details *wifi.ConnectionDetails, diagnostics map[string]dbus.Variant) {
+	if bssid, ok := diagnostics["ConnectedBss"].Value().(string); ok {
+		details.BSSID = bssid
+	}
+	if frequency, ok := diagnostics["Frequency"].Value().(uint32); ok {
+		details.Frequency = uint(frequency)
+	}
+	// TxBitrate is reported in units of 100 kbit/s.
+	if bitrate, ok := diagnostics["TxBitrate"].Value().(uint32); ok {
+		details.Bitrate = bitrate * 100
+	}
+}
+
+// applyInterfaceAddrs adds the global addresses of an interface to details.
+func applyInterfaceAddrs(details *wifi.ConnectionDetails, addrs []net.Addr) {
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		if addr.IsLinkLocalUnicast() || addr.IsLoopback() {
+			continue
+		}
+		bits, _ := ipNet.Mask.Size()
+		prefix := netip.PrefixFrom(addr, bits)
+		if addr.Is4() {
+			details.IPv4Addresses = append(details.IPv4Addresses, prefix)
+		} else {
+			details.IPv6Addresses = append(details.IPv6Addresses, prefix)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+//go:build linux
+
+package iwd
+
+import (
+	"context"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shazow/wifitui/wifi"
+)
+
+const dbusObjectManagerIface = "org.freedesktop.DBus.ObjectManager"
+
+// WatchEvents streams changes reported by iwd. iwd does not publish per-network
+// signal strength as a property, so EventSignalChanged is never sent.
+func (b *Backend) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
+	conn, err := b.bus()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := getManagedObjects(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	matchRules := [][]dbus.MatchOption{
+		{
+			dbus.WithMatchSender(iwdDest),
+			dbus.WithMatchInterface(dbusPropertiesIface),
+			dbus.WithMatchMember("PropertiesChanged"),
+		},
+		{
+			dbus.WithMatchSender(iwdDest),
+			dbus.WithMatchInterface(dbusObjectManagerIface),
+		},
+	}
+	for i, rule := range matchRules {
+		if err := conn.AddMatchSignal(rule...); err != nil {
+			for _, added := range matchRules[:i] {
+				_ = conn.RemoveMatchSignal(added...)
+			}
+			return nil, err
+		}
+	}
+
+	signals := make(chan *dbus.Signal, 32)
+	conn.Signal(signals)
+
+	tracker := newEventTracker(objects)
+	events := make(chan wifi.Event)
+	go func() {
+		defer close(events)
+		defer func() {
+			conn.RemoveSignal(signals)
+			for _, rule := range matchRules {
+				_ = conn.RemoveMatchSignal(rule...)
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				for _, e := range tracker.handle(sig) {
+					select {
+					case events <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// eventTracker turns iwd signals into wifi events. It remembers network
+// names by object path, since removals and station changes only carry paths.
+type eventTracker struct {
+	networks         map[dbus.ObjectPath]string
+	connectedNetwork dbus.ObjectPath
+}
+
+func newEventTracker(objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant) *eventTracker {
+	t := &eventTracker{networks: make(map[dbus.ObjectPath]string)}
+	for path, ifaces := range objects {
+		if props, ok := ifaces[iwdNetworkIface]; ok {
+			t.addNetwork(path, props)
+		}
+		if props, ok := ifaces[iwdStationIface]; ok {
+			if connected, ok := props["ConnectedNetwork"].Value().(dbus.ObjectPath); ok {
+				t.connectedNetwork = connected
+			}
+		}
+	}
+	return t
+}
+
+func (t *eventTracker) addNetwork(path dbus.ObjectPath, props map[string]dbus.Variant) (string, bool) {
+	name, ok := props["Name"].Value().(string)
+	if !ok || name == "" {
+		return "", false
+	}
+	t.networks[path] = name
+	return name, true
+}
+
+// handle returns the events described by sig. Unrelated signals yield none.
+func (t *eventTracker) handle(sig *dbus.Signal) []wifi.Event {
+	now := time.Now()
+	switch sig.Name {
+	case dbusObjectManagerIface + ".InterfacesAdded":
+		if len(sig.Body) < 2 {
+			return nil
+		}
+		path, _ := sig.Body[0].(dbus.ObjectPath)
+		ifaces, ok := sig.Body[1].(map[string]map[string]dbus.Variant)
+		if !ok {
+			return nil
+		}
+		props, ok := ifaces[iwdNetworkIface]
+		if !ok {
+			return nil
+		}
+		if name, ok := t.addNetwork(path, props); ok {
+			return []wifi.Event{{Type: wifi.EventNetworkAppeared, Time: now, SSID: name}}
+		}
+	case dbusObjectManagerIface + ".InterfacesRemoved":
+		if len(sig.Body) < 2 {
+			return nil
+		}
+		path, _ := sig.Body[0].(dbus.ObjectPath)
+		ifaces, _ := sig.Body[1].([]string)
+		for _, iface := range ifaces {
+			if iface != iwdNetworkIface {
+				continue
+			}
+			name, ok := t.networks[path]
+			if !ok {
+				return nil
+			}
+			delete(t.networks, path)
+			return []wifi.Event{{Type: wifi.EventNetworkDisappeared, Time: now, SSID: name}}
+		}
+	case dbusPropertiesIface + ".PropertiesChanged":
+		if len(sig.Body) < 2 {
+			return nil
+		}
+		iface, _ := sig.Body[0].(string)
+		changed, ok := sig.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return nil
+		}
+		switch iface {
+		case iwdDeviceIface:
+			if powered, ok := changed["Powered"].Value().(bool); ok {
+				return []wifi.Event{{Type: wifi.EventRadioToggled, Time: now, RadioEnabled: powered}}
+			}
+		case iwdStationIface:
+			return t.stationChanged(changed, now)
+		}
+	}
+	return nil
+}
+
+func (t *eventTracker) stationChanged(changed map[string]dbus.Variant, now time.Time) []wifi.Event {
+	if connected, ok := changed["ConnectedNetwork"].Value().(dbus.ObjectPath); ok {
+		t.connectedNetwork = connected
+	}
+
+	var events []wifi.Event
+	if state, ok := changed["State"].Value().(string); ok {
+		if connState, ok := connectionStateFromStation(state); ok {
+			events = append(events, wifi.Event{
+				Type:  wifi.EventConnectionStateChanged,
+				Time:  now,
+				SSID:  t.networks[t.connectedNetwork],
+				State: connState,
+			})
+		}
+	}
+	if scanning, ok := changed["Scanning"].Value().(bool); ok && !scanning {
+		events = append(events, wifi.Event{Type: wifi.EventScanCompleted, Time: now})
+	}
+	return events
+}
+
+// connectionStateFromStation maps iwd station states to connection states.
+// Roaming keeps the connection active, so it is not reported.
+func connectionStateFromStation(state string) (wifi.ConnectionState, bool) {
+	switch state {
+	case "connecting", "connecting (auto)":
+		return wifi.ConnectionActivating, true
+	case "connected":
+		return wifi.ConnectionActivated, true
+	case "disconnecting":
+		return wifi.ConnectionDeactivating, true
+	case "disconnected":
+		return wifi.ConnectionDeactivated, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,126 @@
+//go:build linux
+
+package iwd
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shazow/wifitui/wifi"
+)
+
+const iwdAccessPointIface = "net.connman.iwd.AccessPoint"
+
+// getWirelessDevice returns the selected device, or the first one, whichever
+// mode it is in. The Station interface disappears while a device is an access
+// point, so getStationDevice can't be used to switch it back.
+func getWirelessDevice(conn *dbus.Conn, iface string) (dbus.ObjectPath, error) {
+	d, err := getDevice(conn, iface, func(iwdDevice) bool { return true })
+	return d.path, err
+}
+
+// setDeviceMode switches a device between "station" and "ap". iwd replies
+// once the interfaces for the new mode have been added.
+func setDeviceMode(conn *dbus.Conn, device dbus.ObjectPath, mode string) error {
+	return conn.Object(iwdDest, device).Call(dbusPropertiesIface+".Set", 0, iwdDeviceIface, "Mode", dbus.MakeVariant(mode)).Err
+}
+
+// StartHotspot switches the device to access point mode and starts a WPA2
+// network. iwd hands out addresses itself when its network configuration is
+// enabled, and choosing a band requires an AP profile, so neither an open
+// hotspot nor a band is supported here.
+func (b *Backend) StartHotspot(config wifi.HotspotConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.Password == "" {
+		return fmt.Errorf("open hotspots are not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+	if config.Band != wifi.BandUnknown {
+		return fmt.Errorf("choosing a hotspot band is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+	conn, err := b.bus()
+	if err != nil {
+		return err
+	}
+	device, err := getWirelessDevice(conn, b.iface)
+	if err != nil {
+		return err
+	}
+	if err := setDeviceMode(conn, device, "ap"); err != nil {
+		return fmt.Errorf("failed to switch to access point mode: %w", err)
+	}
+	if err := conn.Object(iwdDest, device).Call(iwdAccessPointIface+".Start", 0, config.SSID, config.Password).Err; err != nil {
+		// Leave the device usable as a station again.
+		_ = setDeviceMode(conn, device, "station")
+		return fmt.Errorf("failed to start hotspot: %w", err)
+	}
+	return nil
+}
+
+// StopHotspot stops the access point and switches the device back to
+// station mode.
+func (b *Backend) StopHotspot() error {
+	conn, err := b.bus()
+	if err != nil {
+		return err
+	}
+	device, err := getWirelessDevice(conn, b.iface)
+	if err != nil {
+		return err
+	}
+	objects, err := getManagedObjects(conn)
+	if err != nil {
+		return err
+	}
+	if _, ok := objects[device][iwdAccessPointIface]; !ok {
+		return fmt.Errorf("no hotspot is running: %w", wifi.ErrNotFound)
+	}
+	if err := conn.Object(iwdDest, device).Call(iwdAccessPointIface+".Stop", 0).Err; err != nil {
+		return fmt.Errorf("failed to stop hotspot: %w", err)
+	}
+	return setDeviceMode(conn, device, "station")
+}
+
+// HotspotStatus reads the AccessPoint properties of the device. iwd does
+// not expose the passphrase of a running access point.
+func (b *Backend) HotspotStatus() (wifi.HotspotStatus, error) {
+	conn, err := b.bus()
+	if err != nil {
+		return wifi.HotspotStatus{}, err
+	}
+	objects, err := getManagedObjects(conn)
+	if err != nil {
+		return wifi.HotspotStatus{}, err
+	}
+	for _, ifaces := range objects {
+		props, ok := ifaces[iwdAccessPointIface]
+		if !ok {
+			continue
+		}
+		status := hotspotStatus(props)
+		if device, ok := ifaces[iwdDeviceIface]; ok {
+			status.Interface, _ = device["Name"].Value().(string)
+		}
+		if b.iface != "" && status.Interface != b.iface {
+			continue
+		}
+		return status, nil
+	}
+	return wifi.HotspotStatus{}, nil
+}
+
+// hotspotStatus converts AccessPoint properties into a HotspotStatus.
+func hotspotStatus(props map[string]dbus.Variant) wifi.HotspotStatus {
+	var status wifi.HotspotStatus
+	status.Active, _ = props["Started"].Value().(bool)
+	if !status.Active {
+		return status
+	}
+	status.SSID, _ = props["Name"].Value().(string)
+	status.Security = wifi.SecurityWPA
+	if frequency, ok := props["Frequency"].Value().(uint32); ok {
+		status.Band = wifi.FrequencyBand(uint(frequency))
+	}
+	return status
+}
@@ -0,0 +1,331 @@
+//go:build linux
+
+package iwd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/iwd/iwdtest"
+)
+
+// newTestBackend starts a fake iwd with one device and returns a backend
+// connected to it.
+func newTestBackend(t *testing.T) (*iwdtest.Server, dbus.ObjectPath, wifi.Backend) {
+	t.Helper()
+	server := iwdtest.New(t)
+	device := server.AddDevice("wlan0", "02:00:00:00:00:01")
+	backend, err := NewWithConn(server.Connect(t))
+	if err != nil {
+		t.Fatalf("NewWithConn() failed: %v", err)
+	}
+	return server, device, backend
+}
+
+func listNetwork(t *testing.T, backend wifi.Backend, ssid string) (wifi.Network, bool) {
+	t.Helper()
+	result, err := backend.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	for _, n := range result.Networks {
+		if n.SSID == ssid {
+			return n, true
+		}
+	}
+	return wifi.Network{}, false
+}
+
+func TestIntegrationListNetworks(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	server.AddNetwork(device, iwdtest.Network{
+		SSID:       "Home",
+		Strength:   -5000,
+		BSSIDs:     []string{"02:00:00:00:01:01", "02:00:00:00:01:02"},
+		Passphrase: "correct horse",
+	})
+	server.AddNetwork(device, iwdtest.Network{SSID: "Cafe", Strength: -7000})
+	server.AddKnownNetwork(iwdtest.KnownNetwork{SSID: "Cafe", AutoConnect: true})
+	server.AddKnownNetwork(iwdtest.KnownNetwork{SSID: "Away", Passphrase: "far far away"})
+
+	home, ok := listNetwork(t, backend, "Home")
+	if !ok || !home.IsVisible || home.IsKnown || home.Security != wifi.SecurityWPA || len(home.AccessPoints) != 2 {
+		t.Errorf("unexpected Home network: %+v", home)
+	}
+	if home.AccessPoints[0].BSSID != "02:00:00:00:01:01" || home.AccessPoints[0].Strength != 66 {
+		t.Errorf("unexpected Home access point: %+v", home.AccessPoints[0])
+	}
+	cafe, ok := listNetwork(t, backend, "Cafe")
+	if !ok || !cafe.IsVisible || !cafe.IsKnown || !cafe.AutoConnect || cafe.Security != wifi.SecurityOpen {
+		t.Errorf("unexpected Cafe network: %+v", cafe)
+	}
+	away, ok := listNetwork(t, backend, "Away")
+	if !ok || away.IsVisible || !away.IsKnown {
+		t.Errorf("unexpected Away network: %+v", away)
+	}
+}
+
+func TestIntegrationJoinNetwork(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	server.AddNetwork(device, iwdtest.Network{
+		SSID:       "Home",
+		Strength:   -5000,
+		BSSIDs:     []string{"02:00:00:00:01:01"},
+		Frequency:  5180,
+		Passphrase: "correct horse",
+	})
+
+	if err := backend.JoinNetwork("Home", wifi.JoinOptions{Password: "wrong passphrase", Security: wifi.SecurityWPA}); err == nil {
+		t.Fatal("JoinNetwork() with a wrong passphrase succeeded")
+	}
+	if server.AgentRegistered() {
+		t.Error("agent is still registered after a failed join")
+	}
+	if err := backend.JoinNetwork("Home", wifi.JoinOptions{Password: "correct horse", Security: wifi.SecurityWPA}); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	if got, want := server.AgentCalls(), []string{"RequestPassphrase", "RequestPassphrase"}; !slices.Equal(got, want) {
+		t.Errorf("agent calls = %q, want %q", got, want)
+	}
+	if server.AgentRegistered() {
+		t.Error("agent is still registered after joining")
+	}
+
+	if home, _ := listNetwork(t, backend, "Home"); !home.IsActive || !home.IsKnown {
+		t.Errorf("joined network is not active and known: %+v", home)
+	}
+	details, err := backend.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() failed: %v", err)
+	}
+	if details == nil || details.SSID != "Home" || details.BSSID != "02:00:00:00:01:01" || details.Frequency != 5180 || details.Interface != "wlan0" {
+		t.Errorf("unexpected connection details: %+v", details)
+	}
+	known := server.KnownNetworks()
+	if len(known) != 1 || known[0].SSID != "Home" || known[0].Passphrase != "correct horse" {
+		t.Errorf("unexpected known networks: %+v", known)
+	}
+
+	autoConnect := false
+	if err := backend.UpdateNetwork("Home", wifi.UpdateOptions{AutoConnect: &autoConnect}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if known := server.KnownNetworks(); known[0].AutoConnect {
+		t.Error("UpdateNetwork() did not turn off autoconnect")
+	}
+
+	if err := backend.ForgetNetwork("Home"); err != nil {
+		t.Fatalf("ForgetNetwork() failed: %v", err)
+	}
+	if known := server.KnownNetworks(); len(known) != 0 {
+		t.Errorf("ForgetNetwork() left %+v", known)
+	}
+	if details, err := backend.ActiveConnection(); err != nil || details != nil {
+		t.Errorf("ActiveConnection() = %+v, %v after forgetting, want none", details, err)
+	}
+	if err := backend.ForgetNetwork("Home"); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("ForgetNetwork() of an unknown network = %v, want ErrNotFound", err)
+	}
+}
+
+func TestIntegrationActivateKnownNetwork(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	server.AddNetwork(device, iwdtest.Network{SSID: "Home", Strength: -5000, Passphrase: "correct horse"})
+	server.AddKnownNetwork(iwdtest.KnownNetwork{SSID: "Home", Passphrase: "correct horse"})
+
+	if err := backend.ActivateNetwork("Home"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	if calls := server.AgentCalls(); len(calls) != 0 {
+		t.Errorf("activating a known network called the agent: %q", calls)
+	}
+	if home, _ := listNetwork(t, backend, "Home"); !home.IsActive {
+		t.Errorf("activated network is not active: %+v", home)
+	}
+	if err := backend.ActivateNetwork("Elsewhere"); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("ActivateNetwork() of a network out of range = %v, want ErrNotFound", err)
+	}
+}
+
+func TestIntegrationJoinHiddenNetwork(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	server.AddNetwork(device, iwdtest.Network{SSID: "Secret", Strength: -6000, Passphrase: "hunter2hunter2", Hidden: true})
+
+	if _, ok := listNetwork(t, backend, "Secret"); ok {
+		t.Fatal("hidden network is listed before joining")
+	}
+	if err := backend.JoinNetwork("Nowhere", wifi.JoinOptions{Password: "hunter2hunter2", Security: wifi.SecurityWPA, IsHidden: true}); err == nil {
+		t.Error("JoinNetwork() of a hidden network out of range succeeded")
+	}
+	if err := backend.JoinNetwork("Secret", wifi.JoinOptions{Password: "hunter2hunter2", Security: wifi.SecurityWPA, IsHidden: true}); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	secret, ok := listNetwork(t, backend, "Secret")
+	if !ok || !secret.IsActive || !secret.IsHidden {
+		t.Errorf("hidden network is not active and hidden: %+v", secret)
+	}
+}
+
+func TestIntegrationJoinEnterpriseNetwork(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	server.AddNetwork(device, iwdtest.Network{SSID: "Office", Type: iwdtest.Type8021X, Strength: -6000, Passphrase: "s3cret"})
+	opts := wifi.JoinOptions{
+		Password: "s3cret",
+		Security: wifi.SecurityEnterprise,
+		Enterprise: &wifi.EnterpriseCredentials{
+			Identity:   "alice",
+			EAPMethod:  wifi.EAPMethodPEAP,
+			Phase2Auth: "mschapv2",
+		},
+	}
+
+	if err := backend.JoinNetwork("Office", opts); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("JoinNetwork() without a provisioning file = %v, want ErrNotSupported", err)
+	}
+	server.AddKnownNetwork(iwdtest.KnownNetwork{SSID: "Office", Type: iwdtest.Type8021X})
+	if err := backend.JoinNetwork("Office", opts); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	if got, want := server.AgentCalls(), []string{"RequestUserNameAndPassword"}; !slices.Equal(got, want) {
+		t.Errorf("agent calls = %q, want %q", got, want)
+	}
+}
+
+func TestIntegrationScan(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	server.AddNetwork(device, iwdtest.Network{SSID: "Home", Strength: -5000})
+
+	result, err := backend.ListNetworks(wifi.ScanForce)
+	if err != nil || result.ScanError != nil {
+		t.Fatalf("ListNetworks() = %v, %v", result.ScanError, err)
+	}
+	if server.Scans() != 1 {
+		t.Errorf("ListNetworks() requested %d scans, want 1", server.Scans())
+	}
+
+	timeout := scanCompletionTimeout
+	scanCompletionTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { scanCompletionTimeout = timeout })
+	server.StallScans(true)
+	result, err = backend.ListNetworks(wifi.ScanForce)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if !errors.Is(result.ScanError, wifi.ErrScanTimeout) {
+		t.Errorf("ScanError = %v, want ErrScanTimeout", result.ScanError)
+	}
+	if len(result.Networks) != 1 {
+		t.Errorf("ListNetworks() returned %d networks after a failed scan, want the cached one", len(result.Networks))
+	}
+
+	// The stalled scan is still running, so iwd refuses another.
+	result, err = backend.ListNetworks(wifi.ScanForce)
+	var failure *wifi.ScanFailure
+	if err != nil || !errors.As(result.ScanError, &failure) || failure.Stage != wifi.ScanStageRequest || failure.Code != "net.connman.iwd.Busy" {
+		t.Errorf("ListNetworks() during a scan = %#v, %v, want a busy request failure", result.ScanError, err)
+	}
+}
+
+func TestIntegrationSetWireless(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	server.AddNetwork(device, iwdtest.Network{SSID: "Home", Strength: -5000})
+
+	if err := backend.SetWireless(false); err != nil {
+		t.Fatalf("SetWireless(false) failed: %v", err)
+	}
+	if enabled, err := backend.IsWirelessEnabled(); err != nil || enabled {
+		t.Errorf("IsWirelessEnabled() = %v, %v after disabling", enabled, err)
+	}
+	if _, err := backend.ListNetworks(wifi.ScanNever); !errors.Is(err, wifi.ErrWirelessDisabled) {
+		t.Errorf("ListNetworks() = %v while disabled, want ErrWirelessDisabled", err)
+	}
+	if err := backend.SetWireless(true); err != nil {
+		t.Fatalf("SetWireless(true) failed: %v", err)
+	}
+	if enabled, err := backend.IsWirelessEnabled(); err != nil || !enabled {
+		t.Errorf("IsWirelessEnabled() = %v, %v after enabling", enabled, err)
+	}
+	if _, ok := listNetwork(t, backend, "Home"); !ok {
+		t.Error("networks are not listed again after enabling")
+	}
+	if err := backend.SetWireless(true); err != nil {
+		t.Errorf("SetWireless(true) when already enabled failed: %v", err)
+	}
+}
+
+func TestIntegrationHotspot(t *testing.T) {
+	_, _, backend := newTestBackend(t)
+	if err := backend.StartHotspot(wifi.HotspotConfig{SSID: "Share", Password: "sharing is caring"}); err != nil {
+		t.Fatalf("StartHotspot() failed: %v", err)
+	}
+	status, err := backend.HotspotStatus()
+	if err != nil {
+		t.Fatalf("HotspotStatus() failed: %v", err)
+	}
+	if !status.Active || status.SSID != "Share" || status.Interface != "wlan0" || status.Band != wifi.Band2GHz {
+		t.Errorf("unexpected hotspot status: %+v", status)
+	}
+	if _, err := backend.ListNetworks(wifi.ScanNever); err == nil {
+		t.Error("ListNetworks() succeeded without a station")
+	}
+	if err := backend.StopHotspot(); err != nil {
+		t.Fatalf("StopHotspot() failed: %v", err)
+	}
+	if status, err := backend.HotspotStatus(); err != nil || status.Active {
+		t.Errorf("HotspotStatus() = %+v, %v after stopping", status, err)
+	}
+	if err := backend.StopHotspot(); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("StopHotspot() without a hotspot = %v, want ErrNotFound", err)
+	}
+}
+
+func TestIntegrationWatchEvents(t *testing.T) {
+	server, device, backend := newTestBackend(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := backend.WatchEvents(ctx)
+	if err != nil {
+		t.Fatalf("WatchEvents() failed: %v", err)
+	}
+
+	network := server.AddNetwork(device, iwdtest.Network{SSID: "Library", Strength: -6000})
+	waitForEvent(t, events, wifi.Event{Type: wifi.EventNetworkAppeared, SSID: "Library"})
+	if err := backend.JoinNetwork("Library", wifi.JoinOptions{Security: wifi.SecurityOpen}); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	waitForEvent(t, events, wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: "Library", State: wifi.ConnectionActivating})
+	waitForEvent(t, events, wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: "Library", State: wifi.ConnectionActivated})
+	if err := backend.SetWireless(false); err != nil {
+		t.Fatalf("SetWireless(false) failed: %v", err)
+	}
+	waitForEvent(t, events, wifi.Event{Type: wifi.EventRadioToggled, RadioEnabled: false})
+	if err := backend.SetWireless(true); err != nil {
+		t.Fatalf("SetWireless(true) failed: %v", err)
+	}
+	server.RemoveNetwork(network)
+	waitForEvent(t, events, wifi.Event{Type: wifi.EventNetworkDisappeared, SSID: "Library"})
+}
+
+func waitForEvent(t *testing.T, events <-chan wifi.Event, want wifi.Event) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("event channel closed")
+			}
+			e.Time = time.Time{}
+			if e == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %+v", want)
+		}
+	}
+}
@@ -5,13 +5,17 @@ package iwd
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/shazow/wifitui/wifi"
 )
 
-const scanCompletionTimeout = 30 * time.Second
+// scanCompletionTimeout is a variable so tests can shorten it.
+var scanCompletionTimeout = 30 * time.Second
+
 const propertyChangeTimeout = 5 * time.Second
 
 const dbusPropertiesIface = "org.freedesktop.DBus.Properties"
@@ -33,6 +37,18 @@ const (
 // iwd calls RequestPassphrase on this object when it needs credentials.
 type agent struct {
 	passphrase string
+	// username and privateKeyPassphrase are only used by 802.1X networks.
+	username             string
+	privateKeyPassphrase string
+}
+
+func newAgent(opts wifi.JoinOptions) *agent {
+	a := &agent{passphrase: opts.Password}
+	if opts.Enterprise != nil {
+		a.username = opts.Enterprise.Identity
+		a.privateKeyPassphrase = opts.Enterprise.PrivateKeyPassword
+	}
+	return a
 }
 
 func (a *agent) Release() *dbus.Error {
@@ -43,12 +59,19 @@ func (a *agent) RequestPassphrase(_ dbus.ObjectPath) (string, *dbus.Error) {
 	return a.passphrase, nil
 }
 
+// RequestPrivateKeyPassphrase is called for EAP-TLS networks whose provisioned
+// private key is encrypted.
 func (a *agent) RequestPrivateKeyPassphrase(_ dbus.ObjectPath) (string, *dbus.Error) {
+	if a.privateKeyPassphrase != "" {
+		return a.privateKeyPassphrase, nil
+	}
 	return a.passphrase, nil
 }
 
+// RequestUserNameAndPassword is called for 802.1X networks whose provisioning
+// file leaves the inner identity and password to the agent.
 func (a *agent) RequestUserNameAndPassword(_ dbus.ObjectPath) (string, string, *dbus.Error) {
-	return "", a.passphrase, nil
+	return a.username, a.passphrase, nil
 }
 
 func (a *agent) Cancel(_ dbus.ObjectPath) *dbus.Error {
@@ -56,26 +79,56 @@ func (a *agent) Cancel(_ dbus.ObjectPath) *dbus.Error {
 }
 
 // Backend implements the backend.Backend interface using iwd.
-type Backend struct{}
+type Backend struct {
+	// conn is the bus iwd is reached on, or nil for the system bus.
+	conn *dbus.Conn
+	// iface is the name of the selected device, or empty for the first one.
+	iface string
+}
 
-// New creates a new iwd.Backend.
+// New creates a new iwd.Backend on the system bus.
 func New() (wifi.Backend, error) {
 	conn, err := dbus.SystemBus()
 	if err != nil {
 		return nil, err
 	}
-	// Check if iwd is available by calling GetManagedObjects on the root path.
+	if err := checkAvailable(conn); err != nil {
+		return nil, err
+	}
+	return &Backend{}, nil
+}
+
+// NewWithConn creates a new iwd.Backend that reaches iwd on conn, such as a
+// private bus in tests.
+func NewWithConn(conn *dbus.Conn) (wifi.Backend, error) {
+	if err := checkAvailable(conn); err != nil {
+		return nil, err
+	}
+	return &Backend{conn: conn}, nil
+}
+
+// checkAvailable checks if iwd is available by calling GetManagedObjects on
+// the root path.
+func checkAvailable(conn *dbus.Conn) error {
 	obj := conn.Object(iwdDest, iwdPath)
 	if obj == nil {
-		return nil, fmt.Errorf("failed to get dbus object for %s: %w", iwdDest, wifi.ErrNotAvailable)
+		return fmt.Errorf("failed to get dbus object for %s: %w", iwdDest, wifi.ErrNotAvailable)
 	}
 	var managedObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
-	err = obj.Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&managedObjects)
+	err := obj.Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&managedObjects)
 	if err != nil {
-		return nil, fmt.Errorf("iwd is not available: %w", wifi.ErrNotAvailable)
+		return fmt.Errorf("iwd is not available: %w", wifi.ErrNotAvailable)
 	}
+	return nil
+}
 
-	return &Backend{}, nil
+// bus returns the connection iwd is reached on. The system bus is looked up
+// on every call, since godbus reconnects it if it was closed.
+func (b *Backend) bus() (*dbus.Conn, error) {
+	if b.conn != nil {
+		return b.conn, nil
+	}
+	return dbus.SystemBus()
 }
 
 // getManagedObjects returns all iwd managed objects from D-Bus ObjectManager.
@@ -85,18 +138,108 @@ func getManagedObjects(conn *dbus.Conn) (map[dbus.ObjectPath]map[string]map[stri
 	return objects, err
 }
 
-// getStationDevice finds the first Device object path that has a Station interface.
-func getStationDevice(conn *dbus.Conn) (dbus.ObjectPath, error) {
+// iwdDevice is a wireless device from the managed objects.
+type iwdDevice struct {
+	path    dbus.ObjectPath
+	name    string
+	address string
+	// station is false while the device is in access point mode.
+	station bool
+}
+
+// listDevices returns the devices in objects, sorted by path so the first
+// device is stable.
+func listDevices(objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant) []iwdDevice {
+	var devices []iwdDevice
+	for path, ifaces := range objects {
+		props, ok := ifaces[iwdDeviceIface]
+		if !ok {
+			continue
+		}
+		d := iwdDevice{path: path}
+		d.name, _ = props["Name"].Value().(string)
+		d.address, _ = props["Address"].Value().(string)
+		_, d.station = ifaces[iwdStationIface]
+		devices = append(devices, d)
+	}
+	slices.SortFunc(devices, func(a, b iwdDevice) int {
+		return strings.Compare(string(a.path), string(b.path))
+	})
+	return devices
+}
+
+// getDevice returns the device named iface, or the first device matching
+// want if iface is empty.
+func getDevice(conn *dbus.Conn, iface string, want func(iwdDevice) bool) (iwdDevice, error) {
 	objects, err := getManagedObjects(conn)
+	if err != nil {
+		return iwdDevice{}, err
+	}
+	for _, d := range listDevices(objects) {
+		if iface != "" && d.name != iface {
+			continue
+		}
+		if iface != "" || want(d) {
+			return d, nil
+		}
+	}
+	if iface != "" {
+		return iwdDevice{}, fmt.Errorf("no wireless device %s found: %w", iface, wifi.ErrNotFound)
+	}
+	return iwdDevice{}, fmt.Errorf("no wireless device found: %w", wifi.ErrNotFound)
+}
+
+// getStationDevice finds the selected device, or the first one with a
+// Station interface.
+func getStationDevice(conn *dbus.Conn, iface string) (dbus.ObjectPath, error) {
+	d, err := getDevice(conn, iface, func(d iwdDevice) bool { return d.station })
 	if err != nil {
 		return "", err
 	}
-	for path, ifaces := range objects {
-		if _, hasStation := ifaces[iwdStationIface]; hasStation {
-			return path, nil
+	if !d.station {
+		return "", fmt.Errorf("%s is not in station mode: %w", d.name, wifi.ErrNotAvailable)
+	}
+	return d.path, nil
+}
+
+// Devices lists the wireless devices known to iwd.
+func (b *Backend) Devices() ([]wifi.Device, error) {
+	conn, err := b.bus()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := getManagedObjects(conn)
+	if err != nil {
+		return nil, err
+	}
+	devices := listDevices(objects)
+	selected := b.iface
+	if selected == "" {
+		for _, d := range devices {
+			if d.station {
+				selected = d.name
+				break
+			}
 		}
 	}
-	return "", fmt.Errorf("no station device found: %w", wifi.ErrNotFound)
+	result := make([]wifi.Device, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, wifi.Device{Interface: d.name, HardwareAddress: d.address, Selected: d.name == selected})
+	}
+	return result, nil
+}
+
+// SelectDevice switches to the device with the given name.
+func (b *Backend) SelectDevice(iface string) error {
+	conn, err := b.bus()
+	if err != nil {
+		return err
+	}
+	if _, err := getDevice(conn, iface, func(iwdDevice) bool { return true }); err != nil {
+		return err
+	}
+	b.iface = iface
+	return nil
 }
 
 // getKnownNetworkPaths returns all object paths that have the KnownNetwork interface.
@@ -183,12 +326,12 @@ func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error)
 		return wifi.NetworksResult{}, wifi.ErrWirelessDisabled
 	}
 
-	conn, err := dbus.SystemBus()
+	conn, err := b.bus()
 	if err != nil {
 		return wifi.NetworksResult{}, err
 	}
 
-	station, err := getStationDevice(conn)
+	station, err := getStationDevice(conn, b.iface)
 	if err != nil {
 		return wifi.NetworksResult{}, err
 	}
@@ -225,12 +368,16 @@ func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error)
 		var security wifi.SecurityType
 		switch securityType {
 		case "psk":
+			// iwd reports WPA2, WPA3 and transition mode networks all as
+			// psk and negotiates SAE itself when joining, so the precise
+			// type is not available here.
 			security = wifi.SecurityWPA
 		case "8021x":
-			security = wifi.SecurityWPA
+			security = wifi.SecurityEnterprise
 		case "wep":
 			security = wifi.SecurityWEP
 		default:
+			// Includes OWE networks, which iwd also handles transparently.
 			security = wifi.SecurityOpen
 		}
 
@@ -440,7 +587,7 @@ func dbusErrorName(err error) string {
 }
 
 func (b *Backend) ActivateNetwork(ssid string) error {
-	conn, err := dbus.SystemBus()
+	conn, err := b.bus()
 	if err != nil {
 		return err
 	}
@@ -455,8 +602,14 @@ func (b *Backend) ActivateNetwork(ssid string) error {
 	return conn.Object(iwdDest, networkPath).Call(iwdNetworkIface+".Connect", 0).Err
 }
 
+// ActivateAccessPoint is not supported, since iwd only offers connecting to a
+// BSSID through its debug interface when running in developer mode.
+func (b *Backend) ActivateAccessPoint(ssid, bssid string) error {
+	return fmt.Errorf("connecting to a specific access point is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+}
+
 func (b *Backend) ForgetNetwork(ssid string) error {
-	conn, err := dbus.SystemBus()
+	conn, err := b.bus()
 	if err != nil {
 		return err
 	}
@@ -473,8 +626,7 @@ func (b *Backend) ForgetNetwork(ssid string) error {
 
 // registerAgent exports a temporary Agent on the D-Bus connection and registers
 // it with iwd's AgentManager. The returned cleanup function unregisters the agent.
-func registerAgent(conn *dbus.Conn, password string) (cleanup func(), err error) {
-	a := &agent{passphrase: password}
+func registerAgent(conn *dbus.Conn, a *agent) (cleanup func(), err error) {
 	err = conn.Export(a, agentPath, iwdAgentIface)
 	if err != nil {
 		return nil, fmt.Errorf("failed to export agent: %w", err)
@@ -493,23 +645,43 @@ func registerAgent(conn *dbus.Conn, password string) (cleanup func(), err error)
 	}, nil
 }
 
-func (b *Backend) JoinNetwork(ssid string, password string, security wifi.SecurityType, isHidden bool) error {
-	conn, err := dbus.SystemBus()
+// JoinNetwork connects to a network. iwd can only join 802.1X networks that
+// have been provisioned with a /var/lib/iwd/<ssid>.8021x file; the agent
+// supplies the identity, password and private key passphrase on request.
+func (b *Backend) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
+	if opts.Security.IsEnterprise() {
+		if err := opts.Enterprise.Validate(); err != nil {
+			return err
+		}
+	}
+	if opts.IPv4 != nil || opts.IPv6 != nil {
+		return fmt.Errorf("IP configuration is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+	if opts.BSSID != "" {
+		return fmt.Errorf("joining a specific access point is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+	if opts.SaveOnly {
+		// iwd only learns networks by connecting to them, or from files in
+		// its state directory.
+		return fmt.Errorf("saving a network without connecting is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+
+	conn, err := b.bus()
 	if err != nil {
 		return err
 	}
 
 	// Register a temporary agent so iwd can request the passphrase.
-	if password != "" {
-		cleanup, err := registerAgent(conn, password)
+	if opts.Password != "" || opts.Enterprise != nil {
+		cleanup, err := registerAgent(conn, newAgent(opts))
 		if err != nil {
 			return fmt.Errorf("failed to set up credentials agent: %w", err)
 		}
 		defer cleanup()
 	}
 
-	if isHidden {
-		station, err := getStationDevice(conn)
+	if opts.IsHidden {
+		station, err := getStationDevice(conn, b.iface)
 		if err != nil {
 			return err
 		}
@@ -523,7 +695,11 @@ func (b *Backend) JoinNetwork(ssid string, password string, security wifi.Securi
 	if networkPath == "" {
 		return fmt.Errorf("network %s not found: %w", ssid, wifi.ErrNotFound)
 	}
-	return conn.Object(iwdDest, networkPath).Call(iwdNetworkIface+".Connect", 0).Err
+	err = conn.Object(iwdDest, networkPath).Call(iwdNetworkIface+".Connect", 0).Err
+	if opts.Security.IsEnterprise() && dbusErrorName(err) == "net.connman.iwd.NotConfigured" {
+		return fmt.Errorf("iwd requires a provisioning file for 802.1X network %s: %w: %w", ssid, wifi.ErrNotSupported, err)
+	}
+	return err
 }
 
 func (b *Backend) GetSecrets(ssid string) (string, error) {
@@ -534,9 +710,24 @@ func (b *Backend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
 	if opts.Password != nil {
 		return fmt.Errorf("updating secrets is not supported by the iwd backend: %w", wifi.ErrNotSupported)
 	}
+	if opts.IPv4 != nil || opts.IPv6 != nil {
+		// iwd reads static addressing from its network files, not over D-Bus.
+		return fmt.Errorf("IP configuration is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+	if opts.BSSID != nil {
+		return fmt.Errorf("locking a network to an access point is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+	if opts.Band != nil || opts.Channel != nil {
+		return fmt.Errorf("band and channel preferences are not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
+	if opts.Priority != nil {
+		// iwd ranks known networks itself, by signal and when they were
+		// last used.
+		return fmt.Errorf("autoconnect priority is not supported by the iwd backend: %w", wifi.ErrNotSupported)
+	}
 
 	if opts.AutoConnect != nil {
-		conn, err := dbus.SystemBus()
+		conn, err := b.bus()
 		if err != nil {
 			return err
 		}
@@ -555,16 +746,18 @@ func (b *Backend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
 	return nil
 }
 
+// IsWirelessEnabled reports whether the device is powered. iwd removes the
+// Station interface of a device that is powered down, so any device will do.
 func (b *Backend) IsWirelessEnabled() (bool, error) {
-	conn, err := dbus.SystemBus()
+	conn, err := b.bus()
 	if err != nil {
 		return false, err
 	}
-	station, err := getStationDevice(conn)
+	device, err := getWirelessDevice(conn, b.iface)
 	if err != nil {
 		return false, err
 	}
-	poweredVar, err := conn.Object(iwdDest, station).GetProperty(iwdDeviceIface + ".Powered")
+	poweredVar, err := conn.Object(iwdDest, device).GetProperty(iwdDeviceIface + ".Powered")
 	if err != nil {
 		return false, err
 	}
@@ -572,33 +765,45 @@ func (b *Backend) IsWirelessEnabled() (bool, error) {
 }
 
 func (b *Backend) SetWireless(enabled bool) error {
-	conn, err := dbus.SystemBus()
+	conn, err := b.bus()
 	if err != nil {
 		return err
 	}
-	station, err := getStationDevice(conn)
+	device, err := getWirelessDevice(conn, b.iface)
 	if err != nil {
 		return err
 	}
-	obj := conn.Object(iwdDest, station)
-	variant := dbus.MakeVariant(enabled)
-	err = obj.Call("org.freedesktop.DBus.Properties.Set", 0, iwdDeviceIface, "Powered", variant).Err
+	obj := conn.Object(iwdDest, device)
+	poweredVar, err := obj.GetProperty(iwdDeviceIface + ".Powered")
 	if err != nil {
 		return err
 	}
+	if powered, ok := poweredVar.Value().(bool); ok && powered == enabled {
+		return nil
+	}
 
-	// Block until the property is updated.
+	// Subscribe before setting the property, iwd may announce the change
+	// before it replies.
 	signals := make(chan *dbus.Signal, 10)
-	matchPath := dbus.WithMatchObjectPath(station)
+	matchPath := dbus.WithMatchObjectPath(device)
 	matchInterface := dbus.WithMatchInterface("org.freedesktop.DBus.Properties")
 	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
 	conn.AddMatchSignal(matchInterface, matchPath)
 	defer conn.RemoveMatchSignal(matchInterface, matchPath)
 
+	variant := dbus.MakeVariant(enabled)
+	err = obj.Call("org.freedesktop.DBus.Properties.Set", 0, iwdDeviceIface, "Powered", variant).Err
+	if err != nil {
+		return err
+	}
+
+	// Block until the property is updated.
+	timeout := time.After(propertyChangeTimeout)
 	for {
 		select {
 		case signal := <-signals:
-			if signal.Name == "org.freedesktop.DBus.Properties.PropertiesChanged" {
+			if signal.Name == "org.freedesktop.DBus.Properties.PropertiesChanged" && signal.Path == device {
 				if len(signal.Body) < 2 {
 					continue
 				}
@@ -616,8 +821,14 @@ func (b *Backend) SetWireless(enabled bool) error {
 					}
 				}
 			}
-		case <-time.After(propertyChangeTimeout):
+		case <-timeout:
 			return fmt.Errorf("timed out waiting for wireless state change")
 		}
 	}
 }
+
+// Connectivity is not supported, since iwd leaves internet access to
+// whatever configures the addresses.
+func (b *Backend) Connectivity() (wifi.Connectivity, error) {
+	return wifi.ConnectivityUnknown, fmt.Errorf("connectivity checks are not supported by the iwd backend: %w", wifi.ErrNotSupported)
+}
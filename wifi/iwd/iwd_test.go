@@ -5,6 +5,9 @@ package iwd
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
 	"testing"
 	"time"
 
@@ -89,3 +92,165 @@ func scanningSignal(scanning bool) *dbus.Signal {
 		Body: []any{iwdStationIface, map[string]dbus.Variant{"Scanning": dbus.MakeVariant(scanning)}},
 	}
 }
+
+func TestEventTracker(t *testing.T) {
+	const networkPath dbus.ObjectPath = "/net/connman/iwd/0/1/486f6d654e6574_psk"
+	tracker := newEventTracker(map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+		testStationPath: {iwdStationIface: {"State": dbus.MakeVariant("disconnected")}},
+	})
+
+	tests := []struct {
+		name string
+		sig  *dbus.Signal
+		want []wifi.Event
+	}{
+		{
+			name: "network added",
+			sig: &dbus.Signal{
+				Name: dbusObjectManagerIface + ".InterfacesAdded",
+				Body: []any{networkPath, map[string]map[string]dbus.Variant{
+					iwdNetworkIface: {"Name": dbus.MakeVariant("HomeNet")},
+				}},
+			},
+			want: []wifi.Event{{Type: wifi.EventNetworkAppeared, SSID: "HomeNet"}},
+		},
+		{
+			name: "station connecting",
+			sig: &dbus.Signal{
+				Name: dbusPropertiesIface + ".PropertiesChanged",
+				Path: testStationPath,
+				Body: []any{iwdStationIface, map[string]dbus.Variant{
+					"State":            dbus.MakeVariant("connecting"),
+					"ConnectedNetwork": dbus.MakeVariant(networkPath),
+				}},
+			},
+			want: []wifi.Event{{Type: wifi.EventConnectionStateChanged, SSID: "HomeNet", State: wifi.ConnectionActivating}},
+		},
+		{
+			name: "station connected",
+			sig: &dbus.Signal{
+				Name: dbusPropertiesIface + ".PropertiesChanged",
+				Path: testStationPath,
+				Body: []any{iwdStationIface, map[string]dbus.Variant{"State": dbus.MakeVariant("connected")}},
+			},
+			want: []wifi.Event{{Type: wifi.EventConnectionStateChanged, SSID: "HomeNet", State: wifi.ConnectionActivated}},
+		},
+		{
+			name: "scan started",
+			sig:  scanningSignal(true),
+			want: nil,
+		},
+		{
+			name: "scan completed",
+			sig:  scanningSignal(false),
+			want: []wifi.Event{{Type: wifi.EventScanCompleted}},
+		},
+		{
+			name: "radio toggled",
+			sig: &dbus.Signal{
+				Name: dbusPropertiesIface + ".PropertiesChanged",
+				Path: testStationPath,
+				Body: []any{iwdDeviceIface, map[string]dbus.Variant{"Powered": dbus.MakeVariant(false)}},
+			},
+			want: []wifi.Event{{Type: wifi.EventRadioToggled, RadioEnabled: false}},
+		},
+		{
+			name: "network removed",
+			sig: &dbus.Signal{
+				Name: dbusObjectManagerIface + ".InterfacesRemoved",
+				Body: []any{networkPath, []string{iwdNetworkIface}},
+			},
+			want: []wifi.Event{{Type: wifi.EventNetworkDisappeared, SSID: "HomeNet"}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := tracker.handle(tt.sig)
+		for i := range got {
+			got[i].Time = time.Time{}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: handle() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyDiagnostics(t *testing.T) {
+	details := &wifi.ConnectionDetails{SSID: "Home"}
+	applyDiagnostics(details, map[string]dbus.Variant{
+		"ConnectedBss": dbus.MakeVariant("aa:bb:cc:dd:ee:01"),
+		"Frequency":    dbus.MakeVariant(uint32(5180)),
+		"TxBitrate":    dbus.MakeVariant(uint32(8667)),
+		"RSSI":         dbus.MakeVariant(int16(-50)),
+	})
+	want := &wifi.ConnectionDetails{SSID: "Home", BSSID: "aa:bb:cc:dd:ee:01", Frequency: 5180, Bitrate: 866700}
+	if !reflect.DeepEqual(details, want) {
+		t.Errorf("applyDiagnostics() = %+v, want %+v", details, want)
+	}
+}
+
+func TestApplyInterfaceAddrs(t *testing.T) {
+	details := &wifi.ConnectionDetails{}
+	applyInterfaceAddrs(details, []net.Addr{
+		&net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.ParseIP("2001:db8::10"), Mask: net.CIDRMask(64, 128)},
+	})
+	wantV4 := []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")}
+	wantV6 := []netip.Prefix{netip.MustParsePrefix("2001:db8::10/64")}
+	if !reflect.DeepEqual(details.IPv4Addresses, wantV4) {
+		t.Errorf("IPv4Addresses = %v, want %v", details.IPv4Addresses, wantV4)
+	}
+	if !reflect.DeepEqual(details.IPv6Addresses, wantV6) {
+		t.Errorf("IPv6Addresses = %v, want %v", details.IPv6Addresses, wantV6)
+	}
+}
+
+func TestHotspotStatus(t *testing.T) {
+	stopped := hotspotStatus(map[string]dbus.Variant{"Started": dbus.MakeVariant(false)})
+	if stopped != (wifi.HotspotStatus{}) {
+		t.Errorf("hotspotStatus() of a stopped access point = %+v, want inactive", stopped)
+	}
+
+	got := hotspotStatus(map[string]dbus.Variant{
+		"Started":   dbus.MakeVariant(true),
+		"Name":      dbus.MakeVariant("Event"),
+		"Frequency": dbus.MakeVariant(uint32(2437)),
+	})
+	want := wifi.HotspotStatus{
+		Active:        true,
+		HotspotConfig: wifi.HotspotConfig{SSID: "Event", Band: wifi.Band2GHz},
+		Security:      wifi.SecurityWPA,
+	}
+	if got != want {
+		t.Errorf("hotspotStatus() = %+v, want %+v", got, want)
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	objects := map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+		"/net/connman/iwd/0/2": {
+			iwdDeviceIface: {
+				"Name":    dbus.MakeVariant("wlan1"),
+				"Address": dbus.MakeVariant("66:77:88:99:aa:bb"),
+			},
+		},
+		testStationPath: {
+			iwdDeviceIface: {
+				"Name":    dbus.MakeVariant("wlan0"),
+				"Address": dbus.MakeVariant("00:11:22:33:44:55"),
+			},
+			iwdStationIface: {},
+		},
+		"/net/connman/iwd/0": {"net.connman.iwd.Adapter": {}},
+	}
+
+	got := listDevices(objects)
+	want := []iwdDevice{
+		{path: testStationPath, name: "wlan0", address: "00:11:22:33:44:55", station: true},
+		{path: "/net/connman/iwd/0/2", name: "wlan1", address: "66:77:88:99:aa:bb"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listDevices() = %+v, want %+v", got, want)
+	}
+}
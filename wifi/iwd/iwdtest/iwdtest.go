@@ -0,0 +1,921 @@
+//go:build linux
+
+// Package iwdtest runs a fake iwd on a private D-Bus, so that the iwd backend
+// can be tested end to end, agent callbacks and signals included, without an
+// iwd daemon or a radio.
+//
+// The fake models the net.connman.iwd object tree: adapters, devices in
+// station or access point mode, the networks and basic service sets a station
+// sees, known networks and the agent manager. Like iwd, it asks the
+// registered agent for credentials when connecting to a network it does not
+// know, and replies to Connect once the connection has succeeded or failed.
+package iwdtest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/shazow/wifitui/internal/dbustest"
+)
+
+const (
+	service = "net.connman.iwd"
+	root    = dbus.ObjectPath("/net/connman/iwd")
+
+	adapterIface           = "net.connman.iwd.Adapter"
+	deviceIface            = "net.connman.iwd.Device"
+	stationIface           = "net.connman.iwd.Station"
+	stationDiagnosticIface = "net.connman.iwd.StationDiagnostic"
+	accessPointIface       = "net.connman.iwd.AccessPoint"
+	networkIface           = "net.connman.iwd.Network"
+	bssIface               = "net.connman.iwd.BasicServiceSet"
+	knownNetworkIface      = "net.connman.iwd.KnownNetwork"
+	agentManagerIface      = "net.connman.iwd.AgentManager"
+	agentIface             = "net.connman.iwd.Agent"
+	propertiesIface        = "org.freedesktop.DBus.Properties"
+	objectManagerIface     = "org.freedesktop.DBus.ObjectManager"
+
+	// scanDelay makes scans finish after Scan has returned, like they do in
+	// iwd, so that clients have to wait for Scanning to change.
+	scanDelay = 20 * time.Millisecond
+)
+
+// Network types, as reported by Network.Type and KnownNetwork.Type.
+const (
+	TypeOpen  = "open"
+	TypeWEP   = "wep"
+	TypePSK   = "psk"
+	Type8021X = "8021x"
+)
+
+// Network describes a network in range of a device.
+type Network struct {
+	SSID string
+	// Type is one of TypeOpen, TypeWEP, TypePSK or Type8021X, and defaults
+	// to TypePSK if Passphrase is set and TypeOpen otherwise.
+	Type string
+	// Strength is the signal strength in 100 * dBm, as iwd reports it.
+	Strength int16
+	// BSSIDs are the access points of the network. One is made up if
+	// there are none.
+	BSSIDs    []string
+	Frequency uint32
+	// Passphrase is the secret a connection needs: the passphrase of psk
+	// and wep networks, or the password of 8021x networks.
+	Passphrase string
+	// Hidden networks are only listed by Station.GetHiddenAccessPoints
+	// until Station.ConnectHiddenNetwork asks for them.
+	Hidden bool
+}
+
+// KnownNetwork describes a network iwd has a profile for.
+type KnownNetwork struct {
+	SSID        string
+	Type        string
+	Hidden      bool
+	AutoConnect bool
+	// Passphrase is the saved secret, which iwd uses instead of asking the
+	// agent. 8021x profiles leave the identity and password to the agent.
+	Passphrase string
+	// LastConnected is zero if the network was never connected to.
+	LastConnected time.Time
+}
+
+// Server is a fake iwd. Its methods must be called from the test goroutine.
+type Server struct {
+	t    testing.TB
+	bus  *dbustest.Bus
+	conn *dbus.Conn
+
+	mu           sync.Mutex
+	objects      map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	devices      []*device
+	known        map[dbus.ObjectPath]*KnownNetwork
+	agent        *agentRef
+	agentCalls   []string
+	scans        int
+	scansStalled bool
+}
+
+type device struct {
+	path     dbus.ObjectPath
+	name     string
+	address  string
+	powered  bool
+	mode     string
+	networks []*network
+	// connected is the network the station is connected to, or nil.
+	connected *network
+	scanning  bool
+}
+
+type network struct {
+	Network
+	path   dbus.ObjectPath
+	device *device
+	// visible is false for hidden networks that were not asked for yet.
+	visible bool
+}
+
+type agentRef struct {
+	sender string
+	path   dbus.ObjectPath
+}
+
+// New starts a fake iwd on a private bus. It has no devices until AddDevice
+// is called. Backends reach it through a connection from Connect.
+func New(t testing.TB) *Server {
+	t.Helper()
+	bus := dbustest.New(t)
+	s := &Server{
+		t:       t,
+		bus:     bus,
+		conn:    bus.Connect(t),
+		objects: make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant),
+		known:   make(map[dbus.ObjectPath]*KnownNetwork),
+	}
+	s.export("/", objectManagerIface, map[string]any{
+		"GetManagedObjects": s.getManagedObjects,
+	})
+	s.mu.Lock()
+	s.addInterface(root, agentManagerIface, nil, map[string]any{
+		"RegisterAgent":   s.registerAgent,
+		"UnregisterAgent": s.unregisterAgent,
+	})
+	s.mu.Unlock()
+	if reply, err := s.conn.RequestName(service, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: %v", service, err)
+	}
+	return s
+}
+
+// Connect opens a client connection to the bus of the fake.
+func (s *Server) Connect(t testing.TB) *dbus.Conn {
+	t.Helper()
+	return s.bus.Connect(t)
+}
+
+// export exports methods on path. Failing to export is a bug in the fake,
+// and handlers cannot fail the test, so it panics.
+func (s *Server) export(path dbus.ObjectPath, iface string, methods map[string]any) {
+	if err := s.conn.ExportMethodTable(methods, path, iface); err != nil {
+		panic(err)
+	}
+}
+
+func (s *Server) emit(path dbus.ObjectPath, name string, values ...any) {
+	_ = s.conn.Emit(path, name, values...)
+}
+
+func newError(name, message string) *dbus.Error {
+	return dbus.NewError("net.connman.iwd."+name, []any{message})
+}
+
+// --- Object model, all of it called with s.mu held ---
+
+// addInterface adds an interface with its properties and methods to the
+// object at path, creating the object if needed.
+func (s *Server) addInterface(path dbus.ObjectPath, iface string, props map[string]any, methods map[string]any) {
+	ifaces, ok := s.objects[path]
+	if !ok {
+		ifaces = make(map[string]map[string]dbus.Variant)
+		s.objects[path] = ifaces
+		s.export(path, propertiesIface, map[string]any{
+			"Get":    func(iface, name string) (dbus.Variant, *dbus.Error) { return s.getProperty(path, iface, name) },
+			"GetAll": func(iface string) (map[string]dbus.Variant, *dbus.Error) { return s.getAllProperties(path, iface) },
+			"Set": func(iface, name string, value dbus.Variant) *dbus.Error {
+				return s.setProperty(path, iface, name, value)
+			},
+		})
+	}
+	values := make(map[string]dbus.Variant, len(props))
+	for name, value := range props {
+		values[name] = dbus.MakeVariant(value)
+	}
+	ifaces[iface] = values
+	if methods != nil {
+		s.export(path, iface, methods)
+	}
+	s.emit("/", objectManagerIface+".InterfacesAdded", path, map[string]map[string]dbus.Variant{iface: maps.Clone(values)})
+}
+
+func (s *Server) removeInterface(path dbus.ObjectPath, iface string) {
+	ifaces, ok := s.objects[path]
+	if !ok {
+		return
+	}
+	if _, ok := ifaces[iface]; !ok {
+		return
+	}
+	delete(ifaces, iface)
+	_ = s.conn.Export(nil, path, iface)
+	if len(ifaces) == 0 {
+		delete(s.objects, path)
+		_ = s.conn.Export(nil, path, propertiesIface)
+	}
+	s.emit("/", objectManagerIface+".InterfacesRemoved", path, []string{iface})
+}
+
+func (s *Server) removeObject(path dbus.ObjectPath) {
+	for _, iface := range slices.Sorted(maps.Keys(s.objects[path])) {
+		s.removeInterface(path, iface)
+	}
+}
+
+func (s *Server) hasInterface(path dbus.ObjectPath, iface string) bool {
+	_, ok := s.objects[path][iface]
+	return ok
+}
+
+// setProperties changes properties and emits PropertiesChanged for the
+// ones whose value changed. A nil value removes the property.
+func (s *Server) setProperties(path dbus.ObjectPath, iface string, props map[string]any) {
+	values, ok := s.objects[path][iface]
+	if !ok {
+		return
+	}
+	changed := make(map[string]dbus.Variant)
+	invalidated := []string{}
+	for _, name := range slices.Sorted(maps.Keys(props)) {
+		value := props[name]
+		old, existed := values[name]
+		if value == nil {
+			if existed {
+				delete(values, name)
+				invalidated = append(invalidated, name)
+			}
+			continue
+		}
+		variant := dbus.MakeVariant(value)
+		if existed && old.String() == variant.String() {
+			continue
+		}
+		values[name] = variant
+		changed[name] = variant
+	}
+	if len(changed) > 0 || len(invalidated) > 0 {
+		s.emit(path, propertiesIface+".PropertiesChanged", iface, changed, invalidated)
+	}
+}
+
+// --- org.freedesktop.DBus.ObjectManager and Properties ---
+
+func (s *Server) getManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant, len(s.objects))
+	for path, ifaces := range s.objects {
+		objects[path] = make(map[string]map[string]dbus.Variant, len(ifaces))
+		for iface, props := range ifaces {
+			objects[path][iface] = maps.Clone(props)
+		}
+	}
+	return objects, nil
+}
+
+func (s *Server) getProperty(path dbus.ObjectPath, iface, name string) (dbus.Variant, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	props, ok := s.objects[path][iface]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []any{"No such interface " + iface})
+	}
+	value, ok := props[name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.InvalidArgs", []any{"No such property " + name})
+	}
+	return value, nil
+}
+
+func (s *Server) getAllProperties(path dbus.ObjectPath, iface string) (map[string]dbus.Variant, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	props, ok := s.objects[path][iface]
+	if !ok {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []any{"No such interface " + iface})
+	}
+	return maps.Clone(props), nil
+}
+
+func (s *Server) setProperty(path dbus.ObjectPath, iface, name string, value dbus.Variant) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hasInterface(path, iface) {
+		return dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []any{"No such interface " + iface})
+	}
+	switch iface + "." + name {
+	case deviceIface + ".Powered":
+		powered, ok := value.Value().(bool)
+		if !ok {
+			return newError("InvalidArguments", "Powered must be a boolean")
+		}
+		s.setPowered(s.device(path), powered)
+		return nil
+	case deviceIface + ".Mode":
+		mode, ok := value.Value().(string)
+		if !ok || (mode != "station" && mode != "ap") {
+			return newError("InvalidArguments", "Mode must be station or ap")
+		}
+		s.setMode(s.device(path), mode)
+		return nil
+	case knownNetworkIface + ".AutoConnect":
+		autoConnect, ok := value.Value().(bool)
+		if !ok {
+			return newError("InvalidArguments", "AutoConnect must be a boolean")
+		}
+		s.known[path].AutoConnect = autoConnect
+		s.setProperties(path, iface, map[string]any{"AutoConnect": autoConnect})
+		return nil
+	}
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []any{name + " is read-only"})
+}
+
+// --- Test API ---
+
+// AddDevice adds a powered device in station mode, with an adapter of its
+// own.
+func (s *Server) AddDevice(name, address string) dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.devices)
+	adapter := dbus.ObjectPath(fmt.Sprintf("%s/%d", root, n))
+	s.addInterface(adapter, adapterIface, map[string]any{
+		"Powered":        true,
+		"Name":           fmt.Sprintf("phy%d", n),
+		"Model":          "iwdtest",
+		"Vendor":         "iwdtest",
+		"SupportedModes": []string{"station", "ap"},
+	}, nil)
+
+	d := &device{path: dbus.ObjectPath(fmt.Sprintf("%s/%d", adapter, n+1)), name: name, address: address, powered: true, mode: "station"}
+	s.devices = append(s.devices, d)
+	s.addInterface(d.path, deviceIface, map[string]any{
+		"Name":    name,
+		"Address": address,
+		"Powered": true,
+		"Adapter": adapter,
+		"Mode":    "station",
+	}, nil)
+	s.addStation(d)
+	return d.path
+}
+
+// AddNetwork puts a network in range of a device, and returns the path of
+// its Network object. Hidden networks only get the object once they are
+// connected to.
+func (s *Server) AddNetwork(devicePath dbus.ObjectPath, config Network) dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.device(devicePath)
+	if config.Type == "" {
+		config.Type = TypeOpen
+		if config.Passphrase != "" {
+			config.Type = TypePSK
+		}
+	}
+	if len(config.BSSIDs) == 0 {
+		config.BSSIDs = []string{fmt.Sprintf("02:00:00:00:%02x:%02x", len(s.devices), len(d.networks)+1)}
+	}
+	if config.Frequency == 0 {
+		config.Frequency = 2412
+	}
+	n := &network{
+		Network: config,
+		path:    dbus.ObjectPath(fmt.Sprintf("%s/%s_%s", d.path, hex.EncodeToString([]byte(config.SSID)), config.Type)),
+		device:  d,
+		visible: !config.Hidden,
+	}
+	d.networks = append(d.networks, n)
+	if n.visible && s.hasInterface(d.path, stationIface) {
+		s.addNetworkObject(n)
+	}
+	return n.path
+}
+
+// RemoveNetwork takes a network out of range, disconnecting from it.
+func (s *Server) RemoveNetwork(path dbus.ObjectPath) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.devices {
+		for i, n := range d.networks {
+			if n.path != path {
+				continue
+			}
+			if d.connected == n {
+				s.disconnect(d)
+			}
+			d.networks = slices.Delete(d.networks, i, i+1)
+			s.removeNetworkObject(n)
+			return
+		}
+	}
+	s.t.Fatalf("iwdtest: unknown network %s", path)
+}
+
+// AddKnownNetwork adds a network profile, as if it had been loaded from
+// iwd's state directory.
+func (s *Server) AddKnownNetwork(config KnownNetwork) dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if config.Type == "" {
+		config.Type = TypeOpen
+		if config.Passphrase != "" {
+			config.Type = TypePSK
+		}
+	}
+	return s.addKnownNetwork(config)
+}
+
+// KnownNetworks returns the network profiles, sorted by SSID.
+func (s *Server) KnownNetworks() []KnownNetwork {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []KnownNetwork
+	for _, k := range s.known {
+		result = append(result, *k)
+	}
+	slices.SortFunc(result, func(a, b KnownNetwork) int { return strings.Compare(a.SSID, b.SSID) })
+	return result
+}
+
+// AgentCalls returns the agent methods the fake has called, in order.
+func (s *Server) AgentCalls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.agentCalls)
+}
+
+// AgentRegistered reports whether an agent is registered.
+func (s *Server) AgentRegistered() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.agent != nil
+}
+
+// Scans returns the number of scans requested so far.
+func (s *Server) Scans() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scans
+}
+
+// StallScans makes the scans requested from now on never finish.
+func (s *Server) StallScans(stalled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scansStalled = stalled
+}
+
+// device returns the device at path. Must be called with s.mu held.
+func (s *Server) device(path dbus.ObjectPath) *device {
+	for _, d := range s.devices {
+		if d.path == path {
+			return d
+		}
+	}
+	s.t.Fatalf("iwdtest: unknown device %s", path)
+	return nil
+}
+
+func knownNetworkPath(ssid, typ string) dbus.ObjectPath {
+	return dbus.ObjectPath(fmt.Sprintf("%s/%s_%s", root, hex.EncodeToString([]byte(ssid)), typ))
+}
+
+func (s *Server) addKnownNetwork(config KnownNetwork) dbus.ObjectPath {
+	path := knownNetworkPath(config.SSID, config.Type)
+	k := config
+	s.known[path] = &k
+	props := map[string]any{
+		"Name":        config.SSID,
+		"Type":        config.Type,
+		"Hidden":      config.Hidden,
+		"AutoConnect": config.AutoConnect,
+	}
+	if !config.LastConnected.IsZero() {
+		props["LastConnectedTime"] = config.LastConnected.UTC().Format(time.RFC3339)
+	}
+	s.addInterface(path, knownNetworkIface, props, map[string]any{
+		"Forget": func() *dbus.Error { return s.forget(path) },
+	})
+	for _, d := range s.devices {
+		for _, n := range d.networks {
+			if n.SSID == config.SSID && n.Type == config.Type {
+				s.setProperties(n.path, networkIface, map[string]any{"KnownNetwork": path})
+			}
+		}
+	}
+	return path
+}
+
+// --- Station ---
+
+func (s *Server) addStation(d *device) {
+	s.addInterface(d.path, stationIface, map[string]any{
+		"State":    "disconnected",
+		"Scanning": false,
+	}, map[string]any{
+		"Scan":                  func() *dbus.Error { return s.scan(d) },
+		"Disconnect":            func() *dbus.Error { return s.disconnectStation(d) },
+		"GetOrderedNetworks":    func() ([]orderedNetwork, *dbus.Error) { return s.getOrderedNetworks(d), nil },
+		"GetHiddenAccessPoints": func() ([]hiddenAccessPoint, *dbus.Error) { return s.getHiddenAccessPoints(d), nil },
+		"ConnectHiddenNetwork":  func(ssid string) *dbus.Error { return s.connectHiddenNetwork(d, ssid) },
+	})
+	s.addInterface(d.path, stationDiagnosticIface, nil, map[string]any{
+		"GetDiagnostics": func() (map[string]dbus.Variant, *dbus.Error) { return s.getDiagnostics(d) },
+	})
+	for _, n := range d.networks {
+		if n.visible {
+			s.addNetworkObject(n)
+		}
+	}
+}
+
+// removeStation removes the Station interface and the networks it sees, as
+// iwd does when a device is powered down or switched to access point mode.
+func (s *Server) removeStation(d *device) {
+	if !s.hasInterface(d.path, stationIface) {
+		return
+	}
+	if d.connected != nil {
+		s.disconnect(d)
+	}
+	for _, n := range d.networks {
+		s.removeNetworkObject(n)
+	}
+	d.scanning = false
+	s.removeInterface(d.path, stationDiagnosticIface)
+	s.removeInterface(d.path, stationIface)
+}
+
+func (s *Server) addNetworkObject(n *network) {
+	var bsses []dbus.ObjectPath
+	for _, bssid := range n.BSSIDs {
+		path := dbus.ObjectPath(fmt.Sprintf("%s/%s", n.path, strings.ReplaceAll(bssid, ":", "")))
+		s.addInterface(path, bssIface, map[string]any{"Address": bssid}, nil)
+		bsses = append(bsses, path)
+	}
+	props := map[string]any{
+		"Name":               n.SSID,
+		"Connected":          false,
+		"Device":             n.device.path,
+		"Type":               n.Type,
+		"ExtendedServiceSet": bsses,
+	}
+	if path := knownNetworkPath(n.SSID, n.Type); s.known[path] != nil {
+		props["KnownNetwork"] = path
+	}
+	s.addInterface(n.path, networkIface, props, map[string]any{
+		"Connect": func() *dbus.Error { return s.connect(n) },
+	})
+}
+
+func (s *Server) removeNetworkObject(n *network) {
+	for _, bssid := range n.BSSIDs {
+		s.removeObject(dbus.ObjectPath(fmt.Sprintf("%s/%s", n.path, strings.ReplaceAll(bssid, ":", ""))))
+	}
+	s.removeObject(n.path)
+}
+
+// orderedNetwork is an element of Station.GetOrderedNetworks, a(on).
+type orderedNetwork struct {
+	Path     dbus.ObjectPath
+	Strength int16
+}
+
+func (s *Server) getOrderedNetworks(d *device) []orderedNetwork {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := []orderedNetwork{}
+	for _, n := range d.networks {
+		if n.visible {
+			result = append(result, orderedNetwork{Path: n.path, Strength: n.Strength})
+		}
+	}
+	slices.SortStableFunc(result, func(a, b orderedNetwork) int { return int(b.Strength) - int(a.Strength) })
+	return result
+}
+
+// hiddenAccessPoint is an element of Station.GetHiddenAccessPoints, a(sns).
+type hiddenAccessPoint struct {
+	Address  string
+	Strength int16
+	Type     string
+}
+
+func (s *Server) getHiddenAccessPoints(d *device) []hiddenAccessPoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := []hiddenAccessPoint{}
+	for _, n := range d.networks {
+		if !n.visible {
+			for _, bssid := range n.BSSIDs {
+				result = append(result, hiddenAccessPoint{Address: bssid, Strength: n.Strength, Type: n.Type})
+			}
+		}
+	}
+	return result
+}
+
+func (s *Server) scan(d *device) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d.scanning {
+		return newError("Busy", "Operation already in progress")
+	}
+	d.scanning = true
+	s.scans++
+	s.setProperties(d.path, stationIface, map[string]any{"Scanning": true})
+	if s.scansStalled {
+		return nil
+	}
+	time.AfterFunc(scanDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if d.scanning {
+			d.scanning = false
+			s.setProperties(d.path, stationIface, map[string]any{"Scanning": false})
+		}
+	})
+	return nil
+}
+
+func (s *Server) connectHiddenNetwork(d *device, ssid string) *dbus.Error {
+	s.mu.Lock()
+	var hidden *network
+	for _, n := range d.networks {
+		if n.SSID != ssid {
+			continue
+		}
+		if n.visible {
+			s.mu.Unlock()
+			return newError("ServiceSetOverlap", "Network is not hidden")
+		}
+		hidden = n
+	}
+	if hidden == nil {
+		s.mu.Unlock()
+		return newError("NotFound", "No hidden network with that name")
+	}
+	hidden.visible = true
+	s.addNetworkObject(hidden)
+	s.mu.Unlock()
+	return s.connect(hidden)
+}
+
+// connect connects the station to a network, asking the agent for the
+// credentials that are not saved in a known network.
+func (s *Server) connect(n *network) *dbus.Error {
+	s.mu.Lock()
+	d := n.device
+	if !s.hasInterface(d.path, stationIface) {
+		s.mu.Unlock()
+		return newError("NotAvailable", "Station is not available")
+	}
+	if d.connected == n {
+		s.mu.Unlock()
+		return newError("AlreadyConnected", "Already connected")
+	}
+	if d.connected != nil {
+		s.disconnect(d)
+	}
+	known := s.known[knownNetworkPath(n.SSID, n.Type)]
+	if n.Type == Type8021X && known == nil {
+		s.mu.Unlock()
+		return newError("NotConfigured", "Not configured")
+	}
+	d.connected = n
+	s.setProperties(d.path, stationIface, map[string]any{"State": "connecting", "ConnectedNetwork": n.path})
+
+	secret := ""
+	if known != nil {
+		secret = known.Passphrase
+	}
+	var err *dbus.Error
+	if n.Type != TypeOpen && secret == "" {
+		secret, err = s.requestSecret(n)
+		s.mu.Lock()
+	}
+	defer s.mu.Unlock()
+	if d.connected != n {
+		return newError("Aborted", "Operation aborted")
+	}
+	if err == nil && n.Type != TypeOpen && secret != n.Passphrase {
+		err = newError("Failed", "Operation failed")
+	}
+	if err != nil {
+		s.disconnect(d)
+		return err
+	}
+
+	s.setProperties(d.path, stationIface, map[string]any{"State": "connected"})
+	s.setProperties(n.path, networkIface, map[string]any{"Connected": true})
+	now := time.Now()
+	if known != nil {
+		known.LastConnected = now
+		s.setProperties(knownNetworkPath(n.SSID, n.Type), knownNetworkIface, map[string]any{"LastConnectedTime": now.UTC().Format(time.RFC3339)})
+		return nil
+	}
+	passphrase := ""
+	if n.Type != Type8021X {
+		passphrase = secret
+	}
+	s.addKnownNetwork(KnownNetwork{
+		SSID:          n.SSID,
+		Type:          n.Type,
+		Hidden:        n.Hidden,
+		AutoConnect:   true,
+		Passphrase:    passphrase,
+		LastConnected: now,
+	})
+	return nil
+}
+
+// requestSecret asks the agent for the secret of a network. It must be
+// called with s.mu held and returns with it released, since the agent is
+// called over the bus.
+func (s *Server) requestSecret(n *network) (string, *dbus.Error) {
+	agent := s.agent
+	if agent == nil {
+		s.mu.Unlock()
+		return "", newError("NoAgent", "No Agent registered")
+	}
+	method := "RequestPassphrase"
+	if n.Type == Type8021X {
+		method = "RequestUserNameAndPassword"
+	}
+	s.agentCalls = append(s.agentCalls, method)
+	s.mu.Unlock()
+
+	call := s.conn.Object(agent.sender, agent.path).Call(agentIface+"."+method, 0, n.path)
+	if call.Err != nil {
+		return "", newError("Aborted", "Agent failed to provide credentials")
+	}
+	var secret string
+	if n.Type == Type8021X {
+		var username string
+		if err := call.Store(&username, &secret); err != nil {
+			return "", newError("Aborted", "Agent replied with invalid credentials")
+		}
+	} else if err := call.Store(&secret); err != nil {
+		return "", newError("Aborted", "Agent replied with invalid credentials")
+	}
+	return secret, nil
+}
+
+// disconnect drops the connection of a station.
+func (s *Server) disconnect(d *device) {
+	n := d.connected
+	if n == nil {
+		return
+	}
+	d.connected = nil
+	s.setProperties(n.path, networkIface, map[string]any{"Connected": false})
+	s.setProperties(d.path, stationIface, map[string]any{"State": "disconnected", "ConnectedNetwork": nil})
+}
+
+func (s *Server) disconnectStation(d *device) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d.connected == nil {
+		return newError("NotConnected", "Not connected")
+	}
+	s.disconnect(d)
+	return nil
+}
+
+func (s *Server) getDiagnostics(d *device) (map[string]dbus.Variant, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := d.connected
+	if n == nil {
+		return nil, newError("NotConnected", "Not connected")
+	}
+	return map[string]dbus.Variant{
+		"ConnectedBss": dbus.MakeVariant(n.BSSIDs[0]),
+		"Frequency":    dbus.MakeVariant(n.Frequency),
+		"RSSI":         dbus.MakeVariant(n.Strength / 100),
+		"TxBitrate":    dbus.MakeVariant(uint32(1300)),
+	}, nil
+}
+
+// --- Device ---
+
+func (s *Server) setPowered(d *device, powered bool) {
+	if d.powered == powered {
+		return
+	}
+	d.powered = powered
+	if !powered {
+		if d.mode == "ap" {
+			s.removeInterface(d.path, accessPointIface)
+		} else {
+			s.removeStation(d)
+		}
+	}
+	s.setProperties(d.path, deviceIface, map[string]any{"Powered": powered})
+	if powered {
+		s.addModeInterface(d)
+	}
+}
+
+func (s *Server) setMode(d *device, mode string) {
+	if d.mode == mode {
+		return
+	}
+	if d.mode == "ap" {
+		s.removeInterface(d.path, accessPointIface)
+	} else {
+		s.removeStation(d)
+	}
+	d.mode = mode
+	s.setProperties(d.path, deviceIface, map[string]any{"Mode": mode})
+	if d.powered {
+		s.addModeInterface(d)
+	}
+}
+
+func (s *Server) addModeInterface(d *device) {
+	if d.mode == "station" {
+		s.addStation(d)
+		return
+	}
+	s.addInterface(d.path, accessPointIface, map[string]any{"Started": false}, map[string]any{
+		"Start": func(ssid, passphrase string) *dbus.Error { return s.startAccessPoint(d, ssid, passphrase) },
+		"Stop":  func() *dbus.Error { return s.stopAccessPoint(d) },
+	})
+}
+
+func (s *Server) startAccessPoint(d *device, ssid, passphrase string) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if started, _ := s.objects[d.path][accessPointIface]["Started"].Value().(bool); started {
+		return newError("AlreadyExists", "Already started")
+	}
+	if len(passphrase) < 8 || len(passphrase) > 63 {
+		return newError("InvalidArguments", "Invalid passphrase")
+	}
+	s.setProperties(d.path, accessPointIface, map[string]any{"Started": true, "Name": ssid, "Frequency": uint32(2412)})
+	return nil
+}
+
+func (s *Server) stopAccessPoint(d *device) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setProperties(d.path, accessPointIface, map[string]any{"Started": false, "Name": nil, "Frequency": nil})
+	return nil
+}
+
+// --- KnownNetwork ---
+
+func (s *Server) forget(path dbus.ObjectPath) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.known[path]
+	if !ok {
+		return newError("NotFound", "Network not found")
+	}
+	for _, d := range s.devices {
+		for _, n := range d.networks {
+			if n.SSID != k.SSID || n.Type != k.Type {
+				continue
+			}
+			if d.connected == n {
+				s.disconnect(d)
+			}
+			s.setProperties(n.path, networkIface, map[string]any{"KnownNetwork": nil})
+		}
+	}
+	delete(s.known, path)
+	s.removeObject(path)
+	return nil
+}
+
+// --- AgentManager ---
+
+func (s *Server) registerAgent(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.agent != nil {
+		return newError("AlreadyExists", "Object already exists")
+	}
+	s.agent = &agentRef{sender: string(sender), path: path}
+	return nil
+}
+
+func (s *Server) unregisterAgent(sender dbus.Sender, path dbus.ObjectPath) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.agent == nil || s.agent.sender != string(sender) || s.agent.path != path {
+		return newError("NotFound", "Object not found")
+	}
+	s.agent = nil
+	return nil
+}
@@ -1,8 +1,13 @@
 package mock
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/shazow/wifitui/wifi"
@@ -13,7 +18,8 @@ var DefaultActionSleep = 500 * time.Millisecond
 // mockNetwork wraps a backend.Network with mock-specific metadata.
 type mockNetwork struct {
 	wifi.Network
-	Secret string
+	Secret     string
+	Enterprise *wifi.EnterpriseCredentials
 }
 
 // MockBackend is a mock implementation of the backend.Backend interface for testing.
@@ -29,12 +35,129 @@ type MockBackend struct {
 	WirelessEnabled        bool
 	IsWirelessEnabledError error
 	SetWirelessError       error
+	HotspotError           error
+
+	// ConnectivityState is reported while a network is active. It defaults
+	// to full connectivity when empty.
+	ConnectivityState wifi.Connectivity
+
+	// Interfaces are the wireless devices, with the selected one marked. Both
+	// see the same networks.
+	Interfaces []wifi.Device
 
 	// DisableRandomization prevents signal strength changes on scan, useful for deterministic testing.
 	DisableRandomization bool
 
 	// ActionSleep is a delay before every action, to better emulate a real-world backend for the frontend. Set to 0 during testing.
 	ActionSleep time.Duration
+
+	// Passphrases are the passwords that networks accept, by SSID. Joining a
+	// listed network with any other password fails with
+	// wifi.ErrIncorrectPassphrase.
+	Passphrases map[string]string
+
+	// FailAttempts lists, by SSID, the connection attempts that fail with
+	// wifi.ErrIncorrectPassphrase. Attempts are counted from 1 across
+	// ActivateNetwork, ActivateAccessPoint and JoinNetwork.
+	FailAttempts map[string][]int
+
+	// ScanFailures are returned as the scan errors of the next scanning
+	// ListNetworks calls, one per call.
+	ScanFailures []*wifi.ScanFailure
+
+	// attempts counts the connection attempts per SSID.
+	attempts map[string]int
+
+	// mu guards the state above against scenario timelines, which change it
+	// in the background.
+	mu sync.Mutex
+
+	// activeBSSID is the access point of the active network, when one was
+	// chosen explicitly.
+	activeBSSID string
+
+	// hotspot is the running hotspot, or nil if there is none.
+	hotspot *wifi.HotspotConfig
+
+	watchersMu sync.Mutex
+	watchers   map[chan wifi.Event]struct{}
+}
+
+// WatchEvents returns a channel that receives the events caused by calls on
+// this backend, such as activating a network or toggling the radio.
+func (m *MockBackend) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
+	events := make(chan wifi.Event, 16)
+	m.watchersMu.Lock()
+	if m.watchers == nil {
+		m.watchers = make(map[chan wifi.Event]struct{})
+	}
+	m.watchers[events] = struct{}{}
+	m.watchersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.watchersMu.Lock()
+		delete(m.watchers, events)
+		close(events)
+		m.watchersMu.Unlock()
+	}()
+	return events, nil
+}
+
+// emit sends events to all watchers. Events are dropped for watchers that
+// are not keeping up, like a real backend's signal queue would.
+func (m *MockBackend) emit(events ...wifi.Event) {
+	m.watchersMu.Lock()
+	defer m.watchersMu.Unlock()
+	for _, e := range events {
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		for watcher := range m.watchers {
+			select {
+			case watcher <- e:
+			default:
+			}
+		}
+	}
+}
+
+// activeSSID returns the SSID of the active network, or "" if none.
+func (m *MockBackend) activeSSID() string {
+	if m.ActiveNetworkIndex >= 0 && m.ActiveNetworkIndex < len(m.KnownNetworks) {
+		return m.KnownNetworks[m.ActiveNetworkIndex].SSID
+	}
+	return ""
+}
+
+// connect makes ssid the active network and emits the matching connection
+// state events.
+func (m *MockBackend) connect(ssid string) {
+	if previous := m.activeSSID(); previous != "" && previous != ssid {
+		m.emit(wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: previous, State: wifi.ConnectionDeactivated})
+	}
+	m.emit(wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: ssid, State: wifi.ConnectionActivating})
+	m.setActiveNetwork(ssid)
+	m.activeBSSID = ""
+	m.emit(wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: ssid, State: wifi.ConnectionActivated})
+}
+
+// attempt counts a connection attempt to ssid, and fails it with the
+// connection state events of a rejected passphrase when FailAttempts lists it.
+func (m *MockBackend) attempt(ssid string) error {
+	if m.attempts == nil {
+		m.attempts = make(map[string]int)
+	}
+	m.attempts[ssid]++
+	n := m.attempts[ssid]
+	if !slices.Contains(m.FailAttempts[ssid], n) {
+		return nil
+	}
+	m.emit(
+		wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: ssid, State: wifi.ConnectionActivating},
+		wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: ssid, State: wifi.ConnectionFailed},
+	)
+	return fmt.Errorf("attempt %d to connect to %s: %w", n, ssid, wifi.ErrIncorrectPassphrase)
 }
 
 func ago(duration time.Duration) *time.Time {
@@ -51,15 +174,16 @@ func New() (wifi.Backend, error) {
 		{SSID: "Unencrypted_Honeypot", Security: wifi.SecurityOpen, IsVisible: true},
 		{SSID: "YourWiFi.exe", LastConnected: ago(9 * time.Hour), Security: wifi.SecurityWPA},
 		{SSID: "I See Dead Packets", Security: wifi.SecurityWEP, LastConnected: ago(8763 * time.Hour)},
-		{SSID: "Dunder MiffLAN", Security: wifi.SecurityWPA, IsVisible: true},
+		{SSID: "Dunder MiffLAN", Security: wifi.SecuritySAE, IsVisible: true},
 		{SSID: "Police Surveillance 2", AccessPoints: []wifi.AccessPoint{{Strength: 48}}, Security: wifi.SecurityWPA, IsVisible: true},
 		{SSID: "I Believe Wi Can Fi", Security: wifi.SecurityWEP, IsVisible: true},
-		{SSID: "Hot singles in your area", Security: wifi.SecurityWPA, IsVisible: true},
-		{SSID: "TacoBoutAGoodSignal", AccessPoints: []wifi.AccessPoint{{Strength: 99}}, Security: wifi.SecurityWPA, IsVisible: true},
+		{SSID: "Hot singles in your area", Security: wifi.SecurityOWE, IsVisible: true},
+		{SSID: "TacoBoutAGoodSignal", AccessPoints: []wifi.AccessPoint{{Strength: 99}}, Security: wifi.SecurityWPA2WPA3, IsVisible: true},
 		{SSID: "Wi-Fight the Feeling?", Security: wifi.SecurityWEP},
 		{SSID: "xX_D4rkR0ut3r_Xx", Security: wifi.SecurityWPA},
 		{SSID: "Luke I am your WiFi", Security: wifi.SecurityWEP},
 		{SSID: "FreeHugsAndWiFi", LastConnected: ago(400 * time.Hour), Security: wifi.SecurityWPA},
+		{SSID: "Corporate Synergy", AccessPoints: []wifi.AccessPoint{{Strength: 62}}, Security: wifi.SecurityEnterprise, IsVisible: true},
 		// Multi-AP test
 		{SSID: "Mesh Network", IsVisible: true, IsKnown: true, Security: wifi.SecurityWPA, AccessPoints: []wifi.AccessPoint{
 			{BSSID: "AA:BB:CC:DD:EE:03", Strength: 95, Frequency: 5240},
@@ -107,6 +231,10 @@ func New() (wifi.Backend, error) {
 		ActiveNetworkIndex: -1, // No network active initially
 		ActionSleep:        DefaultActionSleep,
 		WirelessEnabled:    true,
+		Interfaces: []wifi.Device{
+			{Interface: "wlan0", HardwareAddress: "02:00:00:00:00:01", Selected: true},
+			{Interface: "wlan1", HardwareAddress: "02:00:00:00:00:02"},
+		},
 	}, nil
 }
 
@@ -129,12 +257,22 @@ func (m *MockBackend) setActiveNetwork(ssid string) {
 
 func (m *MockBackend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error) {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if !m.WirelessEnabled {
 		return wifi.NetworksResult{}, wifi.ErrWirelessDisabled
 	}
+	var scanErr error
+	if scan != wifi.ScanNever && len(m.ScanFailures) > 0 {
+		// A failed scan leaves the previous results in place.
+		scanErr = m.ScanFailures[0]
+		m.ScanFailures = m.ScanFailures[1:]
+		scan = wifi.ScanNever
+	}
 	// For mock, we can re-randomize strengths on each scan
 	if scan != wifi.ScanNever && !m.DisableRandomization {
+		before := copyNetworks(m.VisibleNetworks)
 		s := rand.NewSource(time.Now().Unix())
 		r := rand.New(s)
 		for i := range m.VisibleNetworks {
@@ -144,6 +282,10 @@ func (m *MockBackend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, err
 				}
 			}
 		}
+		m.emit(wifi.DiffNetworks(before, m.VisibleNetworks)...)
+	}
+	if scan != wifi.ScanNever {
+		m.emit(wifi.Event{Type: wifi.EventScanCompleted})
 	}
 
 	// Aggregate and prepare result
@@ -172,6 +314,12 @@ func (m *MockBackend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, err
 				networkToAdd.AutoConnect = knownNetwork.AutoConnect
 				networkToAdd.Security = knownNetwork.Security
 				networkToAdd.LastConnected = knownNetwork.LastConnected
+				networkToAdd.IPv4 = knownNetwork.IPv4
+				networkToAdd.IPv6 = knownNetwork.IPv6
+				networkToAdd.LockedBSSID = knownNetwork.LockedBSSID
+				networkToAdd.PreferredBand = knownNetwork.PreferredBand
+				networkToAdd.PreferredChannel = knownNetwork.PreferredChannel
+				networkToAdd.Priority = knownNetwork.Priority
 				break
 			}
 		}
@@ -196,11 +344,13 @@ func (m *MockBackend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, err
 		result = append(result, c)
 	}
 
-	return wifi.NetworksResult{Networks: result}, nil
+	return wifi.NetworksResult{Networks: result, ScanError: scanErr}, nil
 }
 
 func (m *MockBackend) ActivateNetwork(ssid string) error {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.ActivateError != nil {
 		return m.ActivateError
@@ -208,7 +358,11 @@ func (m *MockBackend) ActivateNetwork(ssid string) error {
 	// "Act on first match" logic for ambiguity.
 	for i, c := range m.KnownNetworks {
 		if c.SSID == ssid {
-			m.setActiveNetwork(ssid)
+			if err := m.attempt(ssid); err != nil {
+				return err
+			}
+			m.connect(ssid)
+			m.activeBSSID = c.LockedBSSID
 			now := time.Now()
 			m.KnownNetworks[i].LastConnected = &now
 			return nil
@@ -217,17 +371,62 @@ func (m *MockBackend) ActivateNetwork(ssid string) error {
 	return fmt.Errorf("cannot activate unknown network %s: %w", ssid, wifi.ErrNotFound)
 }
 
+// ActivateAccessPoint activates a known network on one of its visible access
+// points.
+func (m *MockBackend) ActivateAccessPoint(ssid, bssid string) error {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ActivateError != nil {
+		return m.ActivateError
+	}
+	bssid, err := wifi.ParseBSSID(bssid)
+	if err != nil {
+		return err
+	}
+	if !m.hasAccessPoint(ssid, bssid) {
+		return fmt.Errorf("access point %s of %s: %w", bssid, ssid, wifi.ErrNotFound)
+	}
+	for i, c := range m.KnownNetworks {
+		if c.SSID == ssid {
+			if err := m.attempt(ssid); err != nil {
+				return err
+			}
+			m.connect(ssid)
+			m.activeBSSID = bssid
+			now := time.Now()
+			m.KnownNetworks[i].LastConnected = &now
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot activate unknown network %s: %w", ssid, wifi.ErrNotFound)
+}
+
+func (m *MockBackend) hasAccessPoint(ssid, bssid string) bool {
+	for _, n := range m.VisibleNetworks {
+		if n.SSID != ssid {
+			continue
+		}
+		for _, ap := range n.AccessPoints {
+			if strings.EqualFold(ap.BSSID, bssid) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (m *MockBackend) ForgetNetwork(ssid string) error {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.ForgetError != nil {
 		return m.ForgetError
 	}
 
-	var activeSSID string
-	if m.ActiveNetworkIndex >= 0 && m.ActiveNetworkIndex < len(m.KnownNetworks) {
-		activeSSID = m.KnownNetworks[m.ActiveNetworkIndex].SSID
-	}
+	activeSSID := m.activeSSID()
 
 	var newKnownNetworks []mockNetwork
 	found := false
@@ -247,6 +446,7 @@ func (m *MockBackend) ForgetNetwork(ssid string) error {
 
 	if activeSSID == ssid {
 		m.setActiveNetwork("") // Deactivate all
+		m.emit(wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: ssid, State: wifi.ConnectionDeactivated})
 	} else {
 		m.setActiveNetwork(activeSSID) // Re-sync active network
 	}
@@ -254,12 +454,45 @@ func (m *MockBackend) ForgetNetwork(ssid string) error {
 	return nil
 }
 
-func (m *MockBackend) JoinNetwork(ssid string, password string, security wifi.SecurityType, isHidden bool) error {
+func (m *MockBackend) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.JoinError != nil {
 		return m.JoinError
 	}
+	if opts.Security.IsEnterprise() {
+		if err := opts.Enterprise.Validate(); err != nil {
+			return err
+		}
+	}
+	ipOpts := wifi.UpdateOptions{IPv4: opts.IPv4, IPv6: opts.IPv6}
+	if err := ipOpts.Validate(); err != nil {
+		return err
+	}
+	if opts.LockBSSID && opts.BSSID == "" {
+		return fmt.Errorf("cannot lock without a BSSID: %w", wifi.ErrInvalidBSSID)
+	}
+	var bssid string
+	if opts.BSSID != "" {
+		var err error
+		if bssid, err = wifi.ParseBSSID(opts.BSSID); err != nil {
+			return err
+		}
+		if !m.hasAccessPoint(ssid, bssid) {
+			return fmt.Errorf("access point %s of %s: %w", bssid, ssid, wifi.ErrNotFound)
+		}
+	}
+
+	if want, ok := m.Passphrases[ssid]; ok && !opts.SaveOnly && opts.Password != want {
+		return fmt.Errorf("joining %s: %w", ssid, wifi.ErrIncorrectPassphrase)
+	}
+	if !opts.SaveOnly {
+		if err := m.attempt(ssid); err != nil {
+			return err
+		}
+	}
 
 	var c wifi.Network
 	found := false
@@ -275,20 +508,27 @@ func (m *MockBackend) JoinNetwork(ssid string, password string, security wifi.Se
 	if !found {
 		c = wifi.Network{
 			SSID:     ssid,
-			Security: security,
-			IsHidden: isHidden,
+			Security: opts.Security,
+			IsHidden: opts.IsHidden,
 		}
 	}
 
 	c.IsKnown = true
 	c.AutoConnect = true
+	c.IPv4 = opts.IPv4
+	c.IPv6 = opts.IPv6
+	c.LockedBSSID = ""
+	if opts.LockBSSID {
+		c.LockedBSSID = bssid
+	}
 	if found {
 		m.VisibleNetworks[foundIndex] = c
 	}
 
 	newNetwork := mockNetwork{
-		Network: c,
-		Secret:  password,
+		Network:    c,
+		Secret:     opts.Password,
+		Enterprise: opts.Enterprise,
 	}
 
 	// Check if we are replacing an existing known connection, otherwise append.
@@ -304,7 +544,11 @@ func (m *MockBackend) JoinNetwork(ssid string, password string, security wifi.Se
 		m.KnownNetworks = append(m.KnownNetworks, newNetwork)
 	}
 
-	m.setActiveNetwork(ssid)
+	if opts.SaveOnly {
+		return nil
+	}
+	m.connect(ssid)
+	m.activeBSSID = bssid
 	now := time.Now()
 	if m.ActiveNetworkIndex != -1 {
 		m.KnownNetworks[m.ActiveNetworkIndex].LastConnected = &now
@@ -315,6 +559,8 @@ func (m *MockBackend) JoinNetwork(ssid string, password string, security wifi.Se
 
 func (m *MockBackend) GetSecrets(ssid string) (string, error) {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.GetSecretsError != nil {
 		return "", m.GetSecretsError
@@ -328,12 +574,75 @@ func (m *MockBackend) GetSecrets(ssid string) (string, error) {
 	return "", fmt.Errorf("no secrets for %s: %w", ssid, wifi.ErrNotFound)
 }
 
+// ActiveConnection returns made up addressing for the active network, using
+// its static IP configuration when it has one.
+func (m *MockBackend) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ssid := m.activeSSID()
+	if ssid == "" {
+		return nil, nil
+	}
+	details := &wifi.ConnectionDetails{
+		SSID:          ssid,
+		Bitrate:       866700,
+		Interface:     m.selectedInterface(),
+		IPv4Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.100/24")},
+		IPv4Gateway:   netip.MustParseAddr("192.168.1.1"),
+		IPv6Addresses: []netip.Prefix{netip.MustParsePrefix("fd00::100/64")},
+		DNS:           []netip.Addr{netip.MustParseAddr("192.168.1.1")},
+	}
+	known := m.KnownNetworks[m.ActiveNetworkIndex]
+	for _, n := range m.VisibleNetworks {
+		if n.SSID == ssid && len(n.AccessPoints) > 0 {
+			aps := append([]wifi.AccessPoint(nil), n.AccessPoints...)
+			wifi.SortAccessPoints(aps)
+			ap := aps[0]
+			// Prefer the chosen access point, then the strongest one on the
+			// preferred band.
+			for _, candidate := range aps {
+				if m.activeBSSID != "" {
+					if strings.EqualFold(candidate.BSSID, m.activeBSSID) {
+						ap = candidate
+						break
+					}
+				} else if known.PreferredBand != wifi.BandUnknown && wifi.FrequencyBand(candidate.Frequency) == known.PreferredBand {
+					ap = candidate
+					break
+				}
+			}
+			details.BSSID = ap.BSSID
+			details.Frequency = ap.Frequency
+			break
+		}
+	}
+	if c := known.IPv4; c != nil && c.Method == wifi.IPMethodManual {
+		details.IPv4Addresses = c.Addresses
+		details.IPv4Gateway = c.Gateway
+	}
+	if c := known.IPv6; c != nil && c.Method == wifi.IPMethodManual {
+		details.IPv6Addresses = c.Addresses
+		details.IPv6Gateway = c.Gateway
+	}
+	if c := known.IPv4; c != nil && len(c.DNS) > 0 {
+		details.DNS = c.DNS
+	}
+	return details, nil
+}
+
 func (m *MockBackend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.UpdateNetworkError != nil {
 		return m.UpdateNetworkError
 	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 	// "Act on first match" logic for ambiguity.
 	for i, c := range m.KnownNetworks {
 		if c.SSID == ssid {
@@ -343,6 +652,29 @@ func (m *MockBackend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error
 			if opts.AutoConnect != nil {
 				m.KnownNetworks[i].AutoConnect = *opts.AutoConnect
 			}
+			if opts.IPv4 != nil {
+				m.KnownNetworks[i].IPv4 = opts.IPv4
+			}
+			if opts.IPv6 != nil {
+				m.KnownNetworks[i].IPv6 = opts.IPv6
+			}
+			if opts.BSSID != nil {
+				// Validate has already checked the BSSID, so this only
+				// normalizes it.
+				m.KnownNetworks[i].LockedBSSID, _ = wifi.ParseBSSID(*opts.BSSID)
+			}
+			if opts.Band != nil {
+				m.KnownNetworks[i].PreferredBand = *opts.Band
+				if *opts.Band == wifi.BandUnknown {
+					m.KnownNetworks[i].PreferredChannel = 0
+				}
+			}
+			if opts.Channel != nil {
+				m.KnownNetworks[i].PreferredChannel = *opts.Channel
+			}
+			if opts.Priority != nil {
+				m.KnownNetworks[i].Priority = *opts.Priority
+			}
 			return nil
 		}
 	}
@@ -351,6 +683,8 @@ func (m *MockBackend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error
 
 func (m *MockBackend) IsWirelessEnabled() (bool, error) {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.IsWirelessEnabledError != nil {
 		return false, m.IsWirelessEnabledError
@@ -360,10 +694,125 @@ func (m *MockBackend) IsWirelessEnabled() (bool, error) {
 
 func (m *MockBackend) SetWireless(enabled bool) error {
 	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.SetWirelessError != nil {
 		return m.SetWirelessError
 	}
-	m.WirelessEnabled = enabled
+	if m.WirelessEnabled != enabled {
+		m.WirelessEnabled = enabled
+		m.emit(wifi.Event{Type: wifi.EventRadioToggled, RadioEnabled: enabled})
+	}
+	return nil
+}
+
+func (m *MockBackend) Connectivity() (wifi.Connectivity, error) {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.activeSSID() == "" {
+		return wifi.ConnectivityNone, nil
+	}
+	if m.ConnectivityState == "" {
+		return wifi.ConnectivityFull, nil
+	}
+	return m.ConnectivityState, nil
+}
+
+func (m *MockBackend) Devices() ([]wifi.Device, error) {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]wifi.Device(nil), m.Interfaces...), nil
+}
+
+func (m *MockBackend) SelectDevice(iface string) error {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.ContainsFunc(m.Interfaces, func(d wifi.Device) bool { return d.Interface == iface }) {
+		return fmt.Errorf("no wireless device %s found: %w", iface, wifi.ErrNotFound)
+	}
+	for i := range m.Interfaces {
+		m.Interfaces[i].Selected = m.Interfaces[i].Interface == iface
+	}
+	return nil
+}
+
+// selectedInterface returns the name of the selected device.
+func (m *MockBackend) selectedInterface() string {
+	if d, ok := wifi.SelectedDevice(m.Interfaces); ok {
+		return d.Interface
+	}
+	return "wlan0"
+}
+
+// StartHotspot replaces the station connection with a hotspot, like a real
+// device that can only be in one mode at a time.
+func (m *MockBackend) StartHotspot(config wifi.HotspotConfig) error {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.HotspotError != nil {
+		return m.HotspotError
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if !m.WirelessEnabled {
+		return wifi.ErrWirelessDisabled
+	}
+	if ssid := m.activeSSID(); ssid != "" {
+		m.setActiveNetwork("")
+		m.emit(wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: ssid, State: wifi.ConnectionDeactivated})
+	}
+	m.hotspot = &config
 	return nil
 }
+
+func (m *MockBackend) StopHotspot() error {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.HotspotError != nil {
+		return m.HotspotError
+	}
+	if m.hotspot == nil {
+		return fmt.Errorf("no hotspot is running: %w", wifi.ErrNotFound)
+	}
+	m.hotspot = nil
+	return nil
+}
+
+func (m *MockBackend) HotspotStatus() (wifi.HotspotStatus, error) {
+	time.Sleep(m.ActionSleep)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.HotspotError != nil {
+		return wifi.HotspotStatus{}, m.HotspotError
+	}
+	if m.hotspot == nil {
+		return wifi.HotspotStatus{}, nil
+	}
+	status := wifi.HotspotStatus{Active: true, HotspotConfig: *m.hotspot, Security: wifi.SecurityWPA, Interface: m.selectedInterface()}
+	if m.hotspot.Password == "" {
+		status.Security = wifi.SecurityOpen
+	}
+	return status, nil
+}
+
+func copyNetworks(networks []wifi.Network) []wifi.Network {
+	copied := make([]wifi.Network, len(networks))
+	for i, n := range networks {
+		copied[i] = n
+		copied[i].AccessPoints = append([]wifi.AccessPoint(nil), n.AccessPoints...)
+	}
+	return copied
+}
@@ -1,7 +1,11 @@
 package mock
 
 import (
+	"context"
+	"errors"
+	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/shazow/wifitui/wifi"
 )
@@ -143,7 +147,7 @@ func TestJoinNetwork(t *testing.T) {
 
 	newSSID := "new-network"
 	password := "password"
-	err := b.JoinNetwork(newSSID, password, wifi.SecurityWPA, false)
+	err := b.JoinNetwork(newSSID, wifi.JoinOptions{Password: password, Security: wifi.SecurityWPA})
 	if err != nil {
 		t.Fatalf("JoinNetwork() failed: %v", err)
 	}
@@ -196,7 +200,7 @@ func TestGetSecretsForKnownNetworkWithoutSecret(t *testing.T) {
 	b, _ := New()
 	ssid := "Unencrypted_Honeypot"
 
-	err := b.JoinNetwork(ssid, "", wifi.SecurityOpen, false)
+	err := b.JoinNetwork(ssid, wifi.JoinOptions{Security: wifi.SecurityOpen})
 	if err != nil {
 		t.Fatalf("JoinNetwork() failed: %v", err)
 	}
@@ -297,7 +301,7 @@ func TestJoinNetwork_UpdatePassword(t *testing.T) {
 	password := "password123"
 
 	// 1. Join the network for the first time
-	err := b.JoinNetwork(ssid, password, wifi.SecurityWPA, false)
+	err := b.JoinNetwork(ssid, wifi.JoinOptions{Password: password, Security: wifi.SecurityWPA})
 	if err != nil {
 		t.Fatalf("JoinNetwork() failed on first join: %v", err)
 	}
@@ -327,7 +331,7 @@ func TestJoinNetwork_UpdatePassword(t *testing.T) {
 
 	// 3. Join the same network again with a new password
 	newPassword := "newPassword456"
-	err = b.JoinNetwork(ssid, newPassword, wifi.SecurityWPA, false)
+	err = b.JoinNetwork(ssid, wifi.JoinOptions{Password: newPassword, Security: wifi.SecurityWPA})
 	if err != nil {
 		t.Fatalf("JoinNetwork() failed on second join: %v", err)
 	}
@@ -371,3 +375,303 @@ func TestJoinNetwork_UpdatePassword(t *testing.T) {
 func init() {
 	DefaultActionSleep = 0
 }
+
+func TestJoinNetwork_Enterprise(t *testing.T) {
+	b, _ := New()
+	b.(*MockBackend).ActionSleep = 0
+	ssid := "Corporate Synergy"
+
+	err := b.JoinNetwork(ssid, wifi.JoinOptions{
+		Security:   wifi.SecurityEnterprise,
+		Enterprise: &wifi.EnterpriseCredentials{EAPMethod: wifi.EAPMethodTLS, Identity: "alice"},
+	})
+	if !errors.Is(err, wifi.ErrInvalidCredentials) {
+		t.Fatalf("JoinNetwork() without client certificate error = %v, want ErrInvalidCredentials", err)
+	}
+
+	err = b.JoinNetwork(ssid, wifi.JoinOptions{
+		Password:   "secret",
+		Security:   wifi.SecurityEnterprise,
+		Enterprise: &wifi.EnterpriseCredentials{EAPMethod: wifi.EAPMethodPEAP, Identity: "alice"},
+	})
+	if err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	secret, err := b.GetSecrets(ssid)
+	if err != nil {
+		t.Fatalf("GetSecrets() failed: %v", err)
+	}
+	if secret != "secret" {
+		t.Errorf("GetSecrets() = %q, want %q", secret, "secret")
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	b, _ := New()
+	mock := b.(*MockBackend)
+	mock.ActionSleep = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := b.WatchEvents(ctx)
+	if err != nil {
+		t.Fatalf("WatchEvents() failed: %v", err)
+	}
+
+	if err := b.ActivateNetwork("HideYoKidsHideYoWiFi"); err != nil {
+		t.Fatalf("ActivateNetwork failed: %v", err)
+	}
+	if err := b.SetWireless(false); err != nil {
+		t.Fatalf("SetWireless failed: %v", err)
+	}
+
+	want := []wifi.Event{
+		{Type: wifi.EventConnectionStateChanged, SSID: "HideYoKidsHideYoWiFi", State: wifi.ConnectionActivating},
+		{Type: wifi.EventConnectionStateChanged, SSID: "HideYoKidsHideYoWiFi", State: wifi.ConnectionActivated},
+		{Type: wifi.EventRadioToggled, RadioEnabled: false},
+	}
+	for _, w := range want {
+		got := <-events
+		if got.Time.IsZero() {
+			t.Errorf("event %#v has no time", got)
+		}
+		got.Time = time.Time{}
+		if got != w {
+			t.Fatalf("WatchEvents() sent %#v, want %#v", got, w)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("WatchEvents() channel not closed after cancel")
+	}
+}
+
+func TestActiveConnection(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	mock := b.(*MockBackend)
+	mock.ActionSleep = 0
+
+	details, err := b.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() failed: %v", err)
+	}
+	if details != nil {
+		t.Fatalf("ActiveConnection() = %+v before connecting, want nil", details)
+	}
+
+	static := netip.MustParsePrefix("10.0.0.5/8")
+	ipv4 := &wifi.IPConfig{Method: wifi.IPMethodManual, Addresses: []netip.Prefix{static}}
+	if err := b.UpdateNetwork("Mesh Network", wifi.UpdateOptions{IPv4: ipv4}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if err := b.ActivateNetwork("Mesh Network"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	details, err = b.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() failed: %v", err)
+	}
+	if details == nil {
+		t.Fatal("ActiveConnection() = nil after connecting")
+	}
+	if details.SSID != "Mesh Network" || details.BSSID != "AA:BB:CC:DD:EE:03" || details.Frequency != 5240 {
+		t.Errorf("ActiveConnection() = %+v, want the strongest Mesh Network access point", details)
+	}
+	if len(details.IPv4Addresses) != 1 || details.IPv4Addresses[0] != static {
+		t.Errorf("ActiveConnection() IPv4Addresses = %v, want %v", details.IPv4Addresses, static)
+	}
+}
+
+func TestActivateAccessPoint(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	mock := b.(*MockBackend)
+	mock.ActionSleep = 0
+
+	if err := b.ActivateAccessPoint("Mesh Network", "aa:bb:cc:dd:ee:99"); !errors.Is(err, wifi.ErrNotFound) {
+		t.Fatalf("ActivateAccessPoint() with unknown BSSID error = %v, want ErrNotFound", err)
+	}
+	if err := b.ActivateAccessPoint("Mesh Network", "aa:bb:cc:dd:ee:02"); err != nil {
+		t.Fatalf("ActivateAccessPoint() failed: %v", err)
+	}
+	details, err := b.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() failed: %v", err)
+	}
+	if details == nil || details.BSSID != "AA:BB:CC:DD:EE:02" {
+		t.Errorf("ActiveConnection() = %+v, want access point AA:BB:CC:DD:EE:02", details)
+	}
+}
+
+func TestUpdateNetworkLocksAccessPoint(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	mock := b.(*MockBackend)
+	mock.ActionSleep = 0
+
+	lock := "aa:bb:cc:dd:ee:04"
+	if err := b.UpdateNetwork("Mesh Network", wifi.UpdateOptions{BSSID: &lock}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if n := findConnection(result.Networks, "Mesh Network"); n == nil || n.LockedBSSID != "AA:BB:CC:DD:EE:04" {
+		t.Fatalf("LockedBSSID = %+v, want AA:BB:CC:DD:EE:04", n)
+	}
+
+	// Activating a locked network uses the locked access point.
+	if err := b.ActivateNetwork("Mesh Network"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	if details, err := b.ActiveConnection(); err != nil || details.BSSID != "AA:BB:CC:DD:EE:04" {
+		t.Errorf("ActiveConnection() = %+v, %v, want access point AA:BB:CC:DD:EE:04", details, err)
+	}
+
+	unlock := ""
+	if err := b.UpdateNetwork("Mesh Network", wifi.UpdateOptions{BSSID: &unlock}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	result, _ = b.ListNetworks(wifi.ScanNever)
+	if n := findConnection(result.Networks, "Mesh Network"); n == nil || n.LockedBSSID != "" {
+		t.Errorf("LockedBSSID = %+v after unlocking, want empty", n)
+	}
+}
+
+func TestUpdateNetworkBandPreference(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	mock := b.(*MockBackend)
+	mock.ActionSleep = 0
+
+	band, channel := wifi.Band2GHz, 11
+	if err := b.UpdateNetwork("Mesh Network", wifi.UpdateOptions{Band: &band, Channel: &channel}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if n := findConnection(result.Networks, "Mesh Network"); n == nil || n.PreferredBand != band || n.PreferredChannel != channel {
+		t.Fatalf("preference = %+v, want 2.4GHz channel 11", n)
+	}
+
+	// The connection uses the strongest access point on the preferred band.
+	if err := b.ActivateNetwork("Mesh Network"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	if details, err := b.ActiveConnection(); err != nil || details.BSSID != "AA:BB:CC:DD:EE:01" {
+		t.Errorf("ActiveConnection() = %+v, %v, want access point AA:BB:CC:DD:EE:01", details, err)
+	}
+
+	anyBand := wifi.BandUnknown
+	if err := b.UpdateNetwork("Mesh Network", wifi.UpdateOptions{Band: &anyBand}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	result, _ = b.ListNetworks(wifi.ScanNever)
+	if n := findConnection(result.Networks, "Mesh Network"); n == nil || n.PreferredBand != wifi.BandUnknown || n.PreferredChannel != 0 {
+		t.Errorf("preference = %+v after clearing, want any", n)
+	}
+}
+
+func TestUpdateNetworkPriority(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	b.(*MockBackend).ActionSleep = 0
+
+	priority := 7
+	if err := b.UpdateNetwork("Mesh Network", wifi.UpdateOptions{Priority: &priority}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	result, _ := b.ListNetworks(wifi.ScanNever)
+	if n := findConnection(result.Networks, "Mesh Network"); n == nil || n.Priority != priority {
+		t.Errorf("priority = %+v, want %d", n, priority)
+	}
+}
+
+func TestHotspot(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	mock := b.(*MockBackend)
+	mock.ActionSleep = 0
+
+	if err := b.ActivateNetwork("Mesh Network"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	if err := b.StartHotspot(wifi.HotspotConfig{SSID: "Event", Password: "short"}); !errors.Is(err, wifi.ErrInvalidCredentials) {
+		t.Fatalf("StartHotspot() with a short passphrase = %v, want ErrInvalidCredentials", err)
+	}
+	config := wifi.HotspotConfig{SSID: "Event", Password: "correct horse", Band: wifi.Band5GHz}
+	if err := b.StartHotspot(config); err != nil {
+		t.Fatalf("StartHotspot() failed: %v", err)
+	}
+	status, err := b.HotspotStatus()
+	if err != nil {
+		t.Fatalf("HotspotStatus() failed: %v", err)
+	}
+	if !status.Active || status.HotspotConfig != config {
+		t.Errorf("HotspotStatus() = %+v, want the started hotspot", status)
+	}
+	// The device can't be a station at the same time.
+	if details, _ := b.ActiveConnection(); details != nil {
+		t.Errorf("ActiveConnection() = %+v while the hotspot is running, want nil", details)
+	}
+
+	if err := b.StopHotspot(); err != nil {
+		t.Fatalf("StopHotspot() failed: %v", err)
+	}
+	if status, _ := b.HotspotStatus(); status.Active {
+		t.Error("HotspotStatus() is still active after StopHotspot()")
+	}
+	if err := b.StopHotspot(); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("StopHotspot() without a hotspot = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	b, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	mock := b.(*MockBackend)
+	mock.ActionSleep = 0
+
+	if err := b.SelectDevice("wlan9"); !errors.Is(err, wifi.ErrNotFound) {
+		t.Fatalf("SelectDevice(wlan9) = %v, want ErrNotFound", err)
+	}
+	if err := b.SelectDevice("wlan1"); err != nil {
+		t.Fatalf("SelectDevice(wlan1) failed: %v", err)
+	}
+	devices, err := b.Devices()
+	if err != nil {
+		t.Fatalf("Devices() failed: %v", err)
+	}
+	if selected, ok := wifi.SelectedDevice(devices); !ok || selected.Interface != "wlan1" {
+		t.Fatalf("selected device = %+v, want wlan1", selected)
+	}
+
+	if err := b.ActivateNetwork("Mesh Network"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	details, err := b.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() failed: %v", err)
+	}
+	if details.Interface != "wlan1" {
+		t.Errorf("ActiveConnection().Interface = %q, want wlan1", details.Interface)
+	}
+}
@@ -0,0 +1,444 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// Scenario describes the networks a MockBackend starts with and a timeline of
+// changes to play back while it runs, for demos and for reproducing bug
+// reports. Scenarios are written in YAML or JSON:
+//
+//	networks:
+//	  - ssid: Home
+//	    security: wpa
+//	    secret: correct horse
+//	    known: true
+//	    fail_attempts: [1]
+//	    access_points:
+//	      - {bssid: "02:00:00:00:01:00", strength: 70, frequency: 2412}
+//	timeline:
+//	  - at: 5s
+//	    signal: {ssid: Home, bssid: "02:00:00:00:01:00", strength: 0}
+//	  - at: 10s
+//	    radio: false
+type Scenario struct {
+	// Wireless is whether the radio starts enabled, which it does by default.
+	Wireless *bool `yaml:"wireless"`
+	// Connectivity is reported while a network is active.
+	Connectivity wifi.Connectivity `yaml:"connectivity"`
+	// Devices are the names of the wireless interfaces, the first of which is
+	// selected. There is a single wlan0 by default.
+	Devices  []string          `yaml:"devices"`
+	Networks []ScenarioNetwork `yaml:"networks"`
+	Timeline []ScenarioStep    `yaml:"timeline"`
+}
+
+// ScenarioNetwork is a network of a Scenario. It is visible when it has access
+// points.
+type ScenarioNetwork struct {
+	SSID     string            `yaml:"ssid"`
+	Security wifi.SecurityType `yaml:"security"`
+	Hidden   bool              `yaml:"hidden"`
+	// Secret is the only passphrase the network accepts, and the saved one
+	// when it is known. Any passphrase is accepted when it is empty.
+	Secret      string `yaml:"secret"`
+	Known       bool   `yaml:"known"`
+	AutoConnect bool   `yaml:"autoconnect"`
+	// Active connects to the network from the start. It must be known.
+	Active bool `yaml:"active"`
+	// LastConnected is how long ago the network was last connected to.
+	LastConnected time.Duration         `yaml:"last_connected"`
+	AccessPoints  []ScenarioAccessPoint `yaml:"access_points"`
+	// FailAttempts are the connection attempts, counted from 1, that fail as
+	// if the passphrase was rejected.
+	FailAttempts []int `yaml:"fail_attempts"`
+}
+
+// ScenarioAccessPoint is an access point of a ScenarioNetwork.
+type ScenarioAccessPoint struct {
+	BSSID     string `yaml:"bssid"`
+	Strength  uint8  `yaml:"strength"`
+	Frequency uint   `yaml:"frequency"`
+}
+
+// ScenarioStep is a change that happens a while after the backend is created.
+// Exactly one of its actions is set.
+type ScenarioStep struct {
+	At time.Duration `yaml:"at"`
+
+	// Appear makes a network visible, or adds access points to it.
+	Appear *ScenarioNetwork `yaml:"appear"`
+	// Disappear removes all access points of an SSID.
+	Disappear string `yaml:"disappear"`
+	// Signal changes the strength of an access point, adding it if it is
+	// new. A strength of 0 removes it.
+	Signal *ScenarioSignal `yaml:"signal"`
+	// Disconnect drops the active connection.
+	Disconnect bool `yaml:"disconnect"`
+	// Radio turns the radio on or off, like a hardware kill switch.
+	Radio *bool `yaml:"radio"`
+	// Connectivity changes the reported connectivity.
+	Connectivity wifi.Connectivity `yaml:"connectivity"`
+	// ScanError fails the next scans.
+	ScanError *ScenarioScanError `yaml:"scan_error"`
+}
+
+// ScenarioSignal is the strength of an access point in a ScenarioStep.
+type ScenarioSignal struct {
+	SSID      string `yaml:"ssid"`
+	BSSID     string `yaml:"bssid"`
+	Strength  uint8  `yaml:"strength"`
+	Frequency uint   `yaml:"frequency"`
+}
+
+// ScenarioScanError describes scan failures in a ScenarioStep. Error is one of
+// device-unavailable, permission-denied, auth-required, timeout or protocol,
+// and Message stands in for the platform error.
+type ScenarioScanError struct {
+	Stage   wifi.ScanStage `yaml:"stage"`
+	Code    string         `yaml:"code"`
+	Error   string         `yaml:"error"`
+	Message string         `yaml:"message"`
+	// Count is how many scans fail, one by default.
+	Count int `yaml:"count"`
+}
+
+var scanErrors = map[string]error{
+	"device-unavailable": wifi.ErrScanDeviceUnavailable,
+	"permission-denied":  wifi.ErrScanPermissionDenied,
+	"auth-required":      wifi.ErrScanAuthRequired,
+	"timeout":            wifi.ErrScanTimeout,
+	"protocol":           wifi.ErrScanProtocol,
+}
+
+// LoadScenario reads a scenario from a YAML or JSON file.
+func LoadScenario(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ParseScenario(f)
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// ParseScenario reads a scenario in YAML or JSON and validates it.
+func ParseScenario(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate returns an error if the scenario cannot be played back.
+func (s *Scenario) Validate() error {
+	active := 0
+	for _, n := range s.Networks {
+		if err := n.validate(); err != nil {
+			return err
+		}
+		if n.Active {
+			if !n.Known {
+				return fmt.Errorf("network %q is active but not known", n.SSID)
+			}
+			active++
+		}
+	}
+	if active > 1 {
+		return fmt.Errorf("%d networks are active, want at most one", active)
+	}
+	for i, step := range s.Timeline {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("timeline step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (n ScenarioNetwork) validate() error {
+	if n.SSID == "" {
+		return errors.New("network has no ssid")
+	}
+	for _, ap := range n.AccessPoints {
+		if _, err := wifi.ParseBSSID(ap.BSSID); ap.BSSID != "" && err != nil {
+			return fmt.Errorf("network %q: %w", n.SSID, err)
+		}
+		if ap.Strength > 100 {
+			return fmt.Errorf("network %q: strength %d is above 100", n.SSID, ap.Strength)
+		}
+	}
+	for _, attempt := range n.FailAttempts {
+		if attempt < 1 {
+			return fmt.Errorf("network %q: attempt %d is not counted from 1", n.SSID, attempt)
+		}
+	}
+	return nil
+}
+
+func (step ScenarioStep) validate() error {
+	if step.At < 0 {
+		return fmt.Errorf("negative time %s", step.At)
+	}
+	actions := 0
+	if step.Appear != nil {
+		actions++
+		if err := step.Appear.validate(); err != nil {
+			return err
+		}
+		if step.Appear.Active {
+			return fmt.Errorf("network %q cannot appear active", step.Appear.SSID)
+		}
+	}
+	if step.Disappear != "" {
+		actions++
+	}
+	if step.Signal != nil {
+		actions++
+		if step.Signal.SSID == "" {
+			return errors.New("signal has no ssid")
+		}
+		if _, err := wifi.ParseBSSID(step.Signal.BSSID); err != nil {
+			return err
+		}
+		if step.Signal.Strength > 100 {
+			return fmt.Errorf("strength %d is above 100", step.Signal.Strength)
+		}
+	}
+	if step.Disconnect {
+		actions++
+	}
+	if step.Radio != nil {
+		actions++
+	}
+	if step.Connectivity != "" {
+		actions++
+	}
+	if e := step.ScanError; e != nil {
+		actions++
+		if _, ok := scanErrors[e.Error]; !ok {
+			return fmt.Errorf("unknown scan error %q", e.Error)
+		}
+		switch e.Stage {
+		case "", wifi.ScanStageSetup, wifi.ScanStageRequest, wifi.ScanStageCompletion:
+		default:
+			return fmt.Errorf("unknown scan stage %q", e.Stage)
+		}
+		if e.Count < 0 {
+			return fmt.Errorf("negative scan error count %d", e.Count)
+		}
+	}
+	if actions != 1 {
+		return fmt.Errorf("has %d actions, want exactly one", actions)
+	}
+	return nil
+}
+
+// NewFromScenario creates a mock.Backend with the networks of the scenario
+// and starts playing back its timeline. Signal strengths only change as the
+// timeline says.
+func NewFromScenario(s *Scenario) (wifi.Backend, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	m := &MockBackend{
+		ActiveNetworkIndex:   -1,
+		ActionSleep:          DefaultActionSleep,
+		WirelessEnabled:      s.Wireless == nil || *s.Wireless,
+		ConnectivityState:    s.Connectivity,
+		DisableRandomization: true,
+		Passphrases:          make(map[string]string),
+		FailAttempts:         make(map[string][]int),
+	}
+	devices := s.Devices
+	if len(devices) == 0 {
+		devices = []string{"wlan0"}
+	}
+	for i, name := range devices {
+		m.Interfaces = append(m.Interfaces, wifi.Device{
+			Interface:       name,
+			HardwareAddress: fmt.Sprintf("02:00:00:00:00:%02x", i+1),
+			Selected:        i == 0,
+		})
+	}
+	active := ""
+	for _, n := range s.Networks {
+		m.add(n)
+		if n.Active {
+			active = n.SSID
+		}
+	}
+	if active != "" {
+		m.setActiveNetwork(active)
+	}
+
+	if len(s.Timeline) > 0 {
+		go m.play(s.Timeline)
+	}
+	return m, nil
+}
+
+// add merges a scenario network into the visible and known networks.
+func (m *MockBackend) add(n ScenarioNetwork) {
+	if n.Secret != "" {
+		m.Passphrases[n.SSID] = n.Secret
+	}
+	if len(n.FailAttempts) > 0 {
+		m.FailAttempts[n.SSID] = n.FailAttempts
+	}
+	network := wifi.Network{
+		SSID:        n.SSID,
+		Security:    n.Security,
+		IsHidden:    n.Hidden,
+		IsKnown:     n.Known,
+		AutoConnect: n.AutoConnect,
+	}
+	if n.LastConnected > 0 {
+		network.LastConnected = ago(n.LastConnected)
+	}
+	if n.Known && !slices.ContainsFunc(m.KnownNetworks, func(k mockNetwork) bool { return k.SSID == n.SSID }) {
+		m.KnownNetworks = append(m.KnownNetworks, mockNetwork{Network: network, Secret: n.Secret})
+	}
+	if len(n.AccessPoints) == 0 {
+		return
+	}
+
+	i := slices.IndexFunc(m.VisibleNetworks, func(v wifi.Network) bool { return v.SSID == n.SSID })
+	if i < 0 {
+		network.IsVisible = true
+		network.IsActive = n.SSID == m.activeSSID()
+		m.VisibleNetworks = append(m.VisibleNetworks, network)
+		i = len(m.VisibleNetworks) - 1
+	}
+	for _, ap := range n.AccessPoints {
+		bssid, _ := wifi.ParseBSSID(ap.BSSID)
+		m.setAccessPoint(i, wifi.AccessPoint{SSID: n.SSID, BSSID: bssid, Strength: ap.Strength, Frequency: ap.Frequency})
+	}
+}
+
+// setAccessPoint adds or replaces an access point of the visible network at
+// index i.
+func (m *MockBackend) setAccessPoint(i int, ap wifi.AccessPoint) {
+	aps := m.VisibleNetworks[i].AccessPoints
+	if j := slices.IndexFunc(aps, func(a wifi.AccessPoint) bool { return ap.BSSID != "" && a.BSSID == ap.BSSID }); j >= 0 {
+		if ap.Frequency == 0 {
+			ap.Frequency = aps[j].Frequency
+		}
+		aps[j] = ap
+		return
+	}
+	m.VisibleNetworks[i].AccessPoints = append(aps, ap)
+}
+
+// play applies the steps of a timeline at their times, in order.
+func (m *MockBackend) play(timeline []ScenarioStep) {
+	start := time.Now()
+	steps := slices.Clone(timeline)
+	slices.SortStableFunc(steps, func(a, b ScenarioStep) int { return int(a.At - b.At) })
+	for _, step := range steps {
+		time.Sleep(time.Until(start.Add(step.At)))
+		m.apply(step)
+	}
+}
+
+// apply makes the change of a single timeline step and emits its events.
+func (m *MockBackend) apply(step ScenarioStep) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := m.activeSSID()
+	wasVisible := m.isVisible(previous)
+	before := copyNetworks(m.VisibleNetworks)
+	switch {
+	case step.Appear != nil:
+		m.add(*step.Appear)
+	case step.Disappear != "":
+		m.VisibleNetworks = slices.DeleteFunc(m.VisibleNetworks, func(n wifi.Network) bool { return n.SSID == step.Disappear })
+	case step.Signal != nil:
+		bssid, _ := wifi.ParseBSSID(step.Signal.BSSID)
+		i := slices.IndexFunc(m.VisibleNetworks, func(n wifi.Network) bool { return n.SSID == step.Signal.SSID })
+		switch {
+		case step.Signal.Strength > 0:
+			m.add(ScenarioNetwork{SSID: step.Signal.SSID, AccessPoints: []ScenarioAccessPoint{{
+				BSSID: bssid, Strength: step.Signal.Strength, Frequency: step.Signal.Frequency,
+			}}})
+		case i >= 0:
+			n := &m.VisibleNetworks[i]
+			n.AccessPoints = slices.DeleteFunc(n.AccessPoints, func(ap wifi.AccessPoint) bool { return ap.BSSID == bssid })
+			if len(n.AccessPoints) == 0 {
+				m.VisibleNetworks = slices.Delete(m.VisibleNetworks, i, i+1)
+			}
+		}
+	case step.Disconnect:
+		m.disconnect()
+	case step.Radio != nil:
+		if m.WirelessEnabled != *step.Radio {
+			m.WirelessEnabled = *step.Radio
+			if !m.WirelessEnabled {
+				m.disconnect()
+			}
+			m.emit(wifi.Event{Type: wifi.EventRadioToggled, RadioEnabled: m.WirelessEnabled})
+		}
+	case step.Connectivity != "":
+		m.ConnectivityState = step.Connectivity
+	case step.ScanError != nil:
+		e := step.ScanError
+		message := e.Message
+		if message == "" {
+			message = e.Error
+		}
+		failure := &wifi.ScanFailure{
+			Backend: "mock",
+			Stage:   e.Stage,
+			Device:  m.selectedInterface(),
+			Code:    e.Code,
+			Cause:   fmt.Errorf("%w: %s", scanErrors[e.Error], message),
+		}
+		for range max(e.Count, 1) {
+			m.ScanFailures = append(m.ScanFailures, failure)
+		}
+	}
+	// Losing the last access point of the active network drops it.
+	if wasVisible && !m.isVisible(previous) {
+		m.disconnect()
+	}
+
+	events := wifi.DiffNetworks(before, m.VisibleNetworks)
+	dropped := previous != "" && m.activeSSID() == ""
+	if dropped && !slices.ContainsFunc(events, func(e wifi.Event) bool {
+		return e.SSID == previous && e.State == wifi.ConnectionDeactivated
+	}) {
+		// Networks that were not visible, such as hidden ones, are not
+		// compared by DiffNetworks.
+		events = append(events, wifi.Event{Type: wifi.EventConnectionStateChanged, SSID: previous, State: wifi.ConnectionDeactivated})
+	}
+	m.emit(events...)
+}
+
+// disconnect drops the active connection. apply emits the events.
+func (m *MockBackend) disconnect() {
+	m.setActiveNetwork("")
+	m.activeBSSID = ""
+}
+
+// isVisible returns true if ssid has visible access points.
+func (m *MockBackend) isVisible(ssid string) bool {
+	return ssid != "" && slices.ContainsFunc(m.VisibleNetworks, func(n wifi.Network) bool { return n.SSID == ssid })
+}
@@ -0,0 +1,255 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+func newScenarioBackend(t *testing.T, text string) *MockBackend {
+	t.Helper()
+	s, err := ParseScenario(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseScenario() failed: %v", err)
+	}
+	b, err := NewFromScenario(s)
+	if err != nil {
+		t.Fatalf("NewFromScenario() failed: %v", err)
+	}
+	m := b.(*MockBackend)
+	m.ActionSleep = 0
+	return m
+}
+
+func TestLoadScenario(t *testing.T) {
+	s, err := LoadScenario("testdata/flaky.yaml")
+	if err != nil {
+		t.Fatalf("LoadScenario() failed: %v", err)
+	}
+	if len(s.Networks) != 3 || len(s.Timeline) != 7 {
+		t.Fatalf("loaded %d networks and %d steps", len(s.Networks), len(s.Timeline))
+	}
+	if s.Networks[0].Security != wifi.SecurityWPA2WPA3 || s.Networks[0].LastConnected != 30*time.Hour {
+		t.Errorf("unexpected first network: %+v", s.Networks[0])
+	}
+	if s.Timeline[3].At != 30*time.Second || s.Timeline[3].ScanError.Count != 2 {
+		t.Errorf("unexpected scan error step: %+v", s.Timeline[3])
+	}
+}
+
+func TestParseScenarioJSON(t *testing.T) {
+	m := newScenarioBackend(t, `{
+		"wireless": false,
+		"networks": [{"ssid": "Home", "security": "wpa", "known": true, "secret": "hunter2"}]
+	}`)
+	if m.WirelessEnabled {
+		t.Error("radio is enabled, want it disabled")
+	}
+	if secret, err := m.GetSecrets("Home"); err != nil || secret != "hunter2" {
+		t.Errorf("GetSecrets() = %q, %v", secret, err)
+	}
+}
+
+func TestParseScenarioInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":    "networks: [{ssid: Home, colour: blue}]",
+		"unknown security": "networks: [{ssid: Home, security: wpa4}]",
+		"missing ssid":     "networks: [{security: wpa}]",
+		"active unknown":   "networks: [{ssid: Home, active: true}]",
+		"bad bssid":        `networks: [{ssid: Home, access_points: [{bssid: "nope"}]}]`,
+		"two actions":      "timeline: [{at: 1s, disconnect: true, radio: false}]",
+		"no action":        "timeline: [{at: 1s}]",
+		"bad duration":     "timeline: [{at: soon, disconnect: true}]",
+		"unknown scan":     "timeline: [{scan_error: {error: gremlins}}]",
+		"zero attempt":     "networks: [{ssid: Home, fail_attempts: [0]}]",
+	}
+	for name, text := range tests {
+		if _, err := ParseScenario(strings.NewReader(text)); err == nil {
+			t.Errorf("%s: ParseScenario() returned nil error", name)
+		}
+	}
+}
+
+func TestScenarioNetworks(t *testing.T) {
+	m := newScenarioBackend(t, `
+devices: [wlan0, wlan1]
+networks:
+  - ssid: Home
+    security: wpa
+    known: true
+    active: true
+    secret: hunter2
+    access_points:
+      - {bssid: "02:00:00:00:01:01", strength: 80, frequency: 2412}
+      - {bssid: "02:00:00:00:01:02", strength: 40, frequency: 5180}
+  - ssid: Attic
+    security: wpa
+    hidden: true
+    known: true
+`)
+	result, err := m.ListNetworks(wifi.ScanAuto)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	home := findConnection(result.Networks, "Home")
+	if home == nil || !home.IsActive || !home.IsKnown || len(home.AccessPoints) != 2 || home.Strength() != 80 {
+		t.Errorf("unexpected Home network: %+v", home)
+	}
+	attic := findConnection(result.Networks, "Attic")
+	if attic == nil || attic.IsVisible || !attic.IsHidden {
+		t.Errorf("unexpected Attic network: %+v", attic)
+	}
+	devices, _ := m.Devices()
+	if len(devices) != 2 || !devices[0].Selected {
+		t.Errorf("unexpected devices: %+v", devices)
+	}
+}
+
+func TestScenarioPassphrase(t *testing.T) {
+	m := newScenarioBackend(t, `
+networks:
+  - ssid: Cafe
+    security: wpa
+    secret: espresso
+    fail_attempts: [2]
+    access_points: [{bssid: "02:00:00:00:01:01", strength: 60}]
+`)
+	err := m.JoinNetwork("Cafe", wifi.JoinOptions{Password: "latte", Security: wifi.SecurityWPA})
+	if !errors.Is(err, wifi.ErrIncorrectPassphrase) {
+		t.Fatalf("JoinNetwork() with the wrong passphrase = %v, want ErrIncorrectPassphrase", err)
+	}
+	if m.activeSSID() != "" || len(m.KnownNetworks) != 0 {
+		t.Fatal("a rejected join saved or activated the network")
+	}
+	if err := m.JoinNetwork("Cafe", wifi.JoinOptions{Password: "espresso", Security: wifi.SecurityWPA}); err != nil {
+		t.Fatalf("first JoinNetwork() attempt failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := m.WatchEvents(ctx)
+	if err := m.ActivateNetwork("Cafe"); !errors.Is(err, wifi.ErrIncorrectPassphrase) {
+		t.Fatalf("second attempt = %v, want ErrIncorrectPassphrase", err)
+	}
+	waitForState(t, events, "Cafe", wifi.ConnectionFailed)
+	if err := m.ActivateNetwork("Cafe"); err != nil {
+		t.Fatalf("third attempt failed: %v", err)
+	}
+}
+
+func TestScenarioTimeline(t *testing.T) {
+	m := newScenarioBackend(t, `
+networks:
+  - ssid: Home
+    known: true
+    active: true
+    access_points: [{bssid: "02:00:00:00:01:01", strength: 80}]
+timeline:
+  - at: 10ms
+    appear:
+      ssid: Neighbor
+      access_points: [{bssid: "02:00:00:00:02:01", strength: 20}]
+  - at: 20ms
+    signal: {ssid: Home, bssid: "02:00:00:00:01:01", strength: 30}
+  - at: 30ms
+    signal: {ssid: Home, bssid: "02:00:00:00:01:01", strength: 0}
+  - at: 40ms
+    radio: false
+`)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := m.WatchEvents(ctx)
+
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventNetworkAppeared && e.SSID == "Neighbor"
+	})
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventSignalChanged && e.SSID == "Home" && e.Strength == 30
+	})
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventNetworkDisappeared && e.SSID == "Home"
+	})
+	waitForState(t, events, "Home", wifi.ConnectionDeactivated)
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventRadioToggled && !e.RadioEnabled
+	})
+	if _, err := m.ListNetworks(wifi.ScanNever); !errors.Is(err, wifi.ErrWirelessDisabled) {
+		t.Errorf("ListNetworks() = %v after the radio was killed, want ErrWirelessDisabled", err)
+	}
+}
+
+func TestScenarioScanError(t *testing.T) {
+	m := newScenarioBackend(t, `
+networks:
+  - ssid: Home
+    access_points: [{bssid: "02:00:00:00:01:01", strength: 80}]
+`)
+	m.apply(ScenarioStep{ScanError: &ScenarioScanError{
+		Stage: wifi.ScanStageRequest, Error: "permission-denied", Code: "NotAuthorized", Count: 2,
+	}})
+
+	for i := range 2 {
+		result, err := m.ListNetworks(wifi.ScanAuto)
+		if err != nil {
+			t.Fatalf("ListNetworks() failed: %v", err)
+		}
+		var failure *wifi.ScanFailure
+		if !errors.As(result.ScanError, &failure) || !errors.Is(result.ScanError, wifi.ErrScanPermissionDenied) {
+			t.Fatalf("scan %d: scan error = %v, want a permission ScanFailure", i+1, result.ScanError)
+		}
+		if failure.Stage != wifi.ScanStageRequest || failure.Code != "NotAuthorized" || failure.Device != "wlan0" {
+			t.Errorf("unexpected scan failure: %+v", failure)
+		}
+		if len(result.Networks) != 1 {
+			t.Errorf("failed scan returned %d networks, want the previous results", len(result.Networks))
+		}
+	}
+	if result, _ := m.ListNetworks(wifi.ScanAuto); result.ScanError != nil {
+		t.Errorf("third scan failed: %v", result.ScanError)
+	}
+	if result, _ := m.ListNetworks(wifi.ScanNever); result.ScanError != nil {
+		t.Errorf("cached listing has a scan error: %v", result.ScanError)
+	}
+}
+
+func TestScenarioDisconnectHiddenNetwork(t *testing.T) {
+	m := newScenarioBackend(t, `
+networks:
+  - {ssid: Attic, hidden: true, known: true, active: true}
+`)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := m.WatchEvents(ctx)
+
+	m.apply(ScenarioStep{Disconnect: true})
+	waitForState(t, events, "Attic", wifi.ConnectionDeactivated)
+	if m.activeSSID() != "" {
+		t.Errorf("network %q is still active", m.activeSSID())
+	}
+}
+
+func waitForState(t *testing.T, events <-chan wifi.Event, ssid string, state wifi.ConnectionState) {
+	t.Helper()
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventConnectionStateChanged && e.SSID == ssid && e.State == state
+	})
+}
+
+func waitForEvent(t *testing.T, events <-chan wifi.Event, match func(wifi.Event) bool) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if match(e) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for event")
+		}
+	}
+}
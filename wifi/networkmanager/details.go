@@ -0,0 +1,93 @@
+//go:build linux
+
+package networkmanager
+
+import (
+	"net/netip"
+
+	gonetworkmanager "github.com/Wifx/gonetworkmanager/v3"
+	"github.com/shazow/wifitui/wifi"
+)
+
+// ActiveConnection returns live details of the wireless device's connection,
+// read from its active access point and IP configuration objects.
+func (b *Backend) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	device, err := b.getWirelessDevice()
+	if err != nil {
+		return nil, err
+	}
+	state, err := device.GetPropertyState()
+	if err != nil {
+		return nil, err
+	}
+	if state != gonetworkmanager.NmDeviceStateActivated {
+		return nil, nil
+	}
+	ap, err := device.GetPropertyActiveAccessPoint()
+	if err != nil {
+		return nil, err
+	}
+	if ap == nil {
+		return nil, nil
+	}
+
+	details := &wifi.ConnectionDetails{}
+	if info, err := accessPointInfo(ap); err == nil {
+		details.SSID = info.SSID
+		details.BSSID = info.BSSID
+		details.Frequency = info.Frequency
+	}
+	details.Bitrate, _ = device.GetPropertyBitrate()
+	details.Interface, _ = device.GetPropertyInterface()
+
+	// IP configuration is read best effort, since it is not available until
+	// the connection has been activated.
+	if ip4, err := device.GetPropertyIP4Config(); err == nil && ip4 != nil {
+		if addresses, err := ip4.GetPropertyAddressData(); err == nil {
+			for _, a := range addresses {
+				if prefix, ok := parsePrefix(a.Address, a.Prefix); ok {
+					details.IPv4Addresses = append(details.IPv4Addresses, prefix)
+				}
+			}
+		}
+		if gateway, err := ip4.GetPropertyGateway(); err == nil {
+			details.IPv4Gateway, _ = netip.ParseAddr(gateway)
+		}
+		if servers, err := ip4.GetPropertyNameserverData(); err == nil {
+			for _, server := range servers {
+				if addr, err := netip.ParseAddr(server.Address); err == nil {
+					details.DNS = append(details.DNS, addr)
+				}
+			}
+		}
+	}
+	if ip6, err := device.GetPropertyIP6Config(); err == nil && ip6 != nil {
+		if addresses, err := ip6.GetPropertyAddressData(); err == nil {
+			for _, a := range addresses {
+				if prefix, ok := parsePrefix(a.Address, a.Prefix); ok {
+					details.IPv6Addresses = append(details.IPv6Addresses, prefix)
+				}
+			}
+		}
+		if gateway, err := ip6.GetPropertyGateway(); err == nil {
+			details.IPv6Gateway, _ = netip.ParseAddr(gateway)
+		}
+		if servers, err := ip6.GetPropertyNameservers(); err == nil {
+			for _, server := range servers {
+				if addr, ok := netip.AddrFromSlice(server); ok {
+					details.DNS = append(details.DNS, addr)
+				}
+			}
+		}
+	}
+	return details, nil
+}
+
+func parsePrefix(address string, bits uint32) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	prefix := netip.PrefixFrom(addr, int(bits))
+	return prefix, prefix.IsValid()
+}
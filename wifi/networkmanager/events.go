@@ -0,0 +1,200 @@
+//go:build linux
+
+package networkmanager
+
+import (
+	"time"
+
+	gonetworkmanager "github.com/Wifx/gonetworkmanager/v3"
+	"github.com/godbus/dbus/v5"
+	"github.com/shazow/wifitui/wifi"
+)
+
+// eventTracker turns NetworkManager signals into wifi events. Signals only
+// carry object paths and changed properties, so it caches enough access point
+// state to name the network an event refers to.
+type eventTracker struct {
+	accessPoints map[dbus.ObjectPath]wifi.AccessPoint
+	// lookupAccessPoint reads a newly added access point.
+	lookupAccessPoint func(dbus.ObjectPath) (wifi.AccessPoint, error)
+	// activeSSID returns the SSID of the device's active access point.
+	activeSSID func() string
+
+	lastState      wifi.ConnectionState
+	lastActiveSSID string
+}
+
+func newEventTracker(device gonetworkmanager.DeviceWireless) *eventTracker {
+	t := &eventTracker{
+		accessPoints: make(map[dbus.ObjectPath]wifi.AccessPoint),
+		lookupAccessPoint: func(path dbus.ObjectPath) (wifi.AccessPoint, error) {
+			ap, err := gonetworkmanager.NewAccessPoint(path)
+			if err != nil {
+				return wifi.AccessPoint{}, err
+			}
+			return accessPointInfo(ap)
+		},
+		activeSSID: func() string {
+			ap, err := device.GetPropertyActiveAccessPoint()
+			if err != nil || ap == nil {
+				return ""
+			}
+			ssid, _ := ap.GetPropertySSID()
+			return ssid
+		},
+	}
+	if aps, err := device.GetAllAccessPoints(); err == nil {
+		for _, ap := range aps {
+			if info, err := accessPointInfo(ap); err == nil {
+				t.accessPoints[ap.GetPath()] = info
+			}
+		}
+	}
+	return t
+}
+
+func accessPointInfo(ap gonetworkmanager.AccessPoint) (wifi.AccessPoint, error) {
+	ssid, err := ap.GetPropertySSID()
+	if err != nil {
+		return wifi.AccessPoint{}, err
+	}
+	bssid, _ := ap.GetPropertyHWAddress()
+	strength, _ := ap.GetPropertyStrength()
+	frequency, _ := ap.GetPropertyFrequency()
+	return wifi.AccessPoint{SSID: ssid, BSSID: bssid, Strength: strength, Frequency: uint(frequency)}, nil
+}
+
+// hasSSID reports whether any cached access point broadcasts ssid.
+func (t *eventTracker) hasSSID(ssid string) bool {
+	for _, ap := range t.accessPoints {
+		if ap.SSID == ssid {
+			return true
+		}
+	}
+	return false
+}
+
+// handle returns the events described by sig, which must have passed
+// isNetworkChangeSignal.
+func (t *eventTracker) handle(sig *dbus.Signal) []wifi.Event {
+	now := time.Now()
+	switch sig.Name {
+	case nmWirelessDeviceInterface + ".AccessPointAdded":
+		path, ok := signalPath(sig)
+		if !ok {
+			return nil
+		}
+		ap, err := t.lookupAccessPoint(path)
+		if err != nil {
+			return nil
+		}
+		appeared := ap.SSID != "" && !t.hasSSID(ap.SSID)
+		t.accessPoints[path] = ap
+		if appeared {
+			return []wifi.Event{{Type: wifi.EventNetworkAppeared, Time: now, SSID: ap.SSID, BSSID: ap.BSSID, Strength: ap.Strength}}
+		}
+	case nmWirelessDeviceInterface + ".AccessPointRemoved":
+		path, ok := signalPath(sig)
+		if !ok {
+			return nil
+		}
+		ap, ok := t.accessPoints[path]
+		if !ok {
+			return nil
+		}
+		delete(t.accessPoints, path)
+		if ap.SSID != "" && !t.hasSSID(ap.SSID) {
+			return []wifi.Event{{Type: wifi.EventNetworkDisappeared, Time: now, SSID: ap.SSID}}
+		}
+	case nmDeviceInterface + ".StateChanged":
+		if len(sig.Body) == 0 {
+			return nil
+		}
+		newState, ok := sig.Body[0].(uint32)
+		if !ok {
+			return nil
+		}
+		state, ok := connectionStateFromDevice(gonetworkmanager.NmDeviceState(newState))
+		if !ok || state == t.lastState {
+			return nil
+		}
+		t.lastState = state
+		ssid := t.activeSSID()
+		if ssid == "" {
+			ssid = t.lastActiveSSID
+		}
+		t.lastActiveSSID = ssid
+		return []wifi.Event{{Type: wifi.EventConnectionStateChanged, Time: now, SSID: ssid, State: state}}
+	case dbusPropertiesInterface + ".PropertiesChanged":
+		if len(sig.Body) < 2 {
+			return nil
+		}
+		iface, _ := sig.Body[0].(string)
+		changed, ok := sig.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return nil
+		}
+		switch iface {
+		case nmWirelessAccessPointInterface:
+			ap, known := t.accessPoints[sig.Path]
+			variant, ok := changed["Strength"]
+			if !known || !ok {
+				return nil
+			}
+			strength, ok := variant.Value().(uint8)
+			if !ok || strength == ap.Strength {
+				return nil
+			}
+			ap.Strength = strength
+			t.accessPoints[sig.Path] = ap
+			return []wifi.Event{{Type: wifi.EventSignalChanged, Time: now, SSID: ap.SSID, BSSID: ap.BSSID, Strength: strength}}
+		case nmWirelessDeviceInterface:
+			if _, ok := changed["LastScan"]; ok {
+				return []wifi.Event{{Type: wifi.EventScanCompleted, Time: now}}
+			}
+		case nmInterface:
+			variant, ok := changed["WirelessEnabled"]
+			if !ok {
+				return nil
+			}
+			enabled, ok := variant.Value().(bool)
+			if !ok {
+				return nil
+			}
+			return []wifi.Event{{Type: wifi.EventRadioToggled, Time: now, RadioEnabled: enabled}}
+		}
+	}
+	return nil
+}
+
+func signalPath(sig *dbus.Signal) (dbus.ObjectPath, bool) {
+	if len(sig.Body) == 0 {
+		return "", false
+	}
+	path, ok := sig.Body[0].(dbus.ObjectPath)
+	return path, ok
+}
+
+// connectionStateFromDevice maps a wireless device state to the state of its
+// connection. Unmanaged and unavailable devices have no connection to report.
+func connectionStateFromDevice(state gonetworkmanager.NmDeviceState) (wifi.ConnectionState, bool) {
+	switch state {
+	case gonetworkmanager.NmDeviceStatePrepare,
+		gonetworkmanager.NmDeviceStateConfig,
+		gonetworkmanager.NmDeviceStateNeedAuth,
+		gonetworkmanager.NmDeviceStateIpConfig,
+		gonetworkmanager.NmDeviceStateIpCheck,
+		gonetworkmanager.NmDeviceStateSecondaries:
+		return wifi.ConnectionActivating, true
+	case gonetworkmanager.NmDeviceStateActivated:
+		return wifi.ConnectionActivated, true
+	case gonetworkmanager.NmDeviceStateDeactivating:
+		return wifi.ConnectionDeactivating, true
+	case gonetworkmanager.NmDeviceStateFailed:
+		return wifi.ConnectionFailed, true
+	case gonetworkmanager.NmDeviceStateDisconnected:
+		return wifi.ConnectionDeactivated, true
+	default:
+		return "", false
+	}
+}
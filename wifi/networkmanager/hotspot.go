@@ -0,0 +1,166 @@
+//go:build linux
+
+package networkmanager
+
+import (
+	"fmt"
+
+	gonetworkmanager "github.com/Wifx/gonetworkmanager/v3"
+	"github.com/google/uuid"
+	"github.com/shazow/wifitui/wifi"
+)
+
+// hotspotConnectionID names the profile created by StartHotspot, so a stale
+// one can be replaced and the profile removed again on stop.
+const hotspotConnectionID = "wifitui Hotspot"
+
+// hotspotSettings builds an access point profile that shares this machine's
+// other connections with its clients.
+func hotspotSettings(config wifi.HotspotConfig, deviceInterface string) (gonetworkmanager.ConnectionSettings, error) {
+	settings := gonetworkmanager.ConnectionSettings{
+		"connection": {
+			"id":             hotspotConnectionID,
+			"uuid":           uuid.New().String(),
+			"type":           "802-11-wireless",
+			"interface-name": deviceInterface,
+			"autoconnect":    false,
+		},
+		"802-11-wireless": {
+			"mode": "ap",
+			"ssid": []byte(config.SSID),
+		},
+		"ipv4": {"method": "shared"},
+		"ipv6": {"method": "ignore"},
+	}
+	if err := applyBandPreference(settings["802-11-wireless"], config.Band, nil); err != nil {
+		return nil, err
+	}
+	if config.Password != "" {
+		// Clients are more likely to support WPA2 with CCMP only than WPA3.
+		settings["802-11-wireless"]["security"] = "802-11-wireless-security"
+		settings["802-11-wireless-security"] = map[string]interface{}{
+			"key-mgmt": "wpa-psk",
+			"psk":      config.Password,
+			"proto":    []string{"rsn"},
+			"pairwise": []string{"ccmp"},
+			"group":    []string{"ccmp"},
+		}
+	}
+	return settings, nil
+}
+
+// StartHotspot adds an unsaved access point profile and activates it on the
+// wireless device, replacing any station connection.
+func (b *Backend) StartHotspot(config wifi.HotspotConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	device, err := b.getWirelessDevice()
+	if err != nil {
+		return err
+	}
+	deviceInterface, _ := device.GetPropertyInterface()
+	settings, err := hotspotSettings(config, deviceInterface)
+	if err != nil {
+		return err
+	}
+	if err := b.deleteHotspotProfiles(); err != nil {
+		return err
+	}
+
+	conn, err := b.Settings.AddConnectionUnsaved(settings)
+	if err != nil {
+		return fmt.Errorf("failed to add hotspot connection: %w", err)
+	}
+	activeConn, err := b.NM.ActivateConnection(conn, device, nil)
+	if err == nil {
+		err = waitForActiveConnection(activeConn)
+	}
+	if err != nil {
+		_ = conn.Delete()
+		return fmt.Errorf("failed to start hotspot: %w", err)
+	}
+	return nil
+}
+
+// StopHotspot deactivates the running access point. Profiles created by
+// StartHotspot are removed, others are left for the user to manage.
+func (b *Backend) StopHotspot() error {
+	activeConn, profile, err := b.activeHotspot()
+	if err != nil {
+		return err
+	}
+	if activeConn == nil {
+		return fmt.Errorf("no hotspot is running: %w", wifi.ErrNotFound)
+	}
+	if err := b.NM.DeactivateConnection(activeConn); err != nil {
+		return fmt.Errorf("failed to stop hotspot: %w", err)
+	}
+	if profile.id == hotspotConnectionID {
+		return profile.connection.Delete()
+	}
+	return nil
+}
+
+// HotspotStatus reports the active access point profile, including its
+// passphrase when the user may read secrets.
+func (b *Backend) HotspotStatus() (wifi.HotspotStatus, error) {
+	activeConn, profile, err := b.activeHotspot()
+	if err != nil || activeConn == nil {
+		return wifi.HotspotStatus{}, err
+	}
+	status := wifi.HotspotStatus{
+		Active:        true,
+		HotspotConfig: wifi.HotspotConfig{SSID: profile.ssid, Band: profile.band},
+		Security:      profile.security,
+	}
+	if device, err := b.getWirelessDevice(); err == nil {
+		status.Interface, _ = device.GetPropertyInterface()
+	}
+	if profile.security != wifi.SecurityOpen {
+		if secrets, err := profile.connection.GetSecrets("802-11-wireless-security"); err == nil {
+			status.Password, _ = secrets["802-11-wireless-security"]["psk"].(string)
+		}
+	}
+	return status, nil
+}
+
+// activeHotspot returns the active connection and profile of an access point,
+// or a nil connection if none is running.
+func (b *Backend) activeHotspot() (gonetworkmanager.ActiveConnection, savedProfile, error) {
+	activeConnections, err := b.NM.GetPropertyActiveConnections()
+	if err != nil {
+		return nil, savedProfile{}, err
+	}
+	for _, activeConn := range activeConnections {
+		if typ, err := activeConn.GetPropertyType(); err != nil || typ != "802-11-wireless" {
+			continue
+		}
+		conn, err := activeConn.GetPropertyConnection()
+		if err != nil {
+			continue
+		}
+		if profile, ok := parseSavedProfile(conn); ok && profile.mode == gonetworkmanager.Nm80211ModeAp {
+			return activeConn, profile, nil
+		}
+	}
+	return nil, savedProfile{}, nil
+}
+
+// deleteHotspotProfiles removes profiles left behind by an earlier
+// StartHotspot, so they don't pile up.
+func (b *Backend) deleteHotspotProfiles() error {
+	connections, err := b.Settings.ListConnections()
+	if err != nil {
+		return err
+	}
+	for _, conn := range connections {
+		profile, ok := parseSavedProfile(conn)
+		if ok && profile.id == hotspotConnectionID {
+			if err := conn.Delete(); err != nil {
+				return fmt.Errorf("failed to remove old hotspot connection: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+//go:build linux
+
+package networkmanager_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gonetworkmanager "github.com/Wifx/gonetworkmanager/v3"
+	"github.com/godbus/dbus/v5"
+
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/networkmanager"
+	"github.com/shazow/wifitui/wifi/networkmanager/nmtest"
+)
+
+// newBackend starts a fake NetworkManager with one wireless device and
+// returns a backend connected to it.
+func newBackend(t *testing.T) (*nmtest.Server, dbus.ObjectPath, wifi.Backend) {
+	t.Helper()
+	server := nmtest.New(t)
+	device := server.AddDevice("wlan0", "02:00:00:00:00:01")
+	backend, err := networkmanager.New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return server, device, backend
+}
+
+func findNetwork(t *testing.T, backend wifi.Backend, ssid string) wifi.Network {
+	t.Helper()
+	result, err := backend.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	for _, n := range result.Networks {
+		if n.SSID == ssid {
+			return n
+		}
+	}
+	t.Fatalf("network %q is not listed in %+v", ssid, result.Networks)
+	return wifi.Network{}
+}
+
+func TestIntegration_JoinNetwork(t *testing.T) {
+	server, device, backend := newBackend(t)
+	server.AddAccessPoint(device, nmtest.AccessPoint{
+		SSID:       "Home",
+		BSSID:      "02:00:00:00:01:00",
+		Strength:   70,
+		Frequency:  2412,
+		Passphrase: "correct horse",
+	})
+
+	network := findNetwork(t, backend, "Home")
+	if !network.IsVisible || network.IsKnown || network.Security != wifi.SecurityWPA {
+		t.Fatalf("unexpected network before joining: %+v", network)
+	}
+
+	err := backend.JoinNetwork("Home", wifi.JoinOptions{Password: "wrong passphrase", Security: wifi.SecurityWPA})
+	if !errors.Is(err, wifi.ErrIncorrectPassphrase) {
+		t.Fatalf("JoinNetwork() with a wrong passphrase = %v, want ErrIncorrectPassphrase", err)
+	}
+	if err := backend.JoinNetwork("Home", wifi.JoinOptions{Password: "correct horse", Security: wifi.SecurityWPA}); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+
+	network = findNetwork(t, backend, "Home")
+	if !network.IsKnown || !network.IsActive {
+		t.Errorf("joined network is not known and active: %+v", network)
+	}
+	details, err := backend.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() failed: %v", err)
+	}
+	if details.SSID != "Home" || details.BSSID != "02:00:00:00:01:00" || details.Interface != "wlan0" {
+		t.Errorf("unexpected connection details: %+v", details)
+	}
+	if len(details.IPv4Addresses) != 1 || !details.IPv4Gateway.IsValid() || len(details.DNS) == 0 {
+		t.Errorf("connection details lack addressing: %+v", details)
+	}
+
+	secret, err := backend.GetSecrets("Home")
+	if err != nil || secret != "correct horse" {
+		t.Errorf("GetSecrets() = %q, %v, want the joined passphrase", secret, err)
+	}
+	autoConnect := false
+	if err := backend.UpdateNetwork("Home", wifi.UpdateOptions{AutoConnect: &autoConnect}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	saved := server.SavedConnections()
+	if len(saved) != 1 {
+		t.Fatalf("saved %d connections, want 1", len(saved))
+	}
+	if got := saved[0]["802-11-wireless-security"]["psk"]; got != "correct horse" {
+		t.Errorf("UpdateNetwork() lost the passphrase, psk = %v", got)
+	}
+	if got := saved[0]["connection"]["autoconnect"]; got != false {
+		t.Errorf("autoconnect = %v after UpdateNetwork(), want false", got)
+	}
+
+	if err := backend.ForgetNetwork("Home"); err != nil {
+		t.Fatalf("ForgetNetwork() failed: %v", err)
+	}
+	if saved := server.SavedConnections(); len(saved) != 0 {
+		t.Errorf("ForgetNetwork() left %d saved connections", len(saved))
+	}
+	if network := findNetwork(t, backend, "Home"); network.IsKnown || network.IsActive {
+		t.Errorf("forgotten network is still known or active: %+v", network)
+	}
+}
+
+func TestIntegration_JoinHiddenNetwork(t *testing.T) {
+	server, device, backend := newBackend(t)
+	server.AddAccessPoint(device, nmtest.AccessPoint{
+		SSID:       "Secret",
+		BSSID:      "02:00:00:00:02:00",
+		Strength:   50,
+		Frequency:  5180,
+		Passphrase: "hunter2hunter2",
+		Hidden:     true,
+	})
+
+	err := backend.JoinNetwork("Secret", wifi.JoinOptions{Password: "hunter2hunter2", Security: wifi.SecurityWPA, IsHidden: true})
+	if err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	found := false
+	for _, ssids := range server.ScanRequests() {
+		if len(ssids) == 1 && ssids[0] == "Secret" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no scan for the hidden SSID in %q", server.ScanRequests())
+	}
+	if network := findNetwork(t, backend, "Secret"); !network.IsActive {
+		t.Errorf("hidden network is not active: %+v", network)
+	}
+}
+
+func TestIntegration_ActivateKnownNetwork(t *testing.T) {
+	server, device, backend := newBackend(t)
+	server.AddAccessPoint(device, nmtest.AccessPoint{
+		SSID: "Cafe", BSSID: "02:00:00:00:03:00", Strength: 40, Frequency: 2437,
+	})
+	server.AddConnection(gonetworkmanager.ConnectionSettings{
+		"connection":      {"id": "Cafe", "uuid": "5f0a5b4e-6e1c-4f57-9d0b-3c6e2a9a1f00", "type": "802-11-wireless", "autoconnect": true},
+		"802-11-wireless": {"mode": "infrastructure", "ssid": []byte("Cafe")},
+		"ipv4":            {"method": "auto"},
+		"ipv6":            {"method": "auto"},
+	})
+
+	if network := findNetwork(t, backend, "Cafe"); !network.IsKnown || network.IsActive || network.Security != wifi.SecurityOpen {
+		t.Fatalf("unexpected known network: %+v", network)
+	}
+	if err := backend.ActivateNetwork("Cafe"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	if network := findNetwork(t, backend, "Cafe"); !network.IsActive {
+		t.Errorf("activated network is not active: %+v", network)
+	}
+}
+
+func TestIntegration_Connectivity(t *testing.T) {
+	server, _, backend := newBackend(t)
+	server.SetConnectivity(gonetworkmanager.NmConnectivityPortal)
+	connectivity, err := backend.Connectivity()
+	if err != nil || connectivity != wifi.ConnectivityPortal {
+		t.Errorf("Connectivity() = %q, %v, want %q", connectivity, err, wifi.ConnectivityPortal)
+	}
+}
+
+func TestIntegration_SetWireless(t *testing.T) {
+	_, _, backend := newBackend(t)
+	if err := backend.SetWireless(false); err != nil {
+		t.Fatalf("SetWireless(false) failed: %v", err)
+	}
+	if enabled, err := backend.IsWirelessEnabled(); err != nil || enabled {
+		t.Errorf("IsWirelessEnabled() = %v, %v after disabling", enabled, err)
+	}
+	if err := backend.SetWireless(true); err != nil {
+		t.Fatalf("SetWireless(true) failed: %v", err)
+	}
+	if enabled, err := backend.IsWirelessEnabled(); err != nil || !enabled {
+		t.Errorf("IsWirelessEnabled() = %v, %v after enabling", enabled, err)
+	}
+}
+
+func TestIntegration_WatchEvents(t *testing.T) {
+	server, device, backend := newBackend(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := backend.WatchEvents(ctx)
+	if err != nil {
+		t.Fatalf("WatchEvents() failed: %v", err)
+	}
+
+	ap := server.AddAccessPoint(device, nmtest.AccessPoint{SSID: "Library", BSSID: "02:00:00:00:04:00", Strength: 30, Frequency: 2462})
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventNetworkAppeared && e.SSID == "Library"
+	})
+	server.SetStrength(ap, 80)
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventSignalChanged && e.SSID == "Library" && e.Strength == 80
+	})
+	if err := backend.JoinNetwork("Library", wifi.JoinOptions{Security: wifi.SecurityOpen}); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	waitForEvent(t, events, func(e wifi.Event) bool {
+		return e.Type == wifi.EventConnectionStateChanged && e.State == wifi.ConnectionActivated && e.SSID == "Library"
+	})
+}
+
+func waitForEvent(t *testing.T, events <-chan wifi.Event, match func(wifi.Event) bool) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("event channel closed")
+			}
+			if match(e) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for event")
+		}
+	}
+}
+
+func TestIntegration_Hotspot(t *testing.T) {
+	_, _, backend := newBackend(t)
+	config := wifi.HotspotConfig{SSID: "Share", Password: "sharing is caring"}
+	if err := backend.StartHotspot(config); err != nil {
+		t.Fatalf("StartHotspot() failed: %v", err)
+	}
+	status, err := backend.HotspotStatus()
+	if err != nil {
+		t.Fatalf("HotspotStatus() failed: %v", err)
+	}
+	if !status.Active || status.SSID != "Share" || status.Interface != "wlan0" {
+		t.Errorf("unexpected hotspot status: %+v", status)
+	}
+	if err := backend.StopHotspot(); err != nil {
+		t.Fatalf("StopHotspot() failed: %v", err)
+	}
+	if status, err := backend.HotspotStatus(); err != nil || status.Active {
+		t.Errorf("HotspotStatus() = %+v, %v after stopping", status, err)
+	}
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package networkmanager
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/shazow/wifitui/wifi"
+)
+
+// ipSettingName returns the settings section for an IP family.
+func ipSettingName(ipv6 bool) string {
+	if ipv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// applyIPConfig replaces the addressing in an ipv4 or ipv6 settings section.
+// Only the modern address-data property is written; the legacy addresses
+// property carries the same data and would otherwise take part in the D-Bus
+// type mismatch described in applyUpdateWorkaround.
+func applyIPConfig(section map[string]interface{}, c *wifi.IPConfig, ipv6 bool) {
+	for _, key := range []string{"addresses", "address-data", "gateway", "dns", "dns-search", "ignore-auto-dns"} {
+		delete(section, key)
+	}
+	section["method"] = string(c.Method)
+
+	if len(c.Addresses) > 0 {
+		addressData := make([]map[string]dbus.Variant, 0, len(c.Addresses))
+		for _, prefix := range c.Addresses {
+			addressData = append(addressData, map[string]dbus.Variant{
+				"address": dbus.MakeVariant(prefix.Addr().String()),
+				"prefix":  dbus.MakeVariant(uint32(prefix.Bits())),
+			})
+		}
+		section["address-data"] = addressData
+	}
+	if c.Gateway.IsValid() {
+		section["gateway"] = c.Gateway.String()
+	}
+	if len(c.DNS) > 0 {
+		if ipv6 {
+			dns := make([][]byte, 0, len(c.DNS))
+			for _, addr := range c.DNS {
+				b := addr.As16()
+				dns = append(dns, b[:])
+			}
+			section["dns"] = dns
+		} else {
+			// IPv4 DNS servers are uint32s holding the address bytes in
+			// network order, so they are read back in host byte order.
+			dns := make([]uint32, 0, len(c.DNS))
+			for _, addr := range c.DNS {
+				b := addr.As4()
+				dns = append(dns, binary.NativeEndian.Uint32(b[:]))
+			}
+			section["dns"] = dns
+		}
+		if c.Method == wifi.IPMethodAuto {
+			section["ignore-auto-dns"] = true
+		}
+	}
+	if len(c.DNSSearch) > 0 {
+		section["dns-search"] = c.DNSSearch
+	}
+}
+
+// ipConfigFromSettings reads an ipv4 or ipv6 settings section. It returns nil
+// for methods that IPConfig cannot represent, such as shared or link-local.
+func ipConfigFromSettings(section map[string]interface{}) *wifi.IPConfig {
+	if section == nil {
+		return nil
+	}
+	c := &wifi.IPConfig{}
+	method, _ := section["method"].(string)
+	switch method {
+	case "auto", "dhcp", "":
+		c.Method = wifi.IPMethodAuto
+	case "manual":
+		c.Method = wifi.IPMethodManual
+	case "disabled", "ignore":
+		c.Method = wifi.IPMethodDisabled
+	default:
+		return nil
+	}
+
+	if addressData, ok := section["address-data"].([]map[string]dbus.Variant); ok {
+		for _, data := range addressData {
+			address, _ := data["address"].Value().(string)
+			bits, _ := data["prefix"].Value().(uint32)
+			addr, err := netip.ParseAddr(address)
+			if err != nil {
+				continue
+			}
+			c.Addresses = append(c.Addresses, netip.PrefixFrom(addr, int(bits)))
+		}
+	}
+	if gateway, ok := section["gateway"].(string); ok {
+		c.Gateway, _ = netip.ParseAddr(gateway)
+	}
+	switch dns := section["dns"].(type) {
+	case []uint32:
+		for _, v := range dns {
+			var b [4]byte
+			binary.NativeEndian.PutUint32(b[:], v)
+			c.DNS = append(c.DNS, netip.AddrFrom4(b))
+		}
+	case [][]byte:
+		for _, v := range dns {
+			if addr, ok := netip.AddrFromSlice(v); ok {
+				c.DNS = append(c.DNS, addr)
+			}
+		}
+	}
+	if search, ok := section["dns-search"].([]string); ok {
+		c.DNSSearch = search
+	}
+	return c
+}
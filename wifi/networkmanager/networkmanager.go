@@ -6,7 +6,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +30,9 @@ const (
 
 const (
 	dbusPropertiesInterface        = "org.freedesktop.DBus.Properties"
+	nmInterface                    = "org.freedesktop.NetworkManager"
+	nmObjectPath                   = "/org/freedesktop/NetworkManager"
+	nmDeviceInterface              = "org.freedesktop.NetworkManager.Device"
 	nmWirelessDeviceInterface      = "org.freedesktop.NetworkManager.Device.Wireless"
 	nmWirelessAccessPointInterface = "org.freedesktop.NetworkManager.AccessPoint"
 )
@@ -37,6 +42,9 @@ type Backend struct {
 	NM       gonetworkmanager.NetworkManager
 	Settings gonetworkmanager.Settings
 	Device   gonetworkmanager.DeviceWireless
+	// Interface restricts the backend to the device with this name. When
+	// empty, the first usable wireless device is picked.
+	Interface string
 
 	connections       map[networkKey]gonetworkmanager.Connection
 	accessPoints      map[networkKey]gonetworkmanager.AccessPoint
@@ -78,7 +86,13 @@ type savedProfile struct {
 	keyMgmt       gonetworkmanager.Nm80211APSec
 	lastConnected *time.Time
 	autoConnect   bool
+	priority      int
 	hidden        bool
+	ipv4          *wifi.IPConfig
+	ipv6          *wifi.IPConfig
+	bssid         string
+	band          wifi.Band
+	channel       int
 }
 
 // New creates a new dbus.Backend.
@@ -142,36 +156,106 @@ func (b *Backend) getWirelessDevice() (gonetworkmanager.DeviceWireless, error) {
 		return b.Device, nil
 	}
 
-	devices, err := b.NM.GetDevices()
+	devices, err := b.wirelessDevices()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, device := range devices {
-		if dev, ok := device.(gonetworkmanager.DeviceWireless); ok {
-			// In hwsim and other multi-radio setups, NetworkManager can report AP-side
-			// radios that are intentionally unmanaged or not yet usable for client
-			// scans. Prefer a managed station radio instead of caching the first
-			// wireless device and failing later with "Scanning not allowed while
-			// unavailable".
-			managed, err := dev.GetPropertyManaged()
-			if err == nil && !managed {
+	for _, dev := range devices {
+		if b.Interface != "" {
+			if name, err := dev.GetPropertyInterface(); err != nil || name != b.Interface {
 				continue
 			}
-
-			state, err := dev.GetPropertyState()
-			if err == nil && (state == gonetworkmanager.NmDeviceStateUnmanaged || state == gonetworkmanager.NmDeviceStateUnavailable) {
-				continue
+		}
+		if !isUsableDevice(dev) {
+			if b.Interface != "" {
+				return nil, fmt.Errorf("%s is not managed by NetworkManager: %w", b.Interface, wifi.ErrNotAvailable)
 			}
-
-			b.Device = dev
-			return dev, nil
+			continue
 		}
+		b.Device = dev
+		return dev, nil
 	}
 
+	if b.Interface != "" {
+		return nil, fmt.Errorf("no wireless device %s found: %w", b.Interface, wifi.ErrNotFound)
+	}
 	return nil, fmt.Errorf("no wireless device found: %w", wifi.ErrNotFound)
 }
 
+// wirelessDevices returns all wireless devices known to NetworkManager.
+func (b *Backend) wirelessDevices() ([]gonetworkmanager.DeviceWireless, error) {
+	devices, err := b.NM.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+	var wireless []gonetworkmanager.DeviceWireless
+	for _, device := range devices {
+		if dev, ok := device.(gonetworkmanager.DeviceWireless); ok {
+			wireless = append(wireless, dev)
+		}
+	}
+	return wireless, nil
+}
+
+// isUsableDevice reports whether a device can be used as a station.
+//
+// In hwsim and other multi-radio setups, NetworkManager can report AP-side
+// radios that are intentionally unmanaged or not yet usable for client
+// scans. Prefer a managed station radio instead of caching the first
+// wireless device and failing later with "Scanning not allowed while
+// unavailable".
+func isUsableDevice(dev gonetworkmanager.DeviceWireless) bool {
+	managed, err := dev.GetPropertyManaged()
+	if err == nil && !managed {
+		return false
+	}
+	state, err := dev.GetPropertyState()
+	if err == nil && (state == gonetworkmanager.NmDeviceStateUnmanaged || state == gonetworkmanager.NmDeviceStateUnavailable) {
+		return false
+	}
+	return true
+}
+
+// Devices lists the usable wireless devices.
+func (b *Backend) Devices() ([]wifi.Device, error) {
+	devices, err := b.wirelessDevices()
+	if err != nil {
+		return nil, err
+	}
+	var selectedPath dbus.ObjectPath
+	if selected, err := b.getWirelessDevice(); err == nil {
+		selectedPath = selected.GetPath()
+	}
+	var result []wifi.Device
+	for _, dev := range devices {
+		if !isUsableDevice(dev) {
+			continue
+		}
+		d := wifi.Device{Selected: dev.GetPath() == selectedPath}
+		d.Interface, _ = dev.GetPropertyInterface()
+		d.HardwareAddress, _ = dev.GetPropertyHwAddress()
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+// SelectDevice switches to another wireless device. Cached networks belong
+// to the previous device, so they are dropped.
+func (b *Backend) SelectDevice(iface string) error {
+	previous, previousDevice := b.Interface, b.Device
+	b.Interface, b.Device = iface, nil
+	if _, err := b.getWirelessDevice(); err != nil {
+		b.Interface, b.Device = previous, previousDevice
+		return err
+	}
+	b.connections = make(map[networkKey]gonetworkmanager.Connection)
+	b.accessPoints = make(map[networkKey]gonetworkmanager.AccessPoint)
+	b.networkKeysBySSID = nil
+	b.lastScanAttempt = time.Time{}
+	return nil
+}
+
 func (b *Backend) scanAndWait(device gonetworkmanager.DeviceWireless) error {
 	return b.scanAndWaitWithOptions(device, nil)
 }
@@ -383,9 +467,9 @@ func (b *Backend) scanHiddenSSID(device gonetworkmanager.DeviceWireless, ssid st
 	return b.scanWithOptions(device, true, "ssid:"+ssid, hiddenSSIDScanOptions(ssid))
 }
 
-// WatchNetworkChanges returns a channel that receives a value when NetworkManager
-// reports wireless device or access point changes.
-func (b *Backend) WatchNetworkChanges(ctx context.Context) (<-chan struct{}, error) {
+// WatchEvents streams NetworkManager signals for the wireless device as
+// typed events.
+func (b *Backend) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -414,12 +498,13 @@ func (b *Backend) WatchNetworkChanges(ctx context.Context) (<-chan struct{}, err
 		addedRules = append(addedRules, rule)
 	}
 
+	tracker := newEventTracker(device)
 	signals := make(chan *dbus.Signal, 16)
-	changes := make(chan struct{}, 1)
+	events := make(chan wifi.Event, 16)
 	conn.Signal(signals)
 
 	go func() {
-		defer close(changes)
+		defer close(events)
 		defer conn.RemoveSignal(signals)
 		defer func() {
 			for _, rule := range addedRules {
@@ -438,15 +523,18 @@ func (b *Backend) WatchNetworkChanges(ctx context.Context) (<-chan struct{}, err
 				if !isNetworkChangeSignal(sig, devicePath) {
 					continue
 				}
-				select {
-				case changes <- struct{}{}:
-				default:
+				for _, event := range tracker.handle(sig) {
+					select {
+					case events <- event:
+					case <-ctx.Done():
+						return
+					}
 				}
 			}
 		}
 	}()
 
-	return changes, nil
+	return events, nil
 }
 
 func networkChangeMatchRules(devicePath dbus.ObjectPath) []string {
@@ -456,6 +544,8 @@ func networkChangeMatchRules(devicePath dbus.ObjectPath) []string {
 		fmt.Sprintf("type='signal',interface='%s',member='AccessPointAdded',path='%s'", nmWirelessDeviceInterface, path),
 		fmt.Sprintf("type='signal',interface='%s',member='AccessPointRemoved',path='%s'", nmWirelessDeviceInterface, path),
 		fmt.Sprintf("type='signal',interface='%s',member='PropertiesChanged',arg0='%s'", dbusPropertiesInterface, nmWirelessAccessPointInterface),
+		fmt.Sprintf("type='signal',interface='%s',member='PropertiesChanged',path='%s',arg0='%s'", dbusPropertiesInterface, nmObjectPath, nmInterface),
+		fmt.Sprintf("type='signal',interface='%s',member='StateChanged',path='%s'", nmDeviceInterface, path),
 	}
 }
 
@@ -473,12 +563,16 @@ func isNetworkChangeSignal(sig *dbus.Signal, devicePath dbus.ObjectPath) bool {
 		if !ok {
 			return false
 		}
-		if iface == nmWirelessDeviceInterface {
+		switch iface {
+		case nmWirelessDeviceInterface:
 			return sig.Path == devicePath
+		case nmInterface:
+			return sig.Path == nmObjectPath
 		}
 		return iface == nmWirelessAccessPointInterface
 	case "org.freedesktop.NetworkManager.Device.Wireless.AccessPointAdded",
-		"org.freedesktop.NetworkManager.Device.Wireless.AccessPointRemoved":
+		"org.freedesktop.NetworkManager.Device.Wireless.AccessPointRemoved",
+		"org.freedesktop.NetworkManager.Device.StateChanged":
 		return sig.Path == devicePath
 	default:
 		return false
@@ -549,8 +643,34 @@ func (b *Backend) scanWithOptions(device gonetworkmanager.DeviceWireless, force
 	}
 }
 
+// keyMgmtEAPSuiteB192 is NM_802_11_AP_SEC_KEY_MGMT_EAP_SUITE_B_192, which
+// gonetworkmanager does not define.
+const keyMgmtEAPSuiteB192 gonetworkmanager.Nm80211APSec = 0x2000
+
 func securityFromAccessPoint(flags, wpaFlags, rsnFlags uint32) (wifi.SecurityType, bool) {
 	isSecure := (flags&uint32(gonetworkmanager.Nm80211APFlagsPrivacy) != 0) || (wpaFlags > 0) || (rsnFlags > 0)
+	keyMgmt := wpaFlags | rsnFlags
+	has := func(flag gonetworkmanager.Nm80211APSec) bool { return keyMgmt&uint32(flag) != 0 }
+	psk := has(gonetworkmanager.Nm80211APSecKeyMgmtPSK)
+	sae := has(gonetworkmanager.Nm80211APSecKeyMgmtSAE)
+	switch {
+	case psk && sae:
+		return wifi.SecurityWPA2WPA3, isSecure
+	case sae:
+		return wifi.SecuritySAE, isSecure
+	case psk:
+		return wifi.SecurityWPA, isSecure
+	case has(keyMgmtEAPSuiteB192):
+		return wifi.SecurityEnterpriseWPA3, isSecure
+	case has(gonetworkmanager.Nm80211APSecKeyMgmt8021X):
+		return wifi.SecurityEnterprise, isSecure
+	case has(gonetworkmanager.Nm80211APSecKeyMgmtOWE):
+		return wifi.SecurityOWE, true
+	case keyMgmt == uint32(gonetworkmanager.Nm80211APSecKeyMgmtOWETM):
+		// The open half of an OWE transition pair is still joined as an
+		// open network, the system switches to OWE on its own.
+		return wifi.SecurityOpen, false
+	}
 	if wpaFlags > 0 || rsnFlags > 0 {
 		return wifi.SecurityWPA, isSecure
 	}
@@ -577,6 +697,15 @@ func securityFromSettings(settings gonetworkmanager.ConnectionSettings) wifi.Sec
 	switch {
 	case keyMgmt == "none":
 		return wifi.SecurityWEP
+	case keyMgmt == "sae":
+		return wifi.SecuritySAE
+	case keyMgmt == "owe":
+		return wifi.SecurityOWE
+	case keyMgmt == "wpa-eap-suite-b-192":
+		return wifi.SecurityEnterpriseWPA3
+	case strings.HasPrefix(keyMgmt, "wpa-eap"),
+		keyMgmt == "ieee8021x":
+		return wifi.SecurityEnterprise
 	case strings.Contains(keyMgmt, "wpa"),
 		strings.Contains(keyMgmt, "sae"),
 		strings.Contains(keyMgmt, "802.1x"):
@@ -626,6 +755,8 @@ func keyManagementFromSettings(settings gonetworkmanager.ConnectionSettings) gon
 		return gonetworkmanager.Nm80211APSecKeyMgmtPSK
 	case keyMgmt == "sae":
 		return gonetworkmanager.Nm80211APSecKeyMgmtSAE
+	case keyMgmt == "wpa-eap-suite-b-192":
+		return keyMgmtEAPSuiteB192
 	case strings.HasPrefix(keyMgmt, "wpa-eap"),
 		keyMgmt == "802.1x",
 		keyMgmt == "ieee8021x":
@@ -679,9 +810,18 @@ func parseSavedProfile(conn gonetworkmanager.Connection) (savedProfile, bool) {
 	if autoConnect, ok := connectionSettings["autoconnect"].(bool); ok {
 		profile.autoConnect = autoConnect
 	}
+	if priority, ok := connectionSettings["autoconnect-priority"].(int32); ok {
+		profile.priority = int(priority)
+	}
 	if hidden, ok := wireless["hidden"].(bool); ok {
 		profile.hidden = hidden
 	}
+	profile.ipv4 = ipConfigFromSettings(settings["ipv4"])
+	profile.ipv6 = ipConfigFromSettings(settings["ipv6"])
+	if bssid, ok := wireless["bssid"].([]byte); ok && len(bssid) == 6 {
+		profile.bssid = strings.ToUpper(net.HardwareAddr(bssid).String())
+	}
+	profile.band, profile.channel = bandFromSettings(wireless)
 	return profile, true
 }
 
@@ -695,13 +835,14 @@ func findCompatibleProfile(profiles []savedProfile, key networkKey) (savedProfil
 }
 
 func profileMatchesNetwork(profile savedProfile, key networkKey) bool {
-	if profile.ssid != key.ssid || profile.security != key.security {
+	if profile.ssid != key.ssid || !securityCompatible(profile.security, key.security) {
 		return false
 	}
 	if profile.mode != gonetworkmanager.Nm80211ModeUnknown && uint32(profile.mode) != key.mode {
 		return false
 	}
-	if profile.security != wifi.SecurityWPA {
+	switch profile.security {
+	case wifi.SecurityOpen, wifi.SecurityWEP, wifi.SecurityUnknown:
 		return true
 	}
 
@@ -712,6 +853,16 @@ func profileMatchesNetwork(profile savedProfile, key networkKey) bool {
 	return keyMgmt&(key.wpaFlags|key.rsnFlags) != 0
 }
 
+// securityCompatible returns true if a profile saved with the given security
+// can be used for a network advertising the other. Transition mode networks
+// accept both WPA2 (PSK) and WPA3 (SAE) profiles.
+func securityCompatible(profile, network wifi.SecurityType) bool {
+	if profile == network {
+		return true
+	}
+	return network == wifi.SecurityWPA2WPA3 && (profile == wifi.SecurityWPA || profile == wifi.SecuritySAE)
+}
+
 func addNetworkKey(keys map[string][]networkKey, key networkKey) {
 	for _, existing := range keys[key.ssid] {
 		if existing == key {
@@ -763,7 +914,8 @@ func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error)
 	var knownProfiles []savedProfile
 	for _, knownConn := range knownConnections {
 		profile, ok := parseSavedProfile(knownConn)
-		if !ok {
+		// Hotspot profiles are not networks to join.
+		if !ok || profile.mode == gonetworkmanager.Nm80211ModeAp {
 			continue
 		}
 		knownProfiles = append(knownProfiles, profile)
@@ -794,8 +946,15 @@ func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error)
 
 	applyProfile := func(conn *wifi.Network, profile savedProfile) {
 		conn.IsKnown = true
+		conn.SavedSecurity = profile.security
 		conn.LastConnected = profile.lastConnected
 		conn.AutoConnect = profile.autoConnect
+		conn.Priority = profile.priority
+		conn.IPv4 = profile.ipv4
+		conn.IPv6 = profile.ipv6
+		conn.LockedBSSID = profile.bssid
+		conn.PreferredBand = profile.band
+		conn.PreferredChannel = profile.channel
 		if activeConnectionPath != "" {
 			conn.IsActive = profile.path == activeConnectionPath
 		} else if activeConnectionID != "" {
@@ -902,12 +1061,19 @@ func (b *Backend) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error)
 		newConnections[key] = profile.connection
 		addNetworkKey(newNetworkKeysBySSID, key)
 		conns = append(conns, wifi.Network{
-			SSID:          profile.ssid,
-			IsKnown:       true,
-			IsHidden:      profile.hidden,
-			Security:      profile.security,
-			LastConnected: profile.lastConnected,
-			AutoConnect:   profile.autoConnect,
+			SSID:             profile.ssid,
+			IsKnown:          true,
+			IsHidden:         profile.hidden,
+			Security:         profile.security,
+			SavedSecurity:    profile.security,
+			LastConnected:    profile.lastConnected,
+			AutoConnect:      profile.autoConnect,
+			Priority:         profile.priority,
+			IPv4:             profile.ipv4,
+			IPv6:             profile.ipv6,
+			LockedBSSID:      profile.bssid,
+			PreferredBand:    profile.band,
+			PreferredChannel: profile.channel,
 		})
 		appendedInvisible[profile.path] = true
 	}
@@ -1016,6 +1182,11 @@ func (b *Backend) ActivateNetwork(ssid string) error {
 	if err != nil {
 		return err
 	}
+	// The strongest access point may not satisfy a BSSID lock or band
+	// restriction, so restricted profiles let NetworkManager pick one.
+	if profile, ok := parseSavedProfile(conn); ok && (profile.bssid != "" || profile.band != wifi.BandUnknown) {
+		ap = nil
+	}
 
 	wirelessDevice, err := b.getWirelessDevice()
 	if err != nil {
@@ -1035,6 +1206,113 @@ func (b *Backend) ActivateNetwork(ssid string) error {
 	return waitForActiveConnection(activeConn)
 }
 
+// ActivateAccessPoint activates a known network through one of its access
+// points. NetworkManager may still roam afterwards unless the network is
+// locked to the access point.
+func (b *Backend) ActivateAccessPoint(ssid, bssid string) error {
+	bssid, err := wifi.ParseBSSID(bssid)
+	if err != nil {
+		return err
+	}
+	conn, err := b.getConnection(ssid)
+	if err != nil {
+		return err
+	}
+	wirelessDevice, err := b.getWirelessDevice()
+	if err != nil {
+		return err
+	}
+	ap, err := findAccessPoint(wirelessDevice, ssid, bssid)
+	if err != nil {
+		return err
+	}
+	activeConn, err := b.NM.ActivateWirelessConnection(conn, wirelessDevice, ap)
+	if err != nil {
+		return err
+	}
+	return waitForActiveConnection(activeConn)
+}
+
+// findAccessPoint returns the access point of ssid with the given BSSID from
+// the device's latest scan results.
+func findAccessPoint(device gonetworkmanager.DeviceWireless, ssid, bssid string) (gonetworkmanager.AccessPoint, error) {
+	accessPoints, err := device.GetAllAccessPoints()
+	if err != nil {
+		accessPoints, err = device.GetAccessPoints()
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, ap := range accessPoints {
+		hwAddress, err := ap.GetPropertyHWAddress()
+		if err != nil || !strings.EqualFold(hwAddress, bssid) {
+			continue
+		}
+		if apSSID, err := ap.GetPropertySSID(); err == nil && apSSID == ssid {
+			return ap, nil
+		}
+	}
+	return nil, fmt.Errorf("access point %s not found for %s: %w", bssid, ssid, wifi.ErrNotFound)
+}
+
+// applyBSSIDLock sets or, for an empty bssid, removes the BSSID lock in an
+// 802-11-wireless settings section.
+func applyBSSIDLock(wireless map[string]interface{}, bssid string) {
+	if bssid == "" {
+		delete(wireless, "bssid")
+		return
+	}
+	mac, _ := net.ParseMAC(bssid)
+	wireless["bssid"] = []byte(mac)
+}
+
+// nmBands maps bands to the values of the 802-11-wireless band setting.
+// NetworkManager has no setting for 6 GHz.
+var nmBands = map[wifi.Band]string{
+	wifi.Band2GHz: "bg",
+	wifi.Band5GHz: "a",
+}
+
+func bandFromSettings(wireless map[string]interface{}) (wifi.Band, int) {
+	name, _ := wireless["band"].(string)
+	for band, value := range nmBands {
+		if value == name {
+			channel, _ := wireless["channel"].(uint32)
+			return band, int(channel)
+		}
+	}
+	return wifi.BandUnknown, 0
+}
+
+// applyBandPreference sets or, for BandUnknown, removes the band restriction
+// in an 802-11-wireless settings section. A nil channel keeps the current one
+// if the band stays the same, and removes it otherwise, since NetworkManager
+// rejects a channel outside of the band.
+func applyBandPreference(wireless map[string]interface{}, band wifi.Band, channel *int) error {
+	if band == wifi.BandUnknown {
+		delete(wireless, "band")
+		delete(wireless, "channel")
+		return nil
+	}
+	value, ok := nmBands[band]
+	if !ok {
+		return fmt.Errorf("NetworkManager cannot restrict a network to %s: %w", band, wifi.ErrNotSupported)
+	}
+	if current, _ := wireless["band"].(string); current != value {
+		delete(wireless, "channel")
+	}
+	wireless["band"] = value
+	if channel == nil {
+		return nil
+	}
+	if *channel == 0 {
+		delete(wireless, "channel")
+	} else {
+		wireless["channel"] = uint32(*channel)
+	}
+	return nil
+}
+
 // waitForActiveConnection monitors NetworkManager's activation state until the
 // connection activates, fails, or times out. It keeps the state-change
 // subscription path from the previous inline loop, with a slow poll as a safety
@@ -1139,14 +1417,35 @@ func (b *Backend) ForgetNetwork(ssid string) error {
 	return conn.Delete()
 }
 
-func (b *Backend) JoinNetwork(ssid string, password string, security wifi.SecurityType, isHidden bool) error {
+func (b *Backend) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
+	if opts.Security.IsEnterprise() {
+		if err := opts.Enterprise.Validate(); err != nil {
+			return err
+		}
+	}
+	ipOpts := wifi.UpdateOptions{IPv4: opts.IPv4, IPv6: opts.IPv6}
+	if err := ipOpts.Validate(); err != nil {
+		return err
+	}
+	if opts.LockBSSID && opts.BSSID == "" {
+		return fmt.Errorf("locking requires a BSSID: %w", wifi.ErrInvalidBSSID)
+	}
+	if opts.BSSID != "" {
+		bssid, err := wifi.ParseBSSID(opts.BSSID)
+		if err != nil {
+			return err
+		}
+		opts.BSSID = bssid
+	}
+	isHidden := opts.IsHidden
+
 	wirelessDevice, err := b.getWirelessDevice()
 	if err != nil {
 		return err
 	}
 	deviceInterface, _ := wirelessDevice.GetPropertyInterface()
 	var hiddenScanErr error
-	if isHidden {
+	if isHidden && !opts.SaveOnly {
 		hiddenScanErr = b.scanHiddenSSID(wirelessDevice, ssid)
 	}
 
@@ -1168,21 +1467,55 @@ func (b *Backend) JoinNetwork(ssid string, password string, security wifi.Securi
 	if isHidden {
 		connection["802-11-wireless"]["hidden"] = true
 	}
+	if opts.LockBSSID {
+		applyBSSIDLock(connection["802-11-wireless"], opts.BSSID)
+	}
+	if opts.IPv4 != nil {
+		applyIPConfig(connection["ipv4"], opts.IPv4, false)
+	}
+	if opts.IPv6 != nil {
+		applyIPConfig(connection["ipv6"], opts.IPv6, true)
+	}
 
-	switch security {
+	switch opts.Security {
 	case wifi.SecurityOpen:
 		// No security settings needed
 	case wifi.SecurityWEP:
 		connection["802-11-wireless"]["security"] = "802-11-wireless-security"
 		connection["802-11-wireless-security"] = map[string]interface{}{
 			"key-mgmt": "none",
-			"wep-key0": password,
+			"wep-key0": opts.Password,
 		}
-	default: // WPA/WPA2
+	case wifi.SecurityOWE:
 		connection["802-11-wireless"]["security"] = "802-11-wireless-security"
 		connection["802-11-wireless-security"] = map[string]interface{}{
-			"key-mgmt": "wpa-psk",
-			"psk":      password,
+			"key-mgmt": "owe",
+		}
+	case wifi.SecurityEnterprise, wifi.SecurityEnterpriseWPA3:
+		connection["802-11-wireless"]["security"] = "802-11-wireless-security"
+		connection["802-11-wireless-security"] = map[string]interface{}{
+			"key-mgmt": keyMgmtForSecurity(opts.Security),
+		}
+		connection["802-1x"] = enterpriseSettings(opts.Enterprise, opts.Password)
+	default: // WPA/WPA2/WPA3
+		connection["802-11-wireless"]["security"] = "802-11-wireless-security"
+		connection["802-11-wireless-security"] = map[string]interface{}{
+			"key-mgmt": keyMgmtForSecurity(opts.Security),
+			"psk":      opts.Password,
+		}
+	}
+
+	if opts.SaveOnly {
+		if _, err := b.Settings.AddConnection(connection); err != nil {
+			return fmt.Errorf("failed to add connection: %w", err)
+		}
+		return nil
+	}
+
+	var pinnedAP gonetworkmanager.AccessPoint
+	if opts.BSSID != "" {
+		if pinnedAP, err = findAccessPoint(wirelessDevice, ssid, opts.BSSID); err != nil {
+			return err
 		}
 	}
 
@@ -1198,7 +1531,9 @@ func (b *Backend) JoinNetwork(ssid string, password string, security wifi.Securi
 	}()
 
 	var activeConn gonetworkmanager.ActiveConnection
-	if isHidden {
+	if pinnedAP != nil {
+		activeConn, err = b.NM.ActivateWirelessConnection(conn, wirelessDevice, pinnedAP)
+	} else if isHidden {
 		// Use NetworkManager's generic ActivateConnection for hidden networks as there is no specific object.
 		activeConn, err = b.NM.ActivateConnection(conn, wirelessDevice, nil)
 	} else {
@@ -1233,6 +1568,74 @@ func (b *Backend) JoinNetwork(ssid string, password string, security wifi.Securi
 	return nil
 }
 
+// keyMgmtForSecurity returns the 802-11-wireless-security key-mgmt value used
+// to join a network with the given security.
+func keyMgmtForSecurity(security wifi.SecurityType) string {
+	switch security {
+	case wifi.SecuritySAE:
+		return "sae"
+	case wifi.SecurityOWE:
+		return "owe"
+	case wifi.SecurityEnterprise:
+		return "wpa-eap"
+	case wifi.SecurityEnterpriseWPA3:
+		return "wpa-eap-suite-b-192"
+	default:
+		// Transition mode networks get wpa-psk too: NetworkManager upgrades
+		// to SAE when the device supports it, and the profile still works on
+		// clients that do not.
+		return "wpa-psk"
+	}
+}
+
+// enterpriseSettings builds the 802-1x settings section for a new connection.
+func enterpriseSettings(creds *wifi.EnterpriseCredentials, password string) map[string]interface{} {
+	settings := map[string]interface{}{
+		"eap":      []string{string(creds.EAPMethod)},
+		"identity": creds.Identity,
+	}
+	if creds.AnonymousIdentity != "" {
+		settings["anonymous-identity"] = creds.AnonymousIdentity
+	}
+	if creds.Phase2Auth != "" && creds.EAPMethod != wifi.EAPMethodTLS {
+		settings["phase2-auth"] = creds.Phase2Auth
+	}
+	if password != "" {
+		settings["password"] = password
+	}
+	if creds.CACert != "" {
+		settings["ca-cert"] = certificatePath(creds.CACert)
+	}
+	if creds.ClientCert != "" {
+		settings["client-cert"] = certificatePath(creds.ClientCert)
+	}
+	if creds.PrivateKey != "" {
+		settings["private-key"] = certificatePath(creds.PrivateKey)
+	}
+	if creds.PrivateKeyPassword != "" {
+		settings["private-key-password"] = creds.PrivateKeyPassword
+	}
+	return settings
+}
+
+// certificatePath encodes a file path using NetworkManager's certificate
+// scheme: a NUL-terminated file:// URI with an absolute path.
+func certificatePath(path string) []byte {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return append([]byte("file://"+path), 0)
+}
+
+// secretSettingName returns the settings section that holds the connection's
+// password: 802-1x for enterprise profiles, 802-11-wireless-security otherwise.
+func secretSettingName(settings gonetworkmanager.ConnectionSettings) (name string, key string) {
+	if securityFromSettings(settings).IsEnterprise() {
+		return "802-1x", "password"
+	}
+	return "802-11-wireless-security", "psk"
+}
+
 func (b *Backend) GetSecrets(ssid string) (string, error) {
 	conn, err := b.getConnection(ssid)
 	if err != nil {
@@ -1248,7 +1651,8 @@ func (b *Backend) GetSecrets(ssid string) (string, error) {
 		return "", nil
 	}
 
-	settings, err := conn.GetSecrets("802-11-wireless-security")
+	section, key := secretSettingName(s)
+	settings, err := conn.GetSecrets(section)
 	if err != nil {
 		// Is the failure because we're not in the networkmanager group?
 		if inGroup, errCheck := isUserInGroup("networkmanager"); errCheck == nil && !inGroup {
@@ -1257,9 +1661,9 @@ func (b *Backend) GetSecrets(ssid string) (string, error) {
 		return "", fmt.Errorf("failed to get secrets: %w: %w", wifi.ErrOperationFailed, err)
 	}
 
-	if s, ok := settings["802-11-wireless-security"]; ok {
-		if psk, ok := s["psk"]; ok {
-			if p, ok := psk.(string); ok {
+	if s, ok := settings[section]; ok {
+		if secret, ok := s[key]; ok {
+			if p, ok := secret.(string); ok {
 				return p, nil
 			}
 		}
@@ -1270,25 +1674,109 @@ func (b *Backend) GetSecrets(ssid string) (string, error) {
 
 // applyUpdateWorkaround modifies the settings map to workaround D-Bus type errors.
 //
-// NetworkManager's D-Bus API can return ipv6.addresses and ipv6.routes as an
-// array of array of variants ('aav'), but expects them as an array of structs
-// on update ('a(ayuay)' for addresses and 'a(ayuayu)' for routes). This causes
-// a type mismatch error when calling the Update method with settings that
-// were previously fetched from the API.
+// NetworkManager's D-Bus API returns ipv6.addresses and ipv6.routes as arrays
+// of structs ('a(ayuay)' for addresses and 'a(ayuayu)' for routes), which
+// godbus decodes into slices of []interface{}. Sent back as they are, they
+// encode as an array of array of variants ('aav'), and the Update method
+// fails with a type mismatch error.
 //
-// To avoid this, we remove these properties from the settings map before
-// updating the connection. This is safe because the operations that use this
-// workaround are only intended to modify other properties of the connection.
+// When the modern address-data and route-data properties are present, which
+// NetworkManager prefers and applyIPConfig writes, the legacy properties are
+// redundant and are removed. Otherwise they are the only copy of the
+// addressing, so they are converted to structs that encode with the expected
+// signature. Values that cannot be converted are removed, as they could not
+// be sent either way.
 //
 // See: https://github.com/Wifx/gonetworkmanager/issues/13 and https://github.com/godbus/dbus/issues/400
 func applyUpdateWorkaround(settings map[string]map[string]interface{}) {
-	if ipv6Settings, ok := settings["ipv6"]; ok {
+	ipv6Settings, ok := settings["ipv6"]
+	if !ok {
+		return
+	}
+	if _, ok := ipv6Settings["address-data"]; ok {
 		delete(ipv6Settings, "addresses")
+	} else if addresses, ok := ipv6Settings["addresses"]; ok {
+		if converted, ok := ipv6AddressesFromSettings(addresses); ok {
+			ipv6Settings["addresses"] = converted
+		} else {
+			delete(ipv6Settings, "addresses")
+		}
+	}
+	if _, ok := ipv6Settings["route-data"]; ok {
 		delete(ipv6Settings, "routes")
+	} else if routes, ok := ipv6Settings["routes"]; ok {
+		if converted, ok := ipv6RoutesFromSettings(routes); ok {
+			ipv6Settings["routes"] = converted
+		} else {
+			delete(ipv6Settings, "routes")
+		}
 	}
 }
 
+// ipv6Address encodes as an element of the legacy ipv6.addresses property,
+// '(ayuay)': the address, its prefix length and the gateway.
+type ipv6Address struct {
+	Address []byte
+	Prefix  uint32
+	Gateway []byte
+}
+
+// ipv6Route encodes as an element of the legacy ipv6.routes property,
+// '(ayuayu)': the destination, its prefix length, the next hop and the metric.
+type ipv6Route struct {
+	Dest    []byte
+	Prefix  uint32
+	NextHop []byte
+	Metric  uint32
+}
+
+func ipv6AddressesFromSettings(value interface{}) ([]ipv6Address, bool) {
+	fields, ok := value.([][]interface{})
+	if !ok {
+		return nil, false
+	}
+	addresses := make([]ipv6Address, 0, len(fields))
+	for _, f := range fields {
+		if len(f) != 3 {
+			return nil, false
+		}
+		address, ok1 := f[0].([]byte)
+		prefix, ok2 := f[1].(uint32)
+		gateway, ok3 := f[2].([]byte)
+		if !ok1 || !ok2 || !ok3 {
+			return nil, false
+		}
+		addresses = append(addresses, ipv6Address{Address: address, Prefix: prefix, Gateway: gateway})
+	}
+	return addresses, true
+}
+
+func ipv6RoutesFromSettings(value interface{}) ([]ipv6Route, bool) {
+	fields, ok := value.([][]interface{})
+	if !ok {
+		return nil, false
+	}
+	routes := make([]ipv6Route, 0, len(fields))
+	for _, f := range fields {
+		if len(f) != 4 {
+			return nil, false
+		}
+		dest, ok1 := f[0].([]byte)
+		prefix, ok2 := f[1].(uint32)
+		nextHop, ok3 := f[2].([]byte)
+		metric, ok4 := f[3].(uint32)
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return nil, false
+		}
+		routes = append(routes, ipv6Route{Dest: dest, Prefix: prefix, NextHop: nextHop, Metric: metric})
+	}
+	return routes, true
+}
+
 func (b *Backend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
 	conn, err := b.getConnection(ssid)
 	if err != nil {
 		return err
@@ -1300,10 +1788,11 @@ func (b *Backend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
 	}
 
 	if opts.Password != nil {
-		if _, ok := settings["802-11-wireless-security"]; !ok {
-			settings["802-11-wireless-security"] = make(map[string]interface{})
+		section, key := secretSettingName(settings)
+		if _, ok := settings[section]; !ok {
+			settings[section] = make(map[string]interface{})
 		}
-		settings["802-11-wireless-security"]["psk"] = *opts.Password
+		settings[section][key] = *opts.Password
 	}
 
 	if opts.AutoConnect != nil {
@@ -1314,6 +1803,49 @@ func (b *Backend) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
 		settings["connection"]["autoconnect"] = *opts.AutoConnect
 	}
 
+	if opts.Priority != nil {
+		if _, ok := settings["connection"]; !ok {
+			settings["connection"] = make(map[string]interface{})
+		}
+		settings["connection"]["autoconnect-priority"] = int32(*opts.Priority)
+	}
+
+	if opts.BSSID != nil {
+		if _, ok := settings["802-11-wireless"]; !ok {
+			settings["802-11-wireless"] = make(map[string]interface{})
+		}
+		applyBSSIDLock(settings["802-11-wireless"], *opts.BSSID)
+	}
+
+	if opts.Band != nil || opts.Channel != nil {
+		if _, ok := settings["802-11-wireless"]; !ok {
+			settings["802-11-wireless"] = make(map[string]interface{})
+		}
+		if opts.Band != nil {
+			if err := applyBandPreference(settings["802-11-wireless"], *opts.Band, opts.Channel); err != nil {
+				return err
+			}
+		} else {
+			// Validate only accepts a channel together with the band that
+			// contains it, so without a band this clears the channel.
+			delete(settings["802-11-wireless"], "channel")
+		}
+	}
+
+	for _, ip := range []struct {
+		config *wifi.IPConfig
+		ipv6   bool
+	}{{opts.IPv4, false}, {opts.IPv6, true}} {
+		if ip.config == nil {
+			continue
+		}
+		name := ipSettingName(ip.ipv6)
+		if _, ok := settings[name]; !ok {
+			settings[name] = make(map[string]interface{})
+		}
+		applyIPConfig(settings[name], ip.config, ip.ipv6)
+	}
+
 	applyUpdateWorkaround(settings)
 	return conn.Update(settings)
 }
@@ -1381,3 +1913,37 @@ func (b *Backend) SetWireless(enabled bool) error {
 		}
 	}
 }
+
+// Connectivity asks NetworkManager to recheck connectivity, so a connection
+// that was just activated is not reported with a stale state, and then reads
+// the result. NetworkManager reports unknown when its connectivity checks are
+// disabled.
+func (b *Backend) Connectivity() (wifi.Connectivity, error) {
+	// Rechecking needs no permission, but fails when checks are disabled.
+	// The property is still worth reading in that case, any other failure
+	// is reported.
+	if err := b.NM.CheckConnectivity(); err != nil {
+		if enabled, enabledErr := b.NM.GetPropertyConnectivityCheckEnabled(); enabledErr != nil || enabled {
+			return wifi.ConnectivityUnknown, fmt.Errorf("failed to check connectivity: %w: %w", wifi.ErrOperationFailed, err)
+		}
+	}
+	state, err := b.NM.GetPropertyConnectivity()
+	if err != nil {
+		return wifi.ConnectivityUnknown, err
+	}
+	return connectivityFromNM(state), nil
+}
+
+func connectivityFromNM(state gonetworkmanager.NmConnectivity) wifi.Connectivity {
+	switch state {
+	case gonetworkmanager.NmConnectivityNone:
+		return wifi.ConnectivityNone
+	case gonetworkmanager.NmConnectivityPortal:
+		return wifi.ConnectivityPortal
+	case gonetworkmanager.NmConnectivityLimited:
+		return wifi.ConnectivityLimited
+	case gonetworkmanager.NmConnectivityFull:
+		return wifi.ConnectivityFull
+	}
+	return wifi.ConnectivityUnknown
+}
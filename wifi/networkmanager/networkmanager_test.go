@@ -4,6 +4,9 @@ package networkmanager
 
 import (
 	"errors"
+	"net"
+	"net/netip"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -21,6 +24,11 @@ type mockNM struct {
 	getPropertyActiveConnectionsFunc func() ([]gonetworkmanager.ActiveConnection, error)
 	activateConnectionFunc           func(gonetworkmanager.Connection, gonetworkmanager.Device, *dbus.Object) (gonetworkmanager.ActiveConnection, error)
 	activateWirelessConnectionFunc   func(gonetworkmanager.Connection, gonetworkmanager.Device, gonetworkmanager.AccessPoint) (gonetworkmanager.ActiveConnection, error)
+	deactivateConnectionFunc         func(gonetworkmanager.ActiveConnection) error
+	connectivity                     gonetworkmanager.NmConnectivity
+	connectivityChecked              bool
+	checkConnectivityErr             error
+	connectivityCheckDisabled        bool
 }
 
 func (m *mockNM) GetDevices() ([]gonetworkmanager.Device, error) {
@@ -37,6 +45,19 @@ func (m *mockNM) GetPropertyWirelessEnabled() (bool, error) {
 	return true, nil
 }
 
+func (m *mockNM) CheckConnectivity() error {
+	m.connectivityChecked = true
+	return m.checkConnectivityErr
+}
+
+func (m *mockNM) GetPropertyConnectivityCheckEnabled() (bool, error) {
+	return !m.connectivityCheckDisabled, nil
+}
+
+func (m *mockNM) GetPropertyConnectivity() (gonetworkmanager.NmConnectivity, error) {
+	return m.connectivity, nil
+}
+
 func (m *mockNM) GetPropertyActiveConnections() ([]gonetworkmanager.ActiveConnection, error) {
 	if m.getPropertyActiveConnectionsFunc != nil {
 		return m.getPropertyActiveConnectionsFunc()
@@ -58,16 +79,28 @@ func (m *mockNM) ActivateWirelessConnection(conn gonetworkmanager.Connection, de
 	return nil, nil
 }
 
+func (m *mockNM) DeactivateConnection(conn gonetworkmanager.ActiveConnection) error {
+	if m.deactivateConnectionFunc != nil {
+		return m.deactivateConnectionFunc(conn)
+	}
+	return nil
+}
+
 type mockDeviceWireless struct {
 	gonetworkmanager.DeviceWireless
 	path                     dbus.ObjectPath
 	iface                    string
+	hwAddress                string
 	accessPoints             []gonetworkmanager.AccessPoint
 	allAccessPoints          []gonetworkmanager.AccessPoint
 	getAccessPointsCalled    bool
 	getAllAccessPointsCalled bool
 	managed                  bool
 	state                    gonetworkmanager.NmDeviceState
+	activeAccessPoint        gonetworkmanager.AccessPoint
+	bitrate                  uint32
+	ip4Config                gonetworkmanager.IP4Config
+	ip6Config                gonetworkmanager.IP6Config
 }
 
 func (m *mockDeviceWireless) GetPath() dbus.ObjectPath {
@@ -84,6 +117,10 @@ func (m *mockDeviceWireless) GetPropertyInterface() (string, error) {
 	return m.iface, nil
 }
 
+func (m *mockDeviceWireless) GetPropertyHwAddress() (string, error) {
+	return m.hwAddress, nil
+}
+
 func (m *mockDeviceWireless) GetPropertyManaged() (bool, error) {
 	if !m.managed && m.state == 0 {
 		return true, nil
@@ -115,6 +152,7 @@ type mockSettings struct {
 	gonetworkmanager.Settings
 	connections              []gonetworkmanager.Connection
 	addConnectionUnsavedFunc func(gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error)
+	addConnectionFunc        func(gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error)
 }
 
 func (m *mockSettings) ListConnections() ([]gonetworkmanager.Connection, error) {
@@ -128,12 +166,21 @@ func (m *mockSettings) AddConnectionUnsaved(settings gonetworkmanager.Connection
 	return &mockConnection{}, nil
 }
 
+func (m *mockSettings) AddConnection(settings gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error) {
+	if m.addConnectionFunc != nil {
+		return m.addConnectionFunc(settings)
+	}
+	return &mockConnection{}, nil
+}
+
 type mockConnection struct {
 	gonetworkmanager.Connection
 	path         dbus.ObjectPath
 	settings     gonetworkmanager.ConnectionSettings
+	updated      gonetworkmanager.ConnectionSettings
 	saveCalled   bool
 	deleteCalled bool
+	secrets      gonetworkmanager.ConnectionSettings
 }
 
 func newMockConnection(path, id, ssid string, security wifi.SecurityType) *mockConnection {
@@ -175,6 +222,11 @@ func (m *mockConnection) GetSettings() (gonetworkmanager.ConnectionSettings, err
 	return m.settings, nil
 }
 
+func (m *mockConnection) Update(settings gonetworkmanager.ConnectionSettings) error {
+	m.updated = settings
+	return nil
+}
+
 func (m *mockConnection) Save() error {
 	m.saveCalled = true
 	return nil
@@ -185,9 +237,28 @@ func (m *mockConnection) Delete() error {
 	return nil
 }
 
+func (m *mockConnection) GetSecrets(settingName string) (gonetworkmanager.ConnectionSettings, error) {
+	return m.secrets, nil
+}
+
 type mockActiveConnection struct {
 	gonetworkmanager.ActiveConnection
-	state gonetworkmanager.NmActiveConnectionState
+	state      gonetworkmanager.NmActiveConnectionState
+	connection gonetworkmanager.Connection
+}
+
+func (m *mockActiveConnection) GetPropertyType() (string, error) {
+	return "802-11-wireless", nil
+}
+
+func (m *mockActiveConnection) GetPropertyConnection() (gonetworkmanager.Connection, error) {
+	return m.connection, nil
+}
+
+func (m *mockActiveConnection) GetPropertyID() (string, error) {
+	settings, _ := m.connection.GetSettings()
+	id, _ := settings["connection"]["id"].(string)
+	return id, nil
 }
 
 func (m *mockActiveConnection) SubscribeState(receiver chan gonetworkmanager.StateChange, exit chan struct{}) error {
@@ -296,6 +367,49 @@ func TestGetWirelessDevice_Caching(t *testing.T) {
 	}
 }
 
+func TestDevicesAndSelectDevice(t *testing.T) {
+	wlan0 := &mockDeviceWireless{path: "/devices/1", iface: "wlan0", hwAddress: "00:11:22:33:44:55"}
+	wlan1 := &mockDeviceWireless{path: "/devices/2", iface: "wlan1", hwAddress: "66:77:88:99:aa:bb"}
+	unmanaged := &mockDeviceWireless{path: "/devices/3", iface: "wlan2", state: gonetworkmanager.NmDeviceStateUnmanaged}
+	b := newTestBackend(wlan0, nil)
+	b.NM.(*mockNM).getDevicesFunc = func() ([]gonetworkmanager.Device, error) {
+		return []gonetworkmanager.Device{wlan0, wlan1, unmanaged}, nil
+	}
+
+	devices, err := b.Devices()
+	if err != nil {
+		t.Fatalf("Devices() failed: %v", err)
+	}
+	want := []wifi.Device{
+		{Interface: "wlan0", HardwareAddress: "00:11:22:33:44:55", Selected: true},
+		{Interface: "wlan1", HardwareAddress: "66:77:88:99:aa:bb"},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Fatalf("Devices() = %+v, want %+v", devices, want)
+	}
+
+	b.connections[networkKey{ssid: "Stale"}] = nil
+	if err := b.SelectDevice("wlan1"); err != nil {
+		t.Fatalf("SelectDevice(wlan1) failed: %v", err)
+	}
+	if b.Device != wlan1 {
+		t.Errorf("expected wlan1 to be selected, got %v", b.Device)
+	}
+	if len(b.connections) != 0 {
+		t.Errorf("expected the network cache to be cleared, got %d entries", len(b.connections))
+	}
+
+	if err := b.SelectDevice("wlan9"); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("SelectDevice(wlan9) = %v, want ErrNotFound", err)
+	}
+	if err := b.SelectDevice("wlan2"); !errors.Is(err, wifi.ErrNotAvailable) {
+		t.Errorf("SelectDevice(wlan2) = %v, want ErrNotAvailable", err)
+	}
+	if b.Device != wlan1 {
+		t.Errorf("expected a failed selection to keep wlan1, got %v", b.Device)
+	}
+}
+
 func TestListNetworks_ReturnsCachedListWhenScanFails(t *testing.T) {
 	device := &mockDeviceWireless{
 		accessPoints: []gonetworkmanager.AccessPoint{
@@ -1039,7 +1153,7 @@ func TestJoinNetwork_HiddenRequestsTargetedScan(t *testing.T) {
 		return nil
 	}
 
-	err := b.JoinNetwork("HiddenNet", "password", wifi.SecurityWPA, true)
+	err := b.JoinNetwork("HiddenNet", wifi.JoinOptions{Password: "password", Security: wifi.SecurityWPA, IsHidden: true})
 	if err != nil {
 		t.Fatalf("JoinNetwork(hidden) returned error: %v", err)
 	}
@@ -1062,6 +1176,189 @@ func TestJoinNetwork_HiddenRequestsTargetedScan(t *testing.T) {
 	}
 }
 
+func TestJoinNetwork_SaveOnlyDoesNotActivate(t *testing.T) {
+	device := &mockDeviceWireless{}
+	var saved gonetworkmanager.ConnectionSettings
+
+	b := newTestBackend(device, nil)
+	b.Settings = &mockSettings{
+		addConnectionFunc: func(settings gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error) {
+			saved = settings
+			return &mockConnection{}, nil
+		},
+	}
+	b.scanFunc = func(gonetworkmanager.DeviceWireless, map[string]dbus.Variant) error {
+		t.Fatal("saving a hidden network requested a scan")
+		return nil
+	}
+
+	err := b.JoinNetwork("HiddenNet", wifi.JoinOptions{Password: "password", Security: wifi.SecurityWPA, IsHidden: true, SaveOnly: true})
+	if err != nil {
+		t.Fatalf("JoinNetwork(SaveOnly) returned error: %v", err)
+	}
+	if saved == nil {
+		t.Fatal("JoinNetwork(SaveOnly) did not add a saved connection")
+	}
+	if saved["802-11-wireless"]["hidden"] != true || saved["802-11-wireless-security"]["psk"] != "password" {
+		t.Errorf("saved settings = %#v, want a hidden network with its passphrase", saved)
+	}
+}
+
+func TestJoinNetwork_EnterpriseAdds8021XSettings(t *testing.T) {
+	device := &mockDeviceWireless{}
+	var added gonetworkmanager.ConnectionSettings
+
+	b := newTestBackend(device, nil)
+	b.Settings = &mockSettings{
+		addConnectionUnsavedFunc: func(settings gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error) {
+			added = settings
+			return &mockConnection{}, nil
+		},
+	}
+	b.NM = &mockNM{
+		getDevicesFunc: func() ([]gonetworkmanager.Device, error) {
+			return []gonetworkmanager.Device{device}, nil
+		},
+		activateConnectionFunc: func(conn gonetworkmanager.Connection, device gonetworkmanager.Device, specificObject *dbus.Object) (gonetworkmanager.ActiveConnection, error) {
+			return &mockActiveConnection{}, nil
+		},
+	}
+
+	err := b.JoinNetwork("Corp", wifi.JoinOptions{
+		Password: "secret",
+		Security: wifi.SecurityEnterprise,
+		Enterprise: &wifi.EnterpriseCredentials{
+			Identity:   "alice",
+			EAPMethod:  wifi.EAPMethodPEAP,
+			Phase2Auth: "mschapv2",
+			CACert:     "/etc/ssl/corp-ca.pem",
+		},
+	})
+	if err != nil {
+		t.Fatalf("JoinNetwork(enterprise) returned error: %v", err)
+	}
+	if got := added["802-11-wireless-security"]["key-mgmt"]; got != "wpa-eap" {
+		t.Errorf("key-mgmt = %v, want wpa-eap", got)
+	}
+	dot1x := added["802-1x"]
+	if eap, ok := dot1x["eap"].([]string); !ok || len(eap) != 1 || eap[0] != "peap" {
+		t.Errorf("eap = %#v, want [peap]", dot1x["eap"])
+	}
+	if dot1x["identity"] != "alice" || dot1x["password"] != "secret" || dot1x["phase2-auth"] != "mschapv2" {
+		t.Errorf("unexpected 802-1x settings: %#v", dot1x)
+	}
+	if got, want := string(dot1x["ca-cert"].([]byte)), "file:///etc/ssl/corp-ca.pem\x00"; got != want {
+		t.Errorf("ca-cert = %q, want %q", got, want)
+	}
+}
+
+func TestJoinNetwork_EnterpriseRequiresIdentity(t *testing.T) {
+	b := newTestBackend(&mockDeviceWireless{}, nil)
+	err := b.JoinNetwork("Corp", wifi.JoinOptions{
+		Security:   wifi.SecurityEnterprise,
+		Enterprise: &wifi.EnterpriseCredentials{EAPMethod: wifi.EAPMethodPEAP},
+	})
+	if !errors.Is(err, wifi.ErrInvalidCredentials) {
+		t.Fatalf("JoinNetwork(enterprise without identity) error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestSecurityFromAccessPoint(t *testing.T) {
+	psk := uint32(gonetworkmanager.Nm80211APSecKeyMgmtPSK)
+	sae := uint32(gonetworkmanager.Nm80211APSecKeyMgmtSAE)
+	dot1x := uint32(gonetworkmanager.Nm80211APSecKeyMgmt8021X)
+	owe := uint32(gonetworkmanager.Nm80211APSecKeyMgmtOWE)
+	privacy := uint32(gonetworkmanager.Nm80211APFlagsPrivacy)
+
+	tests := []struct {
+		name                      string
+		flags, wpaFlags, rsnFlags uint32
+		want                      wifi.SecurityType
+	}{
+		{"open", 0, 0, 0, wifi.SecurityOpen},
+		{"wep", privacy, 0, 0, wifi.SecurityWEP},
+		{"wpa2 psk", privacy, 0, psk, wifi.SecurityWPA},
+		{"wpa1 psk", privacy, psk, 0, wifi.SecurityWPA},
+		{"wpa3 sae", privacy, 0, sae, wifi.SecuritySAE},
+		{"wpa2/wpa3 transition", privacy, 0, psk | sae, wifi.SecurityWPA2WPA3},
+		{"owe", privacy, 0, owe, wifi.SecurityOWE},
+		{"owe transition open bss", 0, 0, uint32(gonetworkmanager.Nm80211APSecKeyMgmtOWETM), wifi.SecurityOpen},
+		{"enterprise", privacy, 0, dot1x, wifi.SecurityEnterprise},
+		{"wpa3 enterprise 192-bit", privacy, 0, uint32(keyMgmtEAPSuiteB192), wifi.SecurityEnterpriseWPA3},
+		{"mixed 8021x and psk", privacy, 0, dot1x | psk, wifi.SecurityWPA},
+	}
+	for _, tt := range tests {
+		if got, _ := securityFromAccessPoint(tt.flags, tt.wpaFlags, tt.rsnFlags); got != tt.want {
+			t.Errorf("%s: securityFromAccessPoint = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJoinNetwork_UsesKeyMgmtForSecurity(t *testing.T) {
+	tests := []struct {
+		security wifi.SecurityType
+		want     string
+	}{
+		{wifi.SecurityWPA, "wpa-psk"},
+		{wifi.SecuritySAE, "sae"},
+		{wifi.SecurityWPA2WPA3, "wpa-psk"},
+		{wifi.SecurityOWE, "owe"},
+	}
+	for _, tt := range tests {
+		device := &mockDeviceWireless{}
+		var added gonetworkmanager.ConnectionSettings
+		b := newTestBackend(device, nil)
+		b.Settings = &mockSettings{
+			addConnectionUnsavedFunc: func(settings gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error) {
+				added = settings
+				return &mockConnection{}, nil
+			},
+		}
+		b.NM.(*mockNM).activateConnectionFunc = func(conn gonetworkmanager.Connection, device gonetworkmanager.Device, specificObject *dbus.Object) (gonetworkmanager.ActiveConnection, error) {
+			return &mockActiveConnection{}, nil
+		}
+
+		if err := b.JoinNetwork("Net", wifi.JoinOptions{Password: "password", Security: tt.security}); err != nil {
+			t.Fatalf("JoinNetwork(%v) returned error: %v", tt.security, err)
+		}
+		security := added["802-11-wireless-security"]
+		if got := security["key-mgmt"]; got != tt.want {
+			t.Errorf("JoinNetwork(%v) key-mgmt = %v, want %s", tt.security, got, tt.want)
+		}
+		if _, hasPSK := security["psk"]; hasPSK != tt.security.RequiresPassphrase() {
+			t.Errorf("JoinNetwork(%v) psk set = %v, want %v", tt.security, hasPSK, tt.security.RequiresPassphrase())
+		}
+	}
+}
+
+func TestListNetworks_MarksTransitionNetworkKnownByPSKProfile(t *testing.T) {
+	ap := newMockAccessPoint("Home", "00:00:00:00:00:20", 80)
+	ap.rsnFlags = uint32(gonetworkmanager.Nm80211APSecKeyMgmtPSK | gonetworkmanager.Nm80211APSecKeyMgmtSAE)
+	device := &mockDeviceWireless{
+		accessPoints: []gonetworkmanager.AccessPoint{ap},
+	}
+	knownPSK := newMockConnection("/org/freedesktop/NetworkManager/Settings/6", "Home", "Home", wifi.SecurityWPA)
+	b := newTestBackend(device, []gonetworkmanager.Connection{knownPSK})
+
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks(ScanNever) returned error: %v", err)
+	}
+	if len(result.Networks) != 1 {
+		t.Fatalf("ListNetworks returned %#v, want a single Home network", result.Networks)
+	}
+	network := result.Networks[0]
+	if !network.IsKnown || !network.IsVisible {
+		t.Fatalf("transition Home network was not merged with its PSK profile: %#v", network)
+	}
+	if network.Security != wifi.SecurityWPA2WPA3 {
+		t.Fatalf("Home security = %v, want %v", network.Security, wifi.SecurityWPA2WPA3)
+	}
+	if network.SavedSecurity != wifi.SecurityWPA {
+		t.Errorf("Home saved security = %v, want %v", network.SavedSecurity, wifi.SecurityWPA)
+	}
+}
+
 func TestJoinNetwork_HiddenScanFailureDoesNotAbortActivation(t *testing.T) {
 	device := &mockDeviceWireless{}
 	var activated bool
@@ -1081,7 +1378,7 @@ func TestJoinNetwork_HiddenScanFailureDoesNotAbortActivation(t *testing.T) {
 		return errors.New("scan not allowed")
 	}
 
-	err := b.JoinNetwork("HiddenNet", "password", wifi.SecurityWPA, true)
+	err := b.JoinNetwork("HiddenNet", wifi.JoinOptions{Password: "password", Security: wifi.SecurityWPA, IsHidden: true})
 	if err != nil {
 		t.Fatalf("JoinNetwork(hidden) returned error after targeted scan failure: %v", err)
 	}
@@ -1108,7 +1405,7 @@ func TestJoinNetwork_HiddenActivationFailureIncludesScanFailure(t *testing.T) {
 		return errors.New("targeted scan rejected")
 	}
 
-	err := b.JoinNetwork("HiddenNet", "password", wifi.SecurityWPA, true)
+	err := b.JoinNetwork("HiddenNet", wifi.JoinOptions{Password: "password", Security: wifi.SecurityWPA, IsHidden: true})
 	if err == nil {
 		t.Fatal("JoinNetwork(hidden) returned nil after activation failure")
 	}
@@ -1162,6 +1459,24 @@ func TestIsNetworkChangeSignal(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "NetworkManager properties",
+			sig: &dbus.Signal{
+				Path: dbus.ObjectPath("/org/freedesktop/NetworkManager"),
+				Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+				Body: []interface{}{"org.freedesktop.NetworkManager"},
+			},
+			want: true,
+		},
+		{
+			name: "device state",
+			sig: &dbus.Signal{
+				Path: devicePath,
+				Name: "org.freedesktop.NetworkManager.Device.StateChanged",
+				Body: []interface{}{uint32(gonetworkmanager.NmDeviceStateActivated), uint32(0), uint32(0)},
+			},
+			want: true,
+		},
 		{
 			name: "other device properties",
 			sig: &dbus.Signal{
@@ -1262,3 +1577,793 @@ func TestIsUnavailableDBusError(t *testing.T) {
 type testError string
 
 func (e testError) Error() string { return string(e) }
+
+func TestEventTracker(t *testing.T) {
+	devicePath := dbus.ObjectPath("/org/freedesktop/NetworkManager/Devices/1")
+	existing := newMockAccessPoint("HomeNet", "00:11:22:33:44:55", 60)
+	added := newMockAccessPoint("HomeNet", "66:77:88:99:AA:BB", 40)
+	tracker := newEventTracker(&mockDeviceWireless{accessPoints: []gonetworkmanager.AccessPoint{existing}})
+	tracker.lookupAccessPoint = func(path dbus.ObjectPath) (wifi.AccessPoint, error) {
+		if path == added.path {
+			return accessPointInfo(added)
+		}
+		return wifi.AccessPoint{SSID: "NewNet", BSSID: "CC:CC:CC:CC:CC:CC", Strength: 30}, nil
+	}
+	tracker.activeSSID = func() string { return "HomeNet" }
+
+	wirelessSignal := func(member string, path dbus.ObjectPath) *dbus.Signal {
+		return &dbus.Signal{Path: devicePath, Name: nmWirelessDeviceInterface + "." + member, Body: []interface{}{path}}
+	}
+	propertiesSignal := func(path dbus.ObjectPath, iface string, changed map[string]dbus.Variant) *dbus.Signal {
+		return &dbus.Signal{Path: path, Name: dbusPropertiesInterface + ".PropertiesChanged", Body: []interface{}{iface, changed, []string{}}}
+	}
+	stateSignal := func(state gonetworkmanager.NmDeviceState) *dbus.Signal {
+		return &dbus.Signal{Path: devicePath, Name: nmDeviceInterface + ".StateChanged", Body: []interface{}{uint32(state), uint32(0), uint32(0)}}
+	}
+
+	tests := []struct {
+		name string
+		sig  *dbus.Signal
+		want []wifi.Event
+	}{
+		{
+			name: "second access point of a visible network",
+			sig:  wirelessSignal("AccessPointAdded", added.path),
+			want: nil,
+		},
+		{
+			name: "first access point of a new network",
+			sig:  wirelessSignal("AccessPointAdded", "/org/freedesktop/NetworkManager/AccessPoint/new"),
+			want: []wifi.Event{{Type: wifi.EventNetworkAppeared, SSID: "NewNet", BSSID: "CC:CC:CC:CC:CC:CC", Strength: 30}},
+		},
+		{
+			name: "strength change",
+			sig:  propertiesSignal(existing.path, nmWirelessAccessPointInterface, map[string]dbus.Variant{"Strength": dbus.MakeVariant(uint8(75))}),
+			want: []wifi.Event{{Type: wifi.EventSignalChanged, SSID: "HomeNet", BSSID: "00:11:22:33:44:55", Strength: 75}},
+		},
+		{
+			name: "unchanged strength",
+			sig:  propertiesSignal(existing.path, nmWirelessAccessPointInterface, map[string]dbus.Variant{"Strength": dbus.MakeVariant(uint8(75))}),
+			want: nil,
+		},
+		{
+			name: "one of two access points removed",
+			sig:  wirelessSignal("AccessPointRemoved", existing.path),
+			want: nil,
+		},
+		{
+			name: "last access point removed",
+			sig:  wirelessSignal("AccessPointRemoved", added.path),
+			want: []wifi.Event{{Type: wifi.EventNetworkDisappeared, SSID: "HomeNet"}},
+		},
+		{
+			name: "scan completed",
+			sig:  propertiesSignal(devicePath, nmWirelessDeviceInterface, map[string]dbus.Variant{"LastScan": dbus.MakeVariant(int64(1234))}),
+			want: []wifi.Event{{Type: wifi.EventScanCompleted}},
+		},
+		{
+			name: "radio toggled",
+			sig:  propertiesSignal(nmObjectPath, nmInterface, map[string]dbus.Variant{"WirelessEnabled": dbus.MakeVariant(false)}),
+			want: []wifi.Event{{Type: wifi.EventRadioToggled, RadioEnabled: false}},
+		},
+		{
+			name: "device preparing",
+			sig:  stateSignal(gonetworkmanager.NmDeviceStatePrepare),
+			want: []wifi.Event{{Type: wifi.EventConnectionStateChanged, SSID: "HomeNet", State: wifi.ConnectionActivating}},
+		},
+		{
+			name: "device still configuring",
+			sig:  stateSignal(gonetworkmanager.NmDeviceStateConfig),
+			want: nil,
+		},
+		{
+			name: "device activated",
+			sig:  stateSignal(gonetworkmanager.NmDeviceStateActivated),
+			want: []wifi.Event{{Type: wifi.EventConnectionStateChanged, SSID: "HomeNet", State: wifi.ConnectionActivated}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := tracker.handle(tt.sig)
+		for i := range got {
+			if got[i].Time.IsZero() {
+				t.Errorf("%s: event %d has no time", tt.name, i)
+			}
+			got[i].Time = time.Time{}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: handle() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	// The active access point is gone once the device disconnects, so the
+	// tracker reports the SSID that was last active.
+	tracker.activeSSID = func() string { return "" }
+	got := tracker.handle(stateSignal(gonetworkmanager.NmDeviceStateDisconnected))
+	if len(got) != 1 || got[0].State != wifi.ConnectionDeactivated || got[0].SSID != "HomeNet" {
+		t.Fatalf("handle(disconnected) = %#v, want deactivated HomeNet", got)
+	}
+}
+
+func TestIPConfigSettingsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *wifi.IPConfig
+		ipv6   bool
+	}{
+		{
+			name: "static IPv4",
+			config: &wifi.IPConfig{
+				Method:    wifi.IPMethodManual,
+				Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+				Gateway:   netip.MustParseAddr("192.168.1.1"),
+				DNS:       []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("9.9.9.9")},
+				DNSSearch: []string{"lab.example.com"},
+			},
+		},
+		{
+			name: "static IPv6",
+			config: &wifi.IPConfig{
+				Method:    wifi.IPMethodManual,
+				Addresses: []netip.Prefix{netip.MustParsePrefix("fd00::10/64")},
+				Gateway:   netip.MustParseAddr("fd00::1"),
+				DNS:       []netip.Addr{netip.MustParseAddr("2606:4700:4700::1111")},
+			},
+			ipv6: true,
+		},
+		{
+			name:   "disabled IPv6",
+			config: &wifi.IPConfig{Method: wifi.IPMethodDisabled},
+			ipv6:   true,
+		},
+	}
+	for _, tt := range tests {
+		section := map[string]interface{}{"method": "auto", "may-fail": true}
+		applyIPConfig(section, tt.config, tt.ipv6)
+		if section["may-fail"] != true {
+			t.Errorf("%s: applyIPConfig() dropped unrelated settings: %#v", tt.name, section)
+		}
+		if got := ipConfigFromSettings(section); !reflect.DeepEqual(got, tt.config) {
+			t.Errorf("%s: round trip = %#v, want %#v", tt.name, got, tt.config)
+		}
+	}
+}
+
+func TestIPConfigFromSettingsSkipsUnsupportedMethods(t *testing.T) {
+	if got := ipConfigFromSettings(map[string]interface{}{"method": "shared"}); got != nil {
+		t.Fatalf("ipConfigFromSettings(shared) = %#v, want nil", got)
+	}
+}
+
+func TestUpdateNetwork_IPConfig(t *testing.T) {
+	conn := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "LabNet", "LabNet", wifi.SecurityWPA)
+	conn.settings["ipv4"] = map[string]interface{}{
+		"method":    "auto",
+		"addresses": [][]uint32{},
+	}
+	conn.settings["ipv6"] = map[string]interface{}{
+		"method":       "auto",
+		"addresses":    [][]interface{}{},
+		"address-data": []map[string]dbus.Variant{},
+	}
+	b := newTestBackend(&mockDeviceWireless{}, []gonetworkmanager.Connection{conn})
+
+	networks, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if len(networks.Networks) != 1 || networks.Networks[0].IPv4 == nil || networks.Networks[0].IPv4.Method != wifi.IPMethodAuto {
+		t.Fatalf("ListNetworks() = %#v, want LabNet with automatic IPv4", networks.Networks)
+	}
+
+	err = b.UpdateNetwork("LabNet", wifi.UpdateOptions{
+		IPv4: &wifi.IPConfig{
+			Method:    wifi.IPMethodManual,
+			Addresses: []netip.Prefix{netip.MustParsePrefix("10.0.0.5/24")},
+			Gateway:   netip.MustParseAddr("10.0.0.1"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+
+	ipv4 := conn.updated["ipv4"]
+	if ipv4["method"] != "manual" || ipv4["gateway"] != "10.0.0.1" {
+		t.Errorf("ipv4 settings = %#v, want manual via 10.0.0.1", ipv4)
+	}
+	if _, ok := ipv4["addresses"]; ok {
+		t.Errorf("ipv4 settings kept legacy addresses: %#v", ipv4)
+	}
+	addressData, ok := ipv4["address-data"].([]map[string]dbus.Variant)
+	if !ok || len(addressData) != 1 || addressData[0]["address"].Value() != "10.0.0.5" || addressData[0]["prefix"].Value() != uint32(24) {
+		t.Errorf("ipv4 address-data = %#v, want 10.0.0.5/24", ipv4["address-data"])
+	}
+	// The ipv6 workaround still applies, without losing address-data.
+	ipv6 := conn.updated["ipv6"]
+	if _, ok := ipv6["addresses"]; ok {
+		t.Errorf("ipv6 settings kept legacy addresses: %#v", ipv6)
+	}
+	if _, ok := ipv6["address-data"]; !ok {
+		t.Errorf("ipv6 settings lost address-data: %#v", ipv6)
+	}
+}
+
+func TestUpdateNetwork_KeepsLegacyIPv6Addressing(t *testing.T) {
+	address := netip.MustParseAddr("2001:db8::5").As16()
+	gateway := netip.MustParseAddr("2001:db8::1").As16()
+	dest := netip.MustParseAddr("2001:db8:1::").As16()
+	conn := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "LabNet", "LabNet", wifi.SecurityWPA)
+	// Settings as godbus decodes them from a profile without address-data
+	// or route-data.
+	conn.settings["ipv6"] = map[string]interface{}{
+		"method":    "manual",
+		"addresses": [][]interface{}{{address[:], uint32(64), gateway[:]}},
+		"routes":    [][]interface{}{{dest[:], uint32(48), gateway[:], uint32(100)}},
+	}
+	b := newTestBackend(&mockDeviceWireless{}, []gonetworkmanager.Connection{conn})
+
+	autoConnect := false
+	if err := b.UpdateNetwork("LabNet", wifi.UpdateOptions{AutoConnect: &autoConnect}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+
+	ipv6 := conn.updated["ipv6"]
+	if sig := dbus.SignatureOf(ipv6["addresses"]).String(); sig != "a(ayuay)" {
+		t.Errorf("ipv6 addresses = %#v with signature %q, want a(ayuay)", ipv6["addresses"], sig)
+	}
+	if addresses, ok := ipv6["addresses"].([]ipv6Address); !ok || len(addresses) != 1 || addresses[0].Prefix != 64 {
+		t.Errorf("ipv6 addresses = %#v, want 2001:db8::5/64", ipv6["addresses"])
+	}
+	if sig := dbus.SignatureOf(ipv6["routes"]).String(); sig != "a(ayuayu)" {
+		t.Errorf("ipv6 routes = %#v with signature %q, want a(ayuayu)", ipv6["routes"], sig)
+	}
+	if routes, ok := ipv6["routes"].([]ipv6Route); !ok || len(routes) != 1 || routes[0].Metric != 100 {
+		t.Errorf("ipv6 routes = %#v, want 2001:db8:1::/48", ipv6["routes"])
+	}
+}
+
+func TestUpdateNetwork_RejectsInvalidIPConfig(t *testing.T) {
+	b := newTestBackend(&mockDeviceWireless{}, nil)
+	err := b.UpdateNetwork("LabNet", wifi.UpdateOptions{IPv4: &wifi.IPConfig{Method: wifi.IPMethodManual}})
+	if !errors.Is(err, wifi.ErrInvalidIPConfig) {
+		t.Fatalf("UpdateNetwork() error = %v, want ErrInvalidIPConfig", err)
+	}
+}
+
+func TestJoinNetwork_IPConfig(t *testing.T) {
+	device := &mockDeviceWireless{}
+	var added gonetworkmanager.ConnectionSettings
+
+	b := newTestBackend(device, nil)
+	b.Settings = &mockSettings{
+		addConnectionUnsavedFunc: func(settings gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error) {
+			added = settings
+			return &mockConnection{}, nil
+		},
+	}
+	b.NM = &mockNM{
+		getDevicesFunc: func() ([]gonetworkmanager.Device, error) {
+			return []gonetworkmanager.Device{device}, nil
+		},
+		activateConnectionFunc: func(conn gonetworkmanager.Connection, device gonetworkmanager.Device, specificObject *dbus.Object) (gonetworkmanager.ActiveConnection, error) {
+			return &mockActiveConnection{}, nil
+		},
+	}
+
+	err := b.JoinNetwork("LabNet", wifi.JoinOptions{
+		Password: "password",
+		Security: wifi.SecurityWPA,
+		IPv4: &wifi.IPConfig{
+			Method: wifi.IPMethodAuto,
+			DNS:    []netip.Addr{netip.MustParseAddr("1.1.1.1")},
+		},
+		IPv6: &wifi.IPConfig{Method: wifi.IPMethodDisabled},
+	})
+	if err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	if added["ipv4"]["method"] != "auto" || added["ipv4"]["ignore-auto-dns"] != true {
+		t.Errorf("ipv4 settings = %#v, want auto with ignore-auto-dns", added["ipv4"])
+	}
+	if added["ipv6"]["method"] != "disabled" {
+		t.Errorf("ipv6 settings = %#v, want disabled", added["ipv6"])
+	}
+}
+
+func (m *mockDeviceWireless) GetPropertyActiveAccessPoint() (gonetworkmanager.AccessPoint, error) {
+	return m.activeAccessPoint, nil
+}
+
+func (m *mockDeviceWireless) GetPropertyBitrate() (uint32, error) { return m.bitrate, nil }
+
+func (m *mockDeviceWireless) GetPropertyIP4Config() (gonetworkmanager.IP4Config, error) {
+	return m.ip4Config, nil
+}
+
+func (m *mockDeviceWireless) GetPropertyIP6Config() (gonetworkmanager.IP6Config, error) {
+	return m.ip6Config, nil
+}
+
+type mockIP4Config struct {
+	gonetworkmanager.IP4Config
+	addresses   []gonetworkmanager.IP4AddressData
+	gateway     string
+	nameservers []gonetworkmanager.IP4NameserverData
+}
+
+func (m *mockIP4Config) GetPropertyAddressData() ([]gonetworkmanager.IP4AddressData, error) {
+	return m.addresses, nil
+}
+func (m *mockIP4Config) GetPropertyGateway() (string, error) { return m.gateway, nil }
+func (m *mockIP4Config) GetPropertyNameserverData() ([]gonetworkmanager.IP4NameserverData, error) {
+	return m.nameservers, nil
+}
+
+type mockIP6Config struct {
+	gonetworkmanager.IP6Config
+	addresses   []gonetworkmanager.IP6AddressData
+	gateway     string
+	nameservers [][]byte
+}
+
+func (m *mockIP6Config) GetPropertyAddressData() ([]gonetworkmanager.IP6AddressData, error) {
+	return m.addresses, nil
+}
+func (m *mockIP6Config) GetPropertyGateway() (string, error)       { return m.gateway, nil }
+func (m *mockIP6Config) GetPropertyNameservers() ([][]byte, error) { return m.nameservers, nil }
+
+func TestActiveConnection(t *testing.T) {
+	ap := newMockAccessPoint("Home", "AA:BB:CC:DD:EE:01", 80)
+	ap.frequency = 5180
+	dns6 := netip.MustParseAddr("2001:db8::53").As16()
+	device := &mockDeviceWireless{
+		managed:           true,
+		state:             gonetworkmanager.NmDeviceStateActivated,
+		activeAccessPoint: ap,
+		bitrate:           866700,
+		ip4Config: &mockIP4Config{
+			addresses:   []gonetworkmanager.IP4AddressData{{Address: "192.168.1.10", Prefix: 24}},
+			gateway:     "192.168.1.1",
+			nameservers: []gonetworkmanager.IP4NameserverData{{Address: "192.168.1.1"}},
+		},
+		ip6Config: &mockIP6Config{
+			addresses:   []gonetworkmanager.IP6AddressData{{Address: "2001:db8::10", Prefix: 64}},
+			gateway:     "fe80::1",
+			nameservers: [][]byte{dns6[:]},
+		},
+	}
+	b := newTestBackend(device, nil)
+
+	got, err := b.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() error = %v", err)
+	}
+	want := &wifi.ConnectionDetails{
+		SSID:          "Home",
+		BSSID:         "AA:BB:CC:DD:EE:01",
+		Frequency:     5180,
+		Bitrate:       866700,
+		Interface:     "wlan0",
+		IPv4Addresses: []netip.Prefix{netip.MustParsePrefix("192.168.1.10/24")},
+		IPv4Gateway:   netip.MustParseAddr("192.168.1.1"),
+		IPv6Addresses: []netip.Prefix{netip.MustParsePrefix("2001:db8::10/64")},
+		IPv6Gateway:   netip.MustParseAddr("fe80::1"),
+		DNS:           []netip.Addr{netip.MustParseAddr("192.168.1.1"), netip.MustParseAddr("2001:db8::53")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ActiveConnection() = %+v, want %+v", got, want)
+	}
+}
+
+func TestActiveConnection_NotActivated(t *testing.T) {
+	device := &mockDeviceWireless{
+		managed:           true,
+		state:             gonetworkmanager.NmDeviceStateConfig,
+		activeAccessPoint: newMockAccessPoint("Home", "AA:BB:CC:DD:EE:01", 80),
+	}
+	b := newTestBackend(device, nil)
+
+	got, err := b.ActiveConnection()
+	if err != nil {
+		t.Fatalf("ActiveConnection() error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("ActiveConnection() = %+v, want nil while connecting", got)
+	}
+}
+
+func TestUpdateNetwork_BSSIDLock(t *testing.T) {
+	conn := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "Mesh", "Mesh", wifi.SecurityWPA)
+	conn.settings["802-11-wireless"]["bssid"] = []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x02}
+	b := newTestBackend(&mockDeviceWireless{}, []gonetworkmanager.Connection{conn})
+
+	networks, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if len(networks.Networks) != 1 || networks.Networks[0].LockedBSSID != "AA:BB:CC:DD:EE:02" {
+		t.Fatalf("ListNetworks() = %#v, want Mesh locked to AA:BB:CC:DD:EE:02", networks.Networks)
+	}
+
+	lock := "aa:bb:cc:dd:ee:01"
+	if err := b.UpdateNetwork("Mesh", wifi.UpdateOptions{BSSID: &lock}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if got, ok := conn.updated["802-11-wireless"]["bssid"].([]byte); !ok || net.HardwareAddr(got).String() != lock {
+		t.Errorf("bssid = %#v, want %s", conn.updated["802-11-wireless"]["bssid"], lock)
+	}
+
+	unlock := ""
+	if err := b.UpdateNetwork("Mesh", wifi.UpdateOptions{BSSID: &unlock}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if got, ok := conn.updated["802-11-wireless"]["bssid"]; ok {
+		t.Errorf("bssid = %#v after unlocking, want it removed", got)
+	}
+}
+
+func TestActivateAccessPoint_UsesMatchingAccessPoint(t *testing.T) {
+	strong := newMockAccessPoint("Mesh", "AA:BB:CC:DD:EE:01", 90)
+	weak := newMockAccessPoint("Mesh", "AA:BB:CC:DD:EE:02", 30)
+	device := &mockDeviceWireless{accessPoints: []gonetworkmanager.AccessPoint{strong, weak}}
+	conn := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "Mesh", "Mesh", wifi.SecurityWPA)
+	b := newTestBackend(device, []gonetworkmanager.Connection{conn})
+
+	var activatedAP gonetworkmanager.AccessPoint
+	b.NM.(*mockNM).activateWirelessConnectionFunc = func(conn gonetworkmanager.Connection, device gonetworkmanager.Device, ap gonetworkmanager.AccessPoint) (gonetworkmanager.ActiveConnection, error) {
+		activatedAP = ap
+		return &mockActiveConnection{}, nil
+	}
+
+	if _, err := b.ListNetworks(wifi.ScanNever); err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if err := b.ActivateAccessPoint("Mesh", "aa:bb:cc:dd:ee:02"); err != nil {
+		t.Fatalf("ActivateAccessPoint() failed: %v", err)
+	}
+	if activatedAP != weak {
+		t.Errorf("ActivateAccessPoint used AP %#v, want %#v", activatedAP, weak)
+	}
+	if err := b.ActivateAccessPoint("Mesh", "aa:bb:cc:dd:ee:03"); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("ActivateAccessPoint() with missing BSSID error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestJoinNetwork_LockBSSID(t *testing.T) {
+	pinned := newMockAccessPoint("Mesh", "AA:BB:CC:DD:EE:02", 30)
+	device := &mockDeviceWireless{accessPoints: []gonetworkmanager.AccessPoint{
+		newMockAccessPoint("Mesh", "AA:BB:CC:DD:EE:01", 90),
+		pinned,
+	}}
+	var added gonetworkmanager.ConnectionSettings
+	var activatedAP gonetworkmanager.AccessPoint
+
+	b := newTestBackend(device, nil)
+	b.Settings = &mockSettings{
+		addConnectionUnsavedFunc: func(settings gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error) {
+			added = settings
+			return &mockConnection{}, nil
+		},
+	}
+	b.NM.(*mockNM).activateWirelessConnectionFunc = func(conn gonetworkmanager.Connection, device gonetworkmanager.Device, ap gonetworkmanager.AccessPoint) (gonetworkmanager.ActiveConnection, error) {
+		activatedAP = ap
+		return &mockActiveConnection{}, nil
+	}
+
+	if err := b.JoinNetwork("Mesh", wifi.JoinOptions{LockBSSID: true}); !errors.Is(err, wifi.ErrInvalidBSSID) {
+		t.Fatalf("JoinNetwork() locking without a BSSID error = %v, want ErrInvalidBSSID", err)
+	}
+	err := b.JoinNetwork("Mesh", wifi.JoinOptions{
+		Password:  "password",
+		Security:  wifi.SecurityWPA,
+		BSSID:     "aa:bb:cc:dd:ee:02",
+		LockBSSID: true,
+	})
+	if err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	if activatedAP != pinned {
+		t.Errorf("JoinNetwork used AP %#v, want %#v", activatedAP, pinned)
+	}
+	if got, ok := added["802-11-wireless"]["bssid"].([]byte); !ok || net.HardwareAddr(got).String() != "aa:bb:cc:dd:ee:02" {
+		t.Errorf("bssid = %#v, want aa:bb:cc:dd:ee:02", added["802-11-wireless"]["bssid"])
+	}
+}
+
+func TestUpdateNetwork_BandPreference(t *testing.T) {
+	conn := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "Office", "Office", wifi.SecurityWPA)
+	conn.settings["802-11-wireless"]["band"] = "a"
+	conn.settings["802-11-wireless"]["channel"] = uint32(36)
+	b := newTestBackend(&mockDeviceWireless{}, []gonetworkmanager.Connection{conn})
+
+	networks, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if len(networks.Networks) != 1 || networks.Networks[0].PreferredBand != wifi.Band5GHz || networks.Networks[0].PreferredChannel != 36 {
+		t.Fatalf("ListNetworks() = %#v, want Office on 5GHz channel 36", networks.Networks)
+	}
+
+	band := wifi.Band2GHz
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Band: &band}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	wireless := conn.updated["802-11-wireless"]
+	if wireless["band"] != "bg" {
+		t.Errorf("band = %#v, want bg", wireless["band"])
+	}
+	if _, ok := wireless["channel"]; ok {
+		t.Errorf("channel = %#v after switching to 2.4GHz, want the 5GHz channel removed", wireless["channel"])
+	}
+
+	channel := 0
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Band: &band, Channel: &channel}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if _, ok := conn.updated["802-11-wireless"]["channel"]; ok {
+		t.Errorf("channel = %#v, want it removed", conn.updated["802-11-wireless"]["channel"])
+	}
+
+	conn.settings["802-11-wireless"]["band"] = "a"
+	conn.settings["802-11-wireless"]["channel"] = uint32(36)
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Channel: &channel}); err != nil {
+		t.Fatalf("UpdateNetwork() clearing the channel failed: %v", err)
+	}
+	if _, ok := conn.updated["802-11-wireless"]["channel"]; ok {
+		t.Errorf("channel = %#v, want it removed", conn.updated["802-11-wireless"]["channel"])
+	}
+	if conn.updated["802-11-wireless"]["band"] != "a" {
+		t.Errorf("band = %#v, want the saved band kept", conn.updated["802-11-wireless"]["band"])
+	}
+	channel = 36
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Channel: &channel}); !errors.Is(err, wifi.ErrInvalidBand) {
+		t.Errorf("UpdateNetwork() with a channel and no band error = %v, want ErrInvalidBand", err)
+	}
+
+	anyBand := wifi.BandUnknown
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Band: &anyBand}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if _, ok := conn.updated["802-11-wireless"]["band"]; ok {
+		t.Errorf("band = %#v, want it removed", conn.updated["802-11-wireless"]["band"])
+	}
+
+	band6 := wifi.Band6GHz
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Band: &band6}); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("UpdateNetwork() with 6GHz error = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestApplyBandPreference(t *testing.T) {
+	channel := func(c int) *int { return &c }
+	tests := []struct {
+		name        string
+		band        wifi.Band
+		channel     *int
+		wantBand    string
+		wantChannel uint32
+	}{
+		{"same band keeps the channel", wifi.Band5GHz, nil, "a", 36},
+		{"new band drops the channel", wifi.Band2GHz, nil, "bg", 0},
+		{"new band with its channel", wifi.Band2GHz, channel(6), "bg", 6},
+		{"clearing the channel", wifi.Band5GHz, channel(0), "a", 0},
+		{"any band", wifi.BandUnknown, nil, "", 0},
+	}
+	for _, tt := range tests {
+		wireless := map[string]interface{}{"band": "a", "channel": uint32(36)}
+		if err := applyBandPreference(wireless, tt.band, tt.channel); err != nil {
+			t.Errorf("%s: applyBandPreference() failed: %v", tt.name, err)
+			continue
+		}
+		band, _ := wireless["band"].(string)
+		channel, _ := wireless["channel"].(uint32)
+		if band != tt.wantBand || channel != tt.wantChannel {
+			t.Errorf("%s: band %q channel %d, want band %q channel %d", tt.name, band, channel, tt.wantBand, tt.wantChannel)
+		}
+	}
+}
+
+func TestUpdateNetwork_Priority(t *testing.T) {
+	conn := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "Office", "Office", wifi.SecurityWPA)
+	conn.settings["connection"]["autoconnect-priority"] = int32(5)
+	b := newTestBackend(&mockDeviceWireless{}, []gonetworkmanager.Connection{conn})
+
+	networks, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if len(networks.Networks) != 1 || networks.Networks[0].Priority != 5 {
+		t.Fatalf("ListNetworks() = %#v, want Office with priority 5", networks.Networks)
+	}
+
+	priority := -3
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Priority: &priority}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if got := conn.updated["connection"]["autoconnect-priority"]; got != int32(-3) {
+		t.Errorf("autoconnect-priority = %#v, want int32(-3)", got)
+	}
+
+	priority = wifi.MaxPriority + 1
+	if err := b.UpdateNetwork("Office", wifi.UpdateOptions{Priority: &priority}); !errors.Is(err, wifi.ErrInvalidPriority) {
+		t.Errorf("UpdateNetwork() with priority %d error = %v, want ErrInvalidPriority", priority, err)
+	}
+}
+
+func TestActivateNetwork_LetsNetworkManagerPickRestrictedAccessPoint(t *testing.T) {
+	device := &mockDeviceWireless{accessPoints: []gonetworkmanager.AccessPoint{newMockAccessPoint("Office", "00:00:00:00:00:01", 90)}}
+	conn := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "Office", "Office", wifi.SecurityWPA)
+	conn.settings["802-11-wireless"]["band"] = "a"
+	b := newTestBackend(device, []gonetworkmanager.Connection{conn})
+
+	mockManager := b.NM.(*mockNM)
+	var specificObject *dbus.Object
+	activated := false
+	mockManager.activateConnectionFunc = func(conn gonetworkmanager.Connection, device gonetworkmanager.Device, obj *dbus.Object) (gonetworkmanager.ActiveConnection, error) {
+		activated = true
+		specificObject = obj
+		return &mockActiveConnection{}, nil
+	}
+	mockManager.activateWirelessConnectionFunc = func(conn gonetworkmanager.Connection, device gonetworkmanager.Device, ap gonetworkmanager.AccessPoint) (gonetworkmanager.ActiveConnection, error) {
+		t.Fatalf("ActivateNetwork picked access point %#v for a band restricted profile", ap)
+		return nil, nil
+	}
+
+	if _, err := b.ListNetworks(wifi.ScanNever); err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if err := b.ActivateNetwork("Office"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	if !activated || specificObject != nil {
+		t.Errorf("ActivateNetwork activated = %v with %v, want no specific object", activated, specificObject)
+	}
+}
+
+func TestStartHotspot(t *testing.T) {
+	device := &mockDeviceWireless{managed: true, state: gonetworkmanager.NmDeviceStateActivated}
+	stale := newMockConnection("/org/freedesktop/NetworkManager/Settings/9", hotspotConnectionID, "Old", wifi.SecurityWPA)
+	stale.settings["802-11-wireless"]["mode"] = "ap"
+	b := newTestBackend(device, []gonetworkmanager.Connection{stale})
+
+	var added gonetworkmanager.ConnectionSettings
+	hotspot := &mockConnection{}
+	b.Settings.(*mockSettings).addConnectionUnsavedFunc = func(settings gonetworkmanager.ConnectionSettings) (gonetworkmanager.Connection, error) {
+		added = settings
+		return hotspot, nil
+	}
+	var activated gonetworkmanager.Connection
+	b.NM.(*mockNM).activateConnectionFunc = func(conn gonetworkmanager.Connection, _ gonetworkmanager.Device, specificObject *dbus.Object) (gonetworkmanager.ActiveConnection, error) {
+		activated = conn
+		return &mockActiveConnection{}, nil
+	}
+
+	err := b.StartHotspot(wifi.HotspotConfig{SSID: "Event", Password: "correct horse", Band: wifi.Band5GHz})
+	if err != nil {
+		t.Fatalf("StartHotspot() failed: %v", err)
+	}
+	if !stale.deleteCalled {
+		t.Error("StartHotspot() did not remove the old hotspot profile")
+	}
+	if activated != hotspot {
+		t.Error("StartHotspot() did not activate the new profile")
+	}
+	if hotspot.saveCalled || hotspot.deleteCalled {
+		t.Error("StartHotspot() saved or deleted the new profile")
+	}
+	wireless := added["802-11-wireless"]
+	if wireless["mode"] != "ap" || string(wireless["ssid"].([]byte)) != "Event" || wireless["band"] != "a" {
+		t.Errorf("802-11-wireless = %v, want an ap on band a", wireless)
+	}
+	if added["ipv4"]["method"] != "shared" {
+		t.Errorf("ipv4.method = %v, want shared", added["ipv4"]["method"])
+	}
+	if security := added["802-11-wireless-security"]; security["key-mgmt"] != "wpa-psk" || security["psk"] != "correct horse" {
+		t.Errorf("802-11-wireless-security = %v, want wpa-psk with the passphrase", security)
+	}
+
+	if err := b.StartHotspot(wifi.HotspotConfig{SSID: "Event", Band: wifi.Band6GHz}); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("StartHotspot() on 6GHz = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestHotspotStatusAndStop(t *testing.T) {
+	device := &mockDeviceWireless{managed: true, state: gonetworkmanager.NmDeviceStateActivated}
+	home := newMockConnection("/org/freedesktop/NetworkManager/Settings/1", "Home", "Home", wifi.SecurityWPA)
+	hotspot := newMockConnection("/org/freedesktop/NetworkManager/Settings/2", hotspotConnectionID, "Event", wifi.SecurityWPA)
+	hotspot.settings["802-11-wireless"]["mode"] = "ap"
+	hotspot.secrets = gonetworkmanager.ConnectionSettings{"802-11-wireless-security": {"psk": "correct horse"}}
+	b := newTestBackend(device, []gonetworkmanager.Connection{home, hotspot})
+
+	nm := b.NM.(*mockNM)
+	var active []gonetworkmanager.ActiveConnection
+	nm.getPropertyActiveConnectionsFunc = func() ([]gonetworkmanager.ActiveConnection, error) {
+		return active, nil
+	}
+	var deactivated gonetworkmanager.ActiveConnection
+	nm.deactivateConnectionFunc = func(conn gonetworkmanager.ActiveConnection) error {
+		deactivated = conn
+		return nil
+	}
+
+	if status, err := b.HotspotStatus(); err != nil || status.Active {
+		t.Fatalf("HotspotStatus() = %+v, %v, want inactive", status, err)
+	}
+	if err := b.StopHotspot(); !errors.Is(err, wifi.ErrNotFound) {
+		t.Fatalf("StopHotspot() without a hotspot = %v, want ErrNotFound", err)
+	}
+
+	activeHotspot := &mockActiveConnection{connection: hotspot}
+	active = []gonetworkmanager.ActiveConnection{activeHotspot}
+	status, err := b.HotspotStatus()
+	if err != nil {
+		t.Fatalf("HotspotStatus() failed: %v", err)
+	}
+	want := wifi.HotspotStatus{
+		Active:        true,
+		HotspotConfig: wifi.HotspotConfig{SSID: "Event", Password: "correct horse"},
+		Security:      wifi.SecurityWPA,
+		Interface:     "wlan0",
+	}
+	if status != want {
+		t.Errorf("HotspotStatus() = %+v, want %+v", status, want)
+	}
+
+	// The hotspot profile is not listed as a network to join.
+	result, err := b.ListNetworks(wifi.ScanNever)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	for _, n := range result.Networks {
+		if n.SSID == "Event" {
+			t.Errorf("ListNetworks() includes the hotspot profile: %+v", n)
+		}
+	}
+
+	if err := b.StopHotspot(); err != nil {
+		t.Fatalf("StopHotspot() failed: %v", err)
+	}
+	if deactivated != activeHotspot {
+		t.Error("StopHotspot() did not deactivate the hotspot")
+	}
+	if !hotspot.deleteCalled || home.deleteCalled {
+		t.Error("StopHotspot() should remove only its own profile")
+	}
+}
+
+func TestConnectivity(t *testing.T) {
+	tests := []struct {
+		nm   gonetworkmanager.NmConnectivity
+		want wifi.Connectivity
+	}{
+		{gonetworkmanager.NmConnectivityUnknown, wifi.ConnectivityUnknown},
+		{gonetworkmanager.NmConnectivityNone, wifi.ConnectivityNone},
+		{gonetworkmanager.NmConnectivityPortal, wifi.ConnectivityPortal},
+		{gonetworkmanager.NmConnectivityLimited, wifi.ConnectivityLimited},
+		{gonetworkmanager.NmConnectivityFull, wifi.ConnectivityFull},
+	}
+	for _, tt := range tests {
+		nm := &mockNM{connectivity: tt.nm}
+		b := &Backend{NM: nm}
+		got, err := b.Connectivity()
+		if err != nil {
+			t.Fatalf("Connectivity() failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Connectivity() with %v = %q, want %q", tt.nm, got, tt.want)
+		}
+		if !nm.connectivityChecked {
+			t.Errorf("Connectivity() did not ask NetworkManager to recheck")
+		}
+	}
+
+	checkErr := errors.New("org.freedesktop.NetworkManager.Failed")
+	disabled := &mockNM{checkConnectivityErr: checkErr, connectivityCheckDisabled: true}
+	if got, err := (&Backend{NM: disabled}).Connectivity(); err != nil || got != wifi.ConnectivityUnknown {
+		t.Errorf("Connectivity() with checks disabled = %q, %v, want unknown without an error", got, err)
+	}
+	failing := &mockNM{checkConnectivityErr: checkErr, connectivity: gonetworkmanager.NmConnectivityUnknown}
+	if _, err := (&Backend{NM: failing}).Connectivity(); !errors.Is(err, checkErr) || !errors.Is(err, wifi.ErrOperationFailed) {
+		t.Errorf("Connectivity() when the recheck fails = %v, want its error", err)
+	}
+}
@@ -0,0 +1,905 @@
+//go:build linux
+
+// Package nmtest runs a fake NetworkManager on a private D-Bus, so that the
+// networkmanager backend can be tested end to end, signals included, without
+// a NetworkManager daemon or a radio.
+//
+// The fake models wireless devices, their access points, saved and unsaved
+// connections and active connections. Activations succeed when the
+// connection's secret matches the passphrase of an access point that
+// broadcasts its SSID, and fail with NetworkManager's "no secrets" reason
+// otherwise.
+package nmtest
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	gonetworkmanager "github.com/Wifx/gonetworkmanager/v3"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+
+	"github.com/shazow/wifitui/internal/dbustest"
+)
+
+const (
+	// Version is the NetworkManager version the fake reports.
+	Version = "1.46.0"
+
+	// activationDelay and scanDelay make activations and scans finish after
+	// the call that started them has returned, like they do in
+	// NetworkManager, so that clients have to wait for the signals.
+	activationDelay = 20 * time.Millisecond
+	scanDelay       = 20 * time.Millisecond
+
+	noObject = dbus.ObjectPath("/")
+)
+
+// secretKeys lists the settings that GetSettings leaves out and GetSecrets
+// returns, by section.
+var secretKeys = map[string][]string{
+	"802-11-wireless-security": {"psk", "wep-key0", "wep-key1", "wep-key2", "wep-key3", "leap-password"},
+	"802-1x":                   {"password", "private-key-password"},
+}
+
+// AccessPoint describes an access point seen by a device.
+type AccessPoint struct {
+	SSID      string
+	BSSID     string
+	Strength  uint8
+	Frequency uint32
+	// Flags, WPAFlags and RSNFlags are advertised as they are. An access
+	// point with a Passphrase and none of them set advertises WPA2-PSK.
+	Flags    gonetworkmanager.Nm80211APFlags
+	WPAFlags gonetworkmanager.Nm80211APSec
+	RSNFlags gonetworkmanager.Nm80211APSec
+	// Passphrase is the secret that connections need to activate through
+	// the access point: their PSK, WEP key or 802.1X password.
+	Passphrase string
+	// Hidden access points broadcast an empty SSID until a scan asks for it.
+	Hidden bool
+}
+
+// Server is a fake NetworkManager. Its methods must be called from the test
+// goroutine.
+type Server struct {
+	t     testing.TB
+	conn  *dbus.Conn
+	start time.Time
+
+	mu           sync.Mutex
+	ids          map[string]int
+	nm           *prop.Properties
+	settings     *prop.Properties
+	devices      []*device
+	accessPoints map[dbus.ObjectPath]*accessPoint
+	connections  []*connection
+	active       []*activeConnection
+	scans        [][]string
+}
+
+type device struct {
+	path         dbus.ObjectPath
+	props        *prop.Properties
+	accessPoints []dbus.ObjectPath
+	lastScan     int64
+}
+
+type accessPoint struct {
+	AccessPoint
+	path   dbus.ObjectPath
+	device *device
+	props  *prop.Properties
+}
+
+type connection struct {
+	path     dbus.ObjectPath
+	props    *prop.Properties
+	settings map[string]map[string]dbus.Variant
+	unsaved  bool
+}
+
+type activeConnection struct {
+	path       dbus.ObjectPath
+	props      *prop.Properties
+	connection *connection
+	device     *device
+	specific   dbus.ObjectPath
+	done       bool
+}
+
+// New starts a fake NetworkManager on a private system bus, see
+// dbustest.NewSystemBus. It has no devices until AddDevice is called.
+func New(t testing.TB) *Server {
+	t.Helper()
+	bus := dbustest.NewSystemBus(t)
+	s := &Server{
+		t:            t,
+		conn:         bus.Connect(t),
+		start:        time.Now(),
+		ids:          make(map[string]int),
+		accessPoints: make(map[dbus.ObjectPath]*accessPoint),
+	}
+
+	s.nm = s.export(gonetworkmanager.NetworkManagerObjectPath, map[string]map[string]any{
+		gonetworkmanager.NetworkManagerInterface: {
+			"GetDevices":           s.getDevices,
+			"GetAllDevices":        s.getDevices,
+			"ActivateConnection":   s.activateConnection,
+			"DeactivateConnection": s.deactivateConnection,
+			"CheckConnectivity":    s.checkConnectivity,
+			"GetPermissions":       s.getPermissions,
+		},
+	}, prop.Map{
+		gonetworkmanager.NetworkManagerInterface: {
+			"Version":                 {Value: Version, Emit: prop.EmitConst},
+			"Devices":                 {Value: []dbus.ObjectPath{}, Emit: prop.EmitTrue},
+			"AllDevices":              {Value: []dbus.ObjectPath{}, Emit: prop.EmitTrue},
+			"ActiveConnections":       {Value: []dbus.ObjectPath{}, Emit: prop.EmitTrue},
+			"PrimaryConnection":       {Value: noObject, Emit: prop.EmitTrue},
+			"NetworkingEnabled":       {Value: true, Emit: prop.EmitTrue},
+			"WirelessEnabled":         {Value: true, Emit: prop.EmitTrue, Writable: true, Callback: s.wirelessEnabledChanged},
+			"WirelessHardwareEnabled": {Value: true, Emit: prop.EmitTrue},
+			"Connectivity":            {Value: uint32(gonetworkmanager.NmConnectivityFull), Emit: prop.EmitTrue},
+		},
+	})
+	s.settings = s.export(gonetworkmanager.SettingsObjectPath, map[string]map[string]any{
+		gonetworkmanager.SettingsInterface: {
+			"ListConnections": s.listConnections,
+			"AddConnection": func(settings map[string]map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+				return s.addConnection(settings, false)
+			},
+			"AddConnectionUnsaved": func(settings map[string]map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+				return s.addConnection(settings, true)
+			},
+			"GetConnectionByUuid": s.getConnectionByUUID,
+		},
+	}, prop.Map{
+		gonetworkmanager.SettingsInterface: {
+			"Connections": {Value: []dbus.ObjectPath{}, Emit: prop.EmitTrue},
+			"Hostname":    {Value: "nmtest", Emit: prop.EmitTrue},
+			"CanModify":   {Value: true, Emit: prop.EmitTrue},
+		},
+	})
+
+	if reply, err := s.conn.RequestName(gonetworkmanager.NetworkManagerInterface, dbus.NameFlagDoNotQueue); err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: %v", gonetworkmanager.NetworkManagerInterface, err)
+	}
+	return s
+}
+
+// export exports methods and properties at path. Failing to export is a bug
+// in the fake, and handlers cannot fail the test, so it panics.
+func (s *Server) export(path dbus.ObjectPath, methods map[string]map[string]any, props prop.Map) *prop.Properties {
+	for iface, table := range methods {
+		if err := s.conn.ExportMethodTable(table, path, iface); err != nil {
+			panic(err)
+		}
+	}
+	p, err := prop.Export(s.conn, path, props)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (s *Server) unexport(path dbus.ObjectPath, ifaces ...string) {
+	for _, iface := range append(ifaces, "org.freedesktop.DBus.Properties") {
+		_ = s.conn.Export(nil, path, iface)
+	}
+}
+
+func (s *Server) newPath(kind string) dbus.ObjectPath {
+	s.ids[kind]++
+	return dbus.ObjectPath(fmt.Sprintf("%s/%s/%d", gonetworkmanager.NetworkManagerObjectPath, kind, s.ids[kind]))
+}
+
+func (s *Server) emit(path dbus.ObjectPath, name string, values ...any) {
+	_ = s.conn.Emit(path, name, values...)
+}
+
+// AddDevice adds a managed wireless device.
+func (s *Server) AddDevice(iface, hwAddress string) dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := &device{path: s.newPath("Devices"), lastScan: -1}
+	state := gonetworkmanager.NmDeviceStateDisconnected
+	if !s.wirelessEnabled() {
+		state = gonetworkmanager.NmDeviceStateUnavailable
+	}
+	d.props = s.export(d.path, map[string]map[string]any{
+		gonetworkmanager.DeviceInterface: {
+			"Disconnect": func() *dbus.Error { return s.disconnect(d) },
+		},
+		gonetworkmanager.DeviceWirelessInterface: {
+			"GetAccessPoints":    func() ([]dbus.ObjectPath, *dbus.Error) { return s.getAccessPoints(d), nil },
+			"GetAllAccessPoints": func() ([]dbus.ObjectPath, *dbus.Error) { return s.getAccessPoints(d), nil },
+			"RequestScan":        func(options map[string]dbus.Variant) *dbus.Error { return s.requestScan(d, options) },
+		},
+	}, prop.Map{
+		gonetworkmanager.DeviceInterface: {
+			"Interface":        {Value: iface, Emit: prop.EmitTrue},
+			"HwAddress":        {Value: hwAddress, Emit: prop.EmitTrue},
+			"Driver":           {Value: "mac80211_hwsim", Emit: prop.EmitConst},
+			"DeviceType":       {Value: uint32(gonetworkmanager.NmDeviceTypeWifi), Emit: prop.EmitConst},
+			"Managed":          {Value: true, Emit: prop.EmitTrue},
+			"State":            {Value: uint32(state), Emit: prop.EmitTrue},
+			"ActiveConnection": {Value: noObject, Emit: prop.EmitTrue},
+			"Ip4Config":        {Value: noObject, Emit: prop.EmitTrue},
+			"Ip6Config":        {Value: noObject, Emit: prop.EmitTrue},
+		},
+		gonetworkmanager.DeviceWirelessInterface: {
+			"HwAddress":         {Value: hwAddress, Emit: prop.EmitTrue},
+			"PermHwAddress":     {Value: hwAddress, Emit: prop.EmitConst},
+			"Mode":              {Value: uint32(gonetworkmanager.Nm80211ModeInfra), Emit: prop.EmitTrue},
+			"Bitrate":           {Value: uint32(0), Emit: prop.EmitTrue},
+			"AccessPoints":      {Value: []dbus.ObjectPath{}, Emit: prop.EmitTrue},
+			"ActiveAccessPoint": {Value: noObject, Emit: prop.EmitTrue},
+			"LastScan":          {Value: d.lastScan, Emit: prop.EmitTrue},
+		},
+	})
+	s.devices = append(s.devices, d)
+	paths := s.devicePaths()
+	s.nm.SetMust(gonetworkmanager.NetworkManagerInterface, "Devices", paths)
+	s.nm.SetMust(gonetworkmanager.NetworkManagerInterface, "AllDevices", paths)
+	s.emit(gonetworkmanager.NetworkManagerObjectPath, gonetworkmanager.NetworkManagerInterface+".DeviceAdded", d.path)
+	return d.path
+}
+
+// SetManaged marks a device as managed by NetworkManager or not.
+func (s *Server) SetManaged(devicePath dbus.ObjectPath, managed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.device(devicePath)
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "Managed", managed)
+	state := gonetworkmanager.NmDeviceStateUnmanaged
+	if managed {
+		state = gonetworkmanager.NmDeviceStateDisconnected
+	}
+	s.setDeviceState(d, state, gonetworkmanager.NmDeviceStateReasonNone)
+}
+
+// AddAccessPoint makes an access point visible to a device, as if a scan
+// had found it.
+func (s *Server) AddAccessPoint(devicePath dbus.ObjectPath, ap AccessPoint) dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.device(devicePath)
+	if ap.Passphrase != "" && ap.Flags == 0 && ap.WPAFlags == 0 && ap.RSNFlags == 0 {
+		ap.Flags = gonetworkmanager.Nm80211APFlagsPrivacy
+		ap.RSNFlags = gonetworkmanager.Nm80211APSecPairCCMP | gonetworkmanager.Nm80211APSecGroupCCMP | gonetworkmanager.Nm80211APSecKeyMgmtPSK
+	}
+	a := &accessPoint{AccessPoint: ap, path: s.newPath("AccessPoint"), device: d}
+	ssid := []byte(ap.SSID)
+	if ap.Hidden {
+		ssid = []byte{}
+	}
+	a.props = s.export(a.path, nil, prop.Map{
+		gonetworkmanager.AccessPointInterface: {
+			"Flags":      {Value: uint32(ap.Flags), Emit: prop.EmitTrue},
+			"WpaFlags":   {Value: uint32(ap.WPAFlags), Emit: prop.EmitTrue},
+			"RsnFlags":   {Value: uint32(ap.RSNFlags), Emit: prop.EmitTrue},
+			"Ssid":       {Value: ssid, Emit: prop.EmitTrue},
+			"Frequency":  {Value: ap.Frequency, Emit: prop.EmitTrue},
+			"HwAddress":  {Value: ap.BSSID, Emit: prop.EmitTrue},
+			"Mode":       {Value: uint32(gonetworkmanager.Nm80211ModeInfra), Emit: prop.EmitTrue},
+			"MaxBitrate": {Value: uint32(130000), Emit: prop.EmitTrue},
+			"Strength":   {Value: ap.Strength, Emit: prop.EmitTrue},
+			"LastSeen":   {Value: int32(time.Since(s.start).Seconds()), Emit: prop.EmitTrue},
+		},
+	})
+	s.accessPoints[a.path] = a
+	d.accessPoints = append(d.accessPoints, a.path)
+	d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "AccessPoints", slices.Clone(d.accessPoints))
+	s.emit(d.path, gonetworkmanager.DeviceWirelessInterface+".AccessPointAdded", a.path)
+	return a.path
+}
+
+// RemoveAccessPoint makes an access point disappear from its device.
+func (s *Server) RemoveAccessPoint(path dbus.ObjectPath) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.accessPoints[path]
+	if !ok {
+		s.t.Fatalf("nmtest: unknown access point %s", path)
+	}
+	delete(s.accessPoints, path)
+	d := a.device
+	d.accessPoints = slices.DeleteFunc(d.accessPoints, func(p dbus.ObjectPath) bool { return p == path })
+	d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "AccessPoints", slices.Clone(d.accessPoints))
+	s.emit(d.path, gonetworkmanager.DeviceWirelessInterface+".AccessPointRemoved", path)
+	s.unexport(path)
+}
+
+// SetStrength changes the signal strength of an access point.
+func (s *Server) SetStrength(path dbus.ObjectPath, strength uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.accessPoints[path]
+	if !ok {
+		s.t.Fatalf("nmtest: unknown access point %s", path)
+	}
+	a.Strength = strength
+	a.props.SetMust(gonetworkmanager.AccessPointInterface, "Strength", strength)
+}
+
+// SetConnectivity sets the connectivity that NetworkManager reports.
+func (s *Server) SetConnectivity(connectivity gonetworkmanager.NmConnectivity) {
+	s.nm.SetMust(gonetworkmanager.NetworkManagerInterface, "Connectivity", uint32(connectivity))
+}
+
+// AddConnection adds a saved connection, as if it had been loaded from disk.
+func (s *Server) AddConnection(settings gonetworkmanager.ConnectionSettings) dbus.ObjectPath {
+	encoded := make(map[string]map[string]dbus.Variant, len(settings))
+	for name, section := range settings {
+		encoded[name] = make(map[string]dbus.Variant, len(section))
+		for key, value := range section {
+			encoded[name][key] = dbus.MakeVariant(value)
+		}
+	}
+	path, err := s.addConnection(encoded, false)
+	if err != nil {
+		s.t.Fatalf("nmtest: failed to add connection: %v", err)
+	}
+	return path
+}
+
+// SavedConnections returns the settings of the saved connections, secrets
+// included, in the order they were added.
+func (s *Server) SavedConnections() []gonetworkmanager.ConnectionSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []gonetworkmanager.ConnectionSettings
+	for _, c := range s.connections {
+		if c.unsaved {
+			continue
+		}
+		settings := make(gonetworkmanager.ConnectionSettings, len(c.settings))
+		for name, section := range c.settings {
+			settings[name] = make(map[string]any, len(section))
+			for key, value := range section {
+				settings[name][key] = value.Value()
+			}
+		}
+		result = append(result, settings)
+	}
+	return result
+}
+
+// ScanRequests returns the SSIDs that each scan so far asked for, which are
+// empty for ordinary scans.
+func (s *Server) ScanRequests() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.scans)
+}
+
+// device returns the device at path. Must be called with s.mu held.
+func (s *Server) device(path dbus.ObjectPath) *device {
+	for _, d := range s.devices {
+		if d.path == path {
+			return d
+		}
+	}
+	s.t.Fatalf("nmtest: unknown device %s", path)
+	return nil
+}
+
+func (s *Server) devicePaths() []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, 0, len(s.devices))
+	for _, d := range s.devices {
+		paths = append(paths, d.path)
+	}
+	return paths
+}
+
+func (s *Server) wirelessEnabled() bool {
+	return s.nm.GetMust(gonetworkmanager.NetworkManagerInterface, "WirelessEnabled").(bool)
+}
+
+func deviceState(d *device) gonetworkmanager.NmDeviceState {
+	return gonetworkmanager.NmDeviceState(d.props.GetMust(gonetworkmanager.DeviceInterface, "State").(uint32))
+}
+
+func (s *Server) setDeviceState(d *device, state gonetworkmanager.NmDeviceState, reason gonetworkmanager.NmDeviceStateReason) {
+	old := deviceState(d)
+	if old == state {
+		return
+	}
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "State", uint32(state))
+	s.emit(d.path, gonetworkmanager.DeviceInterface+".StateChanged", uint32(state), uint32(old), uint32(reason))
+}
+
+// --- org.freedesktop.NetworkManager ---
+
+func (s *Server) getDevices() ([]dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.devicePaths(), nil
+}
+
+func (s *Server) getPermissions() (map[string]string, *dbus.Error) {
+	return map[string]string{
+		"org.freedesktop.NetworkManager.enable-disable-wifi":    "yes",
+		"org.freedesktop.NetworkManager.settings.modify.system": "yes",
+		"org.freedesktop.NetworkManager.wifi.scan":              "yes",
+	}, nil
+}
+
+func (s *Server) checkConnectivity() (uint32, *dbus.Error) {
+	return s.nm.GetMust(gonetworkmanager.NetworkManagerInterface, "Connectivity").(uint32), nil
+}
+
+// wirelessEnabledChanged runs while the property is locked, so the devices
+// are updated once it has been set.
+func (s *Server) wirelessEnabledChanged(change *prop.Change) *dbus.Error {
+	enabled := change.Value.(bool)
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, d := range s.devices {
+			if enabled {
+				s.setDeviceState(d, gonetworkmanager.NmDeviceStateDisconnected, gonetworkmanager.NmDeviceStateReasonNone)
+				continue
+			}
+			for _, ac := range s.active {
+				if ac.device == d {
+					s.deactivate(ac, gonetworkmanager.NmActiveConnectionStateReasonDeviceDisconnected)
+				}
+			}
+			s.setDeviceState(d, gonetworkmanager.NmDeviceStateUnavailable, gonetworkmanager.NmDeviceStateReasonNone)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) activateConnection(connectionPath, devicePath, specificObject dbus.ObjectPath) (dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.connection(connectionPath)
+	if c == nil {
+		return "", dbus.NewError("org.freedesktop.NetworkManager.UnknownConnection", []any{"Connection not found"})
+	}
+	var d *device
+	for _, candidate := range s.devices {
+		if candidate.path == devicePath || (devicePath == noObject && d == nil) {
+			d = candidate
+		}
+	}
+	if d == nil {
+		return "", dbus.NewError("org.freedesktop.NetworkManager.UnknownDevice", []any{"Device not found"})
+	}
+	switch deviceState(d) {
+	case gonetworkmanager.NmDeviceStateUnmanaged, gonetworkmanager.NmDeviceStateUnavailable:
+		return "", dbus.NewError("org.freedesktop.NetworkManager.ConnectionNotAvailable", []any{"Device is not available"})
+	}
+	for _, ac := range s.active {
+		if ac.device == d {
+			s.deactivate(ac, gonetworkmanager.NmActiveConnectionStateReasonDeviceDisconnected)
+		}
+	}
+
+	id, _ := c.settings["connection"]["id"].Value().(string)
+	uuid, _ := c.settings["connection"]["uuid"].Value().(string)
+	typ, _ := c.settings["connection"]["type"].Value().(string)
+	ac := &activeConnection{path: s.newPath("ActiveConnection"), connection: c, device: d, specific: specificObject}
+	ac.props = s.export(ac.path, nil, prop.Map{
+		gonetworkmanager.ActiveConnectionInterface: {
+			"Connection":     {Value: c.path, Emit: prop.EmitTrue},
+			"SpecificObject": {Value: specificObject, Emit: prop.EmitTrue},
+			"Id":             {Value: id, Emit: prop.EmitTrue},
+			"Uuid":           {Value: uuid, Emit: prop.EmitTrue},
+			"Type":           {Value: typ, Emit: prop.EmitTrue},
+			"Devices":        {Value: []dbus.ObjectPath{d.path}, Emit: prop.EmitTrue},
+			"State":          {Value: uint32(gonetworkmanager.NmActiveConnectionStateActivating), Emit: prop.EmitTrue},
+			"StateFlags":     {Value: uint32(0), Emit: prop.EmitTrue},
+			"Default":        {Value: false, Emit: prop.EmitTrue},
+			"Default6":       {Value: false, Emit: prop.EmitTrue},
+			"Ip4Config":      {Value: noObject, Emit: prop.EmitTrue},
+			"Ip6Config":      {Value: noObject, Emit: prop.EmitTrue},
+			"Vpn":            {Value: false, Emit: prop.EmitConst},
+			"Master":         {Value: noObject, Emit: prop.EmitConst},
+		},
+	})
+	s.active = append(s.active, ac)
+	s.setActiveConnections()
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "ActiveConnection", ac.path)
+	s.setDeviceState(d, gonetworkmanager.NmDeviceStatePrepare, gonetworkmanager.NmDeviceStateReasonNone)
+
+	time.AfterFunc(activationDelay, func() { s.finishActivation(ac) })
+	return ac.path, nil
+}
+
+// finishActivation connects or fails an activating connection.
+func (s *Server) finishActivation(ac *activeConnection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ac.done {
+		return
+	}
+	settings := ac.connection.settings
+	d := ac.device
+	wireless := settings["802-11-wireless"]
+	mode, _ := wireless["mode"].Value().(string)
+
+	var ap *accessPoint
+	if mode != "ap" {
+		ap = s.findAccessPoint(ac)
+		if ap == nil {
+			s.fail(ac, gonetworkmanager.NmActiveConnectionStateReasonDeviceDisconnected, gonetworkmanager.NmDeviceStateReasonSsidNotFound)
+			return
+		}
+		if ap.Passphrase != "" && secret(settings) != ap.Passphrase {
+			s.fail(ac, gonetworkmanager.NmActiveConnectionStateReasonNoSecrets, gonetworkmanager.NmDeviceStateReasonNoSecrets)
+			return
+		}
+	}
+
+	n := s.ids["ActiveConnection"]
+	ip4 := s.newPath("IP4Config")
+	s.export(ip4, nil, prop.Map{
+		gonetworkmanager.IP4ConfigInterface: {
+			"AddressData": {Value: []map[string]dbus.Variant{{
+				"address": dbus.MakeVariant(fmt.Sprintf("192.168.%d.%d", n/200, 10+n%200)),
+				"prefix":  dbus.MakeVariant(uint32(24)),
+			}}, Emit: prop.EmitTrue},
+			"Gateway": {Value: fmt.Sprintf("192.168.%d.1", n/200), Emit: prop.EmitTrue},
+			"NameserverData": {Value: []map[string]dbus.Variant{{
+				"address": dbus.MakeVariant(fmt.Sprintf("192.168.%d.1", n/200)),
+			}}, Emit: prop.EmitTrue},
+			"Domains":  {Value: []string{}, Emit: prop.EmitTrue},
+			"Searches": {Value: []string{}, Emit: prop.EmitTrue},
+		},
+	})
+	ip6 := s.newPath("IP6Config")
+	s.export(ip6, nil, prop.Map{
+		gonetworkmanager.IP6ConfigInterface: {
+			"AddressData": {Value: []map[string]dbus.Variant{{
+				"address": dbus.MakeVariant(fmt.Sprintf("fd00::%x", 0x10+n)),
+				"prefix":  dbus.MakeVariant(uint32(64)),
+			}}, Emit: prop.EmitTrue},
+			"Gateway":     {Value: "fd00::1", Emit: prop.EmitTrue},
+			"Nameservers": {Value: [][]byte{net.ParseIP("fd00::1").To16()}, Emit: prop.EmitTrue},
+			"Domains":     {Value: []string{}, Emit: prop.EmitTrue},
+			"Searches":    {Value: []string{}, Emit: prop.EmitTrue},
+		},
+	})
+
+	if section, ok := settings["connection"]; ok {
+		section["timestamp"] = dbus.MakeVariant(uint64(time.Now().Unix()))
+	}
+	apPath := noObject
+	if ap != nil {
+		apPath = ap.path
+	}
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "Ip4Config", ip4)
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "Ip6Config", ip6)
+	d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "ActiveAccessPoint", apPath)
+	d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "Bitrate", uint32(130000))
+	if mode == "ap" {
+		d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "Mode", uint32(gonetworkmanager.Nm80211ModeAp))
+	}
+	ac.props.SetMust(gonetworkmanager.ActiveConnectionInterface, "SpecificObject", apPath)
+	ac.props.SetMust(gonetworkmanager.ActiveConnectionInterface, "Ip4Config", ip4)
+	ac.props.SetMust(gonetworkmanager.ActiveConnectionInterface, "Ip6Config", ip6)
+	ac.props.SetMust(gonetworkmanager.ActiveConnectionInterface, "Default", true)
+	s.setActiveState(ac, gonetworkmanager.NmActiveConnectionStateActivated, gonetworkmanager.NmActiveConnectionStateReasonNone)
+	s.nm.SetMust(gonetworkmanager.NetworkManagerInterface, "PrimaryConnection", ac.path)
+	s.setDeviceState(d, gonetworkmanager.NmDeviceStateActivated, gonetworkmanager.NmDeviceStateReasonNone)
+}
+
+// findAccessPoint returns the access point to activate a connection through:
+// the one it was activated with, or else the strongest one broadcasting its
+// SSID that satisfies its BSSID lock.
+func (s *Server) findAccessPoint(ac *activeConnection) *accessPoint {
+	wireless := ac.connection.settings["802-11-wireless"]
+	ssid, _ := wireless["ssid"].Value().([]byte)
+	hidden, _ := wireless["hidden"].Value().(bool)
+	bssid, _ := wireless["bssid"].Value().([]byte)
+	matches := func(a *accessPoint) bool {
+		if a.SSID != string(ssid) || (a.Hidden && !hidden) {
+			return false
+		}
+		if len(bssid) > 0 {
+			mac, err := net.ParseMAC(a.BSSID)
+			return err == nil && slices.Equal(mac, bssid)
+		}
+		return true
+	}
+
+	if ac.specific != noObject {
+		if a, ok := s.accessPoints[ac.specific]; ok && a.device == ac.device && matches(a) {
+			return a
+		}
+		return nil
+	}
+	var best *accessPoint
+	for _, path := range ac.device.accessPoints {
+		a := s.accessPoints[path]
+		if matches(a) && (best == nil || a.Strength > best.Strength) {
+			best = a
+		}
+	}
+	return best
+}
+
+// secret returns the secret a connection authenticates with.
+func secret(settings map[string]map[string]dbus.Variant) string {
+	for _, key := range []struct{ section, key string }{
+		{"802-11-wireless-security", "psk"},
+		{"802-11-wireless-security", "wep-key0"},
+		{"802-1x", "password"},
+	} {
+		if value, ok := settings[key.section][key.key].Value().(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func (s *Server) setActiveState(ac *activeConnection, state gonetworkmanager.NmActiveConnectionState, reason gonetworkmanager.NmActiveConnectionStateReason) {
+	ac.props.SetMust(gonetworkmanager.ActiveConnectionInterface, "State", uint32(state))
+	s.emit(ac.path, gonetworkmanager.ActiveConnectionInterface+".StateChanged", uint32(state), uint32(reason))
+}
+
+func (s *Server) setActiveConnections() {
+	paths := make([]dbus.ObjectPath, 0, len(s.active))
+	for _, ac := range s.active {
+		paths = append(paths, ac.path)
+	}
+	s.nm.SetMust(gonetworkmanager.NetworkManagerInterface, "ActiveConnections", paths)
+}
+
+// fail ends an activation that could not connect.
+func (s *Server) fail(ac *activeConnection, reason gonetworkmanager.NmActiveConnectionStateReason, deviceReason gonetworkmanager.NmDeviceStateReason) {
+	s.setDeviceState(ac.device, gonetworkmanager.NmDeviceStateFailed, deviceReason)
+	s.deactivate(ac, reason)
+}
+
+// deactivate tears down an active connection. Its object stays exported so
+// clients can still read its final state.
+func (s *Server) deactivate(ac *activeConnection, reason gonetworkmanager.NmActiveConnectionStateReason) {
+	if ac.done {
+		return
+	}
+	ac.done = true
+	s.active = slices.DeleteFunc(s.active, func(other *activeConnection) bool { return other == ac })
+	s.setActiveConnections()
+	if s.nm.GetMust(gonetworkmanager.NetworkManagerInterface, "PrimaryConnection").(dbus.ObjectPath) == ac.path {
+		s.nm.SetMust(gonetworkmanager.NetworkManagerInterface, "PrimaryConnection", noObject)
+	}
+	s.setActiveState(ac, gonetworkmanager.NmActiveConnectionStateDeactivated, reason)
+
+	d := ac.device
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "ActiveConnection", noObject)
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "Ip4Config", noObject)
+	d.props.SetMust(gonetworkmanager.DeviceInterface, "Ip6Config", noObject)
+	d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "ActiveAccessPoint", noObject)
+	d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "Bitrate", uint32(0))
+	d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "Mode", uint32(gonetworkmanager.Nm80211ModeInfra))
+	s.setDeviceState(d, gonetworkmanager.NmDeviceStateDisconnected, gonetworkmanager.NmDeviceStateReasonUserRequested)
+}
+
+func (s *Server) deactivateConnection(path dbus.ObjectPath) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ac := range s.active {
+		if ac.path == path {
+			s.deactivate(ac, gonetworkmanager.NmActiveConnectionStateReasonUserDisconnected)
+			return nil
+		}
+	}
+	return dbus.NewError("org.freedesktop.NetworkManager.ConnectionNotActive", []any{"Connection is not active"})
+}
+
+// --- org.freedesktop.NetworkManager.Device ---
+
+func (s *Server) disconnect(d *device) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ac := range s.active {
+		if ac.device == d {
+			s.deactivate(ac, gonetworkmanager.NmActiveConnectionStateReasonUserDisconnected)
+			return nil
+		}
+	}
+	return dbus.NewError("org.freedesktop.NetworkManager.Device.NotActive", []any{"This device is not active"})
+}
+
+func (s *Server) getAccessPoints(d *device) []dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(d.accessPoints)
+}
+
+// requestScan records the scan and bumps LastScan once it is done, revealing
+// the hidden access points it asked for.
+func (s *Server) requestScan(d *device, options map[string]dbus.Variant) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch deviceState(d) {
+	case gonetworkmanager.NmDeviceStateUnmanaged, gonetworkmanager.NmDeviceStateUnavailable:
+		return dbus.NewError("org.freedesktop.NetworkManager.Device.NotAllowed", []any{"Scanning not allowed while unavailable"})
+	}
+	var ssids []string
+	if value, ok := options["ssids"].Value().([][]byte); ok {
+		for _, ssid := range value {
+			ssids = append(ssids, string(ssid))
+		}
+	}
+	s.scans = append(s.scans, ssids)
+
+	time.AfterFunc(scanDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, path := range d.accessPoints {
+			if a := s.accessPoints[path]; a.Hidden && slices.Contains(ssids, a.SSID) {
+				a.props.SetMust(gonetworkmanager.AccessPointInterface, "Ssid", []byte(a.SSID))
+			}
+		}
+		d.lastScan = max(d.lastScan+1, time.Since(s.start).Milliseconds()+1)
+		d.props.SetMust(gonetworkmanager.DeviceWirelessInterface, "LastScan", d.lastScan)
+	})
+	return nil
+}
+
+// --- org.freedesktop.NetworkManager.Settings ---
+
+func (s *Server) connectionPaths() []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, 0, len(s.connections))
+	for _, c := range s.connections {
+		paths = append(paths, c.path)
+	}
+	return paths
+}
+
+// connection returns the connection at path, or nil. Must be called with
+// s.mu held.
+func (s *Server) connection(path dbus.ObjectPath) *connection {
+	for _, c := range s.connections {
+		if c.path == path {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) listConnections() ([]dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connectionPaths(), nil
+}
+
+func (s *Server) getConnectionByUUID(uuid string) (dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.connections {
+		if value, _ := c.settings["connection"]["uuid"].Value().(string); value == uuid {
+			return c.path, nil
+		}
+	}
+	return "", dbus.NewError("org.freedesktop.NetworkManager.Settings.InvalidConnection", []any{"No connection with the UUID was found"})
+}
+
+func (s *Server) addConnection(settings map[string]map[string]dbus.Variant, unsaved bool) (dbus.ObjectPath, *dbus.Error) {
+	if _, ok := settings["connection"]; !ok {
+		return "", dbus.NewError("org.freedesktop.NetworkManager.Settings.Connection.MissingSetting", []any{"connection: setting is required"})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &connection{path: s.newPath("Settings"), settings: settings, unsaved: unsaved}
+	c.props = s.export(c.path, map[string]map[string]any{
+		gonetworkmanager.ConnectionInterface: {
+			"GetSettings":   func() (map[string]map[string]dbus.Variant, *dbus.Error) { return s.getSettings(c), nil },
+			"GetSecrets":    func(name string) (map[string]map[string]dbus.Variant, *dbus.Error) { return s.getSecrets(c, name), nil },
+			"Update":        func(settings map[string]map[string]dbus.Variant) *dbus.Error { return s.update(c, settings, false) },
+			"UpdateUnsaved": func(settings map[string]map[string]dbus.Variant) *dbus.Error { return s.update(c, settings, true) },
+			"Save":          func() *dbus.Error { return s.save(c) },
+			"Delete":        func() *dbus.Error { return s.delete(c) },
+		},
+	}, prop.Map{
+		gonetworkmanager.ConnectionInterface: {
+			"Unsaved":  {Value: unsaved, Emit: prop.EmitTrue},
+			"Flags":    {Value: uint32(0), Emit: prop.EmitTrue},
+			"Filename": {Value: "", Emit: prop.EmitTrue},
+		},
+	})
+	s.connections = append(s.connections, c)
+	s.settings.SetMust(gonetworkmanager.SettingsInterface, "Connections", s.connectionPaths())
+	s.emit(gonetworkmanager.SettingsObjectPath, gonetworkmanager.SettingsInterface+".NewConnection", c.path)
+	return c.path, nil
+}
+
+// --- org.freedesktop.NetworkManager.Settings.Connection ---
+
+func isSecret(section, key string) bool {
+	return slices.Contains(secretKeys[section], key)
+}
+
+func (s *Server) getSettings(c *connection) map[string]map[string]dbus.Variant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make(map[string]map[string]dbus.Variant, len(c.settings))
+	for name, section := range c.settings {
+		result[name] = make(map[string]dbus.Variant, len(section))
+		for key, value := range section {
+			if !isSecret(name, key) {
+				result[name][key] = value
+			}
+		}
+	}
+	return result
+}
+
+func (s *Server) getSecrets(c *connection, name string) map[string]map[string]dbus.Variant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secrets := make(map[string]dbus.Variant)
+	for key, value := range c.settings[name] {
+		if isSecret(name, key) {
+			secrets[key] = value
+		}
+	}
+	return map[string]map[string]dbus.Variant{name: secrets}
+}
+
+// update replaces the settings of a connection. Secrets left out of the new
+// settings are kept, so that settings read with GetSettings can be written
+// back without losing them.
+func (s *Server) update(c *connection, settings map[string]map[string]dbus.Variant, unsaved bool) *dbus.Error {
+	if _, ok := settings["connection"]; !ok {
+		return dbus.NewError("org.freedesktop.NetworkManager.Settings.Connection.MissingSetting", []any{"connection: setting is required"})
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, section := range c.settings {
+		for key, value := range section {
+			if !isSecret(name, key) {
+				continue
+			}
+			if _, ok := settings[name]; !ok {
+				continue
+			}
+			if _, ok := settings[name][key]; !ok {
+				settings[name][key] = value
+			}
+		}
+	}
+	c.settings = settings
+	s.setUnsaved(c, unsaved)
+	s.emit(c.path, gonetworkmanager.ConnectionInterface+".Updated")
+	return nil
+}
+
+func (s *Server) save(c *connection) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setUnsaved(c, false)
+	return nil
+}
+
+func (s *Server) setUnsaved(c *connection, unsaved bool) {
+	c.unsaved = unsaved
+	c.props.SetMust(gonetworkmanager.ConnectionInterface, "Unsaved", unsaved)
+}
+
+// delete removes a connection, deactivating it first if it is active.
+func (s *Server) delete(c *connection) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ac := range slices.Clone(s.active) {
+		if ac.connection == c {
+			s.deactivate(ac, gonetworkmanager.NmActiveConnectionStateReasonConnectionRemoved)
+		}
+	}
+	s.connections = slices.DeleteFunc(s.connections, func(other *connection) bool { return other == c })
+	s.settings.SetMust(gonetworkmanager.SettingsInterface, "Connections", s.connectionPaths())
+	s.emit(c.path, gonetworkmanager.ConnectionInterface+".Removed")
+	s.emit(gonetworkmanager.SettingsObjectPath, gonetworkmanager.SettingsInterface+".ConnectionRemoved", c.path)
+	s.unexport(c.path, gonetworkmanager.ConnectionInterface)
+	return nil
+}
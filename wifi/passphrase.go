@@ -0,0 +1,65 @@
+package wifi
+
+import "fmt"
+
+// Passphrase length limits of the security types that use one.
+const (
+	MinWPAPassphraseLength = 8
+	MaxWPAPassphraseLength = 63
+	// WEP keys are 5 or 13 ASCII characters, for 64 and 128 bit WEP.
+	WEP40PassphraseLength  = 5
+	WEP104PassphraseLength = 13
+)
+
+// ValidatePassphrase returns ErrInvalidCredentials if passphrase does not fit
+// security. WPA also accepts a raw key of 64 hex digits and WEP keys of 10 or
+// 26 hex digits. The WPA limits come from PSK, so they also apply to
+// transition mode networks, but SAE passwords only need to be non-empty.
+// Security types without a passphrase accept anything, since it is ignored.
+func ValidatePassphrase(security SecurityType, passphrase string) error {
+	switch security {
+	case SecuritySAE:
+		if passphrase == "" {
+			return fmt.Errorf("WPA3 passphrase must not be empty: %w", ErrInvalidCredentials)
+		}
+	case SecurityWPA, SecurityWPA2WPA3:
+		if len(passphrase) == 64 && isHex(passphrase) {
+			return nil
+		}
+		if len(passphrase) < MinWPAPassphraseLength || len(passphrase) > MaxWPAPassphraseLength {
+			return fmt.Errorf("WPA passphrase must be %d to %d characters: %w", MinWPAPassphraseLength, MaxWPAPassphraseLength, ErrInvalidCredentials)
+		}
+		if !isPrintableASCII(passphrase) {
+			return fmt.Errorf("WPA passphrase must be printable ASCII: %w", ErrInvalidCredentials)
+		}
+	case SecurityWEP:
+		switch {
+		case len(passphrase) == WEP40PassphraseLength || len(passphrase) == WEP104PassphraseLength:
+			if !isPrintableASCII(passphrase) {
+				return fmt.Errorf("WEP key must be printable ASCII: %w", ErrInvalidCredentials)
+			}
+		case (len(passphrase) == 10 || len(passphrase) == 26) && isHex(passphrase):
+		default:
+			return fmt.Errorf("WEP key must be %d or %d characters: %w", WEP40PassphraseLength, WEP104PassphraseLength, ErrInvalidCredentials)
+		}
+	}
+	return nil
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isPrintableASCII(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
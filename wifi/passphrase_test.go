@@ -0,0 +1,43 @@
+package wifi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidatePassphrase(t *testing.T) {
+	tests := []struct {
+		security   SecurityType
+		passphrase string
+		valid      bool
+	}{
+		{SecurityWPA, "password", true},
+		{SecurityWPA, "short", false},
+		{SecurityWPA, strings.Repeat("a", 63), true},
+		{SecurityWPA, strings.Repeat("x", 64), false},
+		{SecurityWPA, strings.Repeat("ab", 32), true}, // raw key
+		{SecurityWPA, "pässwörd", false},
+		{SecurityWPA2WPA3, "short", false},
+		{SecuritySAE, "short", true},
+		{SecuritySAE, strings.Repeat("x", 100), true},
+		{SecuritySAE, "pässwörd", true},
+		{SecuritySAE, "", false},
+		{SecurityWEP, "abcde", true},
+		{SecurityWEP, "abcdefghijklm", true},
+		{SecurityWEP, "0123456789", true},
+		{SecurityWEP, "abcdef", false},
+		{SecurityWEP, "ghijklmnop", false},
+		{SecurityOpen, "", true},
+		{SecurityEnterprise, "x", true},
+	}
+	for _, tt := range tests {
+		err := ValidatePassphrase(tt.security, tt.passphrase)
+		if tt.valid && err != nil {
+			t.Errorf("ValidatePassphrase(%v, %q) = %v, want nil", tt.security, tt.passphrase, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("ValidatePassphrase(%v, %q) = %v, want %v", tt.security, tt.passphrase, err, ErrInvalidCredentials)
+		}
+	}
+}
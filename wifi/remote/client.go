@@ -0,0 +1,228 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/trace"
+)
+
+// Client is a wifi.Backend that makes its calls on a daemon serving the API
+// on a Unix socket.
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+var _ wifi.Backend = (*Client)(nil)
+
+// Dial returns a client of the daemon listening on socket, after checking
+// that it answers.
+func Dial(socket string) (*Client, error) {
+	c := &Client{
+		socket: socket,
+		http: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}},
+	}
+	if _, err := c.IsWirelessEnabled(); err != nil && !isRemote(err) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// isRemote returns true if err was returned by the daemon's backend, rather
+// than by the connection to the daemon.
+func isRemote(err error) bool {
+	var remoteErr *remoteError
+	return errors.As(err, &remoteErr)
+}
+
+// remoteError is an error answered by the daemon.
+type remoteError struct {
+	err error
+}
+
+func (e *remoteError) Error() string { return e.err.Error() }
+func (e *remoteError) Unwrap() error { return e.err }
+
+// request sends a request with body as JSON, if it is not nil, and returns
+// the response of a successful one.
+func (c *Client) request(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	// The host is ignored, as every connection goes to the socket.
+	req, err := http.NewRequestWithContext(ctx, method, "http://wifitui"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("no wifitui daemon on %s: %w: %w", c.socket, wifi.ErrNotAvailable, err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == nil {
+			return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return nil, &remoteError{err: trace.DecodeError(errResp.Error)}
+	}
+	return resp, nil
+}
+
+// call makes a request and decodes the response into result, if it is not
+// nil.
+func (c *Client) call(method, path string, body, result any) error {
+	resp, err := c.request(context.Background(), method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+	}
+	return nil
+}
+
+func networkPath(ssid string, suffix string) string {
+	return "/networks/" + url.PathEscape(ssid) + suffix
+}
+
+func (c *Client) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error) {
+	mode := "never"
+	for name, m := range scanModes {
+		if m == scan {
+			mode = name
+		}
+	}
+	var resp networksResponse
+	if err := c.call(http.MethodGet, "/networks?scan="+mode, nil, &resp); err != nil {
+		return wifi.NetworksResult{}, err
+	}
+	return wifi.NetworksResult{Networks: resp.Networks, ScanError: trace.DecodeError(resp.ScanError)}, nil
+}
+
+func (c *Client) ActivateNetwork(ssid string) error {
+	return c.call(http.MethodPost, networkPath(ssid, "/activate"), nil, nil)
+}
+
+func (c *Client) ActivateAccessPoint(ssid, bssid string) error {
+	return c.call(http.MethodPost, networkPath(ssid, "/activate"), activateRequest{BSSID: bssid}, nil)
+}
+
+func (c *Client) ForgetNetwork(ssid string) error {
+	return c.call(http.MethodDelete, networkPath(ssid, ""), nil, nil)
+}
+
+func (c *Client) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
+	return c.call(http.MethodPost, networkPath(ssid, "/join"), opts, nil)
+}
+
+func (c *Client) GetSecrets(ssid string) (string, error) {
+	var resp secretResponse
+	err := c.call(http.MethodGet, networkPath(ssid, "/secret"), nil, &resp)
+	return resp.Secret, err
+}
+
+func (c *Client) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	var details *wifi.ConnectionDetails
+	err := c.call(http.MethodGet, "/connection", nil, &details)
+	return details, err
+}
+
+func (c *Client) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
+	return c.call(http.MethodPatch, networkPath(ssid, ""), opts, nil)
+}
+
+func (c *Client) Devices() ([]wifi.Device, error) {
+	var devices []wifi.Device
+	err := c.call(http.MethodGet, "/devices", nil, &devices)
+	return devices, err
+}
+
+// SelectDevice selects the device of the daemon, for all of its clients.
+func (c *Client) SelectDevice(iface string) error {
+	return c.call(http.MethodPut, "/devices/selected", selectRequest{Interface: iface}, nil)
+}
+
+func (c *Client) IsWirelessEnabled() (bool, error) {
+	var resp radioState
+	err := c.call(http.MethodGet, "/radio", nil, &resp)
+	return resp.Enabled, err
+}
+
+func (c *Client) SetWireless(enabled bool) error {
+	return c.call(http.MethodPut, "/radio", radioState{Enabled: enabled}, nil)
+}
+
+func (c *Client) Connectivity() (wifi.Connectivity, error) {
+	var resp connectivityResponse
+	err := c.call(http.MethodGet, "/connectivity", nil, &resp)
+	return resp.Connectivity, err
+}
+
+func (c *Client) StartHotspot(config wifi.HotspotConfig) error {
+	return c.call(http.MethodPut, "/hotspot", config, nil)
+}
+
+func (c *Client) StopHotspot() error {
+	return c.call(http.MethodDelete, "/hotspot", nil, nil)
+}
+
+func (c *Client) HotspotStatus() (wifi.HotspotStatus, error) {
+	var status wifi.HotspotStatus
+	err := c.call(http.MethodGet, "/hotspot", nil, &status)
+	return status, err
+}
+
+// WatchEvents streams the events of the daemon's backend. The channel is
+// closed when ctx is cancelled or the daemon goes away.
+func (c *Client) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
+	resp, err := c.request(ctx, http.MethodGet, "/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan wifi.Event, 16)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var e wifi.Event
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				return
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
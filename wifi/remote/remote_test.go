@@ -0,0 +1,180 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/mock"
+)
+
+// serve starts a daemon for a mock backend and returns a client of it.
+func serve(t *testing.T) (*mock.MockBackend, *Client, string) {
+	t.Helper()
+	b, err := mock.New()
+	if err != nil {
+		t.Fatalf("mock.New() failed: %v", err)
+	}
+	m := b.(*mock.MockBackend)
+	m.ActionSleep = 0
+	m.DisableRandomization = true
+
+	socket := filepath.Join(t.TempDir(), "wifitui.sock")
+	l, err := Listen(socket, "")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	server := &http.Server{Handler: NewHandler(m)}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+
+	client, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	return m, client, socket
+}
+
+func TestListen(t *testing.T) {
+	_, _, socket := serve(t)
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket permissions = %o, want 600", perm)
+	}
+	if _, err := Listen(socket, ""); err == nil {
+		t.Error("Listen() on the socket of a running daemon returned nil error")
+	}
+}
+
+func TestClient(t *testing.T) {
+	_, client, _ := serve(t)
+
+	result, err := client.ListNetworks(wifi.ScanAuto)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	if len(result.Networks) == 0 || result.ScanError != nil {
+		t.Fatalf("ListNetworks() = %+v", result)
+	}
+	if err := client.ActivateNetwork("Password is password"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	details, err := client.ActiveConnection()
+	if err != nil || details == nil || details.SSID != "Password is password" {
+		t.Errorf("ActiveConnection() = %+v, %v", details, err)
+	}
+	if err := client.ActivateAccessPoint("Mesh Network", "aa:bb:cc:dd:ee:02"); err != nil {
+		t.Fatalf("ActivateAccessPoint() failed: %v", err)
+	}
+	if details, _ := client.ActiveConnection(); details == nil || details.BSSID != "AA:BB:CC:DD:EE:02" {
+		t.Errorf("ActivateAccessPoint() connected to %+v", details)
+	}
+
+	// SSIDs may contain anything, including slashes.
+	ssid := "Cafe / Bar?#1"
+	if err := client.JoinNetwork(ssid, wifi.JoinOptions{Password: "hunter22", Security: wifi.SecurityWPA}); err != nil {
+		t.Fatalf("JoinNetwork() failed: %v", err)
+	}
+	if secret, err := client.GetSecrets(ssid); err != nil || secret != "hunter22" {
+		t.Errorf("GetSecrets() = %q, %v", secret, err)
+	}
+	priority := 5
+	if err := client.UpdateNetwork(ssid, wifi.UpdateOptions{Priority: &priority}); err != nil {
+		t.Fatalf("UpdateNetwork() failed: %v", err)
+	}
+	if err := client.ForgetNetwork(ssid); err != nil {
+		t.Fatalf("ForgetNetwork() failed: %v", err)
+	}
+	if err := client.ForgetNetwork(ssid); !errors.Is(err, wifi.ErrNotFound) {
+		t.Errorf("ForgetNetwork() of a forgotten network = %v, want ErrNotFound", err)
+	}
+
+	if err := client.SetWireless(false); err != nil {
+		t.Fatalf("SetWireless() failed: %v", err)
+	}
+	if enabled, err := client.IsWirelessEnabled(); err != nil || enabled {
+		t.Errorf("IsWirelessEnabled() = %v, %v after disabling", enabled, err)
+	}
+	if _, err := client.ListNetworks(wifi.ScanNever); !errors.Is(err, wifi.ErrWirelessDisabled) {
+		t.Errorf("ListNetworks() = %v with the radio off, want ErrWirelessDisabled", err)
+	}
+	if err := client.SetWireless(true); err != nil {
+		t.Fatalf("SetWireless() failed: %v", err)
+	}
+
+	if err := client.SelectDevice("wlan1"); err != nil {
+		t.Fatalf("SelectDevice() failed: %v", err)
+	}
+	devices, err := client.Devices()
+	if err != nil || len(devices) != 2 || !devices[1].Selected {
+		t.Errorf("Devices() = %+v, %v", devices, err)
+	}
+
+	if err := client.StartHotspot(wifi.HotspotConfig{SSID: "Share", Password: "sharing is caring"}); err != nil {
+		t.Fatalf("StartHotspot() failed: %v", err)
+	}
+	if status, err := client.HotspotStatus(); err != nil || !status.Active || status.Password != "sharing is caring" {
+		t.Errorf("HotspotStatus() = %+v, %v", status, err)
+	}
+	if err := client.StopHotspot(); err != nil {
+		t.Fatalf("StopHotspot() failed: %v", err)
+	}
+}
+
+func TestClientScanError(t *testing.T) {
+	m, client, _ := serve(t)
+	m.ScanFailures = []*wifi.ScanFailure{{
+		Backend: "mock",
+		Stage:   wifi.ScanStageRequest,
+		Device:  "wlan0",
+		Cause:   fmt.Errorf("%w: busy", wifi.ErrScanDeviceUnavailable),
+	}}
+	result, err := client.ListNetworks(wifi.ScanForce)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	var failure *wifi.ScanFailure
+	if !errors.As(result.ScanError, &failure) || failure.Stage != wifi.ScanStageRequest || !errors.Is(result.ScanError, wifi.ErrScanDeviceUnavailable) {
+		t.Errorf("scan error %v lost its classification", result.ScanError)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	_, client, _ := serve(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.WatchEvents(ctx)
+	if err != nil {
+		t.Fatalf("WatchEvents() failed: %v", err)
+	}
+	if err := client.ActivateNetwork("Password is password"); err != nil {
+		t.Fatalf("ActivateNetwork() failed: %v", err)
+	}
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == wifi.EventConnectionStateChanged && e.State == wifi.ConnectionActivated {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the activation event")
+		}
+	}
+}
+
+func TestDialWithoutDaemon(t *testing.T) {
+	_, err := Dial(filepath.Join(t.TempDir(), "missing.sock"))
+	if !errors.Is(err, wifi.ErrNotAvailable) {
+		t.Errorf("Dial() = %v, want ErrNotAvailable", err)
+	}
+}
@@ -0,0 +1,362 @@
+// Package remote serves a wifi.Backend over HTTP on a Unix socket, and
+// provides a wifi.Backend that is a client of such a server. This lets other
+// programs control Wi-Fi through a daemon, without linking Go code or needing
+// their own D-Bus permissions. Access is controlled by the permissions of the
+// socket file.
+//
+// The API exchanges JSON documents of the wifi types:
+//
+//	GET    /networks?scan=never|auto|force  {"networks": [...], "scan_error": ...}
+//	POST   /networks/{ssid}/activate        {"bssid": "..."}, the BSSID is optional
+//	POST   /networks/{ssid}/join            wifi.JoinOptions
+//	PATCH  /networks/{ssid}                 wifi.UpdateOptions
+//	DELETE /networks/{ssid}
+//	GET    /networks/{ssid}/secret          {"secret": "..."}
+//	GET    /connection                      wifi.ConnectionDetails, or null
+//	GET    /connectivity                    {"connectivity": "full"}
+//	GET    /devices                         [wifi.Device, ...]
+//	PUT    /devices/selected                {"interface": "wlan1"}
+//	GET    /radio                           {"enabled": true}
+//	PUT    /radio                           {"enabled": false}
+//	GET    /hotspot                         wifi.HotspotStatus
+//	PUT    /hotspot                         wifi.HotspotConfig, starts the hotspot
+//	DELETE /hotspot
+//	GET    /events                          wifi.Event per line, until disconnected
+//
+// Failed requests answer with an error status and {"error": ...}, where the
+// error is a trace.Error that names the wifi errors it matches.
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/trace"
+)
+
+// errInvalidRequest marks errors in requests rather than in the backend.
+var errInvalidRequest = errors.New("invalid request")
+
+// scanModes are the values of the scan parameter of GET /networks.
+var scanModes = map[string]wifi.ScanMode{
+	"never": wifi.ScanNever,
+	"auto":  wifi.ScanAuto,
+	"force": wifi.ScanForce,
+}
+
+type networksResponse struct {
+	Networks  []wifi.Network `json:"networks"`
+	ScanError *trace.Error   `json:"scan_error,omitempty"`
+}
+
+type activateRequest struct {
+	BSSID string `json:"bssid,omitempty"`
+}
+
+type secretResponse struct {
+	Secret string `json:"secret"`
+}
+
+type connectivityResponse struct {
+	Connectivity wifi.Connectivity `json:"connectivity"`
+}
+
+type selectRequest struct {
+	Interface string `json:"interface"`
+}
+
+type radioState struct {
+	Enabled bool `json:"enabled"`
+}
+
+type errorResponse struct {
+	Error *trace.Error `json:"error"`
+}
+
+// server answers API requests with a backend. Backends expect a single
+// caller, so requests take turns, except for event streams.
+type server struct {
+	backend wifi.Backend
+	mu      sync.Mutex
+}
+
+// NewHandler returns the HTTP handler of the API for b.
+func NewHandler(b wifi.Backend) http.Handler {
+	s := &server{backend: b}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /networks", s.listNetworks)
+	mux.HandleFunc("POST /networks/{ssid}/activate", s.activateNetwork)
+	mux.HandleFunc("POST /networks/{ssid}/join", s.joinNetwork)
+	mux.HandleFunc("PATCH /networks/{ssid}", s.updateNetwork)
+	mux.HandleFunc("DELETE /networks/{ssid}", s.forgetNetwork)
+	mux.HandleFunc("GET /networks/{ssid}/secret", s.getSecret)
+	mux.HandleFunc("GET /connection", s.activeConnection)
+	mux.HandleFunc("GET /connectivity", s.connectivity)
+	mux.HandleFunc("GET /devices", s.devices)
+	mux.HandleFunc("PUT /devices/selected", s.selectDevice)
+	mux.HandleFunc("GET /radio", s.radio)
+	mux.HandleFunc("PUT /radio", s.setRadio)
+	mux.HandleFunc("GET /hotspot", s.hotspotStatus)
+	mux.HandleFunc("PUT /hotspot", s.startHotspot)
+	mux.HandleFunc("DELETE /hotspot", s.stopHotspot)
+	mux.HandleFunc("GET /events", s.events)
+	return mux
+}
+
+func (s *server) listNetworks(w http.ResponseWriter, r *http.Request) {
+	scan := wifi.ScanNever
+	if name := r.URL.Query().Get("scan"); name != "" {
+		var ok bool
+		if scan, ok = scanModes[name]; !ok {
+			writeError(w, fmt.Errorf("unknown scan mode %q: %w", name, errInvalidRequest))
+			return
+		}
+	}
+	s.mu.Lock()
+	result, err := s.backend.ListNetworks(scan)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, networksResponse{Networks: result.Networks, ScanError: trace.EncodeError(result.ScanError)})
+}
+
+func (s *server) activateNetwork(w http.ResponseWriter, r *http.Request) {
+	var req activateRequest
+	if r.ContentLength != 0 && !readJSON(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	var err error
+	if req.BSSID != "" {
+		err = s.backend.ActivateAccessPoint(r.PathValue("ssid"), req.BSSID)
+	} else {
+		err = s.backend.ActivateNetwork(r.PathValue("ssid"))
+	}
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+func (s *server) joinNetwork(w http.ResponseWriter, r *http.Request) {
+	var opts wifi.JoinOptions
+	if !readJSON(w, r, &opts) {
+		return
+	}
+	s.mu.Lock()
+	err := s.backend.JoinNetwork(r.PathValue("ssid"), opts)
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+func (s *server) updateNetwork(w http.ResponseWriter, r *http.Request) {
+	var opts wifi.UpdateOptions
+	if !readJSON(w, r, &opts) {
+		return
+	}
+	s.mu.Lock()
+	err := s.backend.UpdateNetwork(r.PathValue("ssid"), opts)
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+func (s *server) forgetNetwork(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	err := s.backend.ForgetNetwork(r.PathValue("ssid"))
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+func (s *server) getSecret(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	secret, err := s.backend.GetSecrets(r.PathValue("ssid"))
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, secretResponse{Secret: secret})
+}
+
+func (s *server) activeConnection(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	details, err := s.backend.ActiveConnection()
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, details)
+}
+
+func (s *server) connectivity(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	connectivity, err := s.backend.Connectivity()
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, connectivityResponse{Connectivity: connectivity})
+}
+
+func (s *server) devices(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	devices, err := s.backend.Devices()
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, devices)
+}
+
+func (s *server) selectDevice(w http.ResponseWriter, r *http.Request) {
+	var req selectRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	err := s.backend.SelectDevice(req.Interface)
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+func (s *server) radio(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	enabled, err := s.backend.IsWirelessEnabled()
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, radioState{Enabled: enabled})
+}
+
+func (s *server) setRadio(w http.ResponseWriter, r *http.Request) {
+	var req radioState
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	err := s.backend.SetWireless(req.Enabled)
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+func (s *server) hotspotStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status, err := s.backend.HotspotStatus()
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *server) startHotspot(w http.ResponseWriter, r *http.Request) {
+	var config wifi.HotspotConfig
+	if !readJSON(w, r, &config) {
+		return
+	}
+	s.mu.Lock()
+	err := s.backend.StartHotspot(config)
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+func (s *server) stopHotspot(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	err := s.backend.StopHotspot()
+	s.mu.Unlock()
+	writeResult(w, err)
+}
+
+// events streams the events of the backend as JSON lines until the client
+// disconnects or the backend stops reporting changes.
+func (s *server) events(w http.ResponseWriter, r *http.Request) {
+	events, err := s.backend.WatchEvents(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jsonl")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for e := range events {
+		if err := enc.Encode(e); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// readJSON decodes the request body into v, answering with an error if it is
+// invalid.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, fmt.Errorf("%w: %w", errInvalidRequest, err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeResult answers a request without a response body.
+func writeResult(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errorStatuses maps errors to HTTP statuses. The first match wins.
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{wifi.ErrNotFound, http.StatusNotFound},
+	{wifi.ErrNotSupported, http.StatusNotImplemented},
+	{wifi.ErrMissingPermission, http.StatusForbidden},
+	{wifi.ErrScanPermissionDenied, http.StatusForbidden},
+	{wifi.ErrWirelessDisabled, http.StatusConflict},
+	{wifi.ErrNotAvailable, http.StatusServiceUnavailable},
+	{wifi.ErrIncorrectPassphrase, http.StatusBadRequest},
+	{wifi.ErrInvalidCredentials, http.StatusBadRequest},
+	{wifi.ErrInvalidIPConfig, http.StatusBadRequest},
+	{wifi.ErrInvalidBSSID, http.StatusBadRequest},
+	{wifi.ErrInvalidBand, http.StatusBadRequest},
+	{wifi.ErrInvalidPriority, http.StatusBadRequest},
+	{errInvalidRequest, http.StatusBadRequest},
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.err) {
+			status = e.status
+			break
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: trace.EncodeError(err)})
+}
@@ -0,0 +1,244 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// Recorder is a wifi.Backend that passes every call through to another
+// backend and writes it to a trace.
+type Recorder struct {
+	backend wifi.Backend
+	start   time.Time
+
+	mu      sync.Mutex
+	w       io.Writer
+	err     error
+	watches int
+}
+
+var _ wifi.Backend = (*Recorder)(nil)
+
+// NewRecorder returns a backend that records the calls made to b into w, one
+// line per call.
+func NewRecorder(b wifi.Backend, w io.Writer) *Recorder {
+	return &Recorder{backend: b, start: time.Now(), w: w}
+}
+
+// Close stops recording and returns the first error that writing the trace
+// failed with.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.w = nil
+	return r.err
+}
+
+func (r *Recorder) write(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.w == nil || r.err != nil {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		r.err = err
+		return
+	}
+	_, r.err = r.w.Write(append(data, '\n'))
+}
+
+// record writes a call that started at start.
+func (r *Recorder) record(start time.Time, method string, args json.RawMessage, result any, err error) {
+	e := Entry{
+		At:       start.Sub(r.start),
+		Method:   method,
+		Args:     args,
+		Error:    EncodeError(err),
+		Duration: time.Since(start),
+	}
+	if result != nil {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			r.mu.Lock()
+			r.err = marshalErr
+			r.mu.Unlock()
+			return
+		}
+		e.Result = data
+	}
+	r.write(e)
+}
+
+func (r *Recorder) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error) {
+	start := time.Now()
+	result, err := r.backend.ListNetworks(scan)
+	r.record(start, "ListNetworks", encodeArgs(scan), networksResult{Networks: result.Networks, ScanError: EncodeError(result.ScanError)}, err)
+	return result, err
+}
+
+func (r *Recorder) ActivateNetwork(ssid string) error {
+	start := time.Now()
+	err := r.backend.ActivateNetwork(ssid)
+	r.record(start, "ActivateNetwork", encodeArgs(ssid), nil, err)
+	return err
+}
+
+func (r *Recorder) ActivateAccessPoint(ssid, bssid string) error {
+	start := time.Now()
+	err := r.backend.ActivateAccessPoint(ssid, bssid)
+	r.record(start, "ActivateAccessPoint", encodeArgs(ssid, bssid), nil, err)
+	return err
+}
+
+func (r *Recorder) ForgetNetwork(ssid string) error {
+	start := time.Now()
+	err := r.backend.ForgetNetwork(ssid)
+	r.record(start, "ForgetNetwork", encodeArgs(ssid), nil, err)
+	return err
+}
+
+func (r *Recorder) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
+	start := time.Now()
+	err := r.backend.JoinNetwork(ssid, opts)
+	r.record(start, "JoinNetwork", encodeArgs(ssid, redactJoinOptions(opts)), nil, err)
+	return err
+}
+
+func (r *Recorder) GetSecrets(ssid string) (string, error) {
+	start := time.Now()
+	secret, err := r.backend.GetSecrets(ssid)
+	r.record(start, "GetSecrets", encodeArgs(ssid), redact(secret), err)
+	return secret, err
+}
+
+func (r *Recorder) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	start := time.Now()
+	details, err := r.backend.ActiveConnection()
+	r.record(start, "ActiveConnection", nil, details, err)
+	return details, err
+}
+
+func (r *Recorder) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
+	start := time.Now()
+	err := r.backend.UpdateNetwork(ssid, opts)
+	r.record(start, "UpdateNetwork", encodeArgs(ssid, redactUpdateOptions(opts)), nil, err)
+	return err
+}
+
+func (r *Recorder) Devices() ([]wifi.Device, error) {
+	start := time.Now()
+	devices, err := r.backend.Devices()
+	r.record(start, "Devices", nil, devices, err)
+	return devices, err
+}
+
+func (r *Recorder) SelectDevice(iface string) error {
+	start := time.Now()
+	err := r.backend.SelectDevice(iface)
+	r.record(start, "SelectDevice", encodeArgs(iface), nil, err)
+	return err
+}
+
+func (r *Recorder) IsWirelessEnabled() (bool, error) {
+	start := time.Now()
+	enabled, err := r.backend.IsWirelessEnabled()
+	r.record(start, "IsWirelessEnabled", nil, enabled, err)
+	return enabled, err
+}
+
+func (r *Recorder) SetWireless(enabled bool) error {
+	start := time.Now()
+	err := r.backend.SetWireless(enabled)
+	r.record(start, "SetWireless", encodeArgs(enabled), nil, err)
+	return err
+}
+
+func (r *Recorder) Connectivity() (wifi.Connectivity, error) {
+	start := time.Now()
+	connectivity, err := r.backend.Connectivity()
+	r.record(start, "Connectivity", nil, connectivity, err)
+	return connectivity, err
+}
+
+func (r *Recorder) StartHotspot(config wifi.HotspotConfig) error {
+	start := time.Now()
+	err := r.backend.StartHotspot(config)
+	config.Password = redact(config.Password)
+	r.record(start, "StartHotspot", encodeArgs(config), nil, err)
+	return err
+}
+
+func (r *Recorder) StopHotspot() error {
+	start := time.Now()
+	err := r.backend.StopHotspot()
+	r.record(start, "StopHotspot", nil, nil, err)
+	return err
+}
+
+func (r *Recorder) HotspotStatus() (wifi.HotspotStatus, error) {
+	start := time.Now()
+	status, err := r.backend.HotspotStatus()
+	recorded := status
+	recorded.Password = redact(status.Password)
+	r.record(start, "HotspotStatus", nil, recorded, err)
+	return status, err
+}
+
+// WatchEvents records the call, and each event it delivers as an entry of
+// its own.
+func (r *Recorder) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
+	start := time.Now()
+	events, err := r.backend.WatchEvents(ctx)
+	r.mu.Lock()
+	r.watches++
+	watch := r.watches
+	r.mu.Unlock()
+	r.write(Entry{At: start.Sub(r.start), Method: "WatchEvents", Error: EncodeError(err), Duration: time.Since(start), Watch: watch})
+	if err != nil {
+		return events, err
+	}
+
+	out := make(chan wifi.Event, cap(events))
+	go func() {
+		defer close(out)
+		for e := range events {
+			r.write(Entry{At: time.Since(r.start), Method: methodEvent, Watch: watch, Event: &e})
+			select {
+			case out <- e:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, nil
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return Redacted
+}
+
+func redactJoinOptions(opts wifi.JoinOptions) wifi.JoinOptions {
+	opts.Password = redact(opts.Password)
+	if opts.Enterprise != nil {
+		enterprise := *opts.Enterprise
+		enterprise.PrivateKeyPassword = redact(enterprise.PrivateKeyPassword)
+		opts.Enterprise = &enterprise
+	}
+	return opts
+}
+
+func redactUpdateOptions(opts wifi.UpdateOptions) wifi.UpdateOptions {
+	if opts.Password != nil {
+		password := redact(*opts.Password)
+		opts.Password = &password
+	}
+	return opts
+}
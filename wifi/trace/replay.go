@@ -0,0 +1,208 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// Replayer is a wifi.Backend that answers calls from a trace. Each call gets
+// the next recorded outcome of the same method with the same arguments, and
+// the last one again once they run out, so the same calls always get the same
+// answers. Calls that were never recorded fail with wifi.ErrNotSupported.
+type Replayer struct {
+	// Speed scales the recorded duration of calls and the delays between
+	// events. At 0, nothing waits.
+	Speed float64
+
+	mu      sync.Mutex
+	calls   map[string][]Entry
+	watches []watch
+}
+
+var _ wifi.Backend = (*Replayer)(nil)
+
+// watch is a recorded WatchEvents call with the events it delivered.
+type watch struct {
+	call   Entry
+	events []Entry
+}
+
+// NewReplayer returns a backend that replays the entries of a trace in real
+// time.
+func NewReplayer(entries []Entry) *Replayer {
+	r := &Replayer{Speed: 1, calls: make(map[string][]Entry)}
+	byNumber := make(map[int]int)
+	for _, e := range entries {
+		switch e.Method {
+		case "WatchEvents":
+			byNumber[e.Watch] = len(r.watches)
+			r.watches = append(r.watches, watch{call: e})
+		case methodEvent:
+			if i, ok := byNumber[e.Watch]; ok && e.Event != nil {
+				r.watches[i].events = append(r.watches[i].events, e)
+			}
+		default:
+			key := e.Method + string(e.Args)
+			r.calls[key] = append(r.calls[key], e)
+		}
+	}
+	return r
+}
+
+func (r *Replayer) wait(d time.Duration) {
+	if r.Speed > 0 {
+		time.Sleep(time.Duration(float64(d) / r.Speed))
+	}
+}
+
+// call replays the next outcome of method with args, decoding its result
+// into result when it is not nil.
+func (r *Replayer) call(method string, args json.RawMessage, result any) error {
+	key := method + string(args)
+	r.mu.Lock()
+	queue := r.calls[key]
+	if len(queue) == 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("%s%s is not in the trace: %w", method, args, wifi.ErrNotSupported)
+	}
+	e := queue[0]
+	if len(queue) > 1 {
+		r.calls[key] = queue[1:]
+	}
+	r.mu.Unlock()
+
+	r.wait(e.Duration)
+	if result != nil && len(e.Result) > 0 {
+		if err := json.Unmarshal(e.Result, result); err != nil {
+			return fmt.Errorf("replaying %s: %w", method, err)
+		}
+	}
+	return DecodeError(e.Error)
+}
+
+func (r *Replayer) ListNetworks(scan wifi.ScanMode) (wifi.NetworksResult, error) {
+	var result networksResult
+	err := r.call("ListNetworks", encodeArgs(scan), &result)
+	return wifi.NetworksResult{Networks: result.Networks, ScanError: DecodeError(result.ScanError)}, err
+}
+
+func (r *Replayer) ActivateNetwork(ssid string) error {
+	return r.call("ActivateNetwork", encodeArgs(ssid), nil)
+}
+
+func (r *Replayer) ActivateAccessPoint(ssid, bssid string) error {
+	return r.call("ActivateAccessPoint", encodeArgs(ssid, bssid), nil)
+}
+
+func (r *Replayer) ForgetNetwork(ssid string) error {
+	return r.call("ForgetNetwork", encodeArgs(ssid), nil)
+}
+
+// JoinNetwork matches the recorded call with the same options, whatever the
+// passphrase, as those were redacted.
+func (r *Replayer) JoinNetwork(ssid string, opts wifi.JoinOptions) error {
+	return r.call("JoinNetwork", encodeArgs(ssid, redactJoinOptions(opts)), nil)
+}
+
+func (r *Replayer) GetSecrets(ssid string) (string, error) {
+	var secret string
+	err := r.call("GetSecrets", encodeArgs(ssid), &secret)
+	return secret, err
+}
+
+func (r *Replayer) ActiveConnection() (*wifi.ConnectionDetails, error) {
+	var details *wifi.ConnectionDetails
+	err := r.call("ActiveConnection", nil, &details)
+	return details, err
+}
+
+func (r *Replayer) UpdateNetwork(ssid string, opts wifi.UpdateOptions) error {
+	return r.call("UpdateNetwork", encodeArgs(ssid, redactUpdateOptions(opts)), nil)
+}
+
+func (r *Replayer) Devices() ([]wifi.Device, error) {
+	var devices []wifi.Device
+	err := r.call("Devices", nil, &devices)
+	return devices, err
+}
+
+func (r *Replayer) SelectDevice(iface string) error {
+	return r.call("SelectDevice", encodeArgs(iface), nil)
+}
+
+func (r *Replayer) IsWirelessEnabled() (bool, error) {
+	var enabled bool
+	err := r.call("IsWirelessEnabled", nil, &enabled)
+	return enabled, err
+}
+
+func (r *Replayer) SetWireless(enabled bool) error {
+	return r.call("SetWireless", encodeArgs(enabled), nil)
+}
+
+func (r *Replayer) Connectivity() (wifi.Connectivity, error) {
+	var connectivity wifi.Connectivity
+	err := r.call("Connectivity", nil, &connectivity)
+	return connectivity, err
+}
+
+func (r *Replayer) StartHotspot(config wifi.HotspotConfig) error {
+	config.Password = redact(config.Password)
+	return r.call("StartHotspot", encodeArgs(config), nil)
+}
+
+func (r *Replayer) StopHotspot() error {
+	return r.call("StopHotspot", nil, nil)
+}
+
+func (r *Replayer) HotspotStatus() (wifi.HotspotStatus, error) {
+	var status wifi.HotspotStatus
+	err := r.call("HotspotStatus", nil, &status)
+	return status, err
+}
+
+// WatchEvents replays the events of the next recorded WatchEvents call, at
+// the same delays after the call. The channel stays open until ctx is
+// cancelled, without further events once the recorded calls run out.
+func (r *Replayer) WatchEvents(ctx context.Context) (<-chan wifi.Event, error) {
+	r.mu.Lock()
+	var w watch
+	if len(r.watches) > 0 {
+		w = r.watches[0]
+		r.watches = r.watches[1:]
+	}
+	r.mu.Unlock()
+	if err := DecodeError(w.call.Error); err != nil {
+		return nil, err
+	}
+
+	events := make(chan wifi.Event, 16)
+	go func() {
+		defer close(events)
+		start := time.Now()
+		for _, e := range w.events {
+			if r.Speed > 0 {
+				delay := time.Duration(float64(e.At-w.call.At)/r.Speed) - time.Since(start)
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return
+				}
+			}
+			event := *e.Event
+			event.Time = time.Now()
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return events, nil
+}
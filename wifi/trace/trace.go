@@ -0,0 +1,234 @@
+// Package trace records the calls made to a wifi.Backend and replays them, so
+// a bug seen on someone else's hardware can be reproduced from a file they
+// send.
+//
+// A trace is a file of JSON lines, one Entry per call or event. Secrets are
+// redacted before they are written.
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/shazow/wifitui/wifi"
+)
+
+// Redacted replaces passphrases and other secrets in a trace.
+const Redacted = "REDACTED"
+
+// methodEvent is the method of entries for events received from WatchEvents.
+const methodEvent = "Event"
+
+// Entry is a line of a trace: a backend call with its arguments and outcome,
+// or an event.
+type Entry struct {
+	// At is when the call started or the event arrived, since the start of
+	// the recording.
+	At time.Duration `json:"at"`
+	// Method is the name of the wifi.Backend method, or "Event".
+	Method string `json:"method"`
+	// Args are the arguments of the call, as a JSON array.
+	Args json.RawMessage `json:"args,omitempty"`
+	// Result is the first return value of the call, if it has one besides
+	// the error.
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    *Error          `json:"error,omitempty"`
+	Duration time.Duration   `json:"duration,omitempty"`
+	// Watch numbers the WatchEvents calls from 1. Events refer to the call
+	// that received them.
+	Watch int         `json:"watch,omitempty"`
+	Event *wifi.Event `json:"event,omitempty"`
+}
+
+// Error is a recorded error. It keeps the wifi errors that the original
+// matched with errors.Is, and the scan failure it wrapped, so that callers
+// handle the replayed error the same way.
+type Error struct {
+	Message     string       `json:"message"`
+	Is          []string     `json:"is,omitempty"`
+	ScanFailure *ScanFailure `json:"scan_failure,omitempty"`
+}
+
+// ScanFailure is a recorded wifi.ScanFailure.
+type ScanFailure struct {
+	Backend string         `json:"backend,omitempty"`
+	Stage   wifi.ScanStage `json:"stage,omitempty"`
+	Device  string         `json:"device,omitempty"`
+	Code    string         `json:"code,omitempty"`
+	Cause   *Error         `json:"cause,omitempty"`
+}
+
+// networksResult is the recorded form of wifi.NetworksResult, whose scan
+// error does not encode by itself.
+type networksResult struct {
+	Networks  []wifi.Network `json:"networks"`
+	ScanError *Error         `json:"scan_error,omitempty"`
+}
+
+// sentinel is an error that callers test for, with its name in traces.
+type sentinel struct {
+	name string
+	err  error
+}
+
+var sentinels = []sentinel{
+	{"ErrIncorrectPassphrase", wifi.ErrIncorrectPassphrase},
+	{"ErrWirelessDisabled", wifi.ErrWirelessDisabled},
+	{"ErrNotFound", wifi.ErrNotFound},
+	{"ErrNotAvailable", wifi.ErrNotAvailable},
+	{"ErrOperationFailed", wifi.ErrOperationFailed},
+	{"ErrNotSupported", wifi.ErrNotSupported},
+	{"ErrAccessPointMismatch", wifi.ErrAccessPointMismatch},
+	{"ErrInvalidCredentials", wifi.ErrInvalidCredentials},
+	{"ErrInvalidIPConfig", wifi.ErrInvalidIPConfig},
+	{"ErrInvalidBSSID", wifi.ErrInvalidBSSID},
+	{"ErrInvalidBand", wifi.ErrInvalidBand},
+	{"ErrInvalidPriority", wifi.ErrInvalidPriority},
+	{"ErrMissingPermission", wifi.ErrMissingPermission},
+	{"ErrScanDeviceUnavailable", wifi.ErrScanDeviceUnavailable},
+	{"ErrScanPermissionDenied", wifi.ErrScanPermissionDenied},
+	{"ErrScanAuthRequired", wifi.ErrScanAuthRequired},
+	{"ErrScanTimeout", wifi.ErrScanTimeout},
+	{"ErrScanProtocol", wifi.ErrScanProtocol},
+}
+
+// EncodeError records err, or returns nil if it is nil.
+func EncodeError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{Message: err.Error()}
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			e.Is = append(e.Is, s.name)
+		}
+	}
+	var failure *wifi.ScanFailure
+	if errors.As(err, &failure) {
+		e.ScanFailure = &ScanFailure{
+			Backend: failure.Backend,
+			Stage:   failure.Stage,
+			Device:  failure.Device,
+			Code:    failure.Code,
+			Cause:   EncodeError(failure.Cause),
+		}
+	}
+	return e
+}
+
+// DecodeError returns an error with the message of a recorded one, which
+// matches the same wifi errors and unwraps to its scan failure. It returns nil
+// if e is nil.
+func DecodeError(e *Error) error {
+	if e == nil {
+		return nil
+	}
+	replayed := &replayedError{message: e.Message}
+	if f := e.ScanFailure; f != nil {
+		failure := &wifi.ScanFailure{
+			Backend: f.Backend,
+			Stage:   f.Stage,
+			Device:  f.Device,
+			Code:    f.Code,
+			Cause:   DecodeError(f.Cause),
+		}
+		if failure.Error() == e.Message {
+			return failure
+		}
+		replayed.failure = failure
+	}
+	for _, name := range e.Is {
+		i := slices.IndexFunc(sentinels, func(s sentinel) bool { return s.name == name })
+		if i >= 0 {
+			replayed.is = append(replayed.is, sentinels[i].err)
+		}
+	}
+	return replayed
+}
+
+// replayedError has the message of a recorded error and matches the same
+// wifi errors.
+type replayedError struct {
+	message string
+	is      []error
+	failure *wifi.ScanFailure
+}
+
+func (e *replayedError) Error() string {
+	return e.message
+}
+
+func (e *replayedError) Is(target error) bool {
+	return slices.Contains(e.is, target)
+}
+
+func (e *replayedError) Unwrap() error {
+	if e.failure == nil {
+		return nil
+	}
+	return e.failure
+}
+
+// encodeArgs encodes call arguments as a JSON array.
+func encodeArgs(args ...any) json.RawMessage {
+	if len(args) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		// Arguments are plain wifi types, which always encode.
+		panic(err)
+	}
+	return data
+}
+
+// Read parses the entries of a trace.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", line, err)
+		}
+		if len(e.Args) > 0 {
+			// Arguments are matched as text, so they must be compact like
+			// the ones the replayer encodes.
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, e.Args); err != nil {
+				return nil, fmt.Errorf("trace line %d: %w", line, err)
+			}
+			e.Args = compact.Bytes()
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Load opens a trace file for replay.
+func Load(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewReplayer(entries), nil
+}
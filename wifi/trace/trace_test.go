@@ -0,0 +1,187 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shazow/wifitui/wifi"
+	"github.com/shazow/wifitui/wifi/mock"
+)
+
+func newMock(t *testing.T) *mock.MockBackend {
+	t.Helper()
+	b, err := mock.New()
+	if err != nil {
+		t.Fatalf("mock.New() failed: %v", err)
+	}
+	m := b.(*mock.MockBackend)
+	m.ActionSleep = 0
+	m.DisableRandomization = true
+	return m
+}
+
+// session makes the calls of a short session, returning their outcomes as
+// text to compare recordings and replays.
+func session(t *testing.T, b wifi.Backend) []string {
+	t.Helper()
+	var outcomes []string
+	add := func(result any, err error) {
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			t.Fatalf("json.Marshal() failed: %v", marshalErr)
+		}
+		outcomes = append(outcomes, fmt.Sprintf("%s %v", data, err))
+	}
+
+	result, err := b.ListNetworks(wifi.ScanAuto)
+	add(result.Networks, err)
+	add(result.ScanError, nil)
+	add(nil, b.ActivateNetwork("Password is password"))
+	add(nil, b.ActivateNetwork("Nowhere"))
+	add(nil, b.JoinNetwork("Dunder MiffLAN", wifi.JoinOptions{Password: "bears beets", Security: wifi.SecuritySAE}))
+	add(b.GetSecrets("Dunder MiffLAN"))
+	add(b.ActiveConnection())
+	add(b.IsWirelessEnabled())
+	add(nil, b.StartHotspot(wifi.HotspotConfig{SSID: "Share", Password: "sharing is caring"}))
+	add(b.HotspotStatus())
+	return outcomes
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	m := newMock(t)
+	m.ScanFailures = []*wifi.ScanFailure{{
+		Backend: "mock",
+		Stage:   wifi.ScanStageRequest,
+		Device:  "wlan0",
+		Code:    "NotAllowed",
+		Cause:   fmt.Errorf("%w: %w", wifi.ErrScanDeviceUnavailable, errors.New("device busy")),
+	}}
+	var buf bytes.Buffer
+	recorder := NewRecorder(m, &buf)
+	recorded := session(t, recorder)
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	for _, secret := range []string{"bears beets", "sharing is caring"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("trace contains the secret %q", secret)
+		}
+	}
+
+	entries, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	replayer := NewReplayer(entries)
+	replayer.Speed = 0
+
+	// Replayed passphrases are redacted, and any passphrase matches.
+	want := recorded
+	want[5] = fmt.Sprintf("%q <nil>", Redacted)
+	want[9] = strings.Replace(want[9], "sharing is caring", Redacted, 1)
+	replayed := session(t, replayer)
+	for i := range want {
+		if replayed[i] != want[i] {
+			t.Errorf("outcome %d:\nreplayed %s\nrecorded %s", i, replayed[i], want[i])
+		}
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	m := newMock(t)
+	m.ScanFailures = []*wifi.ScanFailure{{
+		Backend: "mock",
+		Stage:   wifi.ScanStageCompletion,
+		Cause:   fmt.Errorf("%w: no reply", wifi.ErrScanTimeout),
+	}}
+	m.JoinError = fmt.Errorf("activating: %w", wifi.ErrIncorrectPassphrase)
+	m.IsWirelessEnabledError = fmt.Errorf("checking radio: %w", &wifi.ScanFailure{Stage: wifi.ScanStageSetup, Cause: wifi.ErrMissingPermission})
+
+	var buf bytes.Buffer
+	recorder := NewRecorder(m, &buf)
+	recorder.ListNetworks(wifi.ScanForce)
+	recorder.JoinNetwork("Home", wifi.JoinOptions{Password: "wrong"})
+	recorder.IsWirelessEnabled()
+	entries, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	replayer := NewReplayer(entries)
+	replayer.Speed = 0
+
+	result, err := replayer.ListNetworks(wifi.ScanForce)
+	if err != nil {
+		t.Fatalf("ListNetworks() failed: %v", err)
+	}
+	var failure *wifi.ScanFailure
+	if !errors.As(result.ScanError, &failure) || failure.Stage != wifi.ScanStageCompletion || !errors.Is(result.ScanError, wifi.ErrScanTimeout) {
+		t.Errorf("replayed scan error %#v lost its classification", result.ScanError)
+	}
+	if got, want := result.ScanError.Error(), "mock completion: scan timed out: no reply"; got != want {
+		t.Errorf("scan error = %q, want %q", got, want)
+	}
+
+	err = replayer.JoinNetwork("Home", wifi.JoinOptions{Password: "another"})
+	if !errors.Is(err, wifi.ErrIncorrectPassphrase) || err.Error() != "activating: incorrect passphrase" {
+		t.Errorf("JoinNetwork() = %v, want the recorded ErrIncorrectPassphrase", err)
+	}
+	// The last outcome repeats.
+	if err := replayer.JoinNetwork("Home", wifi.JoinOptions{Password: "another"}); !errors.Is(err, wifi.ErrIncorrectPassphrase) {
+		t.Errorf("repeated JoinNetwork() = %v", err)
+	}
+
+	_, err = replayer.IsWirelessEnabled()
+	if !errors.As(err, &failure) || !errors.Is(err, wifi.ErrMissingPermission) || failure.Stage != wifi.ScanStageSetup {
+		t.Errorf("IsWirelessEnabled() = %v, want a wrapped scan failure", err)
+	}
+
+	if err := replayer.ActivateNetwork("Home"); !errors.Is(err, wifi.ErrNotSupported) {
+		t.Errorf("unrecorded ActivateNetwork() = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestReplayEvents(t *testing.T) {
+	m := newMock(t)
+	var buf bytes.Buffer
+	recorder := NewRecorder(m, &buf)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := recorder.WatchEvents(ctx)
+	if err != nil {
+		t.Fatalf("WatchEvents() failed: %v", err)
+	}
+	recorder.SetWireless(false)
+	if e := <-events; e.Type != wifi.EventRadioToggled {
+		t.Fatalf("recorded event %+v, want a radio toggle", e)
+	}
+	cancel()
+	for range events {
+	}
+	recorder.Close()
+
+	entries, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	replayer := NewReplayer(entries)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	replayed, err := replayer.WatchEvents(ctx)
+	if err != nil {
+		t.Fatalf("WatchEvents() failed: %v", err)
+	}
+	select {
+	case e := <-replayed:
+		if e.Type != wifi.EventRadioToggled || e.RadioEnabled {
+			t.Errorf("replayed event %+v, want the radio turning off", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the replayed event")
+	}
+}